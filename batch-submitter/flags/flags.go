@@ -42,7 +42,7 @@ var (
 	}
 	TssClientUrl = cli.StringFlag{
 		Name:     "tss-client-url",
-		Usage:    "HTTP provider URL for tss",
+		Usage:    "HTTP provider URL for tss, comma separated to fail over between the active/standby managers",
 		Required: true,
 		EnvVar:   "TSS_CLIENT_RPC",
 	}
@@ -1,20 +1,32 @@
 package tss_client
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/go-resty/resty/v2"
 	"github.com/mantlenetworkio/mantle/tss/common"
-	"github.com/syndtr/goleveldb/leveldb/errors"
 )
 
 var errTssHTTPError = errors.New("tss http error")
 
 type TssClient interface {
 	GetSignStateBatch(BatchData common.SignStateRequest) ([]byte, error)
+	GetSignTxBatch(BatchData common.SignTxBatchRequest) ([]byte, error)
 }
 
+// Client fails over between the tss managers of an active/standby
+// deployment. Only the leader listens, so a request is sent to the next
+// manager when the current one can not be reached.
 type Client struct {
-	client *resty.Client
+	clients []*resty.Client
+	urls    []string
+
+	lock    sync.Mutex
+	current int
 }
 
 type TssResponse struct {
@@ -22,29 +34,63 @@ type TssResponse struct {
 	RollBack  bool   `json:"roll_back"`
 }
 
-func NewClient(url string) *Client {
-	client := resty.New()
-	client.SetHostURL(url)
-	client.OnAfterResponse(func(c *resty.Client, r *resty.Response) error {
-		statusCode := r.StatusCode()
-		if statusCode >= 400 {
-			method := r.Request.Method
-			url := r.Request.URL
-			return fmt.Errorf("%d cannot %s %s: %w", statusCode, method, url, errTssHTTPError)
+// NewClient returns a client of the comma separated manager urls.
+func NewClient(urls string) *Client {
+	c := &Client{}
+	for _, url := range strings.Split(urls, ",") {
+		url = strings.TrimSpace(url)
+		if len(url) == 0 {
+			continue
+		}
+		client := resty.New()
+		client.SetHostURL(url)
+		client.OnAfterResponse(func(c *resty.Client, r *resty.Response) error {
+			statusCode := r.StatusCode()
+			if statusCode >= 400 {
+				method := r.Request.Method
+				url := r.Request.URL
+				return fmt.Errorf("%d cannot %s %s: %w", statusCode, method, url, errTssHTTPError)
+			}
+			return nil
+		})
+		c.clients = append(c.clients, client)
+		c.urls = append(c.urls, url)
+	}
+	return c
+}
+
+// post sends the request to the current manager, and to the next ones when
+// it can not be reached. An error response of a manager is returned as is.
+func (c *Client) post(path string, body interface{}) (*resty.Response, error) {
+	if len(c.clients) == 0 {
+		return nil, errors.New("no tss manager url")
+	}
+	c.lock.Lock()
+	current := c.current
+	c.lock.Unlock()
+
+	var err error
+	for i := 0; i < len(c.clients); i++ {
+		index := (current + i) % len(c.clients)
+		var response *resty.Response
+		response, err = c.clients[index].R().SetBody(body).Post(path)
+		if err == nil || errors.Is(err, errTssHTTPError) {
+			if index != current {
+				log.Info("failed over to another tss manager", "url", c.urls[index])
+				c.lock.Lock()
+				c.current = index
+				c.lock.Unlock()
+			}
+			return response, err
 		}
-		return nil
-	})
-	return &Client{
-		client: client,
+		log.Warn("tss manager unreachable", "url", c.urls[index], "err", err)
 	}
+	return nil, err
 }
 
 func (c *Client) GetSignStateBatch(BatchData common.SignStateRequest) ([]byte, error) {
-	var signature []byte
-	response, err := c.client.R().
-		SetBody(map[string]interface{}{"start_block": BatchData.StartBlock, "offset_starts_at_index": BatchData.OffsetStartsAtIndex, "state_roots": BatchData.StateRoots}).
-		SetResult(signature).
-		Post("/api/v1/sign/state")
+	response, err := c.post("/api/v1/sign/state",
+		map[string]interface{}{"start_block": BatchData.StartBlock, "offset_starts_at_index": BatchData.OffsetStartsAtIndex, "state_roots": BatchData.StateRoots})
 	if err != nil {
 		return nil, fmt.Errorf("cannot get signature: %w", err)
 	}
@@ -54,3 +100,16 @@ func (c *Client) GetSignStateBatch(BatchData common.SignStateRequest) ([]byte, e
 		return nil, errors.New("fetch tss manager signature faill")
 	}
 }
+
+func (c *Client) GetSignTxBatch(BatchData common.SignTxBatchRequest) ([]byte, error) {
+	response, err := c.post("/api/v1/sign/tx",
+		map[string]interface{}{"start_block": BatchData.StartBlock, "should_start_at_element": BatchData.ShouldStartAtElement, "tx_hashes": BatchData.TxHashes})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get tx batch signature: %w", err)
+	}
+	if response.StatusCode() == 200 {
+		return response.Body(), nil
+	} else {
+		return nil, errors.New("fetch tss manager tx batch signature fail")
+	}
+}
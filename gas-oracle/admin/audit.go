@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// AuditEntry records a single change made through the admin API
+type AuditEntry struct {
+	Time     time.Time  `json:"time"`
+	Remote   string     `json:"remote,omitempty"`
+	Operator string     `json:"operator,omitempty"`
+	Action   string     `json:"action"`
+	Target   string     `json:"target"`
+	Value    string     `json:"value,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// AuditLog appends every change to a file as one JSON object per line.
+// Every entry is also logged.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewAuditLog opens the audit log at path for appending. When path is
+// empty, changes are only logged.
+func NewAuditLog(path string) (*AuditLog, error) {
+	if path == "" {
+		return &AuditLog{}, nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{file: file}, nil
+}
+
+// Record writes an entry to the audit log
+func (a *AuditLog) Record(entry AuditEntry) error {
+	log.Info("admin change", "action", entry.Action, "target", entry.Target, "value", entry.Value,
+		"until", entry.Until, "operator", entry.Operator, "remote", entry.Remote, "reason", entry.Reason)
+	if a.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the audit log file, after the entries being written
+func (a *AuditLog) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
@@ -0,0 +1,299 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// LoopL2GasPrice is the loop that updates the L2 gas price
+	LoopL2GasPrice = "l2-gas-price"
+	// LoopL1BaseFee is the loop that updates the L1 base fee
+	LoopL1BaseFee = "l1-base-fee"
+)
+
+const (
+	ActionPause         = "pause"
+	ActionResume        = "resume"
+	ActionOverride      = "override"
+	ActionClearOverride = "clear-override"
+	ActionExpire        = "expire"
+)
+
+var (
+	// errUnknownLoop represents the error when a request names a loop
+	// that does not exist
+	errUnknownLoop = errors.New("unknown loop")
+	// errInvalidValue represents the error when an override value is
+	// not a positive integer
+	errInvalidValue = errors.New("invalid override value")
+)
+
+// control is a pause or an override of a loop. A zero until means that
+// the control does not expire.
+type control struct {
+	value *big.Int
+	until time.Time
+	timer *time.Timer
+}
+
+func (c *control) expired(now time.Time) bool {
+	return !c.until.IsZero() && !now.Before(c.until)
+}
+
+// Status describes a pause or an override that is in effect
+type Status struct {
+	Value *big.Int   `json:"value,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Controls holds the pauses and overrides set by operators. Timed controls
+// expire on their own and their expiry is recorded in the audit log. Every
+// change of a loop is signaled on its Changed channel so that the loop
+// applies it right away instead of on its next epoch.
+type Controls struct {
+	mu        sync.Mutex
+	paused    map[string]*control
+	overrides map[string]*control
+	changed   map[string]chan struct{}
+	audit     *AuditLog
+	now       func() time.Time
+}
+
+// NewControls creates Controls that record changes to the audit log
+func NewControls(audit *AuditLog) *Controls {
+	return &Controls{
+		paused:    make(map[string]*control),
+		overrides: make(map[string]*control),
+		changed: map[string]chan struct{}{
+			LoopL2GasPrice: make(chan struct{}, 1),
+			LoopL1BaseFee:  make(chan struct{}, 1),
+		},
+		audit: audit,
+		now:   time.Now,
+	}
+}
+
+func validLoop(loop string) error {
+	if loop != LoopL2GasPrice && loop != LoopL1BaseFee {
+		return fmt.Errorf("%w: %s", errUnknownLoop, loop)
+	}
+	return nil
+}
+
+// Changed returns a channel that receives a value when a control of the
+// loop is set, removed or expires
+func (c *Controls) Changed(loop string) <-chan struct{} {
+	return c.changed[loop]
+}
+
+// notify signals a change of the loop without blocking, a pending signal
+// already covers the change. Must be called with the lock held.
+func (c *Controls) notify(loop string) {
+	select {
+	case c.changed[loop] <- struct{}{}:
+	default:
+	}
+}
+
+// Paused returns true when the loop is paused
+func (c *Controls) Paused(loop string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active(c.paused, loop) != nil
+}
+
+// Override returns the value that the loop must use instead of the
+// computed one, if any
+func (c *Controls) Override(loop string) (*big.Int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctl := c.active(c.overrides, loop)
+	if ctl == nil {
+		return nil, false
+	}
+	return new(big.Int).Set(ctl.value), true
+}
+
+// active returns the control of the loop unless it is not set or it has
+// expired. Expired controls are removed. Must be called with the lock held.
+func (c *Controls) active(controls map[string]*control, loop string) *control {
+	ctl, ok := controls[loop]
+	if !ok {
+		return nil
+	}
+	if ctl.expired(c.now()) {
+		c.expire(controls, loop, ctl)
+		return nil
+	}
+	return ctl
+}
+
+// expire removes a control that has expired. Must be called with the lock
+// held.
+func (c *Controls) expire(controls map[string]*control, loop string, ctl *control) {
+	ctl.stop()
+	delete(controls, loop)
+	action := ActionPause
+	if ctl.value != nil {
+		action = ActionOverride
+	}
+	c.record(AuditEntry{Action: ActionExpire, Target: loop, Value: action})
+	c.notify(loop)
+}
+
+// set replaces the control of the loop, and removes it once it expires
+// without waiting for the loop to look at it. Must be called with the lock
+// held.
+func (c *Controls) set(controls map[string]*control, loop string, ctl *control) {
+	if old, ok := controls[loop]; ok {
+		old.stop()
+	}
+	controls[loop] = ctl
+	if !ctl.until.IsZero() {
+		ctl.timer = time.AfterFunc(ctl.until.Sub(c.now()), func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if controls[loop] == ctl {
+				c.expire(controls, loop, ctl)
+			}
+		})
+	}
+	c.notify(loop)
+}
+
+// remove removes the control of the loop. Must be called with the lock
+// held.
+func (c *Controls) remove(controls map[string]*control, loop string) {
+	if ctl, ok := controls[loop]; ok {
+		ctl.stop()
+		delete(controls, loop)
+	}
+	c.notify(loop)
+}
+
+func (ctl *control) stop() {
+	if ctl.timer != nil {
+		ctl.timer.Stop()
+	}
+}
+
+// Pause pauses a loop for the duration, or until it is resumed when the
+// duration is zero
+func (c *Controls) Pause(loop string, duration time.Duration, entry AuditEntry) error {
+	if err := validLoop(loop); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctl := &control{until: c.until(duration)}
+	entry.Action, entry.Target, entry.Until = ActionPause, loop, untilPtr(ctl.until)
+	if err := c.record(entry); err != nil {
+		return err
+	}
+	c.set(c.paused, loop, ctl)
+	return nil
+}
+
+// Resume resumes a paused loop
+func (c *Controls) Resume(loop string, entry AuditEntry) error {
+	if err := validLoop(loop); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.Action, entry.Target = ActionResume, loop
+	if err := c.record(entry); err != nil {
+		return err
+	}
+	c.remove(c.paused, loop)
+	return nil
+}
+
+// SetOverride makes the loop use value for the duration, or until it is
+// cleared when the duration is zero
+func (c *Controls) SetOverride(loop string, value *big.Int, duration time.Duration, entry AuditEntry) error {
+	if err := validLoop(loop); err != nil {
+		return err
+	}
+	if value == nil || value.Sign() <= 0 {
+		return errInvalidValue
+	}
+	if loop == LoopL2GasPrice && !value.IsUint64() {
+		return errInvalidValue
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctl := &control{value: new(big.Int).Set(value), until: c.until(duration)}
+	entry.Action, entry.Target, entry.Value, entry.Until = ActionOverride, loop, value.String(), untilPtr(ctl.until)
+	if err := c.record(entry); err != nil {
+		return err
+	}
+	c.set(c.overrides, loop, ctl)
+	return nil
+}
+
+// ClearOverride removes the override of a loop
+func (c *Controls) ClearOverride(loop string, entry AuditEntry) error {
+	if err := validLoop(loop); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.Action, entry.Target = ActionClearOverride, loop
+	if err := c.record(entry); err != nil {
+		return err
+	}
+	c.remove(c.overrides, loop)
+	return nil
+}
+
+// Status returns the pauses and the overrides that are in effect
+func (c *Controls) Status() (map[string]Status, map[string]Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	paused := make(map[string]Status)
+	overrides := make(map[string]Status)
+	for _, loop := range []string{LoopL2GasPrice, LoopL1BaseFee} {
+		if ctl := c.active(c.paused, loop); ctl != nil {
+			paused[loop] = Status{Until: untilPtr(ctl.until)}
+		}
+		if ctl := c.active(c.overrides, loop); ctl != nil {
+			overrides[loop] = Status{Value: new(big.Int).Set(ctl.value), Until: untilPtr(ctl.until)}
+		}
+	}
+	return paused, overrides
+}
+
+func (c *Controls) until(duration time.Duration) time.Time {
+	if duration <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(duration)
+}
+
+// record writes to the audit log. Changes are only applied once they have
+// been recorded. Must be called with the lock held.
+func (c *Controls) record(entry AuditEntry) error {
+	entry.Time = c.now()
+	if c.audit == nil {
+		return nil
+	}
+	if err := c.audit.Record(entry); err != nil {
+		log.Error("cannot write audit log", "message", err)
+		return err
+	}
+	return nil
+}
+
+func untilPtr(until time.Time) *time.Time {
+	if until.IsZero() {
+		return nil
+	}
+	return &until
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestControlsPauseAndResume(t *testing.T) {
+	controls := NewControls(nil)
+	if controls.Paused(LoopL2GasPrice) {
+		t.Fatal("expected loop not to be paused")
+	}
+	if err := controls.Pause(LoopL2GasPrice, 0, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	if !controls.Paused(LoopL2GasPrice) {
+		t.Fatal("expected loop to be paused")
+	}
+	if controls.Paused(LoopL1BaseFee) {
+		t.Fatal("expected other loop not to be paused")
+	}
+	if err := controls.Resume(LoopL2GasPrice, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	if controls.Paused(LoopL2GasPrice) {
+		t.Fatal("expected loop to be resumed")
+	}
+	if err := controls.Pause("unknown", 0, AuditEntry{}); err == nil {
+		t.Fatal("expected unknown loop to be rejected")
+	}
+}
+
+func TestControlsOverrideExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	now := time.Unix(1660000000, 0)
+	controls := NewControls(audit)
+	controls.now = func() time.Time { return now }
+
+	err = controls.SetOverride(LoopL1BaseFee, big.NewInt(1000), time.Minute, AuditEntry{Operator: "alice", Reason: "incident"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, ok := controls.Override(LoopL1BaseFee)
+	if !ok || value.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("expected override of 1000, got %v", value)
+	}
+	_, overrides := controls.Status()
+	if status, ok := overrides[LoopL1BaseFee]; !ok || !status.Until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected status %v", overrides)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := controls.Override(LoopL1BaseFee); ok {
+		t.Fatal("expected override to expire")
+	}
+
+	// Both the change and its expiry are in the audit log
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(entries))
+	}
+	if entries[0].Action != ActionOverride || entries[0].Value != "1000" || entries[0].Operator != "alice" {
+		t.Fatalf("unexpected audit entry %+v", entries[0])
+	}
+	if entries[1].Action != ActionExpire || entries[1].Target != LoopL1BaseFee {
+		t.Fatalf("unexpected audit entry %+v", entries[1])
+	}
+}
+
+func TestControlsRejectsInvalidOverride(t *testing.T) {
+	controls := NewControls(nil)
+	if err := controls.SetOverride(LoopL2GasPrice, big.NewInt(0), 0, AuditEntry{}); err == nil {
+		t.Fatal("expected zero override to be rejected")
+	}
+	tooLarge := new(big.Int).Lsh(big.NewInt(1), 64)
+	if err := controls.SetOverride(LoopL2GasPrice, tooLarge, 0, AuditEntry{}); err == nil {
+		t.Fatal("expected override larger than uint64 to be rejected")
+	}
+	if err := controls.SetOverride(LoopL1BaseFee, tooLarge, 0, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestControlsApplyChangesRightAway(t *testing.T) {
+	controls := NewControls(nil)
+	changed := controls.Changed(LoopL2GasPrice)
+
+	if err := controls.SetOverride(LoopL2GasPrice, big.NewInt(1000), 50*time.Millisecond, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expected the override to be signaled")
+	}
+
+	// the override expires without anybody asking for it
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the expiry to be signaled")
+	}
+	controls.mu.Lock()
+	_, ok := controls.overrides[LoopL2GasPrice]
+	controls.mu.Unlock()
+	if ok {
+		t.Fatal("expected the override to be removed on expiry")
+	}
+
+	// a replaced override does not expire with the timer of the old one
+	if err := controls.SetOverride(LoopL2GasPrice, big.NewInt(1000), 50*time.Millisecond, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := controls.SetOverride(LoopL2GasPrice, big.NewInt(2000), 0, AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if value, ok := controls.Override(LoopL2GasPrice); !ok || value.Cmp(big.NewInt(2000)) != 0 {
+		t.Fatalf("expected override of 2000, got %v", value)
+	}
+	if controls.Changed(LoopL1BaseFee) == nil || len(controls.Changed(LoopL1BaseFee)) != 0 {
+		t.Fatal("expected the other loop not to be signaled")
+	}
+}
@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Preview is the result of a dry run of both loops
+type Preview struct {
+	L2GasPrice *GasPricePreview  `json:"l2_gas_price,omitempty"`
+	L1BaseFee  *L1BaseFeePreview `json:"l1_base_fee,omitempty"`
+}
+
+// GasPricePreview is the L2 gas price that would be set if the current
+// epoch ended now
+type GasPricePreview struct {
+	Current         uint64  `json:"current"`
+	Computed        uint64  `json:"computed"`
+	AvgGasPerSecond float64 `json:"avg_gas_per_second"`
+	Next            uint64  `json:"next"`
+	WouldUpdate     bool    `json:"would_update"`
+	Paused          bool    `json:"paused"`
+	Overridden      bool    `json:"overridden"`
+}
+
+// L1BaseFeePreview is the L1 base fee that would be set by the next tick
+type L1BaseFeePreview struct {
+	Current     *big.Int `json:"current"`
+	Next        *big.Int `json:"next"`
+	WouldUpdate bool     `json:"would_update"`
+	Paused      bool     `json:"paused"`
+	Overridden  bool     `json:"overridden"`
+}
+
+// Previewer computes a Preview without sending any transaction
+type Previewer interface {
+	Preview(ctx context.Context) (*Preview, error)
+}
+
+// request is the body of the requests that change the controls
+type request struct {
+	Loop            string `json:"loop"`
+	Value           string `json:"value"`
+	DurationSeconds uint64 `json:"duration_seconds"`
+	Operator        string `json:"operator"`
+	Reason          string `json:"reason"`
+}
+
+type statusResponse struct {
+	Paused    map[string]Status `json:"paused"`
+	Overrides map[string]Status `json:"overrides"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is an HTTP server that lets operators pause the loops, override
+// the values they set and preview the next values. Every request must
+// carry the configured token as a bearer token.
+type Server struct {
+	addr      string
+	token     string
+	controls  *Controls
+	previewer Previewer
+	server    *http.Server
+}
+
+// NewServer creates a Server listening on addr
+func NewServer(addr string, token string, controls *Controls, previewer Previewer) *Server {
+	return &Server{
+		addr:      addr,
+		token:     token,
+		controls:  controls,
+		previewer: previewer,
+	}
+}
+
+// Handler returns the HTTP handler of the admin API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/preview", s.handlePreview)
+	mux.HandleFunc("/pause", s.handleChange(func(req *request, duration time.Duration, entry AuditEntry) error {
+		return s.controls.Pause(req.Loop, duration, entry)
+	}))
+	mux.HandleFunc("/resume", s.handleChange(func(req *request, duration time.Duration, entry AuditEntry) error {
+		return s.controls.Resume(req.Loop, entry)
+	}))
+	mux.HandleFunc("/override", s.handleChange(func(req *request, duration time.Duration, entry AuditEntry) error {
+		value, ok := new(big.Int).SetString(req.Value, 10)
+		if !ok {
+			return errInvalidValue
+		}
+		return s.controls.SetOverride(req.Loop, value, duration, entry)
+	}))
+	mux.HandleFunc("/override/clear", s.handleChange(func(req *request, duration time.Duration, entry AuditEntry) error {
+		return s.controls.ClearOverride(req.Loop, entry)
+	}))
+	return s.authenticate(mux)
+}
+
+// Start starts serving the admin API in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Info("Starting admin server", "addr", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Failure in running admin server", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops the admin server
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			log.Warn("unauthorized admin request", "remote", r.RemoteAddr, "path", r.URL.Path)
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	paused, overrides := s.controls.Status()
+	writeJSON(w, http.StatusOK, statusResponse{Paused: paused, Overrides: overrides})
+}
+
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+	preview, err := s.previewer.Preview(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+func (s *Server) handleChange(change func(*request, time.Duration, AuditEntry) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+		var req request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		entry := AuditEntry{
+			Remote:   r.RemoteAddr,
+			Operator: req.Operator,
+			Reason:   req.Reason,
+		}
+		duration := time.Duration(req.DurationSeconds) * time.Second
+		if err := change(&req, duration, entry); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errUnknownLoop) || errors.Is(err, errInvalidValue) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, errorResponse{Error: err.Error()})
+			return
+		}
+		paused, overrides := s.controls.Status()
+		writeJSON(w, http.StatusOK, statusResponse{Paused: paused, Overrides: overrides})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("cannot write admin response", "message", err)
+	}
+}
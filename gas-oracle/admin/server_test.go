@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mockPreviewer struct{}
+
+func (m *mockPreviewer) Preview(ctx context.Context) (*Preview, error) {
+	return &Preview{
+		L2GasPrice: &GasPricePreview{Current: 1, Computed: 2, Next: 2, WouldUpdate: true},
+	}, nil
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, path, token, body string) (int, map[string]json.RawMessage) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var res map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, res
+}
+
+func TestServerAuthentication(t *testing.T) {
+	handler := NewServer("", "secret", NewControls(nil), &mockPreviewer{}).Handler()
+	if code, _ := doRequest(t, handler, http.MethodGet, "/status", "", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+	if code, _ := doRequest(t, handler, http.MethodGet, "/status", "wrong", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a wrong token, got %d", code)
+	}
+	if code, _ := doRequest(t, handler, http.MethodGet, "/status", "secret", ""); code != http.StatusOK {
+		t.Fatalf("expected 200 with the token, got %d", code)
+	}
+}
+
+func TestServerChanges(t *testing.T) {
+	controls := NewControls(nil)
+	handler := NewServer("", "secret", controls, &mockPreviewer{}).Handler()
+
+	code, _ := doRequest(t, handler, http.MethodPost, "/pause", "secret", `{"loop":"l2-gas-price","reason":"incident"}`)
+	if code != http.StatusOK || !controls.Paused(LoopL2GasPrice) {
+		t.Fatalf("expected loop to be paused, got %d", code)
+	}
+	code, _ = doRequest(t, handler, http.MethodPost, "/resume", "secret", `{"loop":"l2-gas-price"}`)
+	if code != http.StatusOK || controls.Paused(LoopL2GasPrice) {
+		t.Fatalf("expected loop to be resumed, got %d", code)
+	}
+
+	code, res := doRequest(t, handler, http.MethodPost, "/override", "secret", `{"loop":"l1-base-fee","value":"123","duration_seconds":60}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected override to be set, got %d", code)
+	}
+	var overrides map[string]Status
+	if err := json.Unmarshal(res["overrides"], &overrides); err != nil {
+		t.Fatal(err)
+	}
+	if status := overrides[LoopL1BaseFee]; status.Value.Cmp(big.NewInt(123)) != 0 || status.Until == nil {
+		t.Fatalf("unexpected overrides %s", res["overrides"])
+	}
+	code, _ = doRequest(t, handler, http.MethodPost, "/override/clear", "secret", `{"loop":"l1-base-fee"}`)
+	if _, ok := controls.Override(LoopL1BaseFee); code != http.StatusOK || ok {
+		t.Fatalf("expected override to be cleared, got %d", code)
+	}
+
+	if code, _ := doRequest(t, handler, http.MethodPost, "/override", "secret", `{"loop":"l1-base-fee","value":"abc"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected invalid value to be rejected, got %d", code)
+	}
+	if code, _ := doRequest(t, handler, http.MethodPost, "/pause", "secret", `{"loop":"unknown"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected unknown loop to be rejected, got %d", code)
+	}
+	if code, _ := doRequest(t, handler, http.MethodGet, "/pause", "secret", ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected, got %d", code)
+	}
+}
+
+func TestServerPreview(t *testing.T) {
+	handler := NewServer("", "secret", NewControls(nil), &mockPreviewer{}).Handler()
+	code, res := doRequest(t, handler, http.MethodGet, "/preview", "secret", "")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var preview GasPricePreview
+	if err := json.Unmarshal(res["l2_gas_price"], &preview); err != nil {
+		t.Fatal(err)
+	}
+	if preview.Next != 2 || !preview.WouldUpdate {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+}
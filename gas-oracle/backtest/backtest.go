@@ -0,0 +1,282 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+)
+
+const (
+	// LoopL2GasPrice identifies points produced by the simulated `Loop`
+	LoopL2GasPrice = "l2-gas-price"
+	// LoopL1BaseFee identifies points produced by the simulated `BaseFeeLoop`
+	LoopL1BaseFee = "l1-base-fee"
+)
+
+// errInvalidEpochLength represents the error when the base fee polling
+// interval is configured as zero
+var errInvalidEpochLength = errors.New("l1BaseFeeEpochLengthSeconds cannot be less than 1 second")
+
+// Point is a single tick of one of the simulated loops
+type Point struct {
+	Timestamp        uint64   `json:"timestamp"`
+	Loop             string   `json:"loop"`
+	BlockNumber      uint64   `json:"block_number"`
+	AvgGasPerSecond  float64  `json:"avg_gas_per_second"`
+	TokenRatio       float64  `json:"token_ratio"`
+	ComputedGasPrice uint64   `json:"computed_gas_price"`
+	GasPrice         uint64   `json:"gas_price"`
+	L1BaseFee        *big.Int `json:"l1_base_fee"`
+	Updated          bool     `json:"updated"`
+}
+
+// Summary holds aggregate statistics over a backtest. The gas price and
+// L1 base fee statistics describe the values that would have been set
+// on the BVM_GasPriceOracle.
+type Summary struct {
+	DurationSeconds  uint64   `json:"duration_seconds"`
+	Blocks           int      `json:"blocks"`
+	GasPriceEpochs   int      `json:"gas_price_epochs"`
+	GasPriceUpdates  int      `json:"gas_price_updates"`
+	GasPriceSkipped  int      `json:"gas_price_skipped"`
+	EpochsAtFloor    int      `json:"epochs_at_floor"`
+	MinGasPrice      uint64   `json:"min_gas_price"`
+	MaxGasPrice      uint64   `json:"max_gas_price"`
+	MeanGasPrice     float64  `json:"mean_gas_price"`
+	FinalGasPrice    uint64   `json:"final_gas_price"`
+	L1BaseFeeEpochs  int      `json:"l1_base_fee_epochs"`
+	L1BaseFeeUpdates int      `json:"l1_base_fee_updates"`
+	L1BaseFeeSkipped int      `json:"l1_base_fee_skipped"`
+	MinL1BaseFee     *big.Int `json:"min_l1_base_fee"`
+	MaxL1BaseFee     *big.Int `json:"max_l1_base_fee"`
+	FinalL1BaseFee   *big.Int `json:"final_l1_base_fee"`
+}
+
+// Result is the output of a backtest
+type Result struct {
+	Series  []Point `json:"series"`
+	Summary Summary `json:"summary"`
+}
+
+// simulation replays a dataset on simulated time. It tracks the
+// latest observed values as of the simulated time and the values
+// that would be stored in the BVM_GasPriceOracle.
+type simulation struct {
+	records []Record
+	now     uint64
+	// head is the index of the latest record at or before now
+	head      int
+	ratio     float64
+	baseFee   *big.Int
+	gasPrice  uint64
+	l1BaseFee *big.Int
+}
+
+// PriceRatio implements tokenprice.PriceRatioProvider using the latest
+// token ratio observed as of the simulated time
+func (s *simulation) PriceRatio() (float64, error) {
+	return s.ratio, nil
+}
+
+// advance moves the simulated time forward and picks up all of the
+// records that have been observed by then
+func (s *simulation) advance(now uint64) {
+	s.now = now
+	for s.head+1 < len(s.records) && s.records[s.head+1].Timestamp <= now {
+		s.head++
+		s.observe(s.records[s.head])
+	}
+}
+
+func (s *simulation) observe(record Record) {
+	if record.L1BaseFee != nil {
+		s.baseFee = record.L1BaseFee
+	}
+	if record.TokenRatio > 0 {
+		s.ratio = record.TokenRatio
+	}
+}
+
+// Run replays the dataset through the GasPricer, the GasPriceUpdater and
+// the L1 base fee logic. The L2 gas price is updated every epoch and the L1
+// base fee every L1 base fee epoch, both starting at the first record.
+func Run(cfg *Config, dataset *Dataset) (*Result, error) {
+	if cfg.L1BaseFeeEpochLengthSeconds < 1 {
+		return nil, errInvalidEpochLength
+	}
+	if err := dataset.validate(); err != nil {
+		return nil, err
+	}
+
+	first := dataset.Records[0]
+	sim := &simulation{
+		records:   dataset.Records,
+		ratio:     1,
+		gasPrice:  cfg.InitialGasPrice,
+		l1BaseFee: new(big.Int),
+	}
+	if cfg.InitialL1BaseFee != nil {
+		sim.l1BaseFee = new(big.Int).Set(cfg.InitialL1BaseFee)
+	}
+	sim.observe(first)
+	sim.advance(first.Timestamp)
+
+	gasPricer, err := gasprices.NewGasPricer(
+		cfg.InitialGasPrice,
+		cfg.FloorPrice,
+		sim,
+		func() float64 {
+			return float64(cfg.TargetGasPerSecond)
+		},
+		cfg.MaxPercentChangePerEpoch,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result       = new(Result)
+		epochGasUsed uint64
+		updated      bool
+	)
+	getLatestBlockNumberFn := func() (uint64, error) {
+		return sim.records[sim.head].BlockNumber, nil
+	}
+	getGasUsedByBlockFn := func(number *big.Int) (uint64, error) {
+		if !number.IsUint64() || number.Uint64() < first.BlockNumber ||
+			number.Uint64()-first.BlockNumber >= uint64(len(sim.records)) {
+			return 0, fmt.Errorf("block %d not found in dataset", number)
+		}
+		gasUsed := sim.records[number.Uint64()-first.BlockNumber].GasUsed
+		epochGasUsed += gasUsed
+		return gasUsed, nil
+	}
+	// updateL2GasPriceFn mirrors the checks done before sending a
+	// SetGasPrice transaction
+	updateL2GasPriceFn := func(updatedGasPrice uint64) error {
+		if sim.gasPrice == updatedGasPrice ||
+			!gasprices.IsDifferenceSignificant(sim.gasPrice, updatedGasPrice, cfg.L2GasPriceSignificanceFactor) {
+			result.Summary.GasPriceSkipped++
+			return nil
+		}
+		sim.gasPrice = updatedGasPrice
+		result.Summary.GasPriceUpdates++
+		updated = true
+		return nil
+	}
+
+	gasPriceUpdater, err := gasprices.NewGasPriceUpdater(
+		gasPricer,
+		first.BlockNumber,
+		cfg.AverageBlockGasLimitPerEpoch,
+		cfg.EpochLengthSeconds,
+		getLatestBlockNumberFn,
+		getGasUsedByBlockFn,
+		updateL2GasPriceFn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	end := dataset.Records[len(dataset.Records)-1].Timestamp
+	nextGasPriceEpoch := first.Timestamp + cfg.EpochLengthSeconds
+	nextBaseFeeEpoch := first.Timestamp + cfg.L1BaseFeeEpochLengthSeconds
+	for {
+		if nextGasPriceEpoch <= nextBaseFeeEpoch && nextGasPriceEpoch <= end {
+			sim.advance(nextGasPriceEpoch)
+			nextGasPriceEpoch += cfg.EpochLengthSeconds
+
+			epochGasUsed, updated = 0, false
+			if err := gasPriceUpdater.UpdateGasPrice(); err != nil {
+				return nil, fmt.Errorf("cannot update gas price at %d: %w", sim.now, err)
+			}
+			result.Series = append(result.Series, Point{
+				Timestamp:        sim.now,
+				Loop:             LoopL2GasPrice,
+				BlockNumber:      sim.records[sim.head].BlockNumber,
+				AvgGasPerSecond:  float64(epochGasUsed) / float64(cfg.EpochLengthSeconds),
+				TokenRatio:       sim.ratio,
+				ComputedGasPrice: gasPriceUpdater.GetGasPrice(),
+				GasPrice:         sim.gasPrice,
+				L1BaseFee:        new(big.Int).Set(sim.l1BaseFee),
+				Updated:          updated,
+			})
+		} else if nextBaseFeeEpoch <= end {
+			sim.advance(nextBaseFeeEpoch)
+			nextBaseFeeEpoch += cfg.L1BaseFeeEpochLengthSeconds
+
+			// No L1 base fee has been observed yet
+			if sim.baseFee == nil {
+				continue
+			}
+			updated = false
+			tip := gasprices.ScaleL1BaseFee(sim.baseFee, sim.ratio)
+			if gasprices.IsDifferenceSignificant(sim.l1BaseFee.Uint64(), tip.Uint64(), cfg.L1BaseFeeSignificanceFactor) {
+				sim.l1BaseFee = tip
+				result.Summary.L1BaseFeeUpdates++
+				updated = true
+			} else {
+				result.Summary.L1BaseFeeSkipped++
+			}
+			result.Series = append(result.Series, Point{
+				Timestamp:        sim.now,
+				Loop:             LoopL1BaseFee,
+				BlockNumber:      sim.records[sim.head].BlockNumber,
+				TokenRatio:       sim.ratio,
+				ComputedGasPrice: gasPriceUpdater.GetGasPrice(),
+				GasPrice:         sim.gasPrice,
+				L1BaseFee:        new(big.Int).Set(sim.l1BaseFee),
+				Updated:          updated,
+			})
+		} else {
+			break
+		}
+	}
+
+	result.Summary.DurationSeconds = end - first.Timestamp
+	result.Summary.Blocks = len(dataset.Records)
+	summarize(&result.Summary, result.Series, cfg.FloorPrice, sim)
+
+	log.Info("Backtest complete", "blocks", result.Summary.Blocks,
+		"gas-price-epochs", result.Summary.GasPriceEpochs, "gas-price-updates", result.Summary.GasPriceUpdates,
+		"l1-base-fee-epochs", result.Summary.L1BaseFeeEpochs, "l1-base-fee-updates", result.Summary.L1BaseFeeUpdates)
+
+	return result, nil
+}
+
+// summarize computes the statistics over the series of points
+func summarize(summary *Summary, series []Point, floorPrice uint64, sim *simulation) {
+	var total float64
+	for _, point := range series {
+		switch point.Loop {
+		case LoopL2GasPrice:
+			if summary.GasPriceEpochs == 0 || point.GasPrice < summary.MinGasPrice {
+				summary.MinGasPrice = point.GasPrice
+			}
+			if point.GasPrice > summary.MaxGasPrice {
+				summary.MaxGasPrice = point.GasPrice
+			}
+			if point.GasPrice <= floorPrice {
+				summary.EpochsAtFloor++
+			}
+			total += float64(point.GasPrice)
+			summary.GasPriceEpochs++
+		case LoopL1BaseFee:
+			if summary.MinL1BaseFee == nil || point.L1BaseFee.Cmp(summary.MinL1BaseFee) < 0 {
+				summary.MinL1BaseFee = point.L1BaseFee
+			}
+			if summary.MaxL1BaseFee == nil || point.L1BaseFee.Cmp(summary.MaxL1BaseFee) > 0 {
+				summary.MaxL1BaseFee = point.L1BaseFee
+			}
+			summary.L1BaseFeeEpochs++
+		}
+	}
+	if summary.GasPriceEpochs > 0 {
+		summary.MeanGasPrice = total / float64(summary.GasPriceEpochs)
+	}
+	summary.FinalGasPrice = sim.gasPrice
+	summary.FinalL1BaseFee = new(big.Int).Set(sim.l1BaseFee)
+}
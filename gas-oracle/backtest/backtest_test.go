@@ -0,0 +1,243 @@
+package backtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const startTime = uint64(1660000000)
+
+func makeTestConfig() *Config {
+	return &Config{
+		FloorPrice:                   1,
+		TargetGasPerSecond:           1_000_000,
+		MaxPercentChangePerEpoch:     0.1,
+		AverageBlockGasLimitPerEpoch: 11_000_000,
+		EpochLengthSeconds:           10,
+		L1BaseFeeEpochLengthSeconds:  15,
+		L2GasPriceSignificanceFactor: 0.05,
+		L1BaseFeeSignificanceFactor:  0.1,
+		InitialGasPrice:              100,
+	}
+}
+
+// makeTestDataset returns 20 blocks, one every 2 seconds. The first 10
+// blocks are full and the rest are almost empty. The L1 base fee goes
+// from 30 gwei to 45 gwei at block 112.
+func makeTestDataset() *Dataset {
+	dataset := new(Dataset)
+	for i := uint64(0); i < 20; i++ {
+		record := Record{
+			Timestamp:   startTime + 2*i,
+			BlockNumber: 100 + i,
+			GasUsed:     4_000_000,
+		}
+		if i >= 10 {
+			record.GasUsed = 100_000
+		}
+		switch i {
+		case 0:
+			record.L1BaseFee = big.NewInt(30_000_000_000)
+		case 12:
+			record.L1BaseFee = big.NewInt(45_000_000_000)
+		}
+		dataset.Records = append(dataset.Records, record)
+	}
+	return dataset
+}
+
+func TestRun(t *testing.T) {
+	result, err := Run(makeTestConfig(), makeTestDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		timestamp uint64
+		loop      string
+		gasPrice  uint64
+		l1BaseFee int64
+		updated   bool
+	}{
+		// 2M gas per second is bounded by the max change per epoch
+		// and the result is rounded up
+		{timestamp: startTime + 10, loop: LoopL2GasPrice, gasPrice: 111, updated: true},
+		{timestamp: startTime + 15, loop: LoopL1BaseFee, gasPrice: 111, l1BaseFee: 30_000_000_000, updated: true},
+		{timestamp: startTime + 20, loop: LoopL2GasPrice, gasPrice: 123, l1BaseFee: 30_000_000_000, updated: true},
+		// 50k gas per second is bounded by the max change per epoch
+		{timestamp: startTime + 30, loop: LoopL2GasPrice, gasPrice: 111, l1BaseFee: 30_000_000_000, updated: true},
+		{timestamp: startTime + 30, loop: LoopL1BaseFee, gasPrice: 111, l1BaseFee: 45_000_000_000, updated: true},
+	}
+	if len(result.Series) != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), len(result.Series))
+	}
+	for i, point := range result.Series {
+		exp := expected[i]
+		if point.Timestamp != exp.timestamp || point.Loop != exp.loop {
+			t.Fatalf("point %d: expected %s at %d, got %s at %d", i, exp.loop, exp.timestamp, point.Loop, point.Timestamp)
+		}
+		if point.GasPrice != exp.gasPrice {
+			t.Fatalf("point %d: expected gas price %d, got %d", i, exp.gasPrice, point.GasPrice)
+		}
+		if point.L1BaseFee.Cmp(big.NewInt(exp.l1BaseFee)) != 0 {
+			t.Fatalf("point %d: expected l1 base fee %d, got %s", i, exp.l1BaseFee, point.L1BaseFee)
+		}
+		if point.Updated != exp.updated {
+			t.Fatalf("point %d: expected updated to be %t", i, exp.updated)
+		}
+	}
+
+	summary := result.Summary
+	if summary.GasPriceEpochs != 3 || summary.GasPriceUpdates != 3 {
+		t.Fatalf("unexpected gas price epochs %d or updates %d", summary.GasPriceEpochs, summary.GasPriceUpdates)
+	}
+	if summary.MinGasPrice != 111 || summary.MaxGasPrice != 123 || summary.FinalGasPrice != 111 {
+		t.Fatalf("unexpected gas price stats %+v", summary)
+	}
+	if summary.L1BaseFeeEpochs != 2 || summary.L1BaseFeeUpdates != 2 {
+		t.Fatalf("unexpected l1 base fee epochs %d or updates %d", summary.L1BaseFeeEpochs, summary.L1BaseFeeUpdates)
+	}
+	if summary.FinalL1BaseFee.Cmp(big.NewInt(45_000_000_000)) != 0 {
+		t.Fatalf("unexpected final l1 base fee %s", summary.FinalL1BaseFee)
+	}
+}
+
+func TestRunSkipsInsignificantChanges(t *testing.T) {
+	cfg := makeTestConfig()
+	cfg.L2GasPriceSignificanceFactor = 0.5
+	cfg.L1BaseFeeSignificanceFactor = 0.5
+	cfg.InitialL1BaseFee = big.NewInt(40_000_000_000)
+
+	result, err := Run(cfg, makeTestDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := result.Summary
+	if summary.GasPriceUpdates != 0 || summary.GasPriceSkipped != 3 {
+		t.Fatalf("expected all gas price updates to be skipped, got %+v", summary)
+	}
+	if summary.FinalGasPrice != 100 {
+		t.Fatalf("expected gas price to stay at 100, got %d", summary.FinalGasPrice)
+	}
+	if summary.L1BaseFeeUpdates != 0 || summary.L1BaseFeeSkipped != 2 {
+		t.Fatalf("expected all l1 base fee updates to be skipped, got %+v", summary)
+	}
+}
+
+func TestRunScalesByTokenRatio(t *testing.T) {
+	dataset := makeTestDataset()
+	dataset.Records[0].TokenRatio = 2
+	// Keep the gas usage on target so that only the ratio moves the price
+	for i := range dataset.Records {
+		dataset.Records[i].GasUsed = 2_000_000
+	}
+
+	result, err := Run(makeTestConfig(), dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Series[0].GasPrice != 200 {
+		t.Fatalf("expected gas price to be scaled to 200, got %d", result.Series[0].GasPrice)
+	}
+	if result.Series[1].L1BaseFee.Cmp(big.NewInt(60_000_000_000)) != 0 {
+		t.Fatalf("expected l1 base fee to be scaled to 60 gwei, got %s", result.Series[1].L1BaseFee)
+	}
+}
+
+func TestLoadDataset(t *testing.T) {
+	csvDataset, err := LoadDataset("testdata/dataset.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(csvDataset.Records) != 20 {
+		t.Fatalf("expected 20 records, got %d", len(csvDataset.Records))
+	}
+	first := csvDataset.Records[0]
+	if first.BlockNumber != 100 || first.GasUsed != 4_000_000 || first.TokenRatio != 4000 {
+		t.Fatalf("unexpected first record %+v", first)
+	}
+	if first.L1BaseFee.Cmp(big.NewInt(30_000_000_000)) != 0 {
+		t.Fatalf("unexpected l1 base fee %s", first.L1BaseFee)
+	}
+	if csvDataset.Records[1].L1BaseFee != nil {
+		t.Fatal("expected empty l1 base fee to be carried forward")
+	}
+
+	jsonDataset, err := LoadDataset("testdata/dataset.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonDataset.Records) != 3 || jsonDataset.Records[0].TokenRatio != 4000.5 {
+		t.Fatalf("unexpected records %+v", jsonDataset.Records)
+	}
+}
+
+func TestDecodeCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "missing column", data: "timestamp,gas_used\n1,2\n"},
+		{name: "invalid number", data: "timestamp,block_number,gas_used\n1,x,2\n"},
+		{name: "invalid base fee", data: "timestamp,block_number,gas_used,l1_base_fee\n1,2,3,0x10\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeCSV(strings.NewReader(tc.data)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestValidateDataset(t *testing.T) {
+	dataset := makeTestDataset()
+	dataset.Records[5].BlockNumber++
+	if err := dataset.validate(); err == nil {
+		t.Fatal("expected a gap in the blocks to be rejected")
+	}
+
+	dataset = makeTestDataset()
+	dataset.Records[5].Timestamp = startTime
+	if err := dataset.validate(); err == nil {
+		t.Fatal("expected a timestamp going backwards to be rejected")
+	}
+
+	if err := new(Dataset).validate(); err == nil {
+		t.Fatal("expected an empty dataset to be rejected")
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	result, err := Run(makeTestConfig(), makeTestDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(result.Series)+1 {
+		t.Fatalf("expected %d lines, got %d", len(result.Series)+1, len(lines))
+	}
+	if lines[1] != "1660000010,l2-gas-price,105,2000000,1,111,111,0,true" {
+		t.Fatalf("unexpected csv row %q", lines[1])
+	}
+
+	buf.Reset()
+	if err := result.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Series) != len(result.Series) || decoded.Summary.FinalGasPrice != 111 {
+		t.Fatalf("unexpected json output %s", buf.String())
+	}
+}
@@ -0,0 +1,66 @@
+package backtest
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/flags"
+	"github.com/urfave/cli"
+)
+
+// Config represents the pricing parameters used by a backtest. They are
+// read from the same flags as the gas-oracle so that a deployment's
+// configuration can be replayed as is.
+type Config struct {
+	FloorPrice                   uint64
+	TargetGasPerSecond           uint64
+	MaxPercentChangePerEpoch     float64
+	AverageBlockGasLimitPerEpoch uint64
+	EpochLengthSeconds           uint64
+	L1BaseFeeEpochLengthSeconds  uint64
+	L2GasPriceSignificanceFactor float64
+	L1BaseFeeSignificanceFactor  float64
+	InitialGasPrice              uint64
+	InitialL1BaseFee             *big.Int
+	Dataset                      string
+	Output                       string
+	Format                       string
+}
+
+// NewConfig creates a new Config
+func NewConfig(ctx *cli.Context) (*Config, error) {
+	cfg := Config{}
+	cfg.FloorPrice = ctx.GlobalUint64(flags.FloorPriceFlag.Name)
+	cfg.TargetGasPerSecond = ctx.GlobalUint64(flags.TargetGasPerSecondFlag.Name)
+	cfg.MaxPercentChangePerEpoch = ctx.GlobalFloat64(flags.MaxPercentChangePerEpochFlag.Name)
+	cfg.AverageBlockGasLimitPerEpoch = ctx.GlobalUint64(flags.AverageBlockGasLimitPerEpochFlag.Name)
+	cfg.EpochLengthSeconds = ctx.GlobalUint64(flags.EpochLengthSecondsFlag.Name)
+	cfg.L1BaseFeeEpochLengthSeconds = ctx.GlobalUint64(flags.L1BaseFeeEpochLengthSecondsFlag.Name)
+	cfg.L2GasPriceSignificanceFactor = ctx.GlobalFloat64(flags.L2GasPriceSignificanceFactorFlag.Name)
+	cfg.L1BaseFeeSignificanceFactor = ctx.GlobalFloat64(flags.L1BaseFeeSignificanceFactorFlag.Name)
+	cfg.Dataset = ctx.String(flags.BacktestDatasetFlag.Name)
+	cfg.Output = ctx.String(flags.BacktestOutputFlag.Name)
+	cfg.Format = ctx.String(flags.BacktestFormatFlag.Name)
+
+	// Start at the floor unless told otherwise
+	cfg.InitialGasPrice = cfg.FloorPrice
+	if ctx.IsSet(flags.BacktestInitialGasPriceFlag.Name) {
+		cfg.InitialGasPrice = ctx.Uint64(flags.BacktestInitialGasPriceFlag.Name)
+	}
+	if ctx.IsSet(flags.BacktestInitialL1BaseFeeFlag.Name) {
+		value := ctx.String(flags.BacktestInitialL1BaseFeeFlag.Name)
+		baseFee, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("option %q: invalid value %q", flags.BacktestInitialL1BaseFeeFlag.Name, value)
+		}
+		cfg.InitialL1BaseFee = baseFee
+	}
+
+	if cfg.Dataset == "" {
+		return nil, fmt.Errorf("option %q is required", flags.BacktestDatasetFlag.Name)
+	}
+	if cfg.Format != FormatCSV && cfg.Format != FormatJSON {
+		return nil, fmt.Errorf("option %q: unknown format %q", flags.BacktestFormatFlag.Name, cfg.Format)
+	}
+	return &cfg, nil
+}
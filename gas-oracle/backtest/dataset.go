@@ -0,0 +1,162 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// errEmptyDataset represents the error when the dataset contains no records
+	errEmptyDataset = errors.New("dataset is empty")
+	// errMissingColumn represents the error when a required CSV column is
+	// not present in the header
+	errMissingColumn = errors.New("missing column")
+)
+
+// Record is a single observation in a recorded dataset. Every record
+// describes an L2 block. The L1 base fee and the token ratio are optional
+// and when they are not set, the last observed value is carried forward.
+type Record struct {
+	Timestamp   uint64   `json:"timestamp"`
+	BlockNumber uint64   `json:"block_number"`
+	GasUsed     uint64   `json:"gas_used"`
+	L1BaseFee   *big.Int `json:"l1_base_fee,omitempty"`
+	TokenRatio  float64  `json:"token_ratio,omitempty"`
+}
+
+// Dataset is an ordered set of records that can be replayed by the backtest
+type Dataset struct {
+	Records []Record
+}
+
+// LoadDataset reads a dataset from disk. Files with a `.json` extension are
+// parsed as a JSON array of records, everything else is parsed as CSV.
+func LoadDataset(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		records, err = decodeJSON(file)
+	} else {
+		records, err = decodeCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dataset %s: %w", path, err)
+	}
+	dataset := &Dataset{Records: records}
+	if err := dataset.validate(); err != nil {
+		return nil, fmt.Errorf("invalid dataset %s: %w", path, err)
+	}
+	return dataset, nil
+}
+
+// validate ensures that the records are ordered and that the L2 blocks
+// are contiguous, which is required to accumulate gas used over an epoch
+func (d *Dataset) validate() error {
+	if len(d.Records) == 0 {
+		return errEmptyDataset
+	}
+	for i := 1; i < len(d.Records); i++ {
+		prev, cur := d.Records[i-1], d.Records[i]
+		if cur.Timestamp < prev.Timestamp {
+			return fmt.Errorf("timestamp goes backwards at block %d", cur.BlockNumber)
+		}
+		if cur.BlockNumber != prev.BlockNumber+1 {
+			return fmt.Errorf("block %d does not follow block %d", cur.BlockNumber, prev.BlockNumber)
+		}
+	}
+	for _, record := range d.Records {
+		if record.L1BaseFee != nil && record.L1BaseFee.Sign() < 0 {
+			return fmt.Errorf("negative l1 base fee at block %d", record.BlockNumber)
+		}
+		if record.TokenRatio < 0 {
+			return fmt.Errorf("negative token ratio at block %d", record.BlockNumber)
+		}
+	}
+	return nil
+}
+
+func decodeJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// decodeCSV parses a CSV file with a header. The `timestamp`, `block_number`
+// and `gas_used` columns are required, `l1_base_fee` and `token_ratio`
+// are optional and may contain empty cells.
+func decodeCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "block_number", "gas_used"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", errMissingColumn, name)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		var record Record
+		if record.Timestamp, err = strconv.ParseUint(cell("timestamp"), 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: timestamp: %w", line, err)
+		}
+		if record.BlockNumber, err = strconv.ParseUint(cell("block_number"), 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: block_number: %w", line, err)
+		}
+		if record.GasUsed, err = strconv.ParseUint(cell("gas_used"), 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: gas_used: %w", line, err)
+		}
+		if value := cell("l1_base_fee"); value != "" {
+			baseFee, ok := new(big.Int).SetString(value, 10)
+			if !ok {
+				return nil, fmt.Errorf("line %d: l1_base_fee: invalid value %q", line, value)
+			}
+			record.L1BaseFee = baseFee
+		}
+		if value := cell("token_ratio"); value != "" {
+			if record.TokenRatio, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("line %d: token_ratio: %w", line, err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
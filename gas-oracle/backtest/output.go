@@ -0,0 +1,60 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+const (
+	// FormatCSV writes the series as CSV
+	FormatCSV = "csv"
+	// FormatJSON writes the series and the summary as a JSON object
+	FormatJSON = "json"
+)
+
+var csvHeader = []string{
+	"timestamp",
+	"loop",
+	"block_number",
+	"avg_gas_per_second",
+	"token_ratio",
+	"computed_gas_price",
+	"gas_price",
+	"l1_base_fee",
+	"updated",
+}
+
+// WriteCSV writes the price series as CSV with a header
+func (r *Result) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, point := range r.Series {
+		row := []string{
+			strconv.FormatUint(point.Timestamp, 10),
+			point.Loop,
+			strconv.FormatUint(point.BlockNumber, 10),
+			strconv.FormatFloat(point.AvgGasPerSecond, 'f', -1, 64),
+			strconv.FormatFloat(point.TokenRatio, 'f', -1, 64),
+			strconv.FormatUint(point.ComputedGasPrice, 10),
+			strconv.FormatUint(point.GasPrice, 10),
+			point.L1BaseFee.String(),
+			strconv.FormatBool(point.Updated),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the price series and the summary as a JSON object
+func (r *Result) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -170,6 +170,100 @@ var (
 		Value:  "test",
 		EnvVar: "GAS_PRICE_ORACLE_METRICS_INFLUX_DB_PASSWORD",
 	}
+	AdminEnabledFlag = cli.BoolFlag{
+		Name:   "admin",
+		Usage:  "Enable the operator admin HTTP API",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_ENABLE",
+	}
+	AdminHTTPFlag = cli.StringFlag{
+		Name:   "admin.addr",
+		Usage:  "Admin HTTP server listening interface",
+		Value:  "127.0.0.1",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_HTTP",
+	}
+	AdminPortFlag = cli.IntFlag{
+		Name:   "admin.port",
+		Usage:  "Admin HTTP server listening port",
+		Value:  7300,
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_PORT",
+	}
+	AdminTokenFlag = cli.StringFlag{
+		Name:   "admin.token",
+		Usage:  "Bearer token required by every admin request",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_TOKEN",
+	}
+	AdminAuditLogFlag = cli.StringFlag{
+		Name:   "admin.audit-log",
+		Usage:  "Path of the file that every admin change is appended to",
+		Value:  "gas-oracle-audit.log",
+		EnvVar: "GAS_PRICE_ORACLE_ADMIN_AUDIT_LOG",
+	}
+	HABackendFlag = cli.StringFlag{
+		Name:   "ha.backend",
+		Usage:  "Lease backend for leader election, file or redis. Leader election is disabled when not set",
+		EnvVar: "GAS_PRICE_ORACLE_HA_BACKEND",
+	}
+	HALeaseFileFlag = cli.StringFlag{
+		Name:   "ha.lease-file",
+		Usage:  "Path of the lease file used by the file backend",
+		Value:  "gas-oracle.lease",
+		EnvVar: "GAS_PRICE_ORACLE_HA_LEASE_FILE",
+	}
+	HARedisURLFlag = cli.StringFlag{
+		Name:   "ha.redis-url",
+		Usage:  "Redis URL used by the redis backend",
+		Value:  "redis://127.0.0.1:6379",
+		EnvVar: "GAS_PRICE_ORACLE_HA_REDIS_URL",
+	}
+	HALeaseKeyFlag = cli.StringFlag{
+		Name:   "ha.lease-key",
+		Usage:  "Redis key of the lease used by the redis backend",
+		Value:  "gas-oracle/leader",
+		EnvVar: "GAS_PRICE_ORACLE_HA_LEASE_KEY",
+	}
+	HALeaseTTLSecondsFlag = cli.Uint64Flag{
+		Name:   "ha.lease-ttl-seconds",
+		Usage:  "Time after which a standby takes over when the leader stops renewing the lease",
+		Value:  15,
+		EnvVar: "GAS_PRICE_ORACLE_HA_LEASE_TTL_SECONDS",
+	}
+	HANodeIDFlag = cli.StringFlag{
+		Name:   "ha.node-id",
+		Usage:  "Unique id of this instance in the leader election, defaults to the hostname",
+		EnvVar: "GAS_PRICE_ORACLE_HA_NODE_ID",
+	}
+	HATakeoverTimeoutSecondsFlag = cli.Uint64Flag{
+		Name:   "ha.takeover-timeout-seconds",
+		Usage:  "Time a new leader waits for transactions of the previous leader before replacing them",
+		Value:  30,
+		EnvVar: "GAS_PRICE_ORACLE_HA_TAKEOVER_TIMEOUT_SECONDS",
+	}
+	BacktestDatasetFlag = cli.StringFlag{
+		Name:   "dataset",
+		Usage:  "Path to the recorded dataset, JSON when ending in .json otherwise CSV",
+		EnvVar: "GAS_PRICE_ORACLE_BACKTEST_DATASET",
+	}
+	BacktestOutputFlag = cli.StringFlag{
+		Name:   "output",
+		Usage:  "Path to write the price series to, stdout when not set",
+		EnvVar: "GAS_PRICE_ORACLE_BACKTEST_OUTPUT",
+	}
+	BacktestFormatFlag = cli.StringFlag{
+		Name:   "format",
+		Value:  "csv",
+		Usage:  "Output format of the price series, csv or json",
+		EnvVar: "GAS_PRICE_ORACLE_BACKTEST_FORMAT",
+	}
+	BacktestInitialGasPriceFlag = cli.Uint64Flag{
+		Name:   "initial-gas-price",
+		Usage:  "L2 gas price at the start of the backtest, defaults to the floor price",
+		EnvVar: "GAS_PRICE_ORACLE_BACKTEST_INITIAL_GAS_PRICE",
+	}
+	BacktestInitialL1BaseFeeFlag = cli.StringFlag{
+		Name:   "initial-l1-base-fee",
+		Usage:  "L1 base fee at the start of the backtest",
+		EnvVar: "GAS_PRICE_ORACLE_BACKTEST_INITIAL_L1_BASE_FEE",
+	}
 )
 
 var Flags = []cli.Flag{
@@ -202,4 +296,24 @@ var Flags = []cli.Flag{
 	MetricsInfluxDBDatabaseFlag,
 	MetricsInfluxDBUsernameFlag,
 	MetricsInfluxDBPasswordFlag,
+	AdminEnabledFlag,
+	AdminHTTPFlag,
+	AdminPortFlag,
+	AdminTokenFlag,
+	AdminAuditLogFlag,
+	HABackendFlag,
+	HALeaseFileFlag,
+	HARedisURLFlag,
+	HALeaseKeyFlag,
+	HALeaseTTLSecondsFlag,
+	HANodeIDFlag,
+	HATakeoverTimeoutSecondsFlag,
+}
+
+var BacktestFlags = []cli.Flag{
+	BacktestDatasetFlag,
+	BacktestOutputFlag,
+	BacktestFormatFlag,
+	BacktestInitialGasPriceFlag,
+	BacktestInitialL1BaseFeeFlag,
 }
@@ -69,19 +69,11 @@ func (g *GasPriceUpdater) UpdateGasPrice() error {
 		return nil
 	}
 
-	// Accumulate the amount of gas that has been used in the epoch
-	totalGasUsed := uint64(0)
-	for i := g.epochStartBlockNumber + 1; i <= latestBlockNumber; i++ {
-		gasUsed, err := g.getGasUsedByBlockFn(new(big.Int).SetUint64(i))
-		log.Trace("fetching gas used", "height", i, "gas-used", gasUsed, "total-gas", totalGasUsed)
-		if err != nil {
-			return err
-		}
-		totalGasUsed += gasUsed
+	averageGasPerSecond, err := g.averageGasPerSecond(latestBlockNumber)
+	if err != nil {
+		return err
 	}
 
-	averageGasPerSecond := float64(totalGasUsed) / float64(g.epochLengthSeconds)
-
 	log.Debug("UpdateGasPrice", "average-gas-per-second", averageGasPerSecond, "current-price", g.gasPricer.curPrice)
 	_, err = g.gasPricer.CompleteEpoch(averageGasPerSecond)
 	if err != nil {
@@ -95,6 +87,54 @@ func (g *GasPriceUpdater) UpdateGasPrice() error {
 	return nil
 }
 
+// ApplyGasPrice sets the gas price of the current epoch again without
+// completing the epoch. It is used to apply a change made by an operator.
+func (g *GasPriceUpdater) ApplyGasPrice() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.updateL2GasPriceFn(g.gasPricer.curPrice)
+}
+
+// PreviewGasPrice returns the gas price that would be set if the current
+// epoch ended now along with the average gas per second of the epoch. It
+// does not update any state.
+func (g *GasPriceUpdater) PreviewGasPrice() (uint64, float64, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	latestBlockNumber, err := g.getLatestBlockNumberFn()
+	if err != nil {
+		return 0, 0, err
+	}
+	if latestBlockNumber < g.epochStartBlockNumber {
+		return 0, 0, errors.New("Latest block number less than the last epoch's block number")
+	}
+	averageGasPerSecond, err := g.averageGasPerSecond(latestBlockNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	gasPrice, err := g.gasPricer.CalcNextEpochGasPrice(averageGasPerSecond)
+	if err != nil {
+		return 0, 0, err
+	}
+	return gasPrice, averageGasPerSecond, nil
+}
+
+// averageGasPerSecond accumulates the amount of gas that has been used
+// in the epoch up to the latest block
+func (g *GasPriceUpdater) averageGasPerSecond(latestBlockNumber uint64) (float64, error) {
+	totalGasUsed := uint64(0)
+	for i := g.epochStartBlockNumber + 1; i <= latestBlockNumber; i++ {
+		gasUsed, err := g.getGasUsedByBlockFn(new(big.Int).SetUint64(i))
+		log.Trace("fetching gas used", "height", i, "gas-used", gasUsed, "total-gas", totalGasUsed)
+		if err != nil {
+			return 0, err
+		}
+		totalGasUsed += gasUsed
+	}
+	return float64(totalGasUsed) / float64(g.epochLengthSeconds), nil
+}
+
 func (g *GasPriceUpdater) GetGasPrice() uint64 {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
@@ -175,3 +175,71 @@ func TestUsageOfGasPriceUpdater(t *testing.T) {
 		}
 	}
 }
+
+func TestPreviewGasPriceDoesNotUpdateState(t *testing.T) {
+	gasPricer, err := NewGasPricer(100, 1, tokenprice.FixedRatio(1), func() float64 { return 10 }, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	gasUpdater, err := NewGasPriceUpdater(
+		gasPricer,
+		0,
+		100,
+		10,
+		func() (uint64, error) { return 2, nil },
+		func(*big.Int) (uint64, error) { return 100, nil },
+		func(uint64) error {
+			called = true
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 200 gas over 10 seconds is twice the target
+	gasPrice, avgGasPerSecond, err := gasUpdater.PreviewGasPrice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gasPrice != 150 || avgGasPerSecond != 20 {
+		t.Fatalf("unexpected preview %d at %f gas per second", gasPrice, avgGasPerSecond)
+	}
+	if called || gasUpdater.GetGasPrice() != 100 || gasUpdater.epochStartBlockNumber != 0 {
+		t.Fatal("expected preview not to update state")
+	}
+}
+
+func TestApplyGasPriceDoesNotCompleteEpoch(t *testing.T) {
+	gasPricer, err := NewGasPricer(100, 1, tokenprice.FixedRatio(1), func() float64 { return 10 }, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied uint64
+	gasUpdater, err := NewGasPriceUpdater(
+		gasPricer,
+		0,
+		100,
+		10,
+		func() (uint64, error) { return 2, nil },
+		func(*big.Int) (uint64, error) { return 100, nil },
+		func(gasPrice uint64) error {
+			applied = gasPrice
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gasUpdater.ApplyGasPrice(); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 100 {
+		t.Fatalf("expected the current gas price 100 to be applied, got %d", applied)
+	}
+	if gasUpdater.GetGasPrice() != 100 || gasUpdater.epochStartBlockNumber != 0 {
+		t.Fatal("expected the epoch not to be completed")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
@@ -15,7 +16,7 @@ type GasPricer struct {
 	curPrice                 uint64
 	avgGasPerSecondLastEpoch float64
 	floorPrice               uint64
-	tokenPricer              *tokenprice.Client
+	tokenPricer              tokenprice.PriceRatioProvider
 	getTargetGasPerSecond    GetTargetGasPerSecond
 	maxChangePerEpoch        float64
 }
@@ -28,13 +29,16 @@ func GetLinearInterpolationFn(getX func() float64, x1 float64, x2 float64, y1 fl
 }
 
 // NewGasPricer creates a GasPricer and checks its config beforehand
-func NewGasPricer(curPrice, floorPrice uint64, tokenPricer *tokenprice.Client, getTargetGasPerSecond GetTargetGasPerSecond, maxPercentChangePerEpoch float64) (*GasPricer, error) {
+func NewGasPricer(curPrice, floorPrice uint64, tokenPricer tokenprice.PriceRatioProvider, getTargetGasPerSecond GetTargetGasPerSecond, maxPercentChangePerEpoch float64) (*GasPricer, error) {
 	if floorPrice < 1 {
 		return nil, errors.New("floorPrice must be greater than or equal to 1")
 	}
 	if maxPercentChangePerEpoch <= 0 {
 		return nil, errors.New("maxPercentChangePerEpoch must be between (0,100]")
 	}
+	if tokenPricer == nil {
+		return nil, errors.New("tokenPricer must not be nil, use tokenprice.FixedRatio(1) to leave the gas price unscaled")
+	}
 	return &GasPricer{
 		tokenPricer:           tokenPricer,
 		curPrice:              max(curPrice, floorPrice),
@@ -91,9 +95,32 @@ func (p *GasPricer) CompleteEpoch(avgGasPerSecondLastEpoch float64) (uint64, err
 	return gp, nil
 }
 
+// IsDifferenceSignificant reports whether a and b differ by at least the
+// factor c. If c is greater than the result of 1 - (min/max) then the
+// difference is not significant and no update should be sent
+func IsDifferenceSignificant(a, b uint64, c float64) bool {
+	max := max(a, b)
+	min := min(a, b)
+	factor := 1 - (float64(min) / float64(max))
+	return c <= factor
+}
+
+// ScaleL1BaseFee converts an L1 base fee denominated in ETH into one
+// denominated in BIT using the ETH/BIT price ratio
+func ScaleL1BaseFee(baseFee *big.Int, ratio float64) *big.Int {
+	return new(big.Int).Mul(baseFee, big.NewInt(int64(ratio)))
+}
+
 func max(a, b uint64) uint64 {
 	if a >= b {
 		return a
 	}
 	return b
 }
+
+func min(a, b uint64) uint64 {
+	if a >= b {
+		return b
+	}
+	return a
+}
@@ -3,6 +3,8 @@ package gasprices
 import (
 	"math"
 	"testing"
+
+	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
 )
 
 type CalcGasPriceTestCase struct {
@@ -26,6 +28,7 @@ func runCalcGasPriceTests(gp GasPricer, tcs []CalcGasPriceTestCase, t *testing.T
 
 func TestCalcGasPriceFarFromFloor(t *testing.T) {
 	gp := GasPricer{
+		tokenPricer:           tokenprice.FixedRatio(1),
 		curPrice:              100,
 		floorPrice:            1,
 		getTargetGasPerSecond: returnConstFn(10),
@@ -72,6 +75,7 @@ func TestCalcGasPriceFarFromFloor(t *testing.T) {
 
 func TestCalcGasPriceAtFloor(t *testing.T) {
 	gp := GasPricer{
+		tokenPricer:           tokenprice.FixedRatio(1),
 		curPrice:              100,
 		floorPrice:            100,
 		getTargetGasPerSecond: returnConstFn(10),
@@ -102,6 +106,7 @@ func TestCalcGasPriceAtFloor(t *testing.T) {
 
 func TestGasPricerUpdates(t *testing.T) {
 	gp := GasPricer{
+		tokenPricer:           tokenprice.FixedRatio(1),
 		curPrice:              100,
 		floorPrice:            100,
 		getTargetGasPerSecond: returnConstFn(10),
@@ -151,6 +156,7 @@ func TestGasPricerDynamicTarget(t *testing.T) {
 	dynamicGetTarget := GetLinearInterpolationFn(mockTimeNow, startTimestamp, endTimestamp, startGasPerSecond, endGasPerSecond)
 
 	gp := GasPricer{
+		tokenPricer:           tokenprice.FixedRatio(1),
 		curPrice:              100,
 		floorPrice:            1,
 		getTargetGasPerSecond: dynamicGetTarget,
@@ -170,3 +176,33 @@ func TestGasPricerDynamicTarget(t *testing.T) {
 		}
 	}
 }
+
+func TestIsDifferenceSignificant(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      uint64
+		b      uint64
+		sig    float64
+		expect bool
+	}{
+		{name: "test 1", a: 1, b: 1, sig: 0.05, expect: false},
+		{name: "test 2", a: 4, b: 1, sig: 0.25, expect: true},
+		{name: "test 3", a: 3, b: 1, sig: 0.1, expect: true},
+		{name: "test 4", a: 4, b: 1, sig: 0.9, expect: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := IsDifferenceSignificant(tc.a, tc.b, tc.sig)
+			if result != tc.expect {
+				t.Fatalf("mismatch %s", tc.name)
+			}
+		})
+	}
+}
+
+func TestNewGasPricerWithoutTokenPricer(t *testing.T) {
+	if _, err := NewGasPricer(100, 1, nil, returnConstFn(10), 0.5); err == nil {
+		t.Fatal("expected an error without a token pricer")
+	}
+}
@@ -0,0 +1,150 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+var (
+	leaderGauge        = metrics.NewRegisteredGauge("ha/leader", ometrics.DefaultRegistry)
+	lastRenewGauge     = metrics.NewRegisteredGauge("ha/last_renew", ometrics.DefaultRegistry)
+	transitionsCounter = metrics.NewRegisteredCounter("ha/transitions", ometrics.DefaultRegistry)
+	leaseErrorsCounter = metrics.NewRegisteredCounter("ha/lease_errors", ometrics.DefaultRegistry)
+)
+
+// Callbacks are called by the Elector when leadership changes. They are
+// called from the election loop and must not block for long.
+type Callbacks struct {
+	OnStartedLeading func()
+	OnStoppedLeading func()
+}
+
+// Elector competes for a Lease and keeps renewing it while it is the
+// leader. Standbys keep trying to acquire the lease and take over once
+// the leader stops renewing it.
+type Elector struct {
+	lease     Lease
+	id        string
+	ttl       time.Duration
+	callbacks Callbacks
+	leader    int32
+	// leaderUntil is the time at which the lease held by this instance
+	// expires when it is not renewed
+	leaderUntil time.Time
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewElector creates an Elector that identifies itself with id
+func NewElector(lease Lease, id string, ttl time.Duration, callbacks Callbacks) *Elector {
+	return &Elector{
+		lease:     lease,
+		id:        id,
+		ttl:       ttl,
+		callbacks: callbacks,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start runs the election loop in the background
+func (e *Elector) Start() {
+	log.Info("Starting leader election", "id", e.id, "ttl", e.ttl)
+	e.wg.Add(1)
+	go e.loop()
+}
+
+// Stop stops the election loop and releases the lease so that a standby
+// can take over right away
+func (e *Elector) Stop() {
+	close(e.stop)
+	e.wg.Wait()
+	if e.IsLeader() {
+		e.stepDown()
+		ctx, cancel := context.WithTimeout(context.Background(), e.ttl)
+		defer cancel()
+		if err := e.lease.Release(ctx, e.id); err != nil {
+			log.Error("cannot release lease", "message", err)
+		}
+	}
+}
+
+// IsLeader returns true when this instance holds the lease
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+func (e *Elector) loop() {
+	defer e.wg.Done()
+
+	// Renew well before the lease expires so that a slow backend
+	// does not cause a leader to lose the lease
+	interval := e.ttl / 3
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	e.tick(interval)
+	for {
+		select {
+		case <-timer.C:
+			e.tick(interval)
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *Elector) tick(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	acquired, err := e.lease.TryAcquire(ctx, e.id, e.ttl)
+	if err != nil {
+		leaseErrorsCounter.Inc(1)
+		log.Error("cannot acquire lease", "id", e.id, "message", err)
+		// Keep leading until the lease may have expired, another
+		// instance can take over after that
+		if e.IsLeader() && time.Now().After(e.leaderUntil.Add(-timeout)) {
+			e.stepDown()
+		}
+		return
+	}
+
+	lastRenewGauge.Update(time.Now().Unix())
+	if acquired {
+		e.leaderUntil = start.Add(e.ttl)
+		if !e.IsLeader() {
+			e.stepUp()
+		}
+		return
+	}
+	if e.IsLeader() {
+		e.stepDown()
+	}
+}
+
+func (e *Elector) stepUp() {
+	log.Info("Became the leader", "id", e.id)
+	atomic.StoreInt32(&e.leader, 1)
+	leaderGauge.Update(1)
+	transitionsCounter.Inc(1)
+	if e.callbacks.OnStartedLeading != nil {
+		e.callbacks.OnStartedLeading()
+	}
+}
+
+func (e *Elector) stepDown() {
+	log.Warn("Stopped being the leader", "id", e.id)
+	atomic.StoreInt32(&e.leader, 0)
+	leaderGauge.Update(0)
+	transitionsCounter.Inc(1)
+	if e.callbacks.OnStoppedLeading != nil {
+		e.callbacks.OnStoppedLeading()
+	}
+}
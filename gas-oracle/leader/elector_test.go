@@ -0,0 +1,59 @@
+package leader
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	lease := NewFileLease(filepath.Join(t.TempDir(), "lease"))
+	ttl := 150 * time.Millisecond
+
+	started := make(chan string, 2)
+	stopped := make(chan string, 2)
+	newElector := func(id string) *Elector {
+		return NewElector(lease, id, ttl, Callbacks{
+			OnStartedLeading: func() { started <- id },
+			OnStoppedLeading: func() { stopped <- id },
+		})
+	}
+
+	a := newElector("a")
+	a.Start()
+	waitFor(t, a.IsLeader)
+	if id := <-started; id != "a" {
+		t.Fatalf("expected a to start leading, got %s", id)
+	}
+
+	b := newElector("b")
+	b.Start()
+	defer b.Stop()
+	// b stays a standby while a keeps renewing
+	time.Sleep(2 * ttl)
+	if b.IsLeader() {
+		t.Fatal("expected b to be a standby")
+	}
+
+	a.Stop()
+	if a.IsLeader() {
+		t.Fatal("expected a to step down when stopped")
+	}
+	if id := <-stopped; id != "a" {
+		t.Fatalf("expected a to stop leading, got %s", id)
+	}
+	waitFor(t, b.IsLeader)
+	if id := <-started; id != "b" {
+		t.Fatalf("expected b to start leading, got %s", id)
+	}
+}
@@ -0,0 +1,100 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// leaseRecord is the content of a lease file
+type leaseRecord struct {
+	Holder string `json:"holder"`
+	Expiry int64  `json:"expiry"`
+}
+
+// FileLease is a Lease backed by a file on a local or shared filesystem.
+// Every read-modify-write of the file happens under an exclusive flock so
+// that instances on the same host never both hold the lease.
+type FileLease struct {
+	path string
+}
+
+// NewFileLease creates a FileLease stored at path
+func NewFileLease(path string) *FileLease {
+	return &FileLease{path: path}
+}
+
+// TryAcquire implements Lease
+func (l *FileLease) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, errInvalidTTL
+	}
+	acquired := false
+	err := l.update(func(record *leaseRecord) bool {
+		now := time.Now()
+		if record.Holder != "" && record.Holder != id && now.UnixNano() < record.Expiry {
+			return false
+		}
+		record.Holder = id
+		record.Expiry = now.Add(ttl).UnixNano()
+		acquired = true
+		return true
+	})
+	return acquired, err
+}
+
+// Release implements Lease
+func (l *FileLease) Release(ctx context.Context, id string) error {
+	return l.update(func(record *leaseRecord) bool {
+		if record.Holder != id {
+			return false
+		}
+		*record = leaseRecord{}
+		return true
+	})
+}
+
+// update locks the lease file and calls fn with its content. The
+// record is written back when fn returns true.
+func (l *FileLease) update(fn func(*leaseRecord) bool) error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	var record leaseRecord
+	if len(data) > 0 {
+		// A corrupt lease is treated as a free lease
+		if err := json.Unmarshal(data, &record); err != nil {
+			record = leaseRecord{}
+		}
+	}
+	if !fn(&record) {
+		return nil
+	}
+
+	data, err = json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
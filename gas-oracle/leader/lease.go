@@ -0,0 +1,23 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errInvalidTTL represents the error when a lease is requested with a ttl
+// that is not positive
+var errInvalidTTL = errors.New("lease ttl must be positive")
+
+// Lease is a time bound lock that is held by at most one instance at a time.
+// A holder must renew the lease before it expires to keep holding it.
+type Lease interface {
+	// TryAcquire acquires the lease for id when it is free or expired and
+	// renews it when it is already held by id. It returns true when id
+	// holds the lease for the next ttl.
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if it is held by id so that a standby
+	// can take over without waiting for the lease to expire.
+	Release(ctx context.Context, id string) error
+}
@@ -0,0 +1,80 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+)
+
+func testLease(t *testing.T, lease Lease) {
+	ctx := context.Background()
+	ttl := 200 * time.Millisecond
+
+	acquired, err := lease.TryAcquire(ctx, "a", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Fatal("expected a to acquire the free lease")
+	}
+	// The holder can renew the lease
+	if acquired, _ := lease.TryAcquire(ctx, "a", ttl); !acquired {
+		t.Fatal("expected a to renew the lease")
+	}
+	// Nobody else can take it while it is held
+	if acquired, _ := lease.TryAcquire(ctx, "b", ttl); acquired {
+		t.Fatal("expected b not to acquire a held lease")
+	}
+	// Releasing by somebody else does nothing
+	if err := lease.Release(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if acquired, _ := lease.TryAcquire(ctx, "b", ttl); acquired {
+		t.Fatal("expected b not to acquire the lease after releasing it as b")
+	}
+	// The lease is free once released by its holder
+	if err := lease.Release(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if acquired, _ := lease.TryAcquire(ctx, "b", ttl); !acquired {
+		t.Fatal("expected b to acquire the released lease")
+	}
+	if _, err := lease.TryAcquire(ctx, "b", 0); err == nil {
+		t.Fatal("expected a zero ttl to be rejected")
+	}
+}
+
+func TestFileLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lease")
+	testLease(t, NewFileLease(path))
+
+	// The lease can be taken over once it expires
+	lease := NewFileLease(path)
+	time.Sleep(250 * time.Millisecond)
+	if acquired, _ := lease.TryAcquire(context.Background(), "a", time.Second); !acquired {
+		t.Fatal("expected a to acquire the expired lease")
+	}
+}
+
+func TestRedisLease(t *testing.T) {
+	redis, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redis.Close()
+
+	lease, err := NewRedisLease("redis://"+redis.Addr(), "gas-oracle/leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLease(t, lease)
+
+	// The lease can be taken over once it expires
+	redis.FastForward(250 * time.Millisecond)
+	if acquired, _ := lease.TryAcquire(context.Background(), "a", time.Second); !acquired {
+		t.Fatal("expected a to acquire the expired lease")
+	}
+}
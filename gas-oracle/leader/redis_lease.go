@@ -0,0 +1,63 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// acquireScript sets the lease when it is free or already held by the
+// caller, so that acquiring and renewing are a single atomic operation
+var acquireScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease only when it is held by the caller
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLease is a Lease stored under a single key in Redis
+type RedisLease struct {
+	rdb *redis.Client
+	key string
+}
+
+// NewRedisLease creates a RedisLease using the redis url and key
+func NewRedisLease(url string, key string) (*RedisLease, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opts)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	return &RedisLease{rdb: rdb, key: key}, nil
+}
+
+// TryAcquire implements Lease
+func (l *RedisLease) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, errInvalidTTL
+	}
+	res, err := acquireScript.Run(ctx, l.rdb, []string{l.key}, id, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Release implements Lease
+func (l *RedisLease) Release(ctx context.Context, id string) error {
+	return releaseScript.Run(ctx, l.rdb, []string{l.key}, id).Err()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/mantlenetworkio/mantle/gas-oracle/backtest"
 	"github.com/mantlenetworkio/mantle/gas-oracle/flags"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
 	"github.com/mantlenetworkio/mantle/gas-oracle/oracle"
@@ -37,6 +38,15 @@ func main() {
 		return nil
 	}
 
+	app.Commands = []cli.Command{
+		{
+			Name:   "backtest",
+			Usage:  "Replay a recorded dataset through the pricing logic without network access",
+			Flags:  flags.BacktestFlags,
+			Action: backtestAction,
+		},
+	}
+
 	// Define the functionality of the application
 	app.Action = func(ctx *cli.Context) error {
 		if args := ctx.Args(); len(args) > 0 {
@@ -78,3 +88,48 @@ func main() {
 		log.Crit("application failed", "message", err)
 	}
 }
+
+// backtestAction runs the gas pricing logic over a recorded dataset
+// using the configured pricing parameters
+func backtestAction(ctx *cli.Context) error {
+	config, err := backtest.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if config.Output == "" {
+		// Keep the logs out of the series written to stdout
+		loglevel := ctx.GlobalUint64(flags.LogLevelFlag.Name)
+		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(loglevel), log.StreamHandler(os.Stderr, log.TerminalFormat(true))))
+	} else {
+		out, err = os.Create(config.Output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	dataset, err := backtest.LoadDataset(config.Dataset)
+	if err != nil {
+		return err
+	}
+	result, err := backtest.Run(config, dataset)
+	if err != nil {
+		return err
+	}
+
+	switch config.Format {
+	case backtest.FormatJSON:
+		return result.WriteJSON(out)
+	default:
+		summary := result.Summary
+		log.Info("Backtest summary", "duration", summary.DurationSeconds,
+			"min-gas-price", summary.MinGasPrice, "max-gas-price", summary.MaxGasPrice,
+			"mean-gas-price", summary.MeanGasPrice, "final-gas-price", summary.FinalGasPrice,
+			"epochs-at-floor", summary.EpochsAtFloor, "gas-price-skipped", summary.GasPriceSkipped,
+			"min-l1-base-fee", summary.MinL1BaseFee, "max-l1-base-fee", summary.MaxL1BaseFee,
+			"final-l1-base-fee", summary.FinalL1BaseFee, "l1-base-fee-skipped", summary.L1BaseFeeSkipped)
+		return result.WriteCSV(out)
+	}
+}
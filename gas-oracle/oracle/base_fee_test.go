@@ -30,7 +30,7 @@ func TestBaseFeeUpdate(t *testing.T) {
 		gasPrice:              big.NewInt(784637584),
 	}
 
-	update, err := wrapUpdateBaseFee(sim, sim, cfg)
+	update, err := wrapUpdateBaseFee(sim, sim, newTxSender(sim, opts.From, nil, 0), cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"os"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -35,6 +36,20 @@ type Config struct {
 	l1BaseFeeSignificanceFactor      float64
 	enableL1BaseFee                  bool
 	enableL2GasPrice                 bool
+	// Admin API config
+	adminEnabled  bool
+	adminHTTP     string
+	adminPort     int
+	adminToken    string
+	adminAuditLog string
+	// High availability config
+	haBackend                string
+	haLeaseFile              string
+	haRedisURL               string
+	haLeaseKey               string
+	haLeaseTTLSeconds        uint64
+	haNodeID                 string
+	haTakeoverTimeoutSeconds uint64
 	// Metrics config
 	MetricsEnabled          bool
 	MetricsHTTP             string
@@ -96,6 +111,27 @@ func NewConfig(ctx *cli.Context) *Config {
 		cfg.waitForReceipt = true
 	}
 
+	cfg.adminEnabled = ctx.GlobalBool(flags.AdminEnabledFlag.Name)
+	cfg.adminHTTP = ctx.GlobalString(flags.AdminHTTPFlag.Name)
+	cfg.adminPort = ctx.GlobalInt(flags.AdminPortFlag.Name)
+	cfg.adminToken = ctx.GlobalString(flags.AdminTokenFlag.Name)
+	cfg.adminAuditLog = ctx.GlobalString(flags.AdminAuditLogFlag.Name)
+
+	cfg.haBackend = ctx.GlobalString(flags.HABackendFlag.Name)
+	cfg.haLeaseFile = ctx.GlobalString(flags.HALeaseFileFlag.Name)
+	cfg.haRedisURL = ctx.GlobalString(flags.HARedisURLFlag.Name)
+	cfg.haLeaseKey = ctx.GlobalString(flags.HALeaseKeyFlag.Name)
+	cfg.haLeaseTTLSeconds = ctx.GlobalUint64(flags.HALeaseTTLSecondsFlag.Name)
+	cfg.haNodeID = ctx.GlobalString(flags.HANodeIDFlag.Name)
+	cfg.haTakeoverTimeoutSeconds = ctx.GlobalUint64(flags.HATakeoverTimeoutSecondsFlag.Name)
+	if cfg.haNodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Error("cannot get hostname", "message", err)
+		}
+		cfg.haNodeID = hostname
+	}
+
 	cfg.MetricsEnabled = ctx.GlobalBool(flags.MetricsEnabledFlag.Name)
 	cfg.MetricsHTTP = ctx.GlobalString(flags.MetricsHTTPFlag.Name)
 	cfg.MetricsPort = ctx.GlobalInt(flags.MetricsPortFlag.Name)
@@ -11,8 +11,10 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/gas-oracle/admin"
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
 	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+	"github.com/mantlenetworkio/mantle/gas-oracle/leader"
 	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
 )
 
@@ -32,6 +34,12 @@ var (
 	// errNoBaseFee represents the error when the base fee is not found on the
 	// block. This means that the block being queried is pre eip1559
 	errNoBaseFee = errors.New("base fee not found on block")
+	// errUnknownHABackend represents the error when the configured lease
+	// backend for leader election is not supported
+	errUnknownHABackend = errors.New("unknown ha backend")
+	// errNoAdminToken represents the error when the admin API is enabled
+	// without a token to authenticate operators
+	errNoAdminToken = errors.New("no admin token provided")
 )
 
 // GasPriceOracle manages a hot key that can update the L2 Gas Price
@@ -44,6 +52,11 @@ type GasPriceOracle struct {
 	l2Backend       DeployContractBackend
 	l1Backend       bind.ContractTransactor
 	gasPriceUpdater *gasprices.GasPriceUpdater
+	sender          *txSender
+	elector         *leader.Elector
+	controls        *admin.Controls
+	audit           *admin.AuditLog
+	adminServer     *admin.Server
 	config          *Config
 }
 
@@ -74,6 +87,18 @@ func (g *GasPriceOracle) Start() error {
 	log.Info("Starting Gas Price Oracle enableL1BaseFee", "enableL1BaseFee",
 		g.config.enableL1BaseFee, "enableL2GasPrice", g.config.enableL2GasPrice)
 
+	// Standbys run both loops so that they are up to date when they
+	// take over, only the leader sends transactions
+	if g.elector != nil {
+		g.elector.Start()
+	}
+
+	if g.adminServer != nil {
+		if err := g.adminServer.Start(); err != nil {
+			return err
+		}
+	}
+
 	if g.config.enableL1BaseFee {
 		go g.BaseFeeLoop()
 	}
@@ -85,7 +110,20 @@ func (g *GasPriceOracle) Start() error {
 }
 
 func (g *GasPriceOracle) Stop() {
+	if g.adminServer != nil {
+		if err := g.adminServer.Stop(); err != nil {
+			log.Error("cannot stop admin server", "message", err)
+		}
+	}
+	if g.elector != nil {
+		g.elector.Stop()
+	}
 	close(g.stop)
+	if g.audit != nil {
+		if err := g.audit.Close(); err != nil {
+			log.Error("cannot close audit log", "message", err)
+		}
+	}
 }
 
 func (g *GasPriceOracle) Wait() {
@@ -123,6 +161,13 @@ func (g *GasPriceOracle) Loop() {
 				log.Error("cannot update gas price", "message", err)
 			}
 
+		case <-g.controls.Changed(admin.LoopL2GasPrice):
+			// apply the operator's change without waiting for the next epoch
+			log.Info("L2 gas price controls changed")
+			if err := g.gasPriceUpdater.ApplyGasPrice(); err != nil {
+				log.Error("cannot apply gas price", "message", err)
+			}
+
 		case <-g.ctx.Done():
 			g.Stop()
 		}
@@ -133,17 +178,30 @@ func (g *GasPriceOracle) BaseFeeLoop() {
 	timer := time.NewTicker(time.Duration(g.config.l1BaseFeeEpochLengthSeconds) * time.Second)
 	defer timer.Stop()
 
-	updateBaseFee, err := wrapUpdateBaseFee(g.l1Backend, g.l2Backend, g.config)
+	updateBaseFee, err := wrapUpdateBaseFee(g.l1Backend, g.l2Backend, g.sender, g.config)
 	if err != nil {
 		panic(err)
 	}
 
+	update := func() {
+		if g.controls.Paused(admin.LoopL1BaseFee) {
+			log.Info("L1 base fee loop paused")
+			return
+		}
+		if err := updateBaseFee(); err != nil {
+			log.Error("cannot update l1 base fee", "messgae", err)
+		}
+	}
+
 	for {
 		select {
 		case <-timer.C:
-			if err := updateBaseFee(); err != nil {
-				log.Error("cannot update l1 base fee", "messgae", err)
-			}
+			update()
+
+		case <-g.controls.Changed(admin.LoopL1BaseFee):
+			// apply the operator's change without waiting for the next epoch
+			log.Info("L1 base fee controls changed")
+			update()
 
 		case <-g.ctx.Done():
 			g.Stop()
@@ -192,6 +250,19 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Operators can pause the loops and override the values they set
+	var audit *admin.AuditLog
+	if cfg.adminEnabled {
+		if cfg.adminToken == "" {
+			return nil, errNoAdminToken
+		}
+		audit, err = admin.NewAuditLog(cfg.adminAuditLog)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open audit log: %w", err)
+		}
+	}
+	controls := admin.NewControls(audit)
+
 	// Ensure that we can actually connect to both backends
 	log.Info("Connecting to layer two")
 	if err := ensureConnection(l2Client); err != nil {
@@ -267,6 +338,19 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 		return nil, errNoPrivateKey
 	}
 
+	// The sender serializes the transactions of both loops and only
+	// sends them while this instance is the leader
+	owner := crypto.PubkeyToAddress(cfg.privateKey.PublicKey)
+	takeoverTimeout := time.Duration(cfg.haTakeoverTimeoutSeconds) * time.Second
+	sender := newTxSender(l2Client, owner, nil, takeoverTimeout)
+	elector, err := newElector(cfg, sender)
+	if err != nil {
+		return nil, err
+	}
+	if elector != nil {
+		sender.leadership = elector
+	}
+
 	tip, err := l2Client.HeaderByNumber(context.Background(), nil)
 	if err != nil {
 		return nil, err
@@ -279,10 +363,11 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 	getLatestBlockNumberFn := wrapGetLatestBlockNumberFn(l2Client)
 	// updateL2GasPriceFn is used by the GasPriceUpdater to
 	// update the gas price
-	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(l2Client, cfg)
+	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(l2Client, sender, cfg)
 	if err != nil {
 		return nil, err
 	}
+	updateL2GasPriceFn = wrapControlledUpdateL2GasPriceFn(controls, updateL2GasPriceFn)
 	// getGasUsedByBlockFn is used by the GasPriceUpdater
 	// to fetch the amount of gas that a block has used
 	getGasUsedByBlockFn := wrapGetGasUsedByBlock(l2Client)
@@ -312,9 +397,18 @@ func NewGasPriceOracle(cfg *Config) (*GasPriceOracle, error) {
 		stop:            make(chan struct{}),
 		contract:        contract,
 		gasPriceUpdater: gasPriceUpdater,
+		sender:          sender,
+		elector:         elector,
+		controls:        controls,
+		audit:           audit,
 		config:          cfg,
 		l2Backend:       l2Client,
-		l1Backend:       l1Client,
+		l1Backend:       &controlledL1Client{ContractTransactor: l1Client, controls: controls},
+	}
+
+	if cfg.adminEnabled {
+		address := fmt.Sprintf("%s:%d", cfg.adminHTTP, cfg.adminPort)
+		gpo.adminServer = admin.NewServer(address, cfg.adminToken, controls, &gpo)
 	}
 
 	if err := gpo.ensure(); err != nil {
@@ -342,3 +436,33 @@ func ensureConnection(client *ethclient.Client) error {
 	}
 	return nil
 }
+
+// newElector creates the leader election for the configured lease backend.
+// It returns nil when leader election is disabled.
+func newElector(cfg *Config, sender *txSender) (*leader.Elector, error) {
+	var lease leader.Lease
+	switch cfg.haBackend {
+	case "":
+		return nil, nil
+	case "file":
+		lease = leader.NewFileLease(cfg.haLeaseFile)
+	case "redis":
+		redisLease, err := leader.NewRedisLease(cfg.haRedisURL, cfg.haLeaseKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to redis: %w", err)
+		}
+		lease = redisLease
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownHABackend, cfg.haBackend)
+	}
+	if cfg.haLeaseTTLSeconds < 1 {
+		return nil, errors.New("ha lease ttl cannot be less than 1 second")
+	}
+
+	log.Info("Creating leader election", "backend", cfg.haBackend, "id", cfg.haNodeID,
+		"ttl", cfg.haLeaseTTLSeconds)
+	return leader.NewElector(lease, cfg.haNodeID, time.Duration(cfg.haLeaseTTLSeconds)*time.Second, leader.Callbacks{
+		OnStartedLeading: sender.OnStartedLeading,
+		OnStoppedLeading: sender.OnStoppedLeading,
+	}), nil
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/gas-oracle/admin"
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
 	"github.com/mantlenetworkio/mantle/gas-oracle/tokenprice"
 )
 
@@ -37,6 +41,26 @@ func (c *L1Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.
 	if tip == nil {
 		return tip, nil
 	}
-	tip.BaseFee = new(big.Int).Mul(tip.BaseFee, big.NewInt(int64(ratio)))
+	tip.BaseFee = gasprices.ScaleL1BaseFee(tip.BaseFee, ratio)
+	return tip, nil
+}
+
+// controlledL1Client replaces the L1 base fee of the tip with the value
+// set by an operator while an override is in effect
+type controlledL1Client struct {
+	bind.ContractTransactor
+	controls *admin.Controls
+}
+
+func (c *controlledL1Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	tip, err := c.ContractTransactor.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if baseFee, ok := c.controls.Override(admin.LoopL1BaseFee); ok && tip != nil {
+		log.Debug("using overridden L1 base fee", "baseFee", baseFee, "tip", tip.BaseFee)
+		tip = types.CopyHeader(tip)
+		tip.BaseFee = baseFee
+	}
 	return tip, nil
 }
@@ -0,0 +1,69 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/mantlenetworkio/mantle/gas-oracle/admin"
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
+)
+
+// Preview computes the values that the enabled loops would set next,
+// taking pauses and overrides into account. It does not send any
+// transaction nor update the state of the GasPriceUpdater.
+func (g *GasPriceOracle) Preview(ctx context.Context) (*admin.Preview, error) {
+	preview := new(admin.Preview)
+
+	if g.config.enableL2GasPrice {
+		current, err := g.contract.GasPrice(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("cannot get gas price: %w", err)
+		}
+		computed, avgGasPerSecond, err := g.gasPriceUpdater.PreviewGasPrice()
+		if err != nil {
+			return nil, fmt.Errorf("cannot preview gas price: %w", err)
+		}
+		l2 := &admin.GasPricePreview{
+			Current:         current.Uint64(),
+			Computed:        computed,
+			AvgGasPerSecond: avgGasPerSecond,
+			Next:            computed,
+			Paused:          g.controls.Paused(admin.LoopL2GasPrice),
+		}
+		if gasPrice, ok := g.controls.Override(admin.LoopL2GasPrice); ok {
+			l2.Next = gasPrice.Uint64()
+			l2.Overridden = true
+		}
+		l2.WouldUpdate = !l2.Paused && l2.Current != l2.Next &&
+			gasprices.IsDifferenceSignificant(l2.Current, l2.Next, g.config.l2GasPriceSignificanceFactor)
+		preview.L2GasPrice = l2
+	}
+
+	if g.config.enableL1BaseFee {
+		current, err := g.contract.L1BaseFee(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("cannot get l1 base fee: %w", err)
+		}
+		// The L1 backend applies the override
+		tip, err := g.l1Backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if tip.BaseFee == nil {
+			return nil, errNoBaseFee
+		}
+		_, overridden := g.controls.Override(admin.LoopL1BaseFee)
+		l1 := &admin.L1BaseFeePreview{
+			Current:    current,
+			Next:       tip.BaseFee,
+			Paused:     g.controls.Paused(admin.LoopL1BaseFee),
+			Overridden: overridden,
+		}
+		l1.WouldUpdate = !l1.Paused &&
+			gasprices.IsDifferenceSignificant(current.Uint64(), tip.BaseFee.Uint64(), g.config.l1BaseFeeSignificanceFactor)
+		preview.L1BaseFee = l1
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,228 @@
+package oracle
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
+)
+
+// replacementPriceBump is the percentage by which the gas price is raised
+// when replacing a transaction left behind by a previous leader. It must
+// be above the 10% required by the transaction pool.
+const replacementPriceBump = 25
+
+var (
+	txStandbyCounter    = metrics.NewRegisteredCounter("tx/standby", ometrics.DefaultRegistry)
+	nonceResyncCounter  = metrics.NewRegisteredCounter("nonce/resync", ometrics.DefaultRegistry)
+	nonceReplaceCounter = metrics.NewRegisteredCounter("nonce/replace", ometrics.DefaultRegistry)
+)
+
+// NonceBackend is a ContractBackend that can also read the nonce of an
+// account at a block, which is required to detect in flight transactions
+type NonceBackend interface {
+	DeployContractBackend
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// Leadership reports whether this instance is allowed to send transactions
+type Leadership interface {
+	IsLeader() bool
+}
+
+// txSender sends the transactions of both update loops from the owner key.
+// It hands out nonces from a local counter so that the loops never race on
+// a nonce, and it only sends while this instance is the leader.
+type txSender struct {
+	backend         NonceBackend
+	address         common.Address
+	leadership      Leadership
+	takeoverTimeout time.Duration
+
+	// sendMu serializes Send. mu guards the fields below, it is never held
+	// while waiting on the backend so that the leadership callbacks of the
+	// elector do not block.
+	sendMu sync.Mutex
+	mu     sync.Mutex
+	// generation is bumped on every leadership change, a Send that started
+	// in an earlier generation drops its result
+	generation uint64
+	// nonce is the next nonce to use, it is nil when it must be synced
+	// with the backend before the next transaction
+	nonce *uint64
+	// takeover is set when this instance just became the leader and the
+	// previous leader may still have transactions in flight
+	takeover bool
+	// replace is set when the next transaction replaces one that was
+	// left behind by the previous leader
+	replace bool
+}
+
+// newTxSender creates a txSender. When leadership is nil the sender
+// always considers itself the leader.
+func newTxSender(backend NonceBackend, address common.Address, leadership Leadership, takeoverTimeout time.Duration) *txSender {
+	return &txSender{
+		backend:         backend,
+		address:         address,
+		leadership:      leadership,
+		takeoverTimeout: takeoverTimeout,
+	}
+}
+
+// OnStartedLeading makes the sender wait for the transactions of the
+// previous leader before it sends its own
+func (s *txSender) OnStartedLeading() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	s.nonce = nil
+	s.takeover = true
+	s.replace = false
+}
+
+// OnStoppedLeading forgets the local nonce
+func (s *txSender) OnStoppedLeading() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	s.nonce = nil
+	s.takeover = false
+	s.replace = false
+}
+
+// Send builds a transaction with the next nonce and sends it. It returns
+// a nil transaction without an error when this instance is a standby, or
+// when the leadership changed while the transaction was being sent.
+func (s *txSender) Send(ctx context.Context, opts *bind.TransactOpts, build func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if !s.isLeader() {
+		return nil, nil
+	}
+	s.mu.Lock()
+	generation, synced, takeover := s.generation, s.nonce != nil, s.takeover
+	s.mu.Unlock()
+	if !synced {
+		nonce, replace, err := s.sync(ctx, takeover)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if s.generation == generation {
+			s.nonce = &nonce
+			s.replace = replace
+			s.takeover = false
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.generation != generation || s.nonce == nil {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	nonce, replace := *s.nonce, s.replace
+	s.mu.Unlock()
+
+	// Copy the options so that the nonce and the gas price bump
+	// do not leak into the caller's options
+	txOpts := *opts
+	txOpts.Nonce = new(big.Int).SetUint64(nonce)
+	if replace && txOpts.GasPrice != nil {
+		bump := new(big.Int).Mul(txOpts.GasPrice, big.NewInt(100+replacementPriceBump))
+		txOpts.GasPrice = bump.Div(bump, big.NewInt(100))
+	}
+
+	// Syncing the nonce may take up to takeoverTimeout, the leadership
+	// may have been lost in the meantime
+	if !s.isLeader() {
+		return nil, nil
+	}
+	tx, err := build(&txOpts)
+	if err != nil {
+		return nil, err
+	}
+	if !s.isLeader() {
+		return nil, nil
+	}
+	err = s.backend.SendTransaction(ctx, tx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		// The nonce was reset by the leadership change
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	if err != nil {
+		// The nonce may have been used by somebody else, resync it
+		// before the next transaction
+		s.nonce = nil
+		return nil, err
+	}
+	if replace {
+		log.Info("replaced transaction of previous leader", "hash", tx.Hash().Hex(), "nonce", tx.Nonce())
+		nonceReplaceCounter.Inc(1)
+		s.replace = false
+	}
+	next := tx.Nonce() + 1
+	s.nonce = &next
+	return tx, nil
+}
+
+// isLeader reports whether the sender may send, it counts the transactions
+// that are not sent because this instance is a standby
+func (s *txSender) isLeader() bool {
+	if s.leadership != nil && !s.leadership.IsLeader() {
+		txStandbyCounter.Inc(1)
+		return false
+	}
+	return true
+}
+
+// sync returns the next nonce from the backend. After a takeover, it waits
+// for the transactions of the previous leader to be included. When they are
+// not included in time, the lowest pending nonce is replaced, and replace is
+// set.
+func (s *txSender) sync(ctx context.Context, takeover bool) (nonce uint64, replace bool, err error) {
+	nonceResyncCounter.Inc(1)
+	pending, err := s.backend.PendingNonceAt(ctx, s.address)
+	if err != nil {
+		return 0, false, err
+	}
+	if !takeover {
+		return pending, false, nil
+	}
+
+	deadline := time.Now().Add(s.takeoverTimeout)
+	for {
+		latest, err := s.backend.NonceAt(ctx, s.address, nil)
+		if err != nil {
+			return 0, false, err
+		}
+		if latest >= pending {
+			return latest, false, nil
+		}
+		if time.Now().After(deadline) {
+			log.Warn("transactions of previous leader not included, replacing them",
+				"latest", latest, "pending", pending)
+			return latest, true, nil
+		}
+		log.Info("waiting for transactions of previous leader", "latest", latest, "pending", pending)
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package oracle
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+type testLeadership struct {
+	leader int32
+}
+
+func (l *testLeadership) IsLeader() bool {
+	return atomic.LoadInt32(&l.leader) == 1
+}
+
+func (l *testLeadership) set(leader bool) {
+	if leader {
+		atomic.StoreInt32(&l.leader, 1)
+	} else {
+		atomic.StoreInt32(&l.leader, 0)
+	}
+}
+
+// buildTransfer returns a build function of a transfer to the sender itself
+func buildTransfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	tx := types.NewTransaction(opts.Nonce.Uint64(), opts.From, new(big.Int), 21000, big.NewInt(params.GWei), nil)
+	return opts.Signer(opts.From, tx)
+}
+
+func TestTxSenderNonces(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sim, _ := newSimulatedBackend(key)
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	sender := newTxSender(sim, opts.From, nil, 0)
+
+	for i := uint64(0); i < 3; i++ {
+		tx, err := sender.Send(context.Background(), opts, buildTransfer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Nonce() != i {
+			t.Fatalf("expected nonce %d, got %d", i, tx.Nonce())
+		}
+	}
+	if opts.Nonce != nil {
+		t.Fatal("the nonce leaked into the caller's options")
+	}
+}
+
+func TestTxSenderStandby(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sim, _ := newSimulatedBackend(key)
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	leadership := &testLeadership{}
+	sender := newTxSender(sim, opts.From, leadership, 0)
+
+	tx, err := sender.Send(context.Background(), opts, buildTransfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx != nil {
+		t.Fatal("a standby sent a transaction")
+	}
+
+	// the leadership is lost while the transaction is built
+	leadership.set(true)
+	tx, err = sender.Send(context.Background(), opts, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		leadership.set(false)
+		return buildTransfer(opts)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx != nil {
+		t.Fatal("a former leader sent a transaction")
+	}
+	pending, err := sim.PendingNonceAt(context.Background(), opts.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Fatalf("expected no pending transaction, got nonce %d", pending)
+	}
+}
+
+func TestTxSenderResyncsAfterFailure(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sim, _ := newSimulatedBackend(key)
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	sender := newTxSender(sim, opts.From, nil, 0)
+
+	if _, err := sender.Send(context.Background(), opts, buildTransfer); err != nil {
+		t.Fatal(err)
+	}
+	// somebody else uses the next nonce
+	other, err := buildTransfer(&bind.TransactOpts{From: opts.From, Nonce: big.NewInt(1), Signer: opts.Signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sender.Send(context.Background(), opts, buildTransfer); err == nil {
+		t.Fatal("expected a nonce error")
+	}
+	tx, err := sender.Send(context.Background(), opts, buildTransfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 2 {
+		t.Fatalf("expected the resynced nonce 2, got %d", tx.Nonce())
+	}
+}
+
+func TestTxSenderLeadershipChangeDuringTakeover(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sim, _ := newSimulatedBackend(key)
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	leadership := &testLeadership{}
+	leadership.set(true)
+	sender := newTxSender(sim, opts.From, leadership, time.Second)
+
+	// the previous leader left a transaction in flight
+	inflight, err := buildTransfer(&bind.TransactOpts{From: opts.From, Nonce: big.NewInt(0), Signer: opts.Signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), inflight); err != nil {
+		t.Fatal(err)
+	}
+
+	sender.OnStartedLeading()
+	type result struct {
+		tx  *types.Transaction
+		err error
+	}
+	sent := make(chan result, 1)
+	go func() {
+		tx, err := sender.Send(context.Background(), opts, buildTransfer)
+		sent <- result{tx, err}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// the elector is not blocked by the takeover wait
+	stopped := make(chan struct{})
+	go func() {
+		leadership.set(false)
+		sender.OnStoppedLeading()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the leadership callback is blocked by the takeover wait")
+	}
+
+	res := <-sent
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if res.tx != nil {
+		t.Fatal("a former leader sent a transaction")
+	}
+	pending, err := sim.PendingNonceAt(context.Background(), opts.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 {
+		t.Fatalf("expected only the in flight transaction, got pending nonce %d", pending)
+	}
+}
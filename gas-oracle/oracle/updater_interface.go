@@ -12,7 +12,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/mantlenetworkio/mantle/gas-oracle/admin"
 	"github.com/mantlenetworkio/mantle/gas-oracle/bindings"
+	"github.com/mantlenetworkio/mantle/gas-oracle/gasprices"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
 )
 
@@ -62,7 +64,7 @@ type DeployContractBackend interface {
 // to update the L2 gas price
 // perhaps this should take an options struct along with the backend?
 // how can this continue to be decomposed?
-func wrapUpdateL2GasPriceFn(backend DeployContractBackend, cfg *Config) (func(uint64) error, error) {
+func wrapUpdateL2GasPriceFn(backend DeployContractBackend, sender *txSender, cfg *Config) (func(uint64) error, error) {
 	if cfg.privateKey == nil {
 		return nil, errNoPrivateKey
 	}
@@ -120,7 +122,7 @@ func wrapUpdateL2GasPriceFn(backend DeployContractBackend, cfg *Config) (func(ui
 
 		// Only update the gas price when it must be changed by at least
 		// a paramaterizable amount.
-		if !isDifferenceSignificant(currentPrice.Uint64(), updatedGasPrice, cfg.l2GasPriceSignificanceFactor) {
+		if !gasprices.IsDifferenceSignificant(currentPrice.Uint64(), updatedGasPrice, cfg.l2GasPriceSignificanceFactor) {
 			log.Info("gas price did not significantly change", "min-factor", cfg.l2GasPriceSignificanceFactor,
 				"current-price", currentPrice, "next-price", updatedGasPrice)
 			txNotSignificantCounter.Inc(1)
@@ -128,18 +130,21 @@ func wrapUpdateL2GasPriceFn(backend DeployContractBackend, cfg *Config) (func(ui
 		}
 
 		// Set the gas price by sending a transaction
-		tx, err := contract.SetGasPrice(opts, new(big.Int).SetUint64(updatedGasPrice))
+		pre := time.Now()
+		tx, err := sender.Send(context.Background(), opts, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return contract.SetGasPrice(opts, new(big.Int).SetUint64(updatedGasPrice))
+		})
 		if err != nil {
 			return err
 		}
-
-		log.Debug("updating L2 gas price", "tx.gasPrice", tx.GasPrice(), "tx.gasLimit", tx.Gas(),
-			"tx.data", hexutil.Encode(tx.Data()), "tx.to", tx.To().Hex(), "tx.nonce", tx.Nonce())
-		pre := time.Now()
-		if err := backend.SendTransaction(context.Background(), tx); err != nil {
-			return err
+		if tx == nil {
+			log.Debug("standby, not updating L2 gas price", "gas-price", updatedGasPrice)
+			return nil
 		}
 		txSendTimer.Update(time.Since(pre))
+
+		log.Debug("updated L2 gas price", "tx.gasPrice", tx.GasPrice(), "tx.gasLimit", tx.Gas(),
+			"tx.data", hexutil.Encode(tx.Data()), "tx.to", tx.To().Hex(), "tx.nonce", tx.Nonce())
 		log.Info("L2 gas price transaction sent", "hash", tx.Hash().Hex())
 
 		gasPriceGauge.Update(int64(updatedGasPrice))
@@ -162,15 +167,21 @@ func wrapUpdateL2GasPriceFn(backend DeployContractBackend, cfg *Config) (func(ui
 	}, nil
 }
 
-// Only update the gas price when it must be changed by at least
-// a paramaterizable amount. If the param is greater than the result
-// of 1 - (min/max) where min and max are the gas prices then do not
-// update the gas price
-func isDifferenceSignificant(a, b uint64, c float64) bool {
-	max := max(a, b)
-	min := min(a, b)
-	factor := 1 - (float64(min) / float64(max))
-	return c <= factor
+// wrapControlledUpdateL2GasPriceFn applies the pause and the override set
+// by an operator before updating the L2 gas price. The GasPriceUpdater
+// keeps computing prices while the loop is paused or overridden.
+func wrapControlledUpdateL2GasPriceFn(controls *admin.Controls, updateL2GasPriceFn func(uint64) error) func(uint64) error {
+	return func(updatedGasPrice uint64) error {
+		if controls.Paused(admin.LoopL2GasPrice) {
+			log.Info("L2 gas price loop paused", "computed-price", updatedGasPrice)
+			return nil
+		}
+		if gasPrice, ok := controls.Override(admin.LoopL2GasPrice); ok {
+			log.Info("using overridden L2 gas price", "gas-price", gasPrice, "computed-price", updatedGasPrice)
+			updatedGasPrice = gasPrice.Uint64()
+		}
+		return updateL2GasPriceFn(updatedGasPrice)
+	}
 }
 
 // Wait for the receipt by polling the backend
@@ -193,17 +204,3 @@ func waitForReceipt(backend DeployContractBackend, tx *types.Transaction) (*type
 	}
 	return receipt, nil
 }
-
-func max(a, b uint64) uint64 {
-	if a >= b {
-		return a
-	}
-	return b
-}
-
-func min(a, b uint64) uint64 {
-	if a >= b {
-		return b
-	}
-	return a
-}
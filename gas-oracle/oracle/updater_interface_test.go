@@ -69,7 +69,7 @@ func TestWrapUpdateL2GasPriceFn(t *testing.T) {
 		gasPrice:              big.NewInt(783460975),
 	}
 
-	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(sim, cfg)
+	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(sim, newTxSender(sim, opts.From, nil, 0), cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -110,7 +110,7 @@ func TestWrapUpdateL2GasPriceFnNoUpdates(t *testing.T) {
 		// the new gas price must change be 50% for it to actually update
 		l2GasPriceSignificanceFactor: 0.5,
 	}
-	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(sim, cfg)
+	updateL2GasPriceFn, err := wrapUpdateL2GasPriceFn(sim, newTxSender(sim, opts.From, nil, 0), cfg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -167,30 +167,6 @@ func TestWrapUpdateL2GasPriceFnNoUpdates(t *testing.T) {
 	tryUpdate(1, true)
 }
 
-func TestIsDifferenceSignificant(t *testing.T) {
-	tests := []struct {
-		name   string
-		a      uint64
-		b      uint64
-		sig    float64
-		expect bool
-	}{
-		{name: "test 1", a: 1, b: 1, sig: 0.05, expect: false},
-		{name: "test 2", a: 4, b: 1, sig: 0.25, expect: true},
-		{name: "test 3", a: 3, b: 1, sig: 0.1, expect: true},
-		{name: "test 4", a: 4, b: 1, sig: 0.9, expect: false},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			result := isDifferenceSignificant(tc.a, tc.b, tc.sig)
-			if result != tc.expect {
-				t.Fatalf("mismatch %s", tc.name)
-			}
-		})
-	}
-}
-
 func newSimulatedBackend(key *ecdsa.PrivateKey) (*backends.SimulatedBackend, ethdb.Database) {
 	var gasLimit uint64 = 9_000_000
 	auth, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
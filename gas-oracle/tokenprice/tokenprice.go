@@ -11,6 +11,19 @@ import (
 
 var errHTTPError = errors.New("http error")
 
+// PriceRatioProvider returns the ETH/BIT price ratio that is used to scale
+// the L2 gas price and the L1 base fee
+type PriceRatioProvider interface {
+	PriceRatio() (float64, error)
+}
+
+// FixedRatio is a PriceRatioProvider that always returns the same ratio
+type FixedRatio float64
+
+func (r FixedRatio) PriceRatio() (float64, error) {
+	return float64(r), nil
+}
+
 // NewClient create a new Client given a remote HTTP url and update frequency
 func NewClient(url string, frequency uint64) *Client {
 	client := resty.New()
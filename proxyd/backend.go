@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -80,9 +81,30 @@ var (
 		HTTPErrorCode: 429,
 	}
 
+	ErrDuplicateTransaction = &RPCErr{
+		Code:    JSONRPCErrorInternal - 18,
+		Message: "already known",
+	}
+
 	ErrBackendUnexpectedJSONRPC = errors.New("backend returned an unexpected JSON-RPC response")
 )
 
+func ErrInvalidTransaction(msg string) *RPCErr {
+	return &RPCErr{
+		Code:          JSONRPCErrorInternal - 17,
+		Message:       "invalid transaction: " + msg,
+		HTTPErrorCode: 400,
+	}
+}
+
+func ErrGetLogsLimit(msg string) *RPCErr {
+	return &RPCErr{
+		Code:          JSONRPCErrorInternal - 19,
+		Message:       "eth_getLogs " + msg,
+		HTTPErrorCode: 400,
+	}
+}
+
 func ErrInvalidRequest(msg string) *RPCErr {
 	return &RPCErr{
 		Code:          -32601,
@@ -92,6 +114,9 @@ func ErrInvalidRequest(msg string) *RPCErr {
 }
 
 type Backend struct {
+	// outstanding is the number of in flight requests, it is first
+	// so that it is aligned for atomic operations
+	outstanding          int64
 	Name                 string
 	rpcURL               string
 	wsURL                string
@@ -107,6 +132,9 @@ type Backend struct {
 	outOfServiceInterval time.Duration
 	stripTrailingXFF     bool
 	proxydIP             string
+	weight               int
+	latency              latencyTracker
+	breaker              *circuitBreaker
 }
 
 type BackendOpt func(b *Backend)
@@ -175,6 +203,20 @@ func WithProxydIP(ip string) BackendOpt {
 	}
 }
 
+func WithWeight(weight int) BackendOpt {
+	return func(b *Backend) {
+		b.weight = weight
+	}
+}
+
+// WithCircuitBreaker takes the backend out of service with a circuit
+// breaker instead of after MaxRetries failed attempts
+func WithCircuitBreaker(config CircuitBreakerConfig) BackendOpt {
+	return func(b *Backend) {
+		b.breaker = newCircuitBreaker(b.Name, b.rateLimiter, config)
+	}
+}
+
 func NewBackend(
 	name string,
 	rpcURL string,
@@ -189,6 +231,7 @@ func NewBackend(
 		wsURL:           wsURL,
 		rateLimiter:     rateLimiter,
 		maxResponseSize: math.MaxInt64,
+		weight:          1,
 		client: &LimitedHTTPClient{
 			Client:      http.Client{Timeout: 5 * time.Second},
 			sem:         rpcSemaphore,
@@ -209,15 +252,32 @@ func NewBackend(
 }
 
 func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
-	if !b.Online() {
+	online, probe := b.online()
+	if !online {
 		RecordBatchRPCError(ctx, b.Name, reqs, ErrBackendOffline)
 		return nil, ErrBackendOffline
 	}
+	// A circuit breaker probe that ends without a result gives its slot back
+	var recorded bool
+	if probe {
+		defer func() {
+			if !recorded {
+				b.breaker.release()
+			}
+		}()
+	}
 	if b.IsRateLimited() {
 		RecordBatchRPCError(ctx, b.Name, reqs, ErrBackendOverCapacity)
 		return nil, ErrBackendOverCapacity
 	}
 
+	atomic.AddInt64(&b.outstanding, 1)
+	backendOutstandingRequestsGauge.WithLabelValues(b.Name).Inc()
+	defer func() {
+		atomic.AddInt64(&b.outstanding, -1)
+		backendOutstandingRequestsGauge.WithLabelValues(b.Name).Dec()
+	}()
+
 	var lastError error
 	// <= to account for the first attempt not technically being
 	// a retry
@@ -235,9 +295,15 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 			),
 		)
 
+		start := time.Now()
 		res, err := b.doForward(ctx, reqs, isBatch)
 		switch err {
-		case nil: // do nothing
+		case nil:
+			// A backend that does not support a method is not unhealthy
+			if !isMethodNotSupported(res) {
+				b.recordResult(false, time.Since(start))
+				recorded = true
+			}
 		// ErrBackendUnexpectedJSONRPC occurs because infura responds with a single JSON-RPC object
 		// to a batch request whenever any Request Object in the batch would induce a partial error.
 		// We don't label the the backend offline in this case. But the error is still returned to
@@ -259,10 +325,20 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 			)
 			timer.ObserveDuration()
 			RecordBatchRPCError(ctx, b.Name, reqs, err)
+			// Requests cancelled by the client do not count against the backend
+			if ctx.Err() == nil {
+				b.recordResult(true, time.Since(start))
+				b.latency.observeFailure(time.Since(start))
+				recorded = true
+			}
+			if b.breaker != nil && b.breaker.isOpen() {
+				return nil, wrapErr(lastError, "circuit breaker opened forwarding request")
+			}
 			sleepContext(ctx, calcBackoff(i))
 			continue
 		}
 		timer.ObserveDuration()
+		b.latency.observe(time.Since(start))
 
 		MaybeRecordErrorsInRPCRes(ctx, b.Name, reqs, res)
 		return res, err
@@ -273,27 +349,52 @@ func (b *Backend) Forward(ctx context.Context, reqs []*RPCReq, isBatch bool) ([]
 }
 
 func (b *Backend) ProxyWS(clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
-	if !b.Online() {
+	backendConn, err := b.dialWS()
+	if err != nil {
+		return nil, err
+	}
+	return NewWSProxier(b, clientConn, backendConn, methodWhitelist), nil
+}
+
+// dialWS opens a websocket connection to the backend. The dial result is
+// reported to the circuit breaker, so that a dial let through as a probe
+// counts towards closing or reopening the circuit.
+func (b *Backend) dialWS() (*websocket.Conn, error) {
+	online, probe := b.online()
+	if !online {
 		return nil, ErrBackendOffline
 	}
 	if b.IsWSSaturated() {
+		if probe {
+			b.breaker.release()
+		}
 		return nil, ErrBackendOverCapacity
 	}
 
-	backendConn, _, err := b.dialer.Dial(b.wsURL, nil) // nolint:bodyclose
+	start := time.Now()
+	conn, _, err := b.dialer.Dial(b.wsURL, nil) // nolint:bodyclose
 	if err != nil {
+		b.recordResult(true, 0)
 		b.setOffline()
 		if err := b.rateLimiter.DecBackendWSConns(b.Name); err != nil {
 			log.Error("error decrementing backend ws conns", "name", b.Name, "err", err)
 		}
 		return nil, wrapErr(err, "error dialing backend")
 	}
+	b.recordResult(false, time.Since(start))
 
 	activeBackendWsConnsGauge.WithLabelValues(b.Name).Inc()
-	return NewWSProxier(b, clientConn, backendConn, methodWhitelist), nil
+	return conn, nil
 }
 
 func (b *Backend) Online() bool {
+	online, _ := b.online()
+	return online
+}
+
+// online reports whether the backend is in service, and whether a request
+// sent to it is a circuit breaker probe
+func (b *Backend) online() (bool, bool) {
 	online, err := b.rateLimiter.IsBackendOnline(b.Name)
 	if err != nil {
 		log.Warn(
@@ -301,9 +402,12 @@ func (b *Backend) Online() bool {
 			"name", b.Name,
 			"err", err,
 		)
-		return false
+		return false, false
 	}
-	return online
+	if b.breaker != nil {
+		return b.breaker.take(online)
+	}
+	return online, false
 }
 
 func (b *Backend) IsRateLimited() bool {
@@ -343,6 +447,10 @@ func (b *Backend) IsWSSaturated() bool {
 }
 
 func (b *Backend) setOffline() {
+	// The circuit breaker decides when the backend goes out of service
+	if b.breaker != nil {
+		return
+	}
 	err := b.rateLimiter.SetBackendOffline(b.Name, b.outOfServiceInterval)
 	if err != nil {
 		log.Warn(
@@ -353,6 +461,13 @@ func (b *Backend) setOffline() {
 	}
 }
 
+// recordResult reports the result of a request to the circuit breaker
+func (b *Backend) recordResult(failed bool, latency time.Duration) {
+	if b.breaker != nil {
+		b.breaker.record(failed, latency)
+	}
+}
+
 func (b *Backend) doForward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
 	isSingleElementBatch := len(rpcReqs) == 1
 
@@ -472,8 +587,23 @@ func sortBatchRPCResponse(req []*RPCReq, res []*RPCRes) {
 }
 
 type BackendGroup struct {
-	Name     string
-	Backends []*Backend
+	Name      string
+	Backends  []*Backend
+	Consensus *ConsensusPoller
+	Selector  BackendSelector
+}
+
+// candidates returns the backends that may serve a request, in the order
+// they are tried
+func (b *BackendGroup) candidates() []*Backend {
+	backends := b.Backends
+	if b.Consensus != nil {
+		backends = b.Consensus.FilterHealthy(backends)
+	}
+	if b.Selector == nil {
+		return backends
+	}
+	return b.Selector.Order(backends)
 }
 
 func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch bool) ([]*RPCRes, error) {
@@ -483,7 +613,7 @@ func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch b
 
 	rpcRequestsTotal.Inc()
 
-	for _, back := range b.Backends {
+	for _, back := range b.candidates() {
 		res, err := back.Forward(ctx, rpcReqs, isBatch)
 		if errors.Is(err, ErrMethodNotWhitelisted) {
 			return nil, err
@@ -524,7 +654,7 @@ func (b *BackendGroup) Forward(ctx context.Context, rpcReqs []*RPCReq, isBatch b
 }
 
 func (b *BackendGroup) ProxyWS(ctx context.Context, clientConn *websocket.Conn, methodWhitelist *StringSet) (*WSProxier, error) {
-	for _, back := range b.Backends {
+	for _, back := range b.candidates() {
 		proxier, err := back.ProxyWS(clientConn, methodWhitelist)
 		if errors.Is(err, ErrBackendOffline) {
 			log.Warn(
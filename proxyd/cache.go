@@ -124,15 +124,23 @@ type rpcCache struct {
 	handlers map[string]RPCMethodHandler
 }
 
-func newRPCCache(cache Cache, getLatestBlockNumFn GetLatestBlockNumFn, getLatestGasPriceFn GetLatestGasPriceFn, numBlockConfirmations int) RPCCache {
+// newRPCCache creates the cache of RPC responses. Responses about blocks are
+// cached once the blocks are numBlockConfirmations behind the latest block
+// or, when getVerifiedBlockNumFn is set, once they are verified.
+func newRPCCache(cache Cache, getLatestBlockNumFn GetLatestBlockNumFn, getLatestGasPriceFn GetLatestGasPriceFn, getVerifiedBlockNumFn GetLatestBlockNumFn, numBlockConfirmations int) RPCCache {
+	finality := &blockFinality{getLatestBlockNumFn, getVerifiedBlockNumFn, numBlockConfirmations}
 	handlers := map[string]RPCMethodHandler{
-		"eth_chainId":          &StaticMethodHandler{},
-		"net_version":          &StaticMethodHandler{},
-		"eth_getBlockByNumber": &EthGetBlockByNumberMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
-		"eth_getBlockRange":    &EthGetBlockRangeMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
-		"eth_blockNumber":      &EthBlockNumberMethodHandler{getLatestBlockNumFn},
-		"eth_gasPrice":         &EthGasPriceMethodHandler{getLatestGasPriceFn},
-		"eth_call":             &EthCallMethodHandler{cache, getLatestBlockNumFn, numBlockConfirmations},
+		"eth_chainId":               &StaticMethodHandler{},
+		"net_version":               &StaticMethodHandler{},
+		"eth_getBlockByNumber":      &EthGetBlockByNumberMethodHandler{cache, finality},
+		"eth_getBlockRange":         &EthGetBlockRangeMethodHandler{cache, finality},
+		"eth_blockNumber":           &EthBlockNumberMethodHandler{getLatestBlockNumFn},
+		"eth_gasPrice":              &EthGasPriceMethodHandler{getLatestGasPriceFn},
+		"eth_call":                  &EthCallMethodHandler{cache, finality},
+		"eth_getBlockByHash":        &EthGetByHashMethodHandler{"eth_getBlockByHash", "number", true, cache, finality},
+		"eth_getTransactionByHash":  &EthGetByHashMethodHandler{"eth_getTransactionByHash", "blockNumber", false, cache, finality},
+		"eth_getTransactionReceipt": &EthGetByHashMethodHandler{"eth_getTransactionReceipt", "blockNumber", false, cache, finality},
+		"eth_getLogs":               &EthGetLogsMethodHandler{cache, finality},
 	}
 	return &rpcCache{
 		cache:    cache,
@@ -146,14 +154,15 @@ func (c *rpcCache) GetRPC(ctx context.Context, req *RPCReq) (*RPCRes, error) {
 		return nil, nil
 	}
 	res, err := handler.GetRPCMethod(ctx, req)
-	if res != nil {
-		if res == nil {
-			RecordCacheMiss(req.Method)
-		} else {
-			RecordCacheHit(req.Method)
-		}
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		RecordCacheMiss(req.Method)
+	} else {
+		RecordCacheHit(req.Method)
 	}
-	return res, err
+	return res, nil
 }
 
 func (c *rpcCache) PutRPC(ctx context.Context, req *RPCReq, res *RPCRes) error {
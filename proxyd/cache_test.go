@@ -18,7 +18,7 @@ func TestRPCCacheImmutableRPCs(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), getBlockNum, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -134,7 +134,7 @@ func TestRPCCacheBlockNumber(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, nil, numBlockConfirmations)
 
 	req := &RPCReq{
 		JSONRPC: "2.0",
@@ -172,7 +172,7 @@ func TestRPCCacheGasPrice(t *testing.T) {
 	getBlockNum := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), getBlockNum, getGasPrice, nil, numBlockConfirmations)
 
 	req := &RPCReq{
 		JSONRPC: "2.0",
@@ -205,7 +205,7 @@ func TestRPCCacheUnsupportedMethod(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -234,7 +234,7 @@ func TestRPCCacheEthGetBlockByNumber(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations) }
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -290,7 +290,7 @@ func TestRPCCacheEthGetBlockByNumberForRecentBlocks(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -347,7 +347,7 @@ func TestRPCCacheEthGetBlockByNumberInvalidRequest(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -377,7 +377,7 @@ func TestRPCCacheEthGetBlockRange(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations) }
 	ID := []byte(strconv.Itoa(1))
 
 	t.Run("finalized block", func(t *testing.T) {
@@ -427,7 +427,7 @@ func TestRPCCacheEthGetBlockRangeForRecentBlocks(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -498,7 +498,7 @@ func TestRPCCacheEthGetBlockRangeInvalidRequest(t *testing.T) {
 	fn := func(ctx context.Context) (uint64, error) {
 		return blockHead, nil
 	}
-	cache := newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations)
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
 	ID := []byte(strconv.Itoa(1))
 
 	rpcs := []struct {
@@ -556,7 +556,7 @@ func TestRPCCacheEthCall(t *testing.T) {
 		return blockHead, nil
 	}
 
-	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, numBlockConfirmations) }
+	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations) }
 	ID := []byte(strconv.Itoa(1))
 
 	req := &RPCReq{
@@ -620,3 +620,242 @@ func TestRPCCacheEthCall(t *testing.T) {
 		require.Nil(t, cachedRes)
 	})
 }
+
+func TestRPCCacheEthGetTransactionReceipt(t *testing.T) {
+	ctx := context.Background()
+
+	var blockHead uint64
+	fn := func(ctx context.Context) (uint64, error) {
+		return blockHead, nil
+	}
+	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations) }
+	ID := []byte(strconv.Itoa(1))
+
+	req := &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getTransactionReceipt",
+		Params:  []byte(`["0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"]`),
+		ID:      ID,
+	}
+	res := &RPCRes{
+		JSONRPC: "2.0",
+		Result:  map[string]interface{}{"blockNumber": "0x10", "status": "0x1"},
+		ID:      ID,
+	}
+
+	t.Run("finalized block", func(t *testing.T) {
+		blockHead = 0x100
+		cache := makeCache()
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, res, cachedRes)
+
+		// Hashes are not case sensitive
+		upper := *req
+		upper.Params = []byte(`["0x88DF016429689C079F3B2F6AD39FA052532C56795B733DA78A91EBE6A713944B"]`)
+		cachedRes, err = cache.GetRPC(ctx, &upper)
+		require.NoError(t, err)
+		require.Equal(t, res, cachedRes)
+	})
+
+	t.Run("unconfirmed block", func(t *testing.T) {
+		blockHead = 0x11
+		cache := makeCache()
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+
+	t.Run("pending transaction", func(t *testing.T) {
+		blockHead = 0x100
+		cache := makeCache()
+		pendingRes := &RPCRes{
+			JSONRPC: "2.0",
+			Result:  map[string]interface{}{"blockNumber": nil},
+			ID:      ID,
+		}
+		require.NoError(t, cache.PutRPC(ctx, req, pendingRes))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		blockHead = 0x100
+		cache := makeCache()
+		req := &RPCReq{
+			JSONRPC: "2.0",
+			Method:  "eth_getTransactionReceipt",
+			Params:  []byte(`["0x1234"]`),
+			ID:      ID,
+		}
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+}
+
+func TestRPCCacheEthGetBlockByHash(t *testing.T) {
+	ctx := context.Background()
+
+	fn := func(ctx context.Context) (uint64, error) {
+		return 0x100, nil
+	}
+	cache := newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations)
+	ID := []byte(strconv.Itoa(1))
+
+	const hash = "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+	req := &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getBlockByHash",
+		Params:  []byte(`["` + hash + `", false]`),
+		ID:      ID,
+	}
+	res := &RPCRes{
+		JSONRPC: "2.0",
+		Result:  map[string]interface{}{"number": "0x10", "hash": hash},
+		ID:      ID,
+	}
+	require.NoError(t, cache.PutRPC(ctx, req, res))
+	cachedRes, err := cache.GetRPC(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, res, cachedRes)
+
+	// Blocks with full transactions are cached separately
+	fullReq := &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getBlockByHash",
+		Params:  []byte(`["` + hash + `", true]`),
+		ID:      ID,
+	}
+	cachedRes, err = cache.GetRPC(ctx, fullReq)
+	require.NoError(t, err)
+	require.Nil(t, cachedRes)
+}
+
+func TestRPCCacheEthGetLogs(t *testing.T) {
+	ctx := context.Background()
+
+	var blockHead uint64
+	fn := func(ctx context.Context) (uint64, error) {
+		return blockHead, nil
+	}
+	makeCache := func() RPCCache { return newRPCCache(newMemoryCache(), fn, nil, nil, numBlockConfirmations) }
+	ID := []byte(strconv.Itoa(1))
+
+	makeReq := func(filter string) *RPCReq {
+		return &RPCReq{
+			JSONRPC: "2.0",
+			Method:  "eth_getLogs",
+			Params:  []byte(`[` + filter + `]`),
+			ID:      ID,
+		}
+	}
+	res := &RPCRes{
+		JSONRPC: "2.0",
+		Result:  []interface{}{map[string]interface{}{"logIndex": "0x0"}},
+		ID:      ID,
+	}
+
+	t.Run("finalized range", func(t *testing.T) {
+		blockHead = 0x100
+		cache := makeCache()
+		req := makeReq(`{"fromBlock": "0x1", "toBlock": "0x10", "address": "0xDEADBEEF", "topics": [["0xA"]]}`)
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, res, cachedRes)
+
+		cachedRes, err = cache.GetRPC(ctx, makeReq(`{"fromBlock": "0x1", "toBlock": "0x10", "address": "0xdeadbeef", "topics": [["0xa"]]}`))
+		require.NoError(t, err)
+		require.Equal(t, res, cachedRes)
+
+		cachedRes, err = cache.GetRPC(ctx, makeReq(`{"fromBlock": "0x1", "toBlock": "0x11", "address": "0xdeadbeef", "topics": [["0xa"]]}`))
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+
+	t.Run("unconfirmed range", func(t *testing.T) {
+		blockHead = 0x15
+		cache := makeCache()
+		req := makeReq(`{"fromBlock": "0x1", "toBlock": "0x10"}`)
+		require.NoError(t, cache.PutRPC(ctx, req, res))
+		cachedRes, err := cache.GetRPC(ctx, req)
+		require.NoError(t, err)
+		require.Nil(t, cachedRes)
+	})
+
+	for _, filter := range []string{
+		`{"fromBlock": "0x1", "toBlock": "latest"}`,
+		`{"fromBlock": "0x1"}`,
+		`{"blockHash": "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"}`,
+	} {
+		t.Run("uncacheable filter "+filter, func(t *testing.T) {
+			blockHead = 0x100
+			cache := makeCache()
+			req := makeReq(filter)
+			require.NoError(t, cache.PutRPC(ctx, req, res))
+			cachedRes, err := cache.GetRPC(ctx, req)
+			require.NoError(t, err)
+			require.Nil(t, cachedRes)
+		})
+	}
+}
+
+func TestRPCCacheVerifiedIndex(t *testing.T) {
+	ctx := context.Background()
+
+	fn := func(ctx context.Context) (uint64, error) {
+		return math.MaxUint64, nil
+	}
+	var verified uint64
+	verifiedFn := func(ctx context.Context) (uint64, error) {
+		return verified, nil
+	}
+	cache := newRPCCache(newMemoryCache(), fn, nil, verifiedFn, numBlockConfirmations)
+	ID := []byte(strconv.Itoa(1))
+
+	req := &RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_getBlockByNumber",
+		Params:  []byte(`["0x10", false]`),
+		ID:      ID,
+	}
+	res := &RPCRes{
+		JSONRPC: "2.0",
+		Result:  `{"number": "0x10"}`,
+		ID:      ID,
+	}
+
+	// The block is confirmed by the latest block number but not verified
+	verified = 0xf
+	require.NoError(t, cache.PutRPC(ctx, req, res))
+	cachedRes, err := cache.GetRPC(ctx, req)
+	require.NoError(t, err)
+	require.Nil(t, cachedRes)
+
+	verified = 0x10
+	require.NoError(t, cache.PutRPC(ctx, req, res))
+	cachedRes, err = cache.GetRPC(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, res, cachedRes)
+}
+
+func TestCacheWithGeneration(t *testing.T) {
+	ctx := context.Background()
+	tracker := &HeadTracker{}
+	cache := newCacheWithGeneration(newMemoryCache(), tracker)
+
+	require.NoError(t, cache.Put(ctx, "foo", "bar"))
+	val, err := cache.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Equal(t, "bar", val)
+
+	tracker.setGeneration("0x1234")
+	val, err = cache.Get(ctx, "foo")
+	require.NoError(t, err)
+	require.Empty(t, val)
+}
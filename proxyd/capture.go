@@ -0,0 +1,277 @@
+package proxyd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultCaptureMaxFileSize = 100 * 1024 * 1024
+	defaultCaptureMaxFiles    = 10
+	captureBufferSize         = 1000
+	captureFilePrefix         = "capture-"
+	captureFileSuffix         = ".ndjson"
+)
+
+// CaptureRecord is one captured HTTP RPC call. Captures are NDJSON files of
+// records. The auth key and the IP of the client are not recorded.
+type CaptureRecord struct {
+	Time       time.Time       `json:"time"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"`
+	StatusCode int             `json:"status_code"`
+	LatencyMs  float64         `json:"latency_ms"`
+}
+
+// Method returns the method of the captured request, or <batch> for batch
+// requests
+func (c *CaptureRecord) Method() string {
+	if IsBatch(c.Request) {
+		return "<batch>"
+	}
+	req, err := ParseRPCReq(c.Request)
+	if err != nil {
+		return MethodUnknown
+	}
+	return req.Method
+}
+
+// ReadCapture reads the records of a capture file
+func ReadCapture(r io.Reader) ([]*CaptureRecord, error) {
+	var records []*CaptureRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), math.MaxInt32)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record := new(CaptureRecord)
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, wrapErr(err, "error parsing capture record")
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, wrapErr(err, "error reading capture")
+	}
+	return records, nil
+}
+
+// TrafficCapture writes a sample of the served RPC calls to rotating NDJSON
+// files. Records are written in the background and dropped when the writer
+// falls behind, so that capturing never slows down requests.
+type TrafficCapture struct {
+	dir         string
+	sampleRate  float64
+	maxFileSize int64
+	maxFiles    int
+
+	records chan *CaptureRecord
+	done    chan struct{}
+	once    sync.Once
+
+	file *os.File
+	buf  *bufio.Writer
+	size int64
+}
+
+func NewTrafficCapture(config CaptureConfig) (*TrafficCapture, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("must define a capture dir")
+	}
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("capture sample rate must be between 0 and 1")
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, wrapErr(err, "error creating capture dir")
+	}
+	c := &TrafficCapture{
+		dir:         config.Dir,
+		sampleRate:  config.SampleRate,
+		maxFileSize: config.MaxFileSizeBytes,
+		maxFiles:    config.MaxFiles,
+		records:     make(chan *CaptureRecord, captureBufferSize),
+		done:        make(chan struct{}),
+	}
+	if c.sampleRate == 0 {
+		c.sampleRate = 1
+	}
+	if c.maxFileSize == 0 {
+		c.maxFileSize = defaultCaptureMaxFileSize
+	}
+	if c.maxFiles == 0 {
+		c.maxFiles = defaultCaptureMaxFiles
+	}
+	go c.loop()
+	return c, nil
+}
+
+// Sample reports whether the next call should be captured
+func (c *TrafficCapture) Sample() bool {
+	return c.sampleRate >= 1 || rand.Float64() < c.sampleRate
+}
+
+// Record queues a record to be written
+func (c *TrafficCapture) Record(record *CaptureRecord) {
+	select {
+	case c.records <- record:
+	default:
+		captureDroppedTotal.Inc()
+	}
+}
+
+// Close writes the queued records and closes the current file
+func (c *TrafficCapture) Close() {
+	c.once.Do(func() {
+		close(c.records)
+		<-c.done
+	})
+}
+
+func (c *TrafficCapture) loop() {
+	defer close(c.done)
+	for record := range c.records {
+		if err := c.write(record); err != nil {
+			log.Error("error writing capture record", "err", err)
+			captureDroppedTotal.Inc()
+		}
+		// Flush once the queue is drained
+		if len(c.records) == 0 && c.buf != nil {
+			if err := c.buf.Flush(); err != nil {
+				log.Error("error flushing capture file", "err", err)
+			}
+		}
+	}
+	if c.file != nil {
+		if err := c.buf.Flush(); err != nil {
+			log.Error("error flushing capture file", "err", err)
+		}
+		if err := c.file.Close(); err != nil {
+			log.Error("error closing capture file", "err", err)
+		}
+	}
+}
+
+func (c *TrafficCapture) write(record *CaptureRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if c.file == nil || c.size+int64(len(line)) > c.maxFileSize {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := c.buf.Write(line)
+	c.size += int64(n)
+	if err != nil {
+		return err
+	}
+	captureRecordsTotal.Inc()
+	return nil
+}
+
+// rotate starts a new capture file and deletes the oldest files beyond
+// maxFiles
+func (c *TrafficCapture) rotate() error {
+	if c.file != nil {
+		if err := c.buf.Flush(); err != nil {
+			return err
+		}
+		if err := c.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	name := captureFilePrefix + time.Now().UTC().Format("20060102T150405.000000000") + captureFileSuffix
+	file, err := os.Create(filepath.Join(c.dir, name))
+	if err != nil {
+		return wrapErr(err, "error creating capture file")
+	}
+	c.file = file
+	c.buf = bufio.NewWriter(file)
+	c.size = 0
+
+	files, err := CaptureFiles(c.dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > c.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return wrapErr(err, "error removing capture file")
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// CaptureFiles returns the capture files of dir, oldest first
+func CaptureFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, wrapErr(err, "error listing capture files")
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, captureFilePrefix) && strings.HasSuffix(name, captureFileSuffix) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// captureResponseWriter keeps a copy of the response written to the client
+type captureResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newCaptureResponseWriter(w http.ResponseWriter) *captureResponseWriter {
+	return &captureResponseWriter{ResponseWriter: w, statusCode: 200}
+}
+
+func (w *captureResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *captureResponseWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// record returns the capture record of the call, or nil when the request or
+// the response is not JSON
+func (w *captureResponseWriter) record(start time.Time, body []byte) *CaptureRecord {
+	res := bytes.TrimSpace(w.body.Bytes())
+	if !json.Valid(body) || !json.Valid(res) {
+		return nil
+	}
+	return &CaptureRecord{
+		Time:       start.UTC(),
+		Request:    json.RawMessage(body),
+		Response:   json.RawMessage(res),
+		StatusCode: w.statusCode,
+		LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+	}
+}
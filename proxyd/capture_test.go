@@ -0,0 +1,125 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readCaptureDir(t *testing.T, dir string) []*CaptureRecord {
+	files, err := CaptureFiles(dir)
+	require.NoError(t, err)
+	var records []*CaptureRecord
+	for _, file := range files {
+		f, err := os.Open(file)
+		require.NoError(t, err)
+		fileRecords, err := ReadCapture(f)
+		f.Close()
+		require.NoError(t, err)
+		records = append(records, fileRecords...)
+	}
+	return records
+}
+
+func TestTrafficCaptureRotation(t *testing.T) {
+	dir := t.TempDir()
+	capture, err := NewTrafficCapture(CaptureConfig{
+		Dir:              dir,
+		MaxFileSizeBytes: 300,
+		MaxFiles:         2,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		capture.Record(&CaptureRecord{
+			Time:       time.Unix(int64(i), 0),
+			Request:    json.RawMessage(`{"jsonrpc": "2.0", "method": "eth_chainId", "id": 1}`),
+			Response:   json.RawMessage(`{"jsonrpc":"2.0","result":"0x1","id":1}`),
+			StatusCode: 200,
+		})
+		// Rotated files are named after the time they were created
+		time.Sleep(time.Millisecond)
+	}
+	capture.Close()
+
+	files, err := CaptureFiles(dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		require.LessOrEqual(t, info.Size(), int64(300))
+	}
+
+	// The newest records are kept, compacted to one line each
+	records := readCaptureDir(t, dir)
+	require.Equal(t, time.Unix(9, 0).UTC(), records[len(records)-1].Time.UTC())
+	require.Equal(t, `{"jsonrpc":"2.0","method":"eth_chainId","id":1}`, string(records[0].Request))
+	require.Equal(t, "eth_chainId", records[0].Method())
+}
+
+func TestReplay(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := ParseRPCReq(body)
+		require.NoError(t, err)
+		if req.Method == "eth_blockNumber" {
+			w.WriteHeader(503)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32011,"message":"no backends available for method"},"id":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc": "2.0", "result": "0x1", "id": 1}`))
+	}))
+	defer target.Close()
+
+	start := time.Now()
+	records := []*CaptureRecord{
+		{
+			Time:       start,
+			Request:    json.RawMessage(`{"jsonrpc":"2.0","method":"eth_chainId","id":1}`),
+			Response:   json.RawMessage(`{"jsonrpc":"2.0","result":"0x1","id":1}`),
+			StatusCode: 200,
+			LatencyMs:  5,
+		},
+		{
+			Time:       start.Add(100 * time.Millisecond),
+			Request:    json.RawMessage(`{"jsonrpc":"2.0","method":"eth_gasPrice","id":1}`),
+			Response:   json.RawMessage(`{"jsonrpc":"2.0","result":"0x2","id":1}`),
+			StatusCode: 200,
+			LatencyMs:  10,
+		},
+		{
+			Time:       start.Add(200 * time.Millisecond),
+			Request:    json.RawMessage(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`),
+			Response:   json.RawMessage(`{"jsonrpc":"2.0","result":"0x3","id":1}`),
+			StatusCode: 200,
+			LatencyMs:  15,
+		},
+	}
+
+	// The capture is replayed at twice its speed
+	replayStart := time.Now()
+	summary := Replay(context.Background(), ReplayConfig{Target: target.URL, Speed: 2}, records)
+	require.GreaterOrEqual(t, time.Since(replayStart), 100*time.Millisecond)
+
+	require.Equal(t, 3, summary.Total)
+	require.Equal(t, 1, summary.Matched)
+	require.Equal(t, 2, summary.Mismatched)
+	require.Equal(t, map[string]int{"eth_gasPrice": 1, "eth_blockNumber": 1}, summary.MismatchesByMethod)
+	require.Equal(t, "status code 503, captured 200", summary.Results[2].Mismatch)
+	require.Equal(t, 10*time.Millisecond, summary.CapturedLatency.P50)
+
+	var out strings.Builder
+	summary.Print(&out, 1)
+	require.Contains(t, out.String(), "replayed 3 requests: 1 matched, 2 mismatched, 0 failed")
+	require.Contains(t, out.String(), "eth_gasPrice: response")
+	require.NotContains(t, out.String(), "eth_blockNumber: status")
+}
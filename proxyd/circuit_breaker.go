@@ -0,0 +1,273 @@
+package proxyd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitHalfOpen = "half_open"
+	CircuitOpen     = "open"
+
+	defaultBreakerWindow         = 10 * time.Second
+	defaultBreakerMinRequests    = 20
+	defaultBreakerErrorRate      = 0.5
+	defaultBreakerSlowRate       = 0.5
+	defaultBreakerOpenDuration   = 30 * time.Second
+	defaultBreakerHalfOpenProbes = 3
+
+	// JSONRPCErrorMethodNotFound is returned by backends that do not
+	// support a method
+	JSONRPCErrorMethodNotFound = -32601
+)
+
+var circuitStateValues = map[string]float64{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// breakerBucket holds the results of one second of the sliding window
+type breakerBucket struct {
+	sec      int64
+	total    int
+	failures int
+	slow     int
+}
+
+// circuitBreaker takes a backend out of service when its error rate or the
+// rate of its slow responses crosses a threshold over a sliding window.
+// After the open period a few probe requests are let through, and the
+// backend gets full traffic again only when they succeed. The open state,
+// the probe count and the probe successes are kept in the
+// BackendRateLimiter, so that all proxyd instances sharing Redis agree on
+// them. A probe that gets no result gives its slot back.
+type circuitBreaker struct {
+	name               string
+	lim                BackendRateLimiter
+	minRequests        int
+	errorRateThreshold float64
+	latencyThreshold   time.Duration
+	slowRateThreshold  float64
+	openDuration       time.Duration
+	halfOpenProbes     int
+
+	mtx     sync.Mutex
+	state   string
+	buckets []breakerBucket
+}
+
+func newCircuitBreaker(name string, lim BackendRateLimiter, config CircuitBreakerConfig) *circuitBreaker {
+	window := secondsToDuration(config.WindowSeconds)
+	if window == 0 {
+		window = defaultBreakerWindow
+	}
+	cb := &circuitBreaker{
+		name:               name,
+		lim:                lim,
+		minRequests:        config.MinRequests,
+		errorRateThreshold: config.ErrorRateThreshold,
+		latencyThreshold:   time.Duration(config.LatencyThresholdMs) * time.Millisecond,
+		slowRateThreshold:  config.SlowRateThreshold,
+		openDuration:       secondsToDuration(config.OpenSeconds),
+		halfOpenProbes:     config.HalfOpenProbes,
+		state:              CircuitClosed,
+		buckets:            make([]breakerBucket, int(window/time.Second)),
+	}
+	if cb.minRequests == 0 {
+		cb.minRequests = defaultBreakerMinRequests
+	}
+	if cb.errorRateThreshold == 0 {
+		cb.errorRateThreshold = defaultBreakerErrorRate
+	}
+	if cb.slowRateThreshold == 0 {
+		cb.slowRateThreshold = defaultBreakerSlowRate
+	}
+	if cb.openDuration == 0 {
+		cb.openDuration = defaultBreakerOpenDuration
+	}
+	if cb.halfOpenProbes == 0 {
+		cb.halfOpenProbes = defaultBreakerHalfOpenProbes
+	}
+	backendCircuitStateGauge.WithLabelValues(name).Set(circuitStateValues[CircuitClosed])
+	return cb
+}
+
+// allow reports whether a request may be sent to the backend. online is
+// whether the rate limiter has the backend in service.
+func (cb *circuitBreaker) allow(online bool) bool {
+	allowed, _ := cb.take(online)
+	return allowed
+}
+
+// take reports whether a request may be sent to the backend, and whether
+// the request is a probe. A probe must either be recorded or released.
+func (cb *circuitBreaker) take(online bool) (bool, bool) {
+	cb.mtx.Lock()
+	if !online {
+		// Another proxyd instance opened the circuit
+		if cb.state != CircuitOpen {
+			cb.transition(CircuitOpen, "opened by another instance")
+		}
+		cb.mtx.Unlock()
+		return false, false
+	}
+	switch cb.state {
+	case CircuitClosed:
+		cb.mtx.Unlock()
+		return true, false
+	case CircuitOpen:
+		cb.transition(CircuitHalfOpen, "open period elapsed")
+	}
+	cb.mtx.Unlock()
+
+	probes, err := cb.lim.IncBackendProbes(cb.name, cb.openDuration)
+	if err != nil {
+		log.Error(
+			"error counting circuit breaker probes, assuming they are exhausted",
+			"name", cb.name,
+			"err", err,
+		)
+		return false, false
+	}
+	if probes > cb.halfOpenProbes {
+		// Give the count back so that released probes free a slot
+		cb.release()
+		return false, false
+	}
+	return true, true
+}
+
+// release gives back the slot of a probe that got no result, because it
+// was rate limited, cancelled, or answered in a way that says nothing about
+// the health of the backend
+func (cb *circuitBreaker) release() {
+	if err := cb.lim.DecBackendProbes(cb.name); err != nil {
+		log.Warn(
+			"error releasing circuit breaker probe",
+			"name", cb.name,
+			"err", err,
+		)
+	}
+}
+
+// isOpen reports whether the backend is out of service
+func (cb *circuitBreaker) isOpen() bool {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+	return cb.state == CircuitOpen
+}
+
+// record adds the result of a request to the sliding window, and opens or
+// closes the circuit accordingly
+func (cb *circuitBreaker) record(failed bool, latency time.Duration) {
+	slow := !failed && cb.latencyThreshold != 0 && latency > cb.latencyThreshold
+
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	switch cb.state {
+	case CircuitClosed:
+		now := time.Now().Unix()
+		bucket := &cb.buckets[now%int64(len(cb.buckets))]
+		if bucket.sec != now {
+			*bucket = breakerBucket{sec: now}
+		}
+		bucket.total++
+		if failed {
+			bucket.failures++
+		}
+		if slow {
+			bucket.slow++
+		}
+
+		var total, failures, slowCount int
+		for _, b := range cb.buckets {
+			if now-b.sec < int64(len(cb.buckets)) {
+				total += b.total
+				failures += b.failures
+				slowCount += b.slow
+			}
+		}
+		if total < cb.minRequests {
+			return
+		}
+		if errorRate := float64(failures) / float64(total); errorRate >= cb.errorRateThreshold {
+			cb.open("error rate", "error_rate", errorRate)
+		} else if slowRate := float64(slowCount) / float64(total); cb.latencyThreshold != 0 && slowRate >= cb.slowRateThreshold {
+			cb.open("latency", "slow_rate", slowRate)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			cb.open("probe failed")
+			return
+		}
+		if slow {
+			cb.open("probe was slow")
+			return
+		}
+		successes, err := cb.lim.IncBackendProbeSuccesses(cb.name, cb.openDuration)
+		if err != nil {
+			log.Error(
+				"error counting circuit breaker probe successes",
+				"name", cb.name,
+				"err", err,
+			)
+			return
+		}
+		if successes >= cb.halfOpenProbes {
+			cb.transition(CircuitClosed, "probes succeeded")
+		}
+	case CircuitOpen:
+		// Requests sent before the circuit opened
+	}
+}
+
+// open takes the backend out of service for the open period
+func (cb *circuitBreaker) open(reason string, ctx ...interface{}) {
+	if err := cb.lim.SetBackendOffline(cb.name, cb.openDuration); err != nil {
+		log.Warn(
+			"error setting backend offline",
+			"name", cb.name,
+			"err", err,
+		)
+	}
+	// The next half open period starts with fresh probes
+	if err := cb.lim.ResetBackendProbes(cb.name); err != nil {
+		log.Warn(
+			"error resetting circuit breaker probes",
+			"name", cb.name,
+			"err", err,
+		)
+	}
+	cb.transition(CircuitOpen, reason, ctx...)
+}
+
+// transition must be called with the lock held
+func (cb *circuitBreaker) transition(state string, reason string, ctx ...interface{}) {
+	cb.state = state
+	if state == CircuitClosed {
+		for i := range cb.buckets {
+			cb.buckets[i] = breakerBucket{}
+		}
+	}
+
+	backendCircuitStateGauge.WithLabelValues(cb.name).Set(circuitStateValues[state])
+	circuitBreakerTransitionsTotal.WithLabelValues(cb.name, state).Inc()
+	ctx = append([]interface{}{"name", cb.name, "state", state, "reason", reason}, ctx...)
+	if state == CircuitClosed {
+		log.Info("circuit breaker state changed", ctx...)
+	} else {
+		log.Warn("circuit breaker state changed", ctx...)
+	}
+}
+
+// isMethodNotSupported reports whether a backend answered a single request
+// with a method not found error. These responses say nothing about the
+// health of the backend.
+func isMethodNotSupported(res []*RPCRes) bool {
+	return len(res) == 1 && res[0].IsError() && res[0].Error.Code == JSONRPCErrorMethodNotFound
+}
@@ -0,0 +1,218 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func newTestCircuitBreaker(lim BackendRateLimiter, config CircuitBreakerConfig) *circuitBreaker {
+	cb := newCircuitBreaker("test", lim, config)
+	cb.openDuration = 100 * time.Millisecond
+	return cb
+}
+
+func online(t *testing.T, lim BackendRateLimiter) bool {
+	online, err := lim.IsBackendOnline("test")
+	require.NoError(t, err)
+	return online
+}
+
+func TestCircuitBreakerErrorRate(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	cb := newTestCircuitBreaker(lim, CircuitBreakerConfig{
+		MinRequests:        4,
+		ErrorRateThreshold: 0.5,
+		HalfOpenProbes:     2,
+	})
+
+	// Below the minimum number of requests the circuit stays closed
+	cb.record(true, 0)
+	cb.record(true, 0)
+	cb.record(true, 0)
+	require.True(t, cb.allow(online(t, lim)))
+
+	cb.record(false, 0)
+	require.Equal(t, CircuitOpen, cb.state)
+	require.False(t, online(t, lim))
+	require.False(t, cb.allow(online(t, lim)))
+
+	// After the open period only the probes are let through
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, cb.allow(online(t, lim)))
+	require.Equal(t, CircuitHalfOpen, cb.state)
+	require.True(t, cb.allow(online(t, lim)))
+	require.False(t, cb.allow(online(t, lim)))
+
+	cb.record(false, 0)
+	require.Equal(t, CircuitHalfOpen, cb.state)
+	cb.record(false, 0)
+	require.Equal(t, CircuitClosed, cb.state)
+	require.True(t, cb.allow(online(t, lim)))
+
+	// The window is reset when the circuit closes
+	cb.record(true, 0)
+	cb.record(true, 0)
+	require.Equal(t, CircuitClosed, cb.state)
+}
+
+func TestCircuitBreakerProbeFailure(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	cb := newTestCircuitBreaker(lim, CircuitBreakerConfig{MinRequests: 1})
+
+	cb.record(true, 0)
+	require.Equal(t, CircuitOpen, cb.state)
+
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, cb.allow(online(t, lim)))
+	cb.record(true, 0)
+	require.Equal(t, CircuitOpen, cb.state)
+	require.False(t, online(t, lim))
+}
+
+func TestCircuitBreakerLatency(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	cb := newTestCircuitBreaker(lim, CircuitBreakerConfig{
+		MinRequests:        4,
+		LatencyThresholdMs: 100,
+		SlowRateThreshold:  0.75,
+	})
+
+	cb.record(false, 200*time.Millisecond)
+	cb.record(false, 200*time.Millisecond)
+	cb.record(false, 10*time.Millisecond)
+	cb.record(false, 10*time.Millisecond)
+	require.Equal(t, CircuitClosed, cb.state)
+
+	for i := 0; i < 3; i++ {
+		cb.record(false, 200*time.Millisecond)
+	}
+	require.Equal(t, CircuitClosed, cb.state)
+	cb.record(false, 200*time.Millisecond)
+	require.Equal(t, CircuitOpen, cb.state)
+}
+
+func TestCircuitBreakerSharedState(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	config := CircuitBreakerConfig{MinRequests: 1, HalfOpenProbes: 1}
+	a := newTestCircuitBreaker(lim, config)
+	b := newTestCircuitBreaker(lim, config)
+
+	a.record(true, 0)
+	require.False(t, b.allow(online(t, lim)))
+	require.Equal(t, CircuitOpen, b.state)
+
+	// The probes are shared between the instances
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, a.allow(online(t, lim)))
+	require.False(t, b.allow(online(t, lim)))
+	require.Equal(t, CircuitHalfOpen, b.state)
+}
+
+func TestIsMethodNotSupported(t *testing.T) {
+	notFound := NewRPCErrorRes(nil, &RPCErr{Code: JSONRPCErrorMethodNotFound, Message: "method not found"})
+	require.True(t, isMethodNotSupported([]*RPCRes{notFound}))
+	require.False(t, isMethodNotSupported([]*RPCRes{NewRPCErrorRes(nil, ErrInternal)}))
+	require.False(t, isMethodNotSupported([]*RPCRes{NewRPCRes(nil, "0x1")}))
+	require.False(t, isMethodNotSupported([]*RPCRes{notFound, notFound}))
+}
+
+func TestCircuitBreakerReleasedProbe(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	cb := newTestCircuitBreaker(lim, CircuitBreakerConfig{MinRequests: 1, HalfOpenProbes: 1})
+
+	cb.record(true, 0)
+	time.Sleep(150 * time.Millisecond)
+	allowed, probe := cb.take(online(t, lim))
+	require.True(t, allowed)
+	require.True(t, probe)
+	require.False(t, cb.allow(online(t, lim)))
+
+	// A probe without a result frees its slot
+	cb.release()
+	allowed, probe = cb.take(online(t, lim))
+	require.True(t, allowed)
+	require.True(t, probe)
+}
+
+func TestCircuitBreakerSharedProbeSuccesses(t *testing.T) {
+	lim := NewLocalBackendRateLimiter()
+	config := CircuitBreakerConfig{MinRequests: 1, HalfOpenProbes: 2}
+	a := newTestCircuitBreaker(lim, config)
+	b := newTestCircuitBreaker(lim, config)
+
+	a.record(true, 0)
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, a.allow(online(t, lim)))
+	require.True(t, b.allow(online(t, lim)))
+
+	// The successes of both instances count towards closing the circuit
+	a.record(false, 0)
+	require.Equal(t, CircuitHalfOpen, a.state)
+	b.record(false, 0)
+	require.Equal(t, CircuitClosed, b.state)
+}
+
+func TestCircuitBreakerProbeMethodNotFound(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "method not found"}, "id": 1}`))
+	}))
+	defer server.Close()
+	be := NewBackend("test", server.URL, server.URL, NewLocalBackendRateLimiter(), semaphore.NewWeighted(100),
+		WithMaxRetries(0), WithStrippedTrailingXFF(), WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 1, HalfOpenProbes: 1}))
+	be.breaker.openDuration = 100 * time.Millisecond
+
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_foo", ID: []byte("1")}
+	_, err := be.Forward(context.Background(), []*RPCReq{req}, false)
+	require.Error(t, err)
+	require.True(t, be.breaker.isOpen())
+
+	// The probes answered with method not found do not use up the slot
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		_, err = be.Forward(context.Background(), []*RPCReq{req}, false)
+		require.NoError(t, err)
+	}
+}
+
+func TestRedisBackendProbes(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+	lim, err := NewRedisRateLimiter(fmt.Sprintf("redis://127.0.0.1:%s", redis.Port()))
+	require.NoError(t, err)
+
+	probes, err := lim.IncBackendProbes("test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, probes)
+	require.NoError(t, lim.DecBackendProbes("test"))
+	require.NoError(t, lim.DecBackendProbes("test"))
+	probes, err = lim.IncBackendProbes("test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, probes)
+
+	successes, err := lim.IncBackendProbeSuccesses("test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, successes)
+
+	require.NoError(t, lim.ResetBackendProbes("test"))
+	probes, err = lim.IncBackendProbes("test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, probes)
+	successes, err = lim.IncBackendProbeSuccesses("test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, successes)
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/ethereum/go-ethereum/log"
@@ -32,19 +33,53 @@ func main() {
 		log.Crit("must specify a config file on the command line")
 	}
 
+	if os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			log.Crit("error replaying capture", "err", err)
+		}
+		return
+	}
+
 	config := new(proxyd.Config)
 	if _, err := toml.DecodeFile(os.Args[1], config); err != nil {
 		log.Crit("error reading config file", "err", err)
 	}
 
-	shutdown, err := proxyd.Start(config)
+	shutdown, reload, err := proxyd.StartWithReload(config)
 	if err != nil {
 		log.Crit("error starting proxyd", "err", err)
 	}
 
+	reloads := make(chan struct{}, 1)
+	requestReload := func() {
+		select {
+		case reloads <- struct{}{}:
+		default:
+		}
+	}
+	stopWatch := make(chan struct{})
+	if config.Server.ConfigWatchIntervalSeconds != 0 {
+		interval := time.Duration(config.Server.ConfigWatchIntervalSeconds) * time.Second
+		go proxyd.WatchConfigFile(os.Args[1], interval, stopWatch, requestReload)
+	}
+
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	recvSig := <-sig
-	log.Info("caught signal, shutting down", "signal", recvSig)
-	shutdown()
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for {
+		select {
+		case recvSig := <-sig:
+			if recvSig == syscall.SIGHUP {
+				log.Info("caught SIGHUP, reloading config")
+				requestReload()
+				continue
+			}
+			log.Info("caught signal, shutting down", "signal", recvSig)
+			close(stopWatch)
+			shutdown()
+			return
+		case <-reloads:
+			// Errors are logged and the current config is kept
+			_ = proxyd.ReloadConfigFile(os.Args[1], reload)
+		}
+	}
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/proxyd"
+)
+
+// runReplay sends the requests of capture files to a target and prints how
+// its responses differ from the captured ones:
+//
+//	proxyd replay -target http://localhost:8545 [-speed 1] capture.ndjson...
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := flags.String("target", "", "URL to send the captured requests to")
+	speed := flags.Float64("speed", 1, "timing scale of the capture, 0 replays as fast as possible one request at a time")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each request")
+	maxMismatches := flags.Int("max-mismatches", 20, "number of mismatches to print")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *target == "" {
+		return errors.New("must specify a target")
+	}
+	if flags.NArg() == 0 {
+		return errors.New("must specify at least one capture file")
+	}
+
+	var records []*proxyd.CaptureRecord
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		fileRecords, err := proxyd.ReadCapture(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		records = append(records, fileRecords...)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	summary := proxyd.Replay(ctx, proxyd.ReplayConfig{
+		Target:  *target,
+		Speed:   *speed,
+		Timeout: *timeout,
+	}, records)
+	summary.Print(os.Stdout, *maxMismatches)
+	return nil
+}
@@ -21,12 +21,20 @@ type ServerConfig struct {
 
 	EnableRequestLog     bool `toml:"enable_request_log"`
 	MaxRequestBodyLogLen int  `toml:"max_request_body_log_len"`
+
+	// ConfigWatchIntervalSeconds is how often the config file is checked
+	// for changes to reload. The config is also reloaded on SIGHUP.
+	ConfigWatchIntervalSeconds int `toml:"config_watch_interval_seconds"`
 }
 
 type CacheConfig struct {
 	Enabled               bool   `toml:"enabled"`
 	BlockSyncRPCURL       string `toml:"block_sync_rpc_url"`
 	NumBlockConfirmations int    `toml:"num_block_confirmations"`
+	// UseVerifiedIndex only caches the blocks that were verified against the
+	// state commitment chain, as reported by rollup_getInfo, instead of
+	// counting confirmations.
+	UseVerifiedIndex bool `toml:"use_verified_index"`
 }
 
 type RedisConfig struct {
@@ -44,6 +52,80 @@ type RateLimitConfig struct {
 	ExemptOrigins    []string `toml:"exempt_origins"`
 	ExemptUserAgents []string `toml:"exempt_user_agents"`
 	ErrorMessage     string   `toml:"error_message"`
+	// KeyLimits are the limits of each auth key, by alias. The limits of
+	// the "default" alias apply to the keys without limits of their own.
+	KeyLimits map[string]*LimitConfig `toml:"key_limits"`
+	// MethodLimits are the limits of each method, applied to every auth
+	// key, or to every IP when authentication is disabled. A method ending
+	// with * matches every method with that prefix.
+	MethodLimits map[string]*LimitConfig `toml:"method_limits"`
+}
+
+type LimitConfig struct {
+	RatePerSecond int `toml:"rate_per_second"`
+	DailyQuota    int `toml:"daily_quota"`
+}
+
+type TxAdmissionConfig struct {
+	Enabled bool `toml:"enabled"`
+	// ChainID is the chain ID that transactions must be signed for
+	ChainID uint64 `toml:"chain_id"`
+	// MaxCallDataSize defaults to the rollup.maxcalldatasize of l2geth
+	MaxCallDataSize     int          `toml:"max_calldata_size"`
+	DedupeWindowSeconds int          `toml:"dedupe_window_seconds"`
+	SenderLimits        *LimitConfig `toml:"sender_limits"`
+}
+
+// GetLogsConfig bounds eth_getLogs queries and splits the queries over
+// large block ranges into chunks
+type GetLogsConfig struct {
+	Enabled bool `toml:"enabled"`
+	// MaxBlockRange, MaxAddresses and MaxTopics are the limits of the keys
+	// without limits of their own, 0 means no limit
+	MaxBlockRange uint64 `toml:"max_block_range"`
+	MaxAddresses  int    `toml:"max_addresses"`
+	MaxTopics     int    `toml:"max_topics"`
+	// ChunkSize is the number of blocks of each chunk, 0 disables splitting
+	ChunkSize uint64 `toml:"chunk_size"`
+	// MaxParallelChunks is the number of chunks of a query that are sent at
+	// once, defaults to 4
+	MaxParallelChunks int `toml:"max_parallel_chunks"`
+	// KeyLimits are the limits of each auth key, by alias. The limits of
+	// the "default" alias apply to the keys without limits of their own.
+	KeyLimits map[string]*GetLogsLimitConfig `toml:"key_limits"`
+}
+
+type GetLogsLimitConfig struct {
+	MaxBlockRange uint64 `toml:"max_block_range"`
+	MaxAddresses  int    `toml:"max_addresses"`
+	MaxTopics     int    `toml:"max_topics"`
+}
+
+// CaptureConfig records a sample of the HTTP RPC calls to rotating NDJSON
+// files that proxyd replay can send to another proxyd
+type CaptureConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Dir     string `toml:"dir"`
+	// SampleRate is the share of calls that are recorded, defaults to 1
+	SampleRate float64 `toml:"sample_rate"`
+	// MaxFileSizeBytes is the size at which a new file is started,
+	// defaults to 100MB
+	MaxFileSizeBytes int64 `toml:"max_file_size_bytes"`
+	// MaxFiles is the number of files that are kept, defaults to 10
+	MaxFiles int `toml:"max_files"`
+}
+
+type ReadYourWritesConfig struct {
+	Enabled bool `toml:"enabled"`
+	// WriteGroup is the group that takes the writes, defaults to the group
+	// of eth_sendRawTransaction
+	WriteGroup string `toml:"write_group"`
+	// WindowSeconds is how long a write is remembered, defaults to 30
+	WindowSeconds int `toml:"window_seconds"`
+	// ReplicaTimeoutSeconds is how long to wait for a replica to report a
+	// write before reading from the write group. 0 reads from the write
+	// group right away.
+	ReplicaTimeoutSeconds int `toml:"replica_timeout_seconds"`
 }
 
 type BackendOptions struct {
@@ -51,6 +133,34 @@ type BackendOptions struct {
 	MaxResponseSizeBytes   int64 `toml:"max_response_size_bytes"`
 	MaxRetries             int   `toml:"max_retries"`
 	OutOfServiceSeconds    int   `toml:"out_of_service_seconds"`
+
+	CircuitBreaker CircuitBreakerConfig `toml:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig replaces out_of_service_seconds with a circuit
+// breaker per backend when it is enabled
+type CircuitBreakerConfig struct {
+	Enabled bool `toml:"enabled"`
+	// WindowSeconds is the length of the sliding window, defaults to 10
+	WindowSeconds int `toml:"window_seconds"`
+	// MinRequests is the number of requests in the window below which the
+	// circuit does not open, defaults to 20
+	MinRequests int `toml:"min_requests"`
+	// ErrorRateThreshold is the share of failed requests that opens the
+	// circuit, defaults to 0.5
+	ErrorRateThreshold float64 `toml:"error_rate_threshold"`
+	// LatencyThresholdMs is the latency above which a response is slow. 0
+	// disables the latency check.
+	LatencyThresholdMs int `toml:"latency_threshold_ms"`
+	// SlowRateThreshold is the share of slow responses that opens the
+	// circuit, defaults to 0.5
+	SlowRateThreshold float64 `toml:"slow_rate_threshold"`
+	// OpenSeconds is how long the backend is out of service before probing
+	// it, defaults to 30
+	OpenSeconds int `toml:"open_seconds"`
+	// HalfOpenProbes is the number of probe requests that must succeed to
+	// close the circuit, defaults to 3
+	HalfOpenProbes int `toml:"half_open_probes"`
 }
 
 type BackendConfig struct {
@@ -64,12 +174,27 @@ type BackendConfig struct {
 	ClientCertFile   string `toml:"client_cert_file"`
 	ClientKeyFile    string `toml:"client_key_file"`
 	StripTrailingXFF bool   `toml:"strip_trailing_xff"`
+	// Weight is the share of traffic of the backend relative to the
+	// other backends of a group, it defaults to 1
+	Weight int `toml:"weight"`
 }
 
 type BackendsConfig map[string]*BackendConfig
 
 type BackendGroupConfig struct {
 	Backends []string `toml:"backends"`
+
+	// Strategy is one of first_healthy (the default),
+	// weighted_round_robin, least_outstanding or ewma_latency
+	Strategy string `toml:"strategy"`
+
+	// ConsensusAware routes only to backends that are within
+	// ConsensusMaxBlockLag blocks of the consensus head and on its fork.
+	// ConsensusMaxBlockLag defaults to 5 blocks.
+	ConsensusAware               bool   `toml:"consensus_aware"`
+	ConsensusPollIntervalSeconds int    `toml:"consensus_poll_interval_seconds"`
+	ConsensusMaxBlockLag         uint64 `toml:"consensus_max_block_lag"`
+	ConsensusBanPeriodSeconds    int    `toml:"consensus_ban_period_seconds"`
 }
 
 type BackendGroupsConfig map[string]*BackendGroupConfig
@@ -77,18 +202,25 @@ type BackendGroupsConfig map[string]*BackendGroupConfig
 type MethodMappingsConfig map[string]string
 
 type Config struct {
-	WSBackendGroup    string              `toml:"ws_backend_group"`
-	Server            ServerConfig        `toml:"server"`
-	Cache             CacheConfig         `toml:"cache"`
-	Redis             RedisConfig         `toml:"redis"`
-	Metrics           MetricsConfig       `toml:"metrics"`
-	RateLimit         RateLimitConfig     `toml:"rate_limit"`
-	BackendOptions    BackendOptions      `toml:"backend"`
-	Backends          BackendsConfig      `toml:"backends"`
-	Authentication    map[string]string   `toml:"authentication"`
-	BackendGroups     BackendGroupsConfig `toml:"backend_groups"`
-	RPCMethodMappings map[string]string   `toml:"rpc_method_mappings"`
-	WSMethodWhitelist []string            `toml:"ws_method_whitelist"`
+	WSBackendGroup    string               `toml:"ws_backend_group"`
+	Server            ServerConfig         `toml:"server"`
+	Cache             CacheConfig          `toml:"cache"`
+	Redis             RedisConfig          `toml:"redis"`
+	Metrics           MetricsConfig        `toml:"metrics"`
+	RateLimit         RateLimitConfig      `toml:"rate_limit"`
+	TxAdmission       TxAdmissionConfig    `toml:"tx_admission"`
+	ReadYourWrites    ReadYourWritesConfig `toml:"read_your_writes"`
+	GetLogs           GetLogsConfig        `toml:"get_logs"`
+	Capture           CaptureConfig        `toml:"capture"`
+	BackendOptions    BackendOptions       `toml:"backend"`
+	Backends          BackendsConfig       `toml:"backends"`
+	Authentication    map[string]string    `toml:"authentication"`
+	BackendGroups     BackendGroupsConfig  `toml:"backend_groups"`
+	RPCMethodMappings map[string]string    `toml:"rpc_method_mappings"`
+	WSMethodWhitelist []string             `toml:"ws_method_whitelist"`
+	// WSSharedSubscriptions multiplexes identical eth_subscribe calls of WS
+	// clients over shared upstream subscriptions
+	WSSharedSubscriptions bool `toml:"ws_shared_subscriptions"`
 }
 
 func ReadFromEnvOrConfig(value string) (string, error) {
@@ -0,0 +1,335 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultConsensusPollInterval = time.Second
+	defaultConsensusMaxBlockLag  = 5
+	defaultConsensusBanPeriod    = 30 * time.Second
+
+	ConsensusReasonLagging      = "lagging"
+	ConsensusReasonForked       = "forked"
+	ConsensusReasonUnresponsive = "unresponsive"
+)
+
+// backendHead is the latest block reported by a backend
+type backendHead struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   string         `json:"hash"`
+}
+
+type backendConsensusState struct {
+	latest      uint64
+	latestHash  string
+	bannedUntil time.Time
+	reason      string
+	// inConsensus is true when the backend agreed with the consensus at
+	// the last poll, even though it may still be banned
+	inConsensus bool
+}
+
+// ConsensusPoller tracks the latest block of every backend of a group and
+// works out the consensus head. Backends that lag more than maxBlockLag
+// blocks behind it, that are on another fork or that cannot be polled are
+// banned for at least banPeriod, and until they are back in consensus. When
+// every backend is banned the group keeps serving from the best backends
+// rather than failing every request.
+type ConsensusPoller struct {
+	group        *BackendGroup
+	pollInterval time.Duration
+	maxBlockLag  uint64
+	banPeriod    time.Duration
+
+	mtx           sync.RWMutex
+	state         map[*Backend]*backendConsensusState
+	consensusHead uint64
+	polled        bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+type ConsensusOpt func(cp *ConsensusPoller)
+
+func WithConsensusPollInterval(interval time.Duration) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.pollInterval = interval
+	}
+}
+
+func WithConsensusMaxBlockLag(lag uint64) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.maxBlockLag = lag
+	}
+}
+
+func WithConsensusBanPeriod(period time.Duration) ConsensusOpt {
+	return func(cp *ConsensusPoller) {
+		cp.banPeriod = period
+	}
+}
+
+func NewConsensusPoller(group *BackendGroup, opts ...ConsensusOpt) *ConsensusPoller {
+	cp := &ConsensusPoller{
+		group:        group,
+		pollInterval: defaultConsensusPollInterval,
+		maxBlockLag:  defaultConsensusMaxBlockLag,
+		banPeriod:    defaultConsensusBanPeriod,
+		state:        make(map[*Backend]*backendConsensusState),
+		quit:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(cp)
+	}
+	for _, be := range group.Backends {
+		cp.state[be] = new(backendConsensusState)
+	}
+	return cp
+}
+
+func (cp *ConsensusPoller) Start() {
+	cp.wg.Add(1)
+	go func() {
+		defer cp.wg.Done()
+		ticker := time.NewTicker(cp.pollInterval)
+		defer ticker.Stop()
+
+		cp.Poll(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				cp.Poll(context.Background())
+			case <-cp.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (cp *ConsensusPoller) Stop() {
+	close(cp.quit)
+	cp.wg.Wait()
+}
+
+// ConsensusHead returns the block number that the group agrees on
+func (cp *ConsensusPoller) ConsensusHead() uint64 {
+	cp.mtx.RLock()
+	defer cp.mtx.RUnlock()
+	return cp.consensusHead
+}
+
+// IsHealthy returns true when the backend may serve requests. All backends
+// are healthy until the first poll completes.
+func (cp *ConsensusPoller) IsHealthy(be *Backend) bool {
+	cp.mtx.RLock()
+	defer cp.mtx.RUnlock()
+	if !cp.polled {
+		return true
+	}
+	state := cp.state[be]
+	return state != nil && state.bannedUntil.IsZero()
+}
+
+// FilterHealthy returns the healthy backends in their configured order.
+// When none is healthy it falls back to the backends that were in consensus
+// at the last poll while their ban runs, and to all the backends when none
+// responded, so that the group keeps serving.
+func (cp *ConsensusPoller) FilterHealthy(backends []*Backend) []*Backend {
+	healthy := make([]*Backend, 0, len(backends))
+	for _, be := range backends {
+		if cp.IsHealthy(be) {
+			healthy = append(healthy, be)
+		}
+	}
+	if len(healthy) > 0 {
+		return healthy
+	}
+
+	cp.mtx.RLock()
+	defer cp.mtx.RUnlock()
+	for _, be := range backends {
+		if state := cp.state[be]; state != nil && state.inConsensus {
+			healthy = append(healthy, be)
+		}
+	}
+	log.Debug("no backend in consensus, serving from the best backends", "group", cp.group.Name, "backends", len(healthy))
+	if len(healthy) == 0 {
+		return backends
+	}
+	return healthy
+}
+
+// Poll fetches the latest block of every backend and updates the consensus.
+// The consensus head is the median of the reported heads, the lower one
+// for an even count, so it is a block that more than half of the backends
+// reached and a single backend that runs ahead cannot ban the others. The backends within maxBlockLag of it are compared at the lowest
+// block they all have, and the ones that disagree with the majority hash
+// there are considered forked.
+func (cp *ConsensusPoller) Poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, cp.pollInterval)
+	defer cancel()
+
+	heads := make(map[*Backend]*backendHead)
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, be := range cp.group.Backends {
+		wg.Add(1)
+		go func(be *Backend) {
+			defer wg.Done()
+			head, err := be.blockByNumber(ctx, "latest")
+			if err != nil {
+				log.Warn("error polling backend head", "name", be.Name, "group", cp.group.Name, "err", err)
+				return
+			}
+			mtx.Lock()
+			heads[be] = head
+			mtx.Unlock()
+		}(be)
+	}
+	wg.Wait()
+
+	consensusHead := medianHead(heads)
+
+	// Compare the backends within the lag at the lowest block they all have
+	reasons := make(map[*Backend]string)
+	var inLag []*Backend
+	commonHeight := consensusHead
+	for _, be := range cp.group.Backends {
+		head, ok := heads[be]
+		switch {
+		case !ok:
+			reasons[be] = ConsensusReasonUnresponsive
+		case uint64(head.Number)+cp.maxBlockLag < consensusHead:
+			reasons[be] = ConsensusReasonLagging
+		default:
+			inLag = append(inLag, be)
+			if uint64(head.Number) < commonHeight {
+				commonHeight = uint64(head.Number)
+			}
+		}
+	}
+	hashes := make(map[*Backend]string)
+	votes := make(map[string]int)
+	var majority string
+	for _, be := range inLag {
+		hash := heads[be].Hash
+		if uint64(heads[be].Number) != commonHeight {
+			block, err := be.blockByNumber(ctx, hexutil.EncodeUint64(commonHeight))
+			if err != nil {
+				log.Warn("error polling backend block", "name", be.Name, "group", cp.group.Name, "err", err)
+				reasons[be] = ConsensusReasonUnresponsive
+				continue
+			}
+			hash = block.Hash
+		}
+		hashes[be] = hash
+		votes[hash]++
+		// Ties go to the backend that comes first in the config
+		if majority == "" || votes[hash] > votes[majority] {
+			majority = hash
+		}
+	}
+	for be, hash := range hashes {
+		if hash != majority {
+			reasons[be] = ConsensusReasonForked
+		}
+	}
+
+	cp.mtx.Lock()
+	defer cp.mtx.Unlock()
+	now := time.Now()
+	cp.consensusHead = consensusHead
+	cp.polled = true
+	consensusHeadGauge.WithLabelValues(cp.group.Name).Set(float64(consensusHead))
+	for _, be := range cp.group.Backends {
+		state := cp.state[be]
+		if head, ok := heads[be]; ok {
+			state.latest = uint64(head.Number)
+			state.latestHash = head.Hash
+			backendLatestBlockGauge.WithLabelValues(be.Name).Set(float64(state.latest))
+			var lag uint64
+			if state.latest < consensusHead {
+				lag = consensusHead - state.latest
+			}
+			backendBlockLagGauge.WithLabelValues(be.Name).Set(float64(lag))
+		}
+
+		reason, outOfConsensus := reasons[be]
+		state.inConsensus = !outOfConsensus
+		switch {
+		case outOfConsensus:
+			if state.bannedUntil.IsZero() {
+				log.Warn(
+					"banning backend out of consensus",
+					"name", be.Name,
+					"group", cp.group.Name,
+					"reason", reason,
+					"latest", state.latest,
+					"consensus_head", consensusHead,
+				)
+				consensusBansTotal.WithLabelValues(be.Name, reason).Inc()
+			}
+			state.bannedUntil = now.Add(cp.banPeriod)
+			state.reason = reason
+		case !state.bannedUntil.IsZero() && now.After(state.bannedUntil):
+			log.Info("unbanning backend back in consensus", "name", be.Name, "group", cp.group.Name)
+			state.bannedUntil = time.Time{}
+			state.reason = ""
+		}
+		if state.bannedUntil.IsZero() {
+			backendBannedGauge.WithLabelValues(be.Name).Set(0)
+		} else {
+			backendBannedGauge.WithLabelValues(be.Name).Set(1)
+		}
+	}
+}
+
+// medianHead returns the median of the heads, the lower one for an even
+// count, or 0 when there is no head
+func medianHead(heads map[*Backend]*backendHead) uint64 {
+	if len(heads) == 0 {
+		return 0
+	}
+	numbers := make([]uint64, 0, len(heads))
+	for _, head := range heads {
+		numbers = append(numbers, uint64(head.Number))
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+	return numbers[len(numbers)/2]
+}
+
+// blockByNumber fetches the number and hash of a block outside of client
+// traffic
+func (b *Backend) blockByNumber(ctx context.Context, tag string) (*backendHead, error) {
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getBlockByNumber",
+		Params:  mustMarshalJSON([]interface{}{tag, false}),
+		ID:      []byte("1"),
+	}
+	res, err := b.doForward(ctx, []*RPCReq{req}, false)
+	if err != nil {
+		return nil, err
+	}
+	if res[0].IsError() {
+		return nil, res[0].Error
+	}
+	if res[0].Result == nil {
+		return nil, errors.New("block not found")
+	}
+	var head backendHead
+	if err := json.Unmarshal(mustMarshalJSON(res[0].Result), &head); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
@@ -0,0 +1,199 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// chainBackend serves eth_getBlockByNumber for a chain of the given height
+// whose block hashes start with fork, and echoes its name otherwise
+type chainBackend struct {
+	name   string
+	mtx    sync.Mutex
+	height uint64
+	fork   string
+}
+
+func (c *chainBackend) set(height uint64, fork string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.height = height
+	c.fork = fork
+}
+
+func (c *chainBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	req, err := ParseRPCReq(body)
+	if err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	res := &RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID, Result: c.name}
+	if req.Method == "eth_getBlockByNumber" {
+		var params []interface{}
+		_ = json.Unmarshal(req.Params, &params)
+		number := c.height
+		if tag := params[0].(string); tag != "latest" {
+			number, _ = hexutil.DecodeUint64(tag)
+		}
+		res.Result = map[string]string{
+			"number": hexutil.EncodeUint64(number),
+			"hash":   c.fork + hexutil.EncodeUint64(number),
+		}
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func newTestBackendGroup(t *testing.T, backends ...*chainBackend) *BackendGroup {
+	group := &BackendGroup{Name: "main"}
+	sem := semaphore.NewWeighted(100)
+	lim := NewLocalBackendRateLimiter()
+	for _, backend := range backends {
+		server := httptest.NewServer(backend)
+		t.Cleanup(server.Close)
+		group.Backends = append(group.Backends, NewBackend(backend.name, server.URL, server.URL, lim, sem, WithStrippedTrailingXFF()))
+	}
+	return group
+}
+
+func forwardName(t *testing.T, group *BackendGroup) string {
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_chainId", ID: []byte("1")}
+	res, err := group.Forward(context.Background(), []*RPCReq{req}, false)
+	require.NoError(t, err)
+	return res[0].Result.(string)
+}
+
+func TestConsensusPollerBansLaggingBackend(t *testing.T) {
+	lagging := &chainBackend{name: "lagging", height: 90, fork: "0xa"}
+	synced := &chainBackend{name: "synced", height: 100, fork: "0xa"}
+	other := &chainBackend{name: "other", height: 100, fork: "0xa"}
+	group := newTestBackendGroup(t, lagging, synced, other)
+	poller := NewConsensusPoller(group, WithConsensusMaxBlockLag(5), WithConsensusBanPeriod(0))
+	group.Consensus = poller
+
+	// Before the first poll the config order is used
+	require.Equal(t, "lagging", forwardName(t, group))
+
+	poller.Poll(context.Background())
+	require.Equal(t, uint64(100), poller.ConsensusHead())
+	require.False(t, poller.IsHealthy(group.Backends[0]))
+	require.True(t, poller.IsHealthy(group.Backends[1]))
+	require.Equal(t, "synced", forwardName(t, group))
+
+	// Within the lag the backend is back in consensus
+	lagging.set(96, "0xa")
+	poller.Poll(context.Background())
+	require.True(t, poller.IsHealthy(group.Backends[0]))
+	require.Equal(t, "lagging", forwardName(t, group))
+}
+
+func TestConsensusPollerBansForkedBackend(t *testing.T) {
+	first := &chainBackend{name: "first", height: 100, fork: "0xa"}
+	forked := &chainBackend{name: "forked", height: 100, fork: "0xb"}
+	third := &chainBackend{name: "third", height: 98, fork: "0xa"}
+	group := newTestBackendGroup(t, forked, first, third)
+	poller := NewConsensusPoller(group, WithConsensusMaxBlockLag(5), WithConsensusBanPeriod(0))
+	group.Consensus = poller
+
+	poller.Poll(context.Background())
+	require.False(t, poller.IsHealthy(group.Backends[0]))
+	require.True(t, poller.IsHealthy(group.Backends[1]))
+	require.True(t, poller.IsHealthy(group.Backends[2]))
+	require.Equal(t, "first", forwardName(t, group))
+}
+
+func TestConsensusPollerBanPeriod(t *testing.T) {
+	lagging := &chainBackend{name: "lagging", height: 90, fork: "0xa"}
+	synced := &chainBackend{name: "synced", height: 100, fork: "0xa"}
+	other := &chainBackend{name: "other", height: 100, fork: "0xa"}
+	group := newTestBackendGroup(t, lagging, synced, other)
+	poller := NewConsensusPoller(group, WithConsensusMaxBlockLag(5), WithConsensusBanPeriod(time.Hour))
+
+	poller.Poll(context.Background())
+	require.False(t, poller.IsHealthy(group.Backends[0]))
+
+	// Catching up does not lift the ban before the ban period is over
+	lagging.set(100, "0xa")
+	poller.Poll(context.Background())
+	require.False(t, poller.IsHealthy(group.Backends[0]))
+}
+
+func TestConsensusPollerUnresponsiveBackend(t *testing.T) {
+	synced := &chainBackend{name: "synced", height: 100, fork: "0xa"}
+	group := newTestBackendGroup(t, synced)
+	sem := semaphore.NewWeighted(100)
+	group.Backends = append([]*Backend{
+		NewBackend("down", "http://127.0.0.1:1", "ws://127.0.0.1:1", NewLocalBackendRateLimiter(), sem, WithStrippedTrailingXFF()),
+	}, group.Backends...)
+	poller := NewConsensusPoller(group, WithConsensusBanPeriod(0))
+
+	poller.Poll(context.Background())
+	require.False(t, poller.IsHealthy(group.Backends[0]))
+	require.True(t, poller.IsHealthy(group.Backends[1]))
+}
+
+func TestConsensusPollerMedianHead(t *testing.T) {
+	first := &chainBackend{name: "first", height: 100, fork: "0xa"}
+	second := &chainBackend{name: "second", height: 97, fork: "0xa"}
+	ahead := &chainBackend{name: "ahead", height: 200, fork: "0xa"}
+	group := newTestBackendGroup(t, first, second, ahead)
+	// the default lag tolerates a few blocks
+	poller := NewConsensusPoller(group, WithConsensusBanPeriod(0))
+
+	// A single backend running ahead does not ban the others
+	poller.Poll(context.Background())
+	require.Equal(t, uint64(100), poller.ConsensusHead())
+	for _, be := range group.Backends {
+		require.True(t, poller.IsHealthy(be), be.Name)
+	}
+}
+
+func TestConsensusPollerMedianHeadOfTwo(t *testing.T) {
+	honest := &chainBackend{name: "honest", height: 100, fork: "0xa"}
+	ahead := &chainBackend{name: "ahead", height: 200, fork: "0xa"}
+	group := newTestBackendGroup(t, honest, ahead)
+	poller := NewConsensusPoller(group, WithConsensusMaxBlockLag(5), WithConsensusBanPeriod(0))
+
+	// The lower median is used, the backend running ahead does not ban the
+	// other one
+	poller.Poll(context.Background())
+	require.Equal(t, uint64(100), poller.ConsensusHead())
+	for _, be := range group.Backends {
+		require.True(t, poller.IsHealthy(be), be.Name)
+	}
+}
+
+func TestConsensusPollerServesWhenNoneHealthy(t *testing.T) {
+	first := &chainBackend{name: "first", height: 100, fork: "0xa"}
+	second := &chainBackend{name: "second", height: 90, fork: "0xa"}
+	third := &chainBackend{name: "third", height: 100, fork: "0xa"}
+	group := newTestBackendGroup(t, first, second, third)
+	poller := NewConsensusPoller(group, WithConsensusMaxBlockLag(5), WithConsensusBanPeriod(time.Hour))
+	group.Consensus = poller
+
+	poller.Poll(context.Background())
+	require.False(t, poller.IsHealthy(group.Backends[1]))
+
+	// All the backends are banned, the one in consensus keeps serving
+	first.set(80, "0xa")
+	second.set(100, "0xa")
+	third.set(100, "0xb")
+	poller.Poll(context.Background())
+	for _, be := range group.Backends {
+		require.False(t, poller.IsHealthy(be), be.Name)
+	}
+	require.Equal(t, "second", forwardName(t, group))
+}
@@ -0,0 +1,199 @@
+package proxyd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const cacheGenerationKey = "lvc:cache_generation"
+
+var errHeaderNotFound = errors.New("header not found")
+
+// blockFinality decides whether a block is confirmed and the responses
+// about it can be cached. A block is confirmed once it is
+// numBlockConfirmations behind the latest block or, when the verified block
+// number is known, once it has been verified against the state commitment
+// chain.
+type blockFinality struct {
+	getLatestBlockNumFn   GetLatestBlockNumFn
+	getVerifiedBlockNumFn GetLatestBlockNumFn
+	numBlockConfirmations int
+}
+
+func (f *blockFinality) isConfirmed(ctx context.Context, blockNum uint64) (bool, error) {
+	if f.getVerifiedBlockNumFn != nil {
+		verified, err := f.getVerifiedBlockNumFn(ctx)
+		if err != nil {
+			return false, err
+		}
+		return blockNum <= verified, nil
+	}
+	curBlock, err := f.getLatestBlockNumFn(ctx)
+	if err != nil {
+		return false, err
+	}
+	return curBlock > blockNum+uint64(f.numBlockConfirmations), nil
+}
+
+type headerRef struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+}
+
+// HeadTracker follows the head of the block sync node and starts a new
+// cache generation when the head reorgs. The generation is kept in the
+// cache, so that every proxyd instance sharing the cache drops the entries
+// of the old chain.
+type HeadTracker struct {
+	client     *rpc.Client
+	cache      Cache
+	head       *headerRef
+	generation string
+	mtx        sync.RWMutex
+	quit       chan struct{}
+}
+
+func newHeadTracker(client *rpc.Client, cache Cache) *HeadTracker {
+	return &HeadTracker{
+		client: client,
+		cache:  cache,
+		quit:   make(chan struct{}),
+	}
+}
+
+func (h *HeadTracker) Start() {
+	// Load the generation before the cache is used, so that entries of an
+	// older generation are not served after a restart
+	if err := h.poll(context.Background()); err != nil {
+		log.Warn("error tracking head", "err", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(cacheSyncRate)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := h.poll(context.Background()); err != nil {
+					log.Warn("error tracking head", "err", err)
+				}
+			case <-h.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (h *HeadTracker) Stop() {
+	close(h.quit)
+}
+
+// Generation returns the current cache generation
+func (h *HeadTracker) Generation() string {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.generation
+}
+
+func (h *HeadTracker) poll(ctx context.Context) error {
+	generation, err := h.cache.Get(ctx, cacheGenerationKey)
+	if err != nil {
+		return err
+	}
+	h.setGeneration(generation)
+
+	latest, err := h.header(ctx, "latest")
+	if err != nil {
+		return err
+	}
+	last := h.head
+	h.head = latest
+	if last == nil {
+		return nil
+	}
+
+	var reorged bool
+	switch {
+	case latest.Number < last.Number:
+		reorged = true
+	case latest.Number == last.Number:
+		reorged = latest.Hash != last.Hash
+	case latest.Number == last.Number+1:
+		reorged = latest.ParentHash != last.Hash
+	default:
+		canonical, err := h.header(ctx, hexutil.EncodeUint64(uint64(last.Number)))
+		if err != nil {
+			// Check again on the next poll
+			h.head = last
+			return err
+		}
+		reorged = canonical.Hash != last.Hash
+	}
+	if !reorged {
+		return nil
+	}
+
+	log.Warn(
+		"head reorged, invalidating cache",
+		"old_number", uint64(last.Number),
+		"old_hash", last.Hash,
+		"new_number", uint64(latest.Number),
+		"new_hash", latest.Hash,
+	)
+	cacheReorgsTotal.Inc()
+	generation = latest.Hash.Hex()
+	h.setGeneration(generation)
+	return h.cache.Put(ctx, cacheGenerationKey, generation)
+}
+
+func (h *HeadTracker) header(ctx context.Context, number string) (*headerRef, error) {
+	var header *headerRef
+	if err := h.client.CallContext(ctx, &header, "eth_getHeaderByNumber", number); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errHeaderNotFound
+	}
+	return header, nil
+}
+
+func (h *HeadTracker) setGeneration(generation string) {
+	h.mtx.Lock()
+	h.generation = generation
+	h.mtx.Unlock()
+}
+
+// cacheWithGeneration prefixes keys with the cache generation of the head
+// tracker. Entries of older generations are never read again and expire.
+type cacheWithGeneration struct {
+	cache   Cache
+	tracker *HeadTracker
+}
+
+func newCacheWithGeneration(cache Cache, tracker *HeadTracker) *cacheWithGeneration {
+	return &cacheWithGeneration{cache, tracker}
+}
+
+func (c *cacheWithGeneration) key(key string) string {
+	if generation := c.tracker.Generation(); generation != "" {
+		return generation + ":" + key
+	}
+	return key
+}
+
+func (c *cacheWithGeneration) Get(ctx context.Context, key string) (string, error) {
+	return c.cache.Get(ctx, c.key(key))
+}
+
+func (c *cacheWithGeneration) Put(ctx context.Context, key string, value string) error {
+	return c.cache.Put(ctx, c.key(key), value)
+}
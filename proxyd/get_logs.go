@@ -0,0 +1,314 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultGetLogsMaxParallelChunks = 4
+
+	GetLogsLimitBlockRange = "block_range"
+	GetLogsLimitAddresses  = "addresses"
+	GetLogsLimitTopics     = "topics"
+)
+
+// GetLogsGuard bounds the eth_getLogs queries of each auth key and splits
+// the queries over large block ranges into chunks. The chunks are sent to
+// the backend group in parallel, cached like any eth_getLogs call, and
+// their logs are merged in block and log index order.
+type GetLogsGuard struct {
+	limits      GetLogsLimitConfig
+	keyLimits   map[string]GetLogsLimitConfig
+	chunkSize   uint64
+	maxParallel int
+}
+
+func NewGetLogsGuard(config GetLogsConfig) *GetLogsGuard {
+	g := &GetLogsGuard{
+		limits: GetLogsLimitConfig{
+			MaxBlockRange: config.MaxBlockRange,
+			MaxAddresses:  config.MaxAddresses,
+			MaxTopics:     config.MaxTopics,
+		},
+		keyLimits:   make(map[string]GetLogsLimitConfig),
+		chunkSize:   config.ChunkSize,
+		maxParallel: config.MaxParallelChunks,
+	}
+	if g.maxParallel == 0 {
+		g.maxParallel = defaultGetLogsMaxParallelChunks
+	}
+	// Unset key limits fall back to the global limits
+	for alias, limits := range config.KeyLimits {
+		merged := g.limits
+		if limits.MaxBlockRange != 0 {
+			merged.MaxBlockRange = limits.MaxBlockRange
+		}
+		if limits.MaxAddresses != 0 {
+			merged.MaxAddresses = limits.MaxAddresses
+		}
+		if limits.MaxTopics != 0 {
+			merged.MaxTopics = limits.MaxTopics
+		}
+		g.keyLimits[alias] = merged
+	}
+	return g
+}
+
+func (g *GetLogsGuard) limitsFor(auth string) GetLogsLimitConfig {
+	if limits, ok := g.keyLimits[auth]; ok {
+		return limits
+	}
+	if limits, ok := g.keyLimits[DefaultKeyLimits]; ok {
+		return limits
+	}
+	return g.limits
+}
+
+// Handle returns the response of an eth_getLogs call that is over the
+// limits or that was split into chunks. It returns nil when the call should
+// be forwarded as is.
+func (g *GetLogsGuard) Handle(ctx context.Context, group *BackendGroup, cache RPCCache, req *RPCReq) *RPCRes {
+	filter, err := decodeGetLogsParams(req.Params)
+	if err != nil {
+		// The backend answers invalid params
+		return nil
+	}
+
+	auth := GetAuthCtx(ctx)
+	limits := g.limitsFor(auth)
+	if n := countValues(filter.Address); limits.MaxAddresses != 0 && n > limits.MaxAddresses {
+		return g.reject(ctx, req, GetLogsLimitAddresses, fmt.Sprintf("too many addresses, max is %d", limits.MaxAddresses))
+	}
+	if n := countTopics(filter.Topics); limits.MaxTopics != 0 && n > limits.MaxTopics {
+		return g.reject(ctx, req, GetLogsLimitTopics, fmt.Sprintf("too many topics, max is %d", limits.MaxTopics))
+	}
+	if filter.BlockHash != "" {
+		return nil
+	}
+
+	fromBlock, toBlock, err := g.resolveRange(ctx, group, filter)
+	if err != nil {
+		log.Warn("error resolving eth_getLogs block range", "req_id", GetReqID(ctx), "err", err)
+		return NewRPCErrorRes(req.ID, err)
+	}
+	if fromBlock > toBlock {
+		return nil
+	}
+	span := toBlock - fromBlock + 1
+	if limits.MaxBlockRange != 0 && span > limits.MaxBlockRange {
+		return g.reject(ctx, req, GetLogsLimitBlockRange, fmt.Sprintf("block range too large, max is %d blocks", limits.MaxBlockRange))
+	}
+	if g.chunkSize == 0 || span <= g.chunkSize {
+		return nil
+	}
+	return g.split(ctx, group, cache, req, filter, fromBlock, toBlock)
+}
+
+func (g *GetLogsGuard) reject(ctx context.Context, req *RPCReq, limit string, msg string) *RPCRes {
+	log.Info(
+		"rejected eth_getLogs call",
+		"req_id", GetReqID(ctx),
+		"auth", GetAuthCtx(ctx),
+		"limit", limit,
+	)
+	getLogsRejectedTotal.WithLabelValues(GetAuthCtx(ctx), limit).Inc()
+	err := ErrGetLogsLimit(msg)
+	RecordRPCError(ctx, BackendProxyd, req.Method, err)
+	return NewRPCErrorRes(req.ID, err)
+}
+
+// resolveRange returns the block numbers of the range of filter, asking
+// the group for the latest block when the range uses a tag
+func (g *GetLogsGuard) resolveRange(ctx context.Context, group *BackendGroup, filter *logsFilter) (uint64, uint64, error) {
+	var latest *uint64
+	resolve := func(input string) (uint64, error) {
+		switch input {
+		case "earliest":
+			return 0, nil
+		case "", "latest", "pending":
+			if latest == nil {
+				blockNum, err := latestBlockNum(ctx, group)
+				if err != nil {
+					return 0, err
+				}
+				latest = &blockNum
+			}
+			return *latest, nil
+		default:
+			return decodeBlockInput(input)
+		}
+	}
+	fromBlock, err := resolve(filter.FromBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	toBlock, err := resolve(filter.ToBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fromBlock, toBlock, nil
+}
+
+func latestBlockNum(ctx context.Context, group *BackendGroup) (uint64, error) {
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_blockNumber",
+		Params:  json.RawMessage("[]"),
+		ID:      json.RawMessage("1"),
+	}
+	res, err := group.Forward(ctx, []*RPCReq{req}, false)
+	if err != nil {
+		return 0, err
+	}
+	if res[0].IsError() {
+		return 0, res[0].Error
+	}
+	blockNum, ok := res[0].Result.(string)
+	if !ok {
+		return 0, ErrBackendBadResponse
+	}
+	return hexutil.DecodeUint64(blockNum)
+}
+
+type logPosition struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+}
+
+// split sends the chunks of the range to the group and merges their logs
+func (g *GetLogsGuard) split(ctx context.Context, group *BackendGroup, cache RPCCache, req *RPCReq, filter *logsFilter, fromBlock, toBlock uint64) *RPCRes {
+	var chunks []*RPCReq
+	for start := fromBlock; start <= toBlock; start += g.chunkSize {
+		end := start + g.chunkSize - 1
+		if end > toBlock || end < start {
+			end = toBlock
+		}
+		chunkFilter := map[string]interface{}{
+			"fromBlock": hexutil.EncodeUint64(start),
+			"toBlock":   hexutil.EncodeUint64(end),
+		}
+		if filter.Address != nil {
+			chunkFilter["address"] = filter.Address
+		}
+		if filter.Topics != nil {
+			chunkFilter["topics"] = filter.Topics
+		}
+		chunks = append(chunks, &RPCReq{
+			JSONRPC: req.JSONRPC,
+			Method:  req.Method,
+			Params:  mustMarshalJSON([]interface{}{chunkFilter}),
+			ID:      req.ID,
+		})
+		if end == toBlock {
+			break
+		}
+	}
+	getLogsChunksTotal.Add(float64(len(chunks)))
+
+	results := make([]*RPCRes, len(chunks))
+	sem := make(chan struct{}, g.maxParallel)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk *RPCReq) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = forwardChunk(ctx, group, cache, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	var logs []json.RawMessage
+	var positions []logPosition
+	for _, res := range results {
+		if res.IsError() {
+			return res
+		}
+		var chunkLogs []json.RawMessage
+		if err := json.Unmarshal(mustMarshalJSON(res.Result), &chunkLogs); err != nil {
+			return NewRPCErrorRes(req.ID, ErrBackendBadResponse)
+		}
+		for _, chunkLog := range chunkLogs {
+			var pos logPosition
+			if err := json.Unmarshal(chunkLog, &pos); err != nil {
+				return NewRPCErrorRes(req.ID, ErrBackendBadResponse)
+			}
+			logs = append(logs, chunkLog)
+			positions = append(positions, pos)
+		}
+	}
+
+	order := make([]int, len(logs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := positions[order[i]], positions[order[j]]
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		return a.LogIndex < b.LogIndex
+	})
+	merged := make([]json.RawMessage, len(logs))
+	for i, idx := range order {
+		merged[i] = logs[idx]
+	}
+	return NewRPCRes(req.ID, merged)
+}
+
+// forwardChunk answers a chunk from the cache or the group, and caches the
+// response once the chunk is confirmed
+func forwardChunk(ctx context.Context, group *BackendGroup, cache RPCCache, chunk *RPCReq) *RPCRes {
+	if res, _ := cache.GetRPC(ctx, chunk); res != nil {
+		return res
+	}
+	res, err := group.Forward(ctx, []*RPCReq{chunk}, false)
+	if err != nil {
+		log.Error(
+			"error forwarding eth_getLogs chunk",
+			"req_id", GetReqID(ctx),
+			"backend_group", group.Name,
+			"err", err,
+		)
+		return NewRPCErrorRes(chunk.ID, err)
+	}
+	if res[0].Error == nil && res[0].Result != nil {
+		if err := cache.PutRPC(ctx, chunk, res[0]); err != nil {
+			log.Warn("cache put error", "req_id", GetReqID(ctx), "err", err)
+		}
+	}
+	return res[0]
+}
+
+// countValues returns the number of values of an address or topic filter,
+// which is either a single value or a list
+func countValues(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case []interface{}:
+		return len(v)
+	default:
+		return 1
+	}
+}
+
+func countTopics(topics interface{}) int {
+	list, ok := topics.([]interface{})
+	if !ok {
+		return countValues(topics)
+	}
+	n := 0
+	for _, topic := range list {
+		n += countValues(topic)
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// logsBackend answers eth_getLogs with one log per block of the range, in
+// reverse order, and eth_blockNumber with 0x63
+type logsBackend struct {
+	getLogsCalls int32
+}
+
+func (l *logsBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	req, err := ParseRPCReq(body)
+	if err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	res := &RPCRes{JSONRPC: JSONRPCVersion, ID: req.ID, Result: "0x63"}
+	if req.Method == "eth_getLogs" {
+		atomic.AddInt32(&l.getLogsCalls, 1)
+		filter, _ := decodeGetLogsParams(req.Params)
+		from, _ := decodeBlockInput(filter.FromBlock)
+		to, _ := decodeBlockInput(filter.ToBlock)
+		logs := make([]map[string]string, 0)
+		for n := to + 1; n > from; n-- {
+			logs = append(logs, map[string]string{
+				"blockNumber": hexutil.EncodeUint64(n - 1),
+				"logIndex":    "0x0",
+			})
+		}
+		res.Result = logs
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func getLogsReq(filter string) *RPCReq {
+	return &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getLogs",
+		Params:  json.RawMessage("[" + filter + "]"),
+		ID:      json.RawMessage("1"),
+	}
+}
+
+func TestGetLogsGuardLimits(t *testing.T) {
+	guard := NewGetLogsGuard(GetLogsConfig{
+		MaxBlockRange: 100,
+		MaxAddresses:  2,
+		MaxTopics:     3,
+		KeyLimits: map[string]*GetLogsLimitConfig{
+			"partner": {MaxBlockRange: 1000},
+		},
+	})
+	ctx := context.Background()
+	partnerCtx := context.WithValue(ctx, ContextKeyAuth, "partner") // nolint:staticcheck
+
+	res := guard.Handle(ctx, nil, nil, getLogsReq(`{"fromBlock":"0x0","toBlock":"0x63"}`))
+	require.Nil(t, res)
+
+	res = guard.Handle(ctx, nil, nil, getLogsReq(`{"fromBlock":"0x0","toBlock":"0x64"}`))
+	require.Equal(t, "eth_getLogs block range too large, max is 100 blocks", res.Error.Message)
+	require.Nil(t, guard.Handle(partnerCtx, nil, nil, getLogsReq(`{"fromBlock":"0x0","toBlock":"0x64"}`)))
+
+	// Key limits fall back to the global limits
+	res = guard.Handle(partnerCtx, nil, nil, getLogsReq(`{"blockHash":"0x01","address":["0x01","0x02","0x03"]}`))
+	require.Equal(t, "eth_getLogs too many addresses, max is 2", res.Error.Message)
+
+	res = guard.Handle(ctx, nil, nil, getLogsReq(`{"blockHash":"0x01","topics":["0x01",null,["0x02","0x03","0x04"]]}`))
+	require.Equal(t, "eth_getLogs too many topics, max is 3", res.Error.Message)
+	require.Nil(t, guard.Handle(ctx, nil, nil, getLogsReq(`{"blockHash":"0x01","topics":["0x01",null,["0x02","0x03"]]}`)))
+}
+
+func TestGetLogsGuardSplit(t *testing.T) {
+	backend := new(logsBackend)
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	group := &BackendGroup{
+		Name: "main",
+		Backends: []*Backend{
+			NewBackend("logs", server.URL, server.URL, NewLocalBackendRateLimiter(), semaphore.NewWeighted(10), WithStrippedTrailingXFF()),
+		},
+	}
+	// Blocks up to 0x4f are confirmed
+	getLatestBlockNum := func(context.Context) (uint64, error) {
+		return 0x50, nil
+	}
+	cache := newRPCCache(newMemoryCache(), getLatestBlockNum, nil, nil, 0)
+	guard := NewGetLogsGuard(GetLogsConfig{ChunkSize: 30, MaxParallelChunks: 2})
+	ctx := context.Background()
+
+	// The latest block is asked to the group
+	res := guard.Handle(ctx, group, cache, getLogsReq(`{"fromBlock":"0x0","address":"0x01"}`))
+	require.Nil(t, res.Error)
+	logs := res.Result.([]json.RawMessage)
+	require.Equal(t, 100, len(logs))
+	for i, log := range logs {
+		var pos logPosition
+		require.NoError(t, json.Unmarshal(log, &pos))
+		require.Equal(t, uint64(i), uint64(pos.BlockNumber))
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&backend.getLogsCalls))
+
+	// The confirmed chunks are cached
+	res = guard.Handle(ctx, group, cache, getLogsReq(`{"fromBlock":"0x0","toBlock":"0x63","address":"0x01"}`))
+	require.Nil(t, res.Error)
+	require.Equal(t, 100, len(res.Result.([]json.RawMessage)))
+	require.Equal(t, int32(6), atomic.LoadInt32(&backend.getLogsCalls))
+
+	// Ranges within a chunk are forwarded as is
+	require.Nil(t, guard.Handle(ctx, group, cache, getLogsReq(`{"fromBlock":"0x0","toBlock":"0x1d"}`)))
+}
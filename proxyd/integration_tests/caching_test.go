@@ -2,6 +2,7 @@ package integration_tests
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -213,3 +214,65 @@ func countRequests(backend *MockBackend, name string) int {
 	}
 	return count
 }
+
+func TestCachingReorg(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+
+	hdlr := NewBatchRPCResponseRouter()
+	hdlr.SetFallbackRoute("eth_getTransactionReceipt", json.RawMessage(`{"blockNumber": "0x10"}`))
+	hdlr.SetFallbackRoute("eth_getLogs", json.RawMessage(`[]`))
+
+	// mock LVC and head tracker requests
+	hdlr.SetFallbackRoute("eth_blockNumber", "0x64")
+	hdlr.SetFallbackRoute("eth_gasPrice", "0x420")
+	hdlr.SetFallbackRoute("eth_getHeaderByNumber", json.RawMessage(`{
+		"number": "0x64",
+		"hash": "0x0000000000000000000000000000000000000000000000000000000000000001",
+		"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000"
+	}`))
+
+	backend := NewMockBackend(hdlr)
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", backend.URL()))
+	require.NoError(t, os.Setenv("REDIS_URL", fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())))
+	config := ReadConfig("caching")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	// allow time for the block number fetcher to fire
+	time.Sleep(1500 * time.Millisecond)
+
+	receiptParams := []interface{}{"0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"}
+	logsParams := []interface{}{map[string]string{"fromBlock": "0x1", "toBlock": "0x10"}}
+	sendTwice := func() {
+		for i := 0; i < 2; i++ {
+			_, code, err := client.SendRPC("eth_getTransactionReceipt", receiptParams)
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+			_, code, err = client.SendRPC("eth_getLogs", logsParams)
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+		}
+	}
+
+	sendTwice()
+	require.Equal(t, 1, countRequests(backend, "eth_getTransactionReceipt"))
+	require.Equal(t, 1, countRequests(backend, "eth_getLogs"))
+
+	// A different block at the same height invalidates the cache
+	hdlr.SetFallbackRoute("eth_getHeaderByNumber", json.RawMessage(`{
+		"number": "0x64",
+		"hash": "0x0000000000000000000000000000000000000000000000000000000000000002",
+		"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000"
+	}`))
+	time.Sleep(1500 * time.Millisecond)
+
+	sendTwice()
+	require.Equal(t, 2, countRequests(backend, "eth_getTransactionReceipt"))
+	require.Equal(t, 2, countRequests(backend, "eth_getLogs"))
+}
@@ -0,0 +1,59 @@
+package integration_tests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureAndReplay(t *testing.T) {
+	fixture := ReadCaptureFixture("capture_fixture")
+	backend := NewMockBackend(NewCaptureFixtureHandler(fixture))
+	defer backend.Close()
+	require.NoError(t, os.Setenv("FIXTURE_BACKEND_RPC_URL", backend.URL()))
+
+	config := ReadConfig("capture")
+	config.Capture.Dir = t.TempDir()
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+
+	target := proxyd.ReplayConfig{Target: "http://127.0.0.1:8545/secret", Speed: 1}
+	summary := proxyd.Replay(context.Background(), target, fixture)
+	require.Equal(t, len(fixture), summary.Total)
+	require.Equal(t, len(fixture), summary.Matched, "%v", summary.MismatchesByMethod)
+	require.Equal(t, 4, len(backend.Requests()))
+	shutdown()
+
+	// The replayed calls were captured, without the auth key or client IP
+	files, err := proxyd.CaptureFiles(config.Capture.Dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret")
+	require.NotContains(t, string(data), "127.0.0.1")
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	captured, err := proxyd.ReadCapture(f)
+	require.NoError(t, err)
+	require.Equal(t, len(fixture), len(captured))
+
+	// Replaying the capture against a backend that changed shows the
+	// mismatches
+	backend.SetHandler(SingleResponseHandler(200, `{"jsonrpc":"2.0","result":"0x0","id":1}`))
+	config.Capture.Enabled = false
+	shutdown, err = proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	target.Speed = 0
+	summary = proxyd.Replay(context.Background(), target, captured)
+	require.Equal(t, 1, summary.Matched)
+	require.Equal(t, 4, summary.Mismatched)
+	require.Equal(t, 2, summary.MismatchesByMethod["eth_chainId"]+summary.MismatchesByMethod["<batch>"])
+}
@@ -0,0 +1,93 @@
+package integration_tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+const methodNotFoundResponse = `{"jsonrpc":"2.0","error":{"code":-32601,"message":"the method eth_chainId does not exist/is not available"},"id":999}`
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		testCircuitBreaker(t, ReadConfig("circuit_breaker"), func() {
+			time.Sleep(1100 * time.Millisecond)
+		})
+	})
+
+	t.Run("redis", func(t *testing.T) {
+		redis, err := miniredis.Run()
+		require.NoError(t, err)
+		defer redis.Close()
+
+		config := ReadConfig("circuit_breaker")
+		config.Redis.URL = fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())
+		// miniredis only expires keys when its clock is moved
+		testCircuitBreaker(t, config, func() {
+			redis.FastForward(1100 * time.Millisecond)
+		})
+	})
+}
+
+// testCircuitBreaker runs against a circuit breaker that opens after two
+// requests for one second. waitOpen waits for the end of the open period.
+func testCircuitBreaker(t *testing.T, config *proxyd.Config, waitOpen func()) {
+	okHandler := BatchedResponseHandler(200, goodResponse)
+	goodBackend := NewMockBackend(okHandler)
+	defer goodBackend.Close()
+	badBackend := NewMockBackend(SingleResponseHandler(200, methodNotFoundResponse))
+	defer badBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+	require.NoError(t, os.Setenv("BAD_BACKEND_RPC_URL", badBackend.URL()))
+
+	client := NewProxydClient("http://127.0.0.1:8545")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	send := func(expected string) {
+		res, statusCode, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, statusCode)
+		RequireEqualJSON(t, []byte(expected), res)
+	}
+
+	// Unsupported methods do not open the circuit
+	for i := 0; i < 3; i++ {
+		send(methodNotFoundResponse)
+	}
+	require.Equal(t, 3, len(badBackend.Requests()))
+	require.Equal(t, 0, len(goodBackend.Requests()))
+
+	// Transport errors do
+	badBackend.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	for i := 0; i < 3; i++ {
+		send(goodResponse)
+	}
+	require.Equal(t, 5, len(badBackend.Requests()))
+	require.Equal(t, 3, len(goodBackend.Requests()))
+
+	// A failed probe opens the circuit again
+	waitOpen()
+	send(goodResponse)
+	send(goodResponse)
+	require.Equal(t, 6, len(badBackend.Requests()))
+	require.Equal(t, 5, len(goodBackend.Requests()))
+
+	// A successful probe closes it
+	waitOpen()
+	badBackend.SetHandler(okHandler)
+	send(goodResponse)
+	send(goodResponse)
+	require.Equal(t, 8, len(badBackend.Requests()))
+	require.Equal(t, 5, len(goodBackend.Requests()))
+}
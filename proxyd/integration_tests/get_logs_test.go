@@ -0,0 +1,86 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+// logsRangeHandler answers eth_getLogs with one log per block of the range
+func logsRangeHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	req, err := proxyd.ParseRPCReq(body)
+	if err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	var filters []struct {
+		FromBlock hexutil.Uint64 `json:"fromBlock"`
+		ToBlock   hexutil.Uint64 `json:"toBlock"`
+	}
+	if err := json.Unmarshal(req.Params, &filters); err != nil {
+		w.WriteHeader(400)
+		return
+	}
+	logs := make([]map[string]string, 0)
+	for n := filters[0].FromBlock; n <= filters[0].ToBlock; n++ {
+		logs = append(logs, map[string]string{
+			"blockNumber": hexutil.EncodeUint64(uint64(n)),
+			"logIndex":    "0x0",
+		})
+	}
+	_ = json.NewEncoder(w).Encode(&proxyd.RPCRes{JSONRPC: proxyd.JSONRPCVersion, Result: logs, ID: req.ID})
+}
+
+func TestGetLogs(t *testing.T) {
+	backend := NewMockBackend(http.HandlerFunc(logsRangeHandler))
+	defer backend.Close()
+
+	require.NoError(t, os.Setenv("LOGS_BACKEND_RPC_URL", backend.URL()))
+
+	config := ReadConfig("get_logs")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	userClient := NewProxydClient("http://127.0.0.1:8545/user_key")
+	partnerClient := NewProxydClient("http://127.0.0.1:8545/partner_key")
+	largeRange := []interface{}{map[string]interface{}{"fromBlock": "0x0", "toBlock": "0x63"}}
+
+	res, code, err := userClient.SendRPC("eth_getLogs", largeRange)
+	require.NoError(t, err)
+	require.Equal(t, 400, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32019,"message":"eth_getLogs block range too large, max is 50 blocks"},"id":999}`), res)
+	require.Equal(t, 0, len(backend.Requests()))
+
+	res, code, err = partnerClient.SendRPC("eth_getLogs", []interface{}{map[string]interface{}{
+		"fromBlock": "0x0",
+		"toBlock":   "0x1",
+		"address":   []string{"0x01", "0x02", "0x03"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 400, code)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","error":{"code":-32019,"message":"eth_getLogs too many addresses, max is 2"},"id":999}`), res)
+
+	// The partner range is split into 5 chunks
+	res, code, err = partnerClient.SendRPC("eth_getLogs", largeRange)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	var out struct {
+		Result []struct {
+			BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(res, &out))
+	require.Equal(t, 100, len(out.Result))
+	for i, log := range out.Result {
+		require.Equal(t, uint64(i), uint64(log.BlockNumber))
+	}
+	require.Equal(t, 5, len(backend.Requests()))
+}
@@ -0,0 +1,107 @@
+package integration_tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+const keyOverLimitResponse = `{"error":{"code":-32016,"message":"over rate limit"},"id":999,"jsonrpc":"2.0"}`
+
+func TestKeyRateLimits(t *testing.T) {
+	hdlr := NewBatchRPCResponseRouter()
+	hdlr.SetFallbackRoute("eth_chainId", "0x1")
+	hdlr.SetFallbackRoute("debug_traceTransaction", "0x")
+	goodBackend := NewMockBackend(hdlr)
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+
+	config := ReadConfig("key_rate_limit")
+	config.Redis.URL = fmt.Sprintf("redis://127.0.0.1:%s", redis.Port())
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	t.Run("method limit", func(t *testing.T) {
+		res, hdr := sendKeyRPC(t, "key_alice", NewRPCReq("999", "debug_traceTransaction", nil))
+		require.Equal(t, 200, res.StatusCode)
+		require.Equal(t, "0", hdr.Get("X-RateLimit-Remaining"))
+		require.Equal(t, "1", hdr.Get("X-RateLimit-Limit"))
+		require.NotEmpty(t, hdr.Get("X-RateLimit-Reset"))
+
+		res, _ = sendKeyRPC(t, "key_alice", NewRPCReq("999", "debug_traceTransaction", nil))
+		require.Equal(t, 429, res.StatusCode)
+		RequireEqualJSON(t, []byte(keyOverLimitResponse), res.Body)
+	})
+
+	t.Run("default key limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res, hdr := sendKeyRPC(t, "key_bob", NewRPCReq("999", "eth_chainId", nil))
+			require.Equal(t, 200, res.StatusCode)
+			require.Equal(t, "2", hdr.Get("X-RateLimit-Limit"))
+			require.Equal(t, fmt.Sprint(1-i), hdr.Get("X-RateLimit-Remaining"))
+		}
+		res, _ := sendKeyRPC(t, "key_bob", NewRPCReq("999", "eth_chainId", nil))
+		require.Equal(t, 429, res.StatusCode)
+		RequireEqualJSON(t, []byte(keyOverLimitResponse), res.Body)
+	})
+
+	t.Run("batch over key limit", func(t *testing.T) {
+		// alice has used 2 of her 5 calls above
+		res, hdr := sendKeyRPC(t, "key_alice",
+			NewRPCReq("1", "eth_chainId", nil),
+			NewRPCReq("2", "eth_chainId", nil),
+			NewRPCReq("3", "eth_chainId", nil),
+			NewRPCReq("4", "eth_chainId", nil),
+		)
+		require.Equal(t, 200, res.StatusCode)
+		require.Equal(t, "0", hdr.Get("X-RateLimit-Remaining"))
+
+		var batchRes []*proxyd.RPCRes
+		require.NoError(t, json.Unmarshal(res.Body, &batchRes))
+		require.Len(t, batchRes, 4)
+		for _, res := range batchRes[:3] {
+			require.Nil(t, res.Error)
+		}
+		require.Equal(t, proxyd.ErrOverRateLimit.Code, batchRes[3].Error.Code)
+	})
+
+	// The counters are shared through Redis
+	require.NotEmpty(t, redis.Keys())
+}
+
+type keyRPCRes struct {
+	StatusCode int
+	Body       []byte
+}
+
+func sendKeyRPC(t *testing.T, key string, reqs ...*proxyd.RPCReq) (*keyRPCRes, http.Header) {
+	var body []byte
+	var err error
+	if len(reqs) == 1 {
+		body, err = json.Marshal(reqs[0])
+	} else {
+		body, err = json.Marshal(reqs)
+	}
+	require.NoError(t, err)
+
+	res, err := http.Post("http://127.0.0.1:8545/"+key, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return &keyRPCRes{StatusCode: res.StatusCode, Body: resBody}, res.Header
+}
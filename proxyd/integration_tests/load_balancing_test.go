@@ -0,0 +1,85 @@
+package integration_tests
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBalancing(t *testing.T) {
+	lightBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer lightBackend.Close()
+	heavyBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer heavyBackend.Close()
+	slowBackend := NewMockBackend(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		BatchedResponseHandler(200, goodResponse)(w, r)
+	}))
+	defer slowBackend.Close()
+	fastBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer fastBackend.Close()
+
+	require.NoError(t, os.Setenv("LIGHT_BACKEND_RPC_URL", lightBackend.URL()))
+	require.NoError(t, os.Setenv("HEAVY_BACKEND_RPC_URL", heavyBackend.URL()))
+	require.NoError(t, os.Setenv("SLOW_BACKEND_RPC_URL", slowBackend.URL()))
+	require.NoError(t, os.Setenv("FAST_BACKEND_RPC_URL", fastBackend.URL()))
+
+	config := ReadConfig("load_balancing")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	// sendConcurrently sends requests from several clients, each of which
+	// waits for its response before sending the next request
+	sendConcurrently := func(method string, clients, n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < clients; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < n; j++ {
+					_, code, err := client.SendRPC(method, nil)
+					require.NoError(t, err)
+					require.Equal(t, 200, code)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	t.Run("weighted round robin follows the weights", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			_, code, err := client.SendRPC("eth_chainId", nil)
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+		}
+		require.Equal(t, 25, len(lightBackend.Requests()))
+		require.Equal(t, 75, len(heavyBackend.Requests()))
+	})
+
+	t.Run("least outstanding avoids the backend with queued requests", func(t *testing.T) {
+		slowBackend.Reset()
+		fastBackend.Reset()
+		sendConcurrently("eth_blockNumber", 4, 10)
+		require.Greater(t, len(fastBackend.Requests()), 2*len(slowBackend.Requests()))
+	})
+
+	t.Run("ewma latency prefers the faster backend", func(t *testing.T) {
+		slowBackend.Reset()
+		fastBackend.Reset()
+		for i := 0; i < 40; i++ {
+			_, code, err := client.SendRPC("eth_gasPrice", nil)
+			require.NoError(t, err)
+			require.Equal(t, 200, code)
+		}
+		// The slow backend only wins the comparisons where it is drawn
+		// twice, which cannot happen with two backends
+		require.LessOrEqual(t, len(slowBackend.Requests()), 2)
+	})
+}
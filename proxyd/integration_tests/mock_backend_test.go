@@ -77,6 +77,7 @@ func (h *BatchRPCResponseRouter) SetRoute(method string, id string, result inter
 
 	switch result.(type) {
 	case string:
+	case json.RawMessage:
 	case nil:
 		break
 	default:
@@ -97,6 +98,7 @@ func (h *BatchRPCResponseRouter) SetFallbackRoute(method string, result interfac
 
 	switch result.(type) {
 	case string:
+	case json.RawMessage:
 	case nil:
 		break
 	default:
@@ -203,6 +205,104 @@ func (h *BatchRPCResponseRouter) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// CaptureFixtureHandler answers the calls of capture records with their
+// captured responses, matched by method and params
+type CaptureFixtureHandler struct {
+	responses map[string]*proxyd.RPCRes
+}
+
+func NewCaptureFixtureHandler(records []*proxyd.CaptureRecord) *CaptureFixtureHandler {
+	h := &CaptureFixtureHandler{
+		responses: make(map[string]*proxyd.RPCRes),
+	}
+	for _, record := range records {
+		rawReqs := []json.RawMessage{record.Request}
+		var responses []*proxyd.RPCRes
+		if proxyd.IsBatch(record.Request) {
+			var err error
+			if rawReqs, err = proxyd.ParseBatchRPCReq(record.Request); err != nil {
+				panic(err)
+			}
+			if err := json.Unmarshal(record.Response, &responses); err != nil {
+				panic(err)
+			}
+		} else {
+			res := new(proxyd.RPCRes)
+			if err := json.Unmarshal(record.Response, res); err != nil {
+				panic(err)
+			}
+			responses = []*proxyd.RPCRes{res}
+		}
+
+		byID := make(map[string]*proxyd.RPCRes)
+		for _, res := range responses {
+			byID[string(res.ID)] = res
+		}
+		for _, rawReq := range rawReqs {
+			req, err := proxyd.ParseRPCReq(rawReq)
+			if err != nil {
+				panic(err)
+			}
+			if res := byID[string(req.ID)]; res != nil {
+				h.responses[fixtureKey(req)] = res
+			}
+		}
+	}
+	return h
+}
+
+func fixtureKey(req *proxyd.RPCReq) string {
+	var params bytes.Buffer
+	if len(req.Params) > 0 {
+		if err := json.Compact(&params, req.Params); err != nil {
+			panic(err)
+		}
+	}
+	return req.Method + params.String()
+}
+
+func (h *CaptureFixtureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	rawReqs := []json.RawMessage{body}
+	if proxyd.IsBatch(body) {
+		if rawReqs, err = proxyd.ParseBatchRPCReq(body); err != nil {
+			panic(err)
+		}
+	}
+	out := make([]*proxyd.RPCRes, len(rawReqs))
+	for i, rawReq := range rawReqs {
+		req, err := proxyd.ParseRPCReq(rawReq)
+		if err != nil {
+			panic(err)
+		}
+		res := h.responses[fixtureKey(req)]
+		if res == nil {
+			w.WriteHeader(400)
+			return
+		}
+		out[i] = &proxyd.RPCRes{
+			JSONRPC: proxyd.JSONRPCVersion,
+			Result:  res.Result,
+			Error:   res.Error,
+			ID:      req.ID,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	if proxyd.IsBatch(body) {
+		err = enc.Encode(out)
+	} else {
+		err = enc.Encode(out[0])
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
 func NewMockBackend(handler http.Handler) *MockBackend {
 	mb := &MockBackend{
 		handler: handler,
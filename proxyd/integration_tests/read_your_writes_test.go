@@ -0,0 +1,127 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+type rywFixture struct {
+	client       *ProxydHTTPClient
+	sequencer    *MockBackend
+	replica      *MockBackend
+	replicaHdlr  *BatchRPCResponseRouter
+	sender       common.Address
+	rawTx        string
+	txHash       string
+	shutdownFunc func()
+}
+
+func newRYWFixture(t *testing.T, config string) *rywFixture {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x01")
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    4,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1),
+	}), types.NewEIP155Signer(big.NewInt(5000)), key)
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	sequencerHdlr := NewBatchRPCResponseRouter()
+	sequencerHdlr.SetFallbackRoute("eth_sendRawTransaction", tx.Hash().Hex())
+	sequencerHdlr.SetFallbackRoute("eth_getTransactionReceipt", json.RawMessage(`{"status":"0x1"}`))
+	sequencerHdlr.SetFallbackRoute("eth_getTransactionCount", "0x5")
+	replicaHdlr := NewBatchRPCResponseRouter()
+	replicaHdlr.SetFallbackRoute("eth_getTransactionReceipt", nil)
+	replicaHdlr.SetFallbackRoute("eth_getTransactionCount", "0x4")
+
+	f := &rywFixture{
+		client:      NewProxydClient("http://127.0.0.1:8545"),
+		sequencer:   NewMockBackend(sequencerHdlr),
+		replica:     NewMockBackend(replicaHdlr),
+		replicaHdlr: replicaHdlr,
+		sender:      crypto.PubkeyToAddress(key.PublicKey),
+		rawTx:       hexutil.Encode(raw),
+		txHash:      tx.Hash().Hex(),
+	}
+	require.NoError(t, os.Setenv("SEQUENCER_RPC_URL", f.sequencer.URL()))
+	require.NoError(t, os.Setenv("REPLICA_RPC_URL", f.replica.URL()))
+
+	shutdown, err := proxyd.Start(ReadConfig(config))
+	require.NoError(t, err)
+	f.shutdownFunc = shutdown
+	return f
+}
+
+func (f *rywFixture) Close() {
+	f.shutdownFunc()
+	f.sequencer.Close()
+	f.replica.Close()
+}
+
+func (f *rywFixture) send(t *testing.T, method string, params ...interface{}) string {
+	res, code, err := f.client.SendRPC(method, params)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	return string(res)
+}
+
+func TestReadYourWrites(t *testing.T) {
+	f := newRYWFixture(t, "read_your_writes")
+	defer f.Close()
+
+	// Without a recent write, reads go to the replica
+	f.send(t, "eth_getTransactionReceipt", f.txHash)
+	f.send(t, "eth_getTransactionCount", f.sender.Hex(), "pending")
+	require.Equal(t, 1, countRequests(f.replica, "eth_getTransactionReceipt"))
+	require.Equal(t, 1, countRequests(f.replica, "eth_getTransactionCount"))
+	f.replica.Reset()
+
+	f.send(t, "eth_sendRawTransaction", f.rawTx)
+	res := f.send(t, "eth_getTransactionReceipt", f.txHash)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":{"status":"0x1"},"id":999}`), []byte(res))
+	res = f.send(t, "eth_getTransactionCount", f.sender.Hex(), "pending")
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x5","id":999}`), []byte(res))
+	f.send(t, "eth_getTransactionCount", f.sender.Hex(), "latest")
+
+	require.Equal(t, 1, countRequests(f.sequencer, "eth_getTransactionReceipt"))
+	require.Equal(t, 1, countRequests(f.sequencer, "eth_getTransactionCount"))
+	require.Equal(t, 0, countRequests(f.replica, "eth_getTransactionReceipt"))
+	require.Equal(t, 1, countRequests(f.replica, "eth_getTransactionCount"))
+}
+
+func TestReadYourWritesWaitForReplica(t *testing.T) {
+	f := newRYWFixture(t, "read_your_writes_wait")
+	defer f.Close()
+
+	f.send(t, "eth_sendRawTransaction", f.rawTx)
+
+	// The replica does not catch up within the timeout
+	res := f.send(t, "eth_getTransactionCount", f.sender.Hex(), "pending")
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":"0x5","id":999}`), []byte(res))
+	require.Equal(t, 1, countRequests(f.sequencer, "eth_getTransactionCount"))
+	require.Greater(t, countRequests(f.replica, "eth_getTransactionCount"), 1)
+
+	// The replica catches up while the receipt is awaited
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		f.replicaHdlr.SetFallbackRoute("eth_getTransactionReceipt", json.RawMessage(`{"status":"0x1","blockNumber":"0x10"}`))
+	}()
+	res = f.send(t, "eth_getTransactionReceipt", f.txHash)
+	RequireEqualJSON(t, []byte(`{"jsonrpc":"2.0","result":{"status":"0x1","blockNumber":"0x10"},"id":999}`), []byte(res))
+	require.Equal(t, 0, countRequests(f.sequencer, "eth_getTransactionReceipt"))
+}
@@ -0,0 +1,105 @@
+package integration_tests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	firstChainIDResponse  = `{"jsonrpc":"2.0","result":"0x1","id":999}`
+	secondChainIDResponse = `{"jsonrpc":"2.0","result":"0x2","id":999}`
+	subscribeResponse     = `{"jsonrpc":"2.0","result":"0xsub","id":1}`
+)
+
+func TestReload(t *testing.T) {
+	firstHdlr := NewBatchRPCResponseRouter()
+	firstHdlr.SetFallbackRoute("eth_chainId", "0x1")
+	firstBackend := NewMockBackend(firstHdlr)
+	defer firstBackend.Close()
+	secondHdlr := NewBatchRPCResponseRouter()
+	secondHdlr.SetFallbackRoute("eth_chainId", "0x2")
+	secondBackend := NewMockBackend(secondHdlr)
+	defer secondBackend.Close()
+	wsBackend := NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(subscribeResponse))
+	}, nil)
+	defer wsBackend.Close()
+
+	require.NoError(t, os.Setenv("FIRST_BACKEND_RPC_URL", firstBackend.URL()))
+	require.NoError(t, os.Setenv("SECOND_BACKEND_RPC_URL", secondBackend.URL()))
+	require.NoError(t, os.Setenv("WS_BACKEND_URL", wsBackend.URL()))
+
+	config := ReadConfig("reload")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	shutdown, reload, err := proxyd.StartWithReload(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	wsMessages := make(chan []byte, 10)
+	wsClient, err := NewProxydWSClient("ws://127.0.0.1:8546", func(msgType int, data []byte) {
+		wsMessages <- data
+	}, nil)
+	require.NoError(t, err)
+	defer wsClient.HardClose()
+
+	res, code, err := client.SendRPC("eth_chainId", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(firstChainIDResponse), res)
+
+	t.Run("method mappings are swapped", func(t *testing.T) {
+		newConfig := ReadConfig("reload")
+		newConfig.RPCMethodMappings["eth_chainId"] = "alt"
+		require.NoError(t, reload(newConfig))
+
+		res, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(secondChainIDResponse), res)
+	})
+
+	t.Run("websocket sessions carry on", func(t *testing.T) {
+		require.NoError(t, wsClient.WriteMessage(
+			websocket.TextMessage,
+			[]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`),
+		))
+		select {
+		case msg := <-wsMessages:
+			RequireEqualJSON(t, []byte(subscribeResponse), msg)
+		case <-time.After(time.Second):
+			t.Fatal("no message received over websocket after reload")
+		}
+	})
+
+	t.Run("invalid config keeps current config", func(t *testing.T) {
+		newConfig := ReadConfig("reload")
+		newConfig.RPCMethodMappings["eth_chainId"] = "missing"
+		require.Error(t, reload(newConfig))
+
+		res, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(secondChainIDResponse), res)
+	})
+
+	t.Run("auth keys are swapped", func(t *testing.T) {
+		newConfig := ReadConfig("reload")
+		newConfig.Authentication = map[string]string{"secret": "alice"}
+		require.NoError(t, reload(newConfig))
+
+		_, code, err := client.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 401, code)
+
+		authClient := NewProxydClient("http://127.0.0.1:8545/secret")
+		res, code, err := authClient.SendRPC("eth_chainId", nil)
+		require.NoError(t, err)
+		require.Equal(t, 200, code)
+		RequireEqualJSON(t, []byte(firstChainIDResponse), res)
+	})
+}
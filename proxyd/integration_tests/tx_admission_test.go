@@ -0,0 +1,85 @@
+package integration_tests
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	wrongChainIDResponse = `{"jsonrpc":"2.0","error":{"code":-32017,"message":"invalid transaction: invalid chain id 1, expected 5000"},"id":999}`
+	duplicateTxResponse  = `{"jsonrpc":"2.0","error":{"code":-32018,"message":"already known"},"id":999}`
+	nonceTooLowResponse  = `{"jsonrpc":"2.0","error":{"code":-32000,"message":"nonce too low"},"id":999}`
+)
+
+func TestTxAdmission(t *testing.T) {
+	goodBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer goodBackend.Close()
+
+	require.NoError(t, os.Setenv("GOOD_BACKEND_RPC_URL", goodBackend.URL()))
+
+	config := ReadConfig("tx_admission")
+	client := NewProxydClient("http://127.0.0.1:8545")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	rawTx := signRawTx(t, 5000)
+	res, code, err := client.SendRPC("eth_sendRawTransaction", []interface{}{rawTx})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(goodResponse), res)
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	res, code, err = client.SendRPC("eth_sendRawTransaction", []interface{}{rawTx})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(duplicateTxResponse), res)
+
+	res, code, err = client.SendRPC("eth_sendRawTransaction", []interface{}{signRawTx(t, 1)})
+	require.NoError(t, err)
+	require.Equal(t, 400, code)
+	RequireEqualJSON(t, []byte(wrongChainIDResponse), res)
+
+	// Rejected transactions are not forwarded
+	require.Equal(t, 1, len(goodBackend.Requests()))
+
+	// A transaction that the backend did not accept can be sent again
+	rawTx = signRawTx(t, 5000)
+	goodBackend.SetHandler(BatchedResponseHandler(200, nonceTooLowResponse))
+	res, code, err = client.SendRPC("eth_sendRawTransaction", []interface{}{rawTx})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(nonceTooLowResponse), res)
+
+	goodBackend.SetHandler(BatchedResponseHandler(200, goodResponse))
+	res, code, err = client.SendRPC("eth_sendRawTransaction", []interface{}{rawTx})
+	require.NoError(t, err)
+	require.Equal(t, 200, code)
+	RequireEqualJSON(t, []byte(goodResponse), res)
+	require.Equal(t, 3, len(goodBackend.Requests()))
+}
+
+func signRawTx(t *testing.T, chainID int64) string {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	to := common.HexToAddress("0x01")
+	tx := types.NewTx(&types.LegacyTx{
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1),
+	})
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(chainID)), key)
+	require.NoError(t, err)
+	raw, err := signed.MarshalBinary()
+	require.NoError(t, err)
+	return hexutil.Encode(raw)
+}
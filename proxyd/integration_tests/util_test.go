@@ -104,6 +104,21 @@ func ReadConfig(name string) *proxyd.Config {
 	return config
 }
 
+// ReadCaptureFixture reads testdata/<name>.ndjson, a capture in the format
+// written by proxyd
+func ReadCaptureFixture(name string) []*proxyd.CaptureRecord {
+	f, err := os.Open(fmt.Sprintf("testdata/%s.ndjson", name))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	records, err := proxyd.ReadCapture(f)
+	if err != nil {
+		panic(err)
+	}
+	return records
+}
+
 func NewRPCReq(id string, method string, params []interface{}) *proxyd.RPCReq {
 	jsonParams, err := json.Marshal(params)
 	if err != nil {
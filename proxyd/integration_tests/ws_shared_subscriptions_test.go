@@ -0,0 +1,174 @@
+package integration_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mantlenetworkio/mantle/proxyd"
+	"github.com/stretchr/testify/require"
+)
+
+// subscriptionBackend is a WS backend that accepts every eth_subscribe
+// call and keeps track of the subscriptions. mtx is also held while writing
+// to a connection, as the handler and notify write concurrently.
+type subscriptionBackend struct {
+	*MockWSBackend
+	name string
+	mtx  sync.Mutex
+	subs map[string]*websocket.Conn
+}
+
+func newSubscriptionBackend(name string) *subscriptionBackend {
+	sb := &subscriptionBackend{
+		name: name,
+		subs: make(map[string]*websocket.Conn),
+	}
+	sb.MockWSBackend = NewMockWSBackend(nil, func(conn *websocket.Conn, msgType int, data []byte) {
+		var req proxyd.RPCReq
+		if err := json.Unmarshal(data, &req); err != nil || req.Method != "eth_subscribe" {
+			return
+		}
+		sb.mtx.Lock()
+		defer sb.mtx.Unlock()
+		id := fmt.Sprintf("0x%s%d", sb.name, len(sb.subs))
+		sb.subs[id] = conn
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"%s"}`, req.ID, id)))
+	}, nil)
+	return sb
+}
+
+func (sb *subscriptionBackend) numSubs() int {
+	sb.mtx.Lock()
+	defer sb.mtx.Unlock()
+	return len(sb.subs)
+}
+
+func (sb *subscriptionBackend) notify(t *testing.T, id string, result string) {
+	sb.mtx.Lock()
+	defer sb.mtx.Unlock()
+	conn := sb.subs[id]
+	require.NotNil(t, conn)
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":%s}}`, id, result)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+}
+
+type subscriptionClient struct {
+	*ProxydWSClient
+	msgs chan []byte
+}
+
+func newSubscriptionClient(t *testing.T) *subscriptionClient {
+	msgs := make(chan []byte, 10)
+	client, err := NewProxydWSClient("ws://127.0.0.1:8546", func(msgType int, data []byte) {
+		msgs <- data
+	}, nil)
+	require.NoError(t, err)
+	return &subscriptionClient{ProxydWSClient: client, msgs: msgs}
+}
+
+func (c *subscriptionClient) call(t *testing.T, method string, params string) *proxyd.RPCRes {
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"%s","params":%s}`, method, params)
+	require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte(msg)))
+	var res proxyd.RPCRes
+	require.NoError(t, json.Unmarshal(c.next(t), &res))
+	return &res
+}
+
+func (c *subscriptionClient) subscribe(t *testing.T, params string) string {
+	res := c.call(t, "eth_subscribe", params)
+	require.Nil(t, res.Error)
+	return res.Result.(string)
+}
+
+func (c *subscriptionClient) next(t *testing.T) []byte {
+	select {
+	case msg := <-c.msgs:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for ws message")
+		return nil
+	}
+}
+
+func (c *subscriptionClient) requireNotification(t *testing.T, id string, result string) {
+	expected := fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":%s}}`, id, result)
+	RequireEqualJSON(t, []byte(expected), c.next(t))
+}
+
+func TestWSSharedSubscriptions(t *testing.T) {
+	httpBackend := NewMockBackend(BatchedResponseHandler(200, goodResponse))
+	defer httpBackend.Close()
+	first := newSubscriptionBackend("a")
+	defer first.Close()
+	second := newSubscriptionBackend("b")
+	defer second.Close()
+
+	require.NoError(t, os.Setenv("HTTP_BACKEND_RPC_URL", httpBackend.URL()))
+	require.NoError(t, os.Setenv("FIRST_WS_BACKEND_URL", first.URL()))
+	require.NoError(t, os.Setenv("SECOND_WS_BACKEND_URL", second.URL()))
+
+	config := ReadConfig("ws_shared_subscriptions")
+	shutdown, err := proxyd.Start(config)
+	require.NoError(t, err)
+	defer shutdown()
+
+	alice := newSubscriptionClient(t)
+	defer alice.HardClose()
+	bob := newSubscriptionClient(t)
+	defer bob.HardClose()
+
+	// Identical subscriptions share one upstream subscription
+	aliceHeads := alice.subscribe(t, `["newHeads"]`)
+	bobHeads := bob.subscribe(t, `[ "newHeads" ]`)
+	require.NotEqual(t, aliceHeads, bobHeads)
+	require.Equal(t, 1, first.numSubs())
+	bobLogs := bob.subscribe(t, `["logs",{"address":"0x01"}]`)
+	require.Equal(t, 2, first.numSubs())
+	require.Equal(t, 0, second.numSubs())
+
+	first.notify(t, "0xa0", `{"number":"0x1"}`)
+	alice.requireNotification(t, aliceHeads, `{"number":"0x1"}`)
+	bob.requireNotification(t, bobHeads, `{"number":"0x1"}`)
+	first.notify(t, "0xa1", `{"logIndex":"0x0"}`)
+	bob.requireNotification(t, bobLogs, `{"logIndex":"0x0"}`)
+
+	// Other calls are forwarded over HTTP
+	res := alice.call(t, "eth_chainId", `[]`)
+	require.Nil(t, res.Error)
+	require.Equal(t, "hello", res.Result)
+
+	t.Run("resubscribes when the upstream drops", func(t *testing.T) {
+		first.Close()
+		require.Eventually(t, func() bool {
+			return second.numSubs() == 2
+		}, 2*time.Second, 10*time.Millisecond)
+
+		// Clients keep their subscription IDs on the new upstream
+		second.notify(t, "0xb0", `{"number":"0x2"}`)
+		second.notify(t, "0xb1", `{"number":"0x2"}`)
+		alice.requireNotification(t, aliceHeads, `{"number":"0x2"}`)
+		received := make(map[string]bool)
+		for i := 0; i < 2; i++ {
+			var msg struct {
+				Params struct {
+					Subscription string `json:"subscription"`
+				} `json:"params"`
+			}
+			require.NoError(t, json.Unmarshal(bob.next(t), &msg))
+			received[msg.Params.Subscription] = true
+		}
+		require.Equal(t, map[string]bool{bobHeads: true, bobLogs: true}, received)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		res := alice.call(t, "eth_unsubscribe", fmt.Sprintf(`["%s"]`, aliceHeads))
+		require.Equal(t, true, res.Result)
+		res = alice.call(t, "eth_unsubscribe", fmt.Sprintf(`["%s"]`, bobHeads))
+		require.Equal(t, false, res.Result)
+	})
+}
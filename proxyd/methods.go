@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
@@ -49,9 +51,8 @@ func (e *StaticMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res
 }
 
 type EthGetBlockByNumberMethodHandler struct {
-	cache                 Cache
-	getLatestBlockNumFn   GetLatestBlockNumFn
-	numBlockConfirmations int
+	cache    Cache
+	finality *blockFinality
 }
 
 func (e *EthGetBlockByNumberMethodHandler) cacheKey(req *RPCReq) string {
@@ -91,16 +92,12 @@ func (e *EthGetBlockByNumberMethodHandler) PutRPCMethod(ctx context.Context, req
 		return nil
 	}
 	if blockInput != "earliest" {
-		curBlock, err := e.getLatestBlockNumFn(ctx)
-		if err != nil {
-			return err
-		}
 		blockNum, err := decodeBlockInput(blockInput)
 		if err != nil {
 			return err
 		}
-		if curBlock <= blockNum+uint64(e.numBlockConfirmations) {
-			return nil
+		if ok, err := e.finality.isConfirmed(ctx, blockNum); !ok || err != nil {
+			return err
 		}
 	}
 
@@ -109,9 +106,8 @@ func (e *EthGetBlockByNumberMethodHandler) PutRPCMethod(ctx context.Context, req
 }
 
 type EthGetBlockRangeMethodHandler struct {
-	cache                 Cache
-	getLatestBlockNumFn   GetLatestBlockNumFn
-	numBlockConfirmations int
+	cache    Cache
+	finality *blockFinality
 }
 
 func (e *EthGetBlockRangeMethodHandler) cacheKey(req *RPCReq) string {
@@ -148,17 +144,13 @@ func (e *EthGetBlockRangeMethodHandler) PutRPCMethod(ctx context.Context, req *R
 	if err != nil {
 		return err
 	}
-	curBlock, err := e.getLatestBlockNumFn(ctx)
-	if err != nil {
-		return err
-	}
 	if start != "earliest" {
 		startNum, err := decodeBlockInput(start)
 		if err != nil {
 			return err
 		}
-		if curBlock <= startNum+uint64(e.numBlockConfirmations) {
-			return nil
+		if ok, err := e.finality.isConfirmed(ctx, startNum); !ok || err != nil {
+			return err
 		}
 	}
 	if end != "earliest" {
@@ -166,8 +158,8 @@ func (e *EthGetBlockRangeMethodHandler) PutRPCMethod(ctx context.Context, req *R
 		if err != nil {
 			return err
 		}
-		if curBlock <= endNum+uint64(e.numBlockConfirmations) {
-			return nil
+		if ok, err := e.finality.isConfirmed(ctx, endNum); !ok || err != nil {
+			return err
 		}
 	}
 
@@ -176,9 +168,8 @@ func (e *EthGetBlockRangeMethodHandler) PutRPCMethod(ctx context.Context, req *R
 }
 
 type EthCallMethodHandler struct {
-	cache                 Cache
-	getLatestBlockNumFn   GetLatestBlockNumFn
-	numBlockConfirmations int
+	cache    Cache
+	finality *blockFinality
 }
 
 func (e *EthCallMethodHandler) cacheable(params *ethCallParams, blockTag string) bool {
@@ -221,16 +212,12 @@ func (e *EthCallMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, re
 	}
 
 	if blockTag != "earliest" {
-		curBlock, err := e.getLatestBlockNumFn(ctx)
-		if err != nil {
-			return err
-		}
 		blockNum, err := decodeBlockInput(blockTag)
 		if err != nil {
 			return err
 		}
-		if curBlock <= blockNum+uint64(e.numBlockConfirmations) {
-			return nil
+		if ok, err := e.finality.isConfirmed(ctx, blockNum); !ok || err != nil {
+			return err
 		}
 	}
 
@@ -238,6 +225,113 @@ func (e *EthCallMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, re
 	return putImmutableRPCResponse(ctx, e.cache, key, req, res)
 }
 
+// EthGetByHashMethodHandler caches the methods that look up a block or a
+// transaction by hash. Results are cached once the block they belong to is
+// confirmed, which also excludes pending transactions.
+type EthGetByHashMethodHandler struct {
+	method string
+	// numberField is the field of the result with its block number
+	numberField string
+	// includeTx is set for methods that take a full transactions flag
+	includeTx bool
+	cache     Cache
+	finality  *blockFinality
+}
+
+func (e *EthGetByHashMethodHandler) cacheKey(req *RPCReq) string {
+	hash, includeTx, err := decodeGetByHashParams(req.Params, e.includeTx)
+	if err != nil {
+		return ""
+	}
+	if e.includeTx {
+		return fmt.Sprintf("method:%s:%s:%t", e.method, hash, includeTx)
+	}
+	return fmt.Sprintf("method:%s:%s", e.method, hash)
+}
+
+func (e *EthGetByHashMethodHandler) GetRPCMethod(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	key := e.cacheKey(req)
+	if key == "" {
+		return nil, nil
+	}
+	return getImmutableRPCResponse(ctx, e.cache, key, req)
+}
+
+func (e *EthGetByHashMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	key := e.cacheKey(req)
+	if key == "" {
+		return nil
+	}
+	result, ok := res.Result.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	number, ok := result[e.numberField].(string)
+	if !ok {
+		return nil
+	}
+	blockNum, err := decodeBlockInput(number)
+	if err != nil {
+		return err
+	}
+	if ok, err := e.finality.isConfirmed(ctx, blockNum); !ok || err != nil {
+		return err
+	}
+	return putImmutableRPCResponse(ctx, e.cache, key, req, res)
+}
+
+// EthGetLogsMethodHandler caches eth_getLogs calls over a range of
+// confirmed blocks. Filters by block hash or with block tags are not cached.
+type EthGetLogsMethodHandler struct {
+	cache    Cache
+	finality *blockFinality
+}
+
+func (e *EthGetLogsMethodHandler) cacheKey(filter *logsFilter) string {
+	address := strings.ToLower(string(mustMarshalJSON(filter.Address)))
+	topics := strings.ToLower(string(mustMarshalJSON(filter.Topics)))
+	return fmt.Sprintf("method:eth_getLogs:%s:%s:%s:%s", filter.FromBlock, filter.ToBlock, address, topics)
+}
+
+func (e *EthGetLogsMethodHandler) cacheable(filter *logsFilter) bool {
+	if filter.BlockHash != "" || filter.FromBlock == "" || filter.ToBlock == "" {
+		return false
+	}
+	if isBlockDependentParam(filter.FromBlock) || isBlockDependentParam(filter.ToBlock) {
+		return false
+	}
+	return filter.ToBlock != "earliest"
+}
+
+func (e *EthGetLogsMethodHandler) GetRPCMethod(ctx context.Context, req *RPCReq) (*RPCRes, error) {
+	filter, err := decodeGetLogsParams(req.Params)
+	if err != nil {
+		return nil, err
+	}
+	if !e.cacheable(filter) {
+		return nil, nil
+	}
+	return getImmutableRPCResponse(ctx, e.cache, e.cacheKey(filter), req)
+}
+
+func (e *EthGetLogsMethodHandler) PutRPCMethod(ctx context.Context, req *RPCReq, res *RPCRes) error {
+	filter, err := decodeGetLogsParams(req.Params)
+	if err != nil {
+		return err
+	}
+	if !e.cacheable(filter) {
+		return nil
+	}
+	toBlock, err := decodeBlockInput(filter.ToBlock)
+	if err != nil {
+		return err
+	}
+	if ok, err := e.finality.isConfirmed(ctx, toBlock); !ok || err != nil {
+		return err
+	}
+	return putImmutableRPCResponse(ctx, e.cache, e.cacheKey(filter), req, res)
+}
+
 type EthBlockNumberMethodHandler struct {
 	getLatestBlockNumFn GetLatestBlockNumFn
 }
@@ -322,6 +416,58 @@ func decodeGetBlockRangeParams(params json.RawMessage) (string, string, bool, er
 	return startBlockNum, endBlockNum, includeTx, nil
 }
 
+func decodeGetByHashParams(params json.RawMessage, withIncludeTx bool) (string, bool, error) {
+	var list []interface{}
+	if err := json.Unmarshal(params, &list); err != nil {
+		return "", false, err
+	}
+	if (withIncludeTx && len(list) != 2) || (!withIncludeTx && len(list) != 1) {
+		return "", false, errInvalidRPCParams
+	}
+	hash, ok := list[0].(string)
+	if !ok {
+		return "", false, errInvalidRPCParams
+	}
+	if b, err := hexutil.Decode(hash); err != nil || len(b) != common.HashLength {
+		return "", false, errInvalidRPCParams
+	}
+	var includeTx bool
+	if withIncludeTx {
+		if includeTx, ok = list[1].(bool); !ok {
+			return "", false, errInvalidRPCParams
+		}
+	}
+	return strings.ToLower(hash), includeTx, nil
+}
+
+type logsFilter struct {
+	FromBlock string      `json:"fromBlock"`
+	ToBlock   string      `json:"toBlock"`
+	Address   interface{} `json:"address"`
+	Topics    interface{} `json:"topics"`
+	BlockHash string      `json:"blockHash"`
+}
+
+func decodeGetLogsParams(params json.RawMessage) (*logsFilter, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(params, &list); err != nil {
+		return nil, err
+	}
+	if len(list) != 1 {
+		return nil, errInvalidRPCParams
+	}
+	filter := new(logsFilter)
+	if err := json.Unmarshal(list[0], filter); err != nil {
+		return nil, err
+	}
+	for _, input := range []string{filter.FromBlock, filter.ToBlock} {
+		if input != "" && !validBlockInput(input) {
+			return nil, errInvalidRPCParams
+		}
+	}
+	return filter, nil
+}
+
 func decodeBlockInput(input string) (uint64, error) {
 	return hexutil.DecodeUint64(input)
 }
@@ -179,6 +179,21 @@ var (
 		"method",
 	})
 
+	cacheReorgsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "cache_reorgs_total",
+		Help:      "Count of head reorgs that invalidated the cache.",
+	})
+
+	readYourWritesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "read_your_writes_total",
+		Help:      "Count of reads of recent writes, by where they were read from.",
+	}, []string{
+		"method_name",
+		"outcome",
+	})
+
 	lvcErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: MetricsNamespace,
 		Name:      "lvc_errors_total",
@@ -201,6 +216,163 @@ var (
 		Help:      "Count of total batch RPC short-circuits.",
 	})
 
+	backendOutstandingRequestsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_outstanding_requests",
+		Help:      "Gauge of in flight requests to each backend.",
+	}, []string{
+		"backend_name",
+	})
+
+	backendLatestBlockGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_latest_block",
+		Help:      "Latest block reported by each backend.",
+	}, []string{
+		"backend_name",
+	})
+
+	backendBlockLagGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_block_lag",
+		Help:      "Number of blocks each backend is behind the consensus head of its group.",
+	}, []string{
+		"backend_name",
+	})
+
+	backendBannedGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_banned",
+		Help:      "Whether each backend is banned for being out of consensus.",
+	}, []string{
+		"backend_name",
+	})
+
+	consensusHeadGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "group_consensus_head",
+		Help:      "Consensus head of each consensus aware backend group.",
+	}, []string{
+		"backend_group_name",
+	})
+
+	consensusBansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "consensus_bans_total",
+		Help:      "Count of backends banned for being out of consensus.",
+	}, []string{
+		"backend_name",
+		"reason",
+	})
+
+	backendCircuitStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "backend_circuit_state",
+		Help:      "Circuit breaker state of each backend, 0 is closed, 1 is half open and 2 is open.",
+	}, []string{
+		"backend_name",
+	})
+
+	circuitBreakerTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "Count of circuit breaker state changes of each backend.",
+	}, []string{
+		"backend_name",
+		"state",
+	})
+
+	getLogsRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "get_logs_rejected_total",
+		Help:      "Count of eth_getLogs calls rejected for exceeding a limit.",
+	}, []string{
+		"auth",
+		"limit",
+	})
+
+	getLogsChunksTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "get_logs_chunks_total",
+		Help:      "Count of chunks eth_getLogs calls were split into.",
+	})
+
+	captureRecordsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "capture_records_total",
+		Help:      "Count of RPC calls written to the traffic capture.",
+	})
+
+	captureDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "capture_dropped_total",
+		Help:      "Count of sampled RPC calls that could not be written to the traffic capture.",
+	})
+
+	keyUsageTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "key_usage_total",
+		Help:      "Count of RPC calls counted against the rate limits of each auth key.",
+	}, []string{
+		"auth",
+		"method_name",
+	})
+
+	keyRateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "key_rate_limited_total",
+		Help:      "Count of RPC calls rejected by the rate limits of each auth key.",
+	}, []string{
+		"auth",
+		"method_name",
+		"scope",
+	})
+
+	txAdmissionRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "tx_admission_rejections_total",
+		Help:      "Count of raw transactions rejected before being forwarded.",
+	}, []string{
+		"auth",
+		"reason",
+	})
+
+	configReloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "config_reloads_total",
+		Help:      "Count of config reloads, by status.",
+	}, []string{
+		"status",
+	})
+
+	wsSharedSubscriptionsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_shared_subscriptions",
+		Help:      "Gauge of upstream subscriptions shared by WS clients, per backend.",
+	}, []string{
+		"backend_name",
+	})
+
+	wsSubscriptionClientsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_subscription_clients",
+		Help:      "Gauge of client subscriptions served by shared upstream subscriptions.",
+	})
+
+	wsResubscriptionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_resubscriptions_total",
+		Help:      "Count of shared subscriptions moved to a backend after their upstream connection dropped.",
+	}, []string{
+		"backend_name",
+	})
+
+	wsSlowClientsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "ws_slow_clients_total",
+		Help:      "Count of WS clients disconnected because they did not keep up with their subscriptions.",
+	})
+
 	rpcSpecialErrors = []string{
 		"nonce too low",
 		"gas price too high",
@@ -6,39 +6,36 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/semaphore"
 )
 
 func Start(config *Config) (func(), error) {
-	if len(config.Backends) == 0 {
-		return nil, errors.New("must define at least one backend")
-	}
-	if len(config.BackendGroups) == 0 {
-		return nil, errors.New("must define at least one backend group")
-	}
-	if len(config.RPCMethodMappings) == 0 {
-		return nil, errors.New("must define at least one RPC method mapping")
-	}
+	shutdown, _, err := StartWithReload(config)
+	return shutdown, err
+}
 
-	for authKey := range config.Authentication {
-		if authKey == "none" {
-			return nil, errors.New("cannot use none as an auth key")
-		}
+// StartWithReload starts proxyd and also returns a function that applies a
+// new config to the running proxyd. Backends, backend groups, method
+// mappings, authentication, rate limits and transaction admission are
+// reloaded. The other settings require a restart.
+func StartWithReload(config *Config) (func(), func(*Config) error, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, nil, err
 	}
 
 	var redisURL string
 	if config.Redis.URL != "" {
 		rURL, err := ReadFromEnvOrConfig(config.Redis.URL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		redisURL = rURL
 	}
@@ -51,7 +48,7 @@ func Start(config *Config) (func(), error) {
 	} else {
 		lim, err = NewRedisRateLimiter(redisURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -61,173 +58,88 @@ func Start(config *Config) (func(), error) {
 	}
 	rpcRequestSemaphore := semaphore.NewWeighted(maxConcurrentRPCs)
 
-	backendNames := make([]string, 0)
-	backendsByName := make(map[string]*Backend)
-	for name, cfg := range config.Backends {
-		opts := make([]BackendOpt, 0)
-
-		rpcURL, err := ReadFromEnvOrConfig(cfg.RPCURL)
-		if err != nil {
-			return nil, err
-		}
-		wsURL, err := ReadFromEnvOrConfig(cfg.WSURL)
-		if err != nil {
-			return nil, err
-		}
-		if rpcURL == "" {
-			return nil, fmt.Errorf("must define an RPC URL for backend %s", name)
-		}
-		if wsURL == "" {
-			return nil, fmt.Errorf("must define a WS URL for backend %s", name)
-		}
-
-		if config.BackendOptions.ResponseTimeoutSeconds != 0 {
-			timeout := secondsToDuration(config.BackendOptions.ResponseTimeoutSeconds)
-			opts = append(opts, WithTimeout(timeout))
-		}
-		if config.BackendOptions.MaxRetries != 0 {
-			opts = append(opts, WithMaxRetries(config.BackendOptions.MaxRetries))
-		}
-		if config.BackendOptions.MaxResponseSizeBytes != 0 {
-			opts = append(opts, WithMaxResponseSize(config.BackendOptions.MaxResponseSizeBytes))
-		}
-		if config.BackendOptions.OutOfServiceSeconds != 0 {
-			opts = append(opts, WithOutOfServiceDuration(secondsToDuration(config.BackendOptions.OutOfServiceSeconds)))
-		}
-		if cfg.MaxRPS != 0 {
-			opts = append(opts, WithMaxRPS(cfg.MaxRPS))
-		}
-		if cfg.MaxWSConns != 0 {
-			opts = append(opts, WithMaxWSConns(cfg.MaxWSConns))
-		}
-		if cfg.Password != "" {
-			passwordVal, err := ReadFromEnvOrConfig(cfg.Password)
-			if err != nil {
-				return nil, err
-			}
-			opts = append(opts, WithBasicAuth(cfg.Username, passwordVal))
-		}
-		tlsConfig, err := configureBackendTLS(cfg)
-		if err != nil {
-			return nil, err
-		}
-		if tlsConfig != nil {
-			log.Info("using custom TLS config for backend", "name", name)
-			opts = append(opts, WithTLSConfig(tlsConfig))
-		}
-		if cfg.StripTrailingXFF {
-			opts = append(opts, WithStrippedTrailingXFF())
-		}
-		opts = append(opts, WithProxydIP(os.Getenv("PROXYD_IP")))
-		back := NewBackend(name, rpcURL, wsURL, lim, rpcRequestSemaphore, opts...)
-		backendNames = append(backendNames, name)
-		backendsByName[name] = back
-		log.Info("configured backend", "name", name, "rpc_url", rpcURL, "ws_url", wsURL)
-	}
-
-	backendGroups := make(map[string]*BackendGroup)
-	for bgName, bg := range config.BackendGroups {
-		backends := make([]*Backend, 0)
-		for _, bName := range bg.Backends {
-			if backendsByName[bName] == nil {
-				return nil, fmt.Errorf("backend %s is not defined", bName)
-			}
-			backends = append(backends, backendsByName[bName])
-		}
-		group := &BackendGroup{
-			Name:     bgName,
-			Backends: backends,
-		}
-		backendGroups[bgName] = group
-	}
-
-	var wsBackendGroup *BackendGroup
-	if config.WSBackendGroup != "" {
-		wsBackendGroup = backendGroups[config.WSBackendGroup]
-		if wsBackendGroup == nil {
-			return nil, fmt.Errorf("ws backend group %s does not exist", config.WSBackendGroup)
-		}
-	}
-
-	if wsBackendGroup == nil && config.Server.WSPort != 0 {
-		return nil, fmt.Errorf("a ws port was defined, but no ws group was defined")
-	}
-
-	for _, bg := range config.RPCMethodMappings {
-		if backendGroups[bg] == nil {
-			return nil, fmt.Errorf("undefined backend group %s", bg)
-		}
-	}
-
-	var resolvedAuth map[string]string
-
-	if config.Authentication != nil {
-		resolvedAuth = make(map[string]string)
-		for secret, alias := range config.Authentication {
-			resolvedSecret, err := ReadFromEnvOrConfig(secret)
-			if err != nil {
-				return nil, err
-			}
-			resolvedAuth[resolvedSecret] = alias
-		}
+	rl := newReloader(lim, rpcRequestSemaphore, redisURL)
+	routing, backends, err := rl.build(config)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var (
 		rpcCache    RPCCache
 		blockNumLVC *EthLastValueCache
 		gasPriceLVC *EthLastValueCache
+		verifiedLVC *EthLastValueCache
+		headTracker *HeadTracker
 	)
 	if config.Cache.Enabled {
 		var (
 			cache      Cache
 			blockNumFn GetLatestBlockNumFn
 			gasPriceFn GetLatestGasPriceFn
+			verifiedFn GetLatestBlockNumFn
 		)
 
 		if config.Cache.BlockSyncRPCURL == "" {
-			return nil, fmt.Errorf("block sync node required for caching")
+			return nil, nil, fmt.Errorf("block sync node required for caching")
 		}
 		blockSyncRPCURL, err := ReadFromEnvOrConfig(config.Cache.BlockSyncRPCURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if redisURL != "" {
 			if cache, err = newRedisCache(redisURL); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		} else {
 			log.Warn("redis is not configured, using in-memory cache")
 			cache = newMemoryCache()
 		}
 		// Ideally, the BlocKSyncRPCURL should be the sequencer or a HA replica that's not far behind
-		ethClient, err := ethclient.Dial(blockSyncRPCURL)
+		rpcClient, err := rpc.Dial(blockSyncRPCURL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		ethClient := ethclient.NewClient(rpcClient)
 		defer ethClient.Close()
 
 		blockNumLVC, blockNumFn = makeGetLatestBlockNumFn(ethClient, cache)
 		gasPriceLVC, gasPriceFn = makeGetLatestGasPriceFn(ethClient, cache)
-		rpcCache = newRPCCache(newCacheWithCompression(cache), blockNumFn, gasPriceFn, config.Cache.NumBlockConfirmations)
+		if config.Cache.UseVerifiedIndex {
+			verifiedLVC, verifiedFn = makeGetVerifiedBlockNumFn(rpcClient, ethClient, cache)
+		}
+		headTracker = newHeadTracker(rpcClient, cache)
+		headTracker.Start()
+		rpcCache = newRPCCache(
+			newCacheWithGeneration(newCacheWithCompression(cache), headTracker),
+			blockNumFn,
+			gasPriceFn,
+			verifiedFn,
+			config.Cache.NumBlockConfirmations,
+		)
 	}
 
 	srv, err := NewServer(
-		backendGroups,
-		wsBackendGroup,
-		NewStringSetFromStrings(config.WSMethodWhitelist),
-		config.RPCMethodMappings,
+		routing,
 		config.Server.MaxBodySizeBytes,
-		resolvedAuth,
 		secondsToDuration(config.Server.TimeoutSeconds),
 		config.Server.MaxUpstreamBatchSize,
 		rpcCache,
-		config.RateLimit,
 		config.Server.EnableRequestLog,
 		config.Server.MaxRequestBodyLogLen,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error creating server: %w", err)
+		return nil, nil, fmt.Errorf("error creating server: %w", err)
+	}
+	rl.srv = srv
+
+	if config.Capture.Enabled {
+		capture, err := NewTrafficCapture(config.Capture)
+		if err != nil {
+			return nil, nil, err
+		}
+		srv.capture = capture
+		log.Info("capturing traffic", "dir", config.Capture.Dir)
 	}
 
 	if config.Metrics.Enabled {
@@ -269,6 +181,8 @@ func Start(config *Config) (func(), error) {
 		}()
 	}
 
+	rl.commit(config, routing, backends)
+
 	<-errTimer.C
 	log.Info("started proxyd")
 
@@ -280,12 +194,41 @@ func Start(config *Config) (func(), error) {
 		if gasPriceLVC != nil {
 			gasPriceLVC.Stop()
 		}
+		if verifiedLVC != nil {
+			verifiedLVC.Stop()
+		}
+		if headTracker != nil {
+			headTracker.Stop()
+		}
 		srv.Shutdown()
+		if srv.capture != nil {
+			srv.capture.Close()
+		}
+		backendNames := rl.stop()
 		if err := lim.FlushBackendWSConns(backendNames); err != nil {
 			log.Error("error flushing backend ws conns", "err", err)
 		}
 		log.Info("goodbye")
-	}, nil
+	}, rl.reload, nil
+}
+
+func validateConfig(config *Config) error {
+	if len(config.Backends) == 0 {
+		return errors.New("must define at least one backend")
+	}
+	if len(config.BackendGroups) == 0 {
+		return errors.New("must define at least one backend group")
+	}
+	if len(config.RPCMethodMappings) == 0 {
+		return errors.New("must define at least one RPC method mapping")
+	}
+
+	for authKey := range config.Authentication {
+		if authKey == "none" {
+			return errors.New("cannot use none as an auth key")
+		}
+	}
+	return nil
 }
 
 func secondsToDuration(seconds int) time.Duration {
@@ -348,3 +291,25 @@ func makeGetLatestGasPriceFn(client *ethclient.Client, cache Cache) (*EthLastVal
 		return gasPrice.String(), nil
 	})
 }
+
+// makeGetVerifiedBlockNumFn polls the index of the last transaction verified
+// against the state commitment chain. Every L2 block holds one transaction,
+// and the transaction with index i is in block i+1.
+func makeGetVerifiedBlockNumFn(rpcClient *rpc.Client, client *ethclient.Client, cache Cache) (*EthLastValueCache, GetLatestBlockNumFn) {
+	return makeUint64LastValueFn(client, cache, "lvc:verified_block_number", func(ctx context.Context, _ *ethclient.Client) (string, error) {
+		var info struct {
+			RollupContext struct {
+				VerifiedIndex uint64 `json:"verifiedIndex"`
+			} `json:"rollupContext"`
+		}
+		if err := rpcClient.CallContext(ctx, &info, "rollup_getInfo"); err != nil {
+			return "", err
+		}
+		// A verified index of 0 means that nothing was verified yet
+		var blockNum uint64
+		if info.RollupContext.VerifiedIndex > 0 {
+			blockNum = info.RollupContext.VerifiedIndex + 1
+		}
+		return strconv.FormatUint(blockNum, 10), nil
+	})
+}
@@ -41,6 +41,23 @@ end
 return false
 `
 
+const IncProbesScript = `
+local current
+current = redis.call("incr", KEYS[1])
+if current == 1 then
+    redis.call("pexpire", KEYS[1], ARGV[1])
+end
+return current
+`
+
+const DecProbesScript = `
+local current = tonumber(redis.call("get", KEYS[1]))
+if current and current > 0 then
+    return redis.call("decr", KEYS[1])
+end
+return 0
+`
+
 type BackendRateLimiter interface {
 	IsBackendOnline(name string) (bool, error)
 	SetBackendOffline(name string, duration time.Duration) error
@@ -48,6 +65,17 @@ type BackendRateLimiter interface {
 	IncBackendWSConns(name string, max int) (bool, error)
 	DecBackendWSConns(name string) error
 	FlushBackendWSConns(names []string) error
+	// IncBackendProbes counts the circuit breaker probes sent to a backend
+	// and returns the count. The count is reset after ttl.
+	IncBackendProbes(name string, ttl time.Duration) (int, error)
+	// DecBackendProbes gives back a probe that did not get a result
+	DecBackendProbes(name string) error
+	// IncBackendProbeSuccesses counts the circuit breaker probes of a
+	// backend that succeeded and returns the count. The count is reset
+	// after ttl.
+	IncBackendProbeSuccesses(name string, ttl time.Duration) (int, error)
+	// ResetBackendProbes resets the probe and the probe success counts
+	ResetBackendProbes(name string) error
 }
 
 type RedisBackendRateLimiter struct {
@@ -172,6 +200,62 @@ func (r *RedisBackendRateLimiter) FlushBackendWSConns(names []string) error {
 	return nil
 }
 
+func (r *RedisBackendRateLimiter) IncBackendProbes(name string, ttl time.Duration) (int, error) {
+	cmd := r.rdb.Eval(
+		context.Background(),
+		IncProbesScript,
+		[]string{fmt.Sprintf("backend:%s:probes", name)},
+		ttl.Milliseconds(),
+	)
+	probes, err := cmd.Int()
+	if err != nil {
+		RecordRedisError("IncBackendProbes")
+		return -1, wrapErr(err, "error incrementing backend probes")
+	}
+	return probes, nil
+}
+
+func (r *RedisBackendRateLimiter) DecBackendProbes(name string) error {
+	err := r.rdb.Eval(
+		context.Background(),
+		DecProbesScript,
+		[]string{fmt.Sprintf("backend:%s:probes", name)},
+	).Err()
+	if err != nil {
+		RecordRedisError("DecBackendProbes")
+		return wrapErr(err, "error decrementing backend probes")
+	}
+	return nil
+}
+
+func (r *RedisBackendRateLimiter) IncBackendProbeSuccesses(name string, ttl time.Duration) (int, error) {
+	cmd := r.rdb.Eval(
+		context.Background(),
+		IncProbesScript,
+		[]string{fmt.Sprintf("backend:%s:probe_successes", name)},
+		ttl.Milliseconds(),
+	)
+	successes, err := cmd.Int()
+	if err != nil {
+		RecordRedisError("IncBackendProbeSuccesses")
+		return -1, wrapErr(err, "error incrementing backend probe successes")
+	}
+	return successes, nil
+}
+
+func (r *RedisBackendRateLimiter) ResetBackendProbes(name string) error {
+	err := r.rdb.Del(
+		context.Background(),
+		fmt.Sprintf("backend:%s:probes", name),
+		fmt.Sprintf("backend:%s:probe_successes", name),
+	).Err()
+	if err != nil {
+		RecordRedisError("ResetBackendProbes")
+		return wrapErr(err, "error resetting backend probes")
+	}
+	return nil
+}
+
 func (r *RedisBackendRateLimiter) touch() {
 	for {
 		r.tkMtx.Lock()
@@ -186,18 +270,27 @@ func (r *RedisBackendRateLimiter) touch() {
 	}
 }
 
+type localProbes struct {
+	count   int
+	expires time.Time
+}
+
 type LocalBackendRateLimiter struct {
-	deadBackends   map[string]time.Time
-	backendRPS     map[string]int
-	backendWSConns map[string]int
-	mtx            sync.RWMutex
+	deadBackends          map[string]time.Time
+	backendRPS            map[string]int
+	backendWSConns        map[string]int
+	backendProbes         map[string]*localProbes
+	backendProbeSuccesses map[string]*localProbes
+	mtx                   sync.RWMutex
 }
 
 func NewLocalBackendRateLimiter() *LocalBackendRateLimiter {
 	out := &LocalBackendRateLimiter{
-		deadBackends:   make(map[string]time.Time),
-		backendRPS:     make(map[string]int),
-		backendWSConns: make(map[string]int),
+		deadBackends:          make(map[string]time.Time),
+		backendRPS:            make(map[string]int),
+		backendWSConns:        make(map[string]int),
+		backendProbes:         make(map[string]*localProbes),
+		backendProbeSuccesses: make(map[string]*localProbes),
 	}
 	go out.clear()
 	return out
@@ -247,6 +340,47 @@ func (l *LocalBackendRateLimiter) FlushBackendWSConns(names []string) error {
 	return nil
 }
 
+func (l *LocalBackendRateLimiter) IncBackendProbes(name string, ttl time.Duration) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return incLocalProbes(l.backendProbes, name, ttl), nil
+}
+
+func (l *LocalBackendRateLimiter) DecBackendProbes(name string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if probes := l.backendProbes[name]; probes != nil && probes.count > 0 {
+		probes.count -= 1
+	}
+	return nil
+}
+
+func (l *LocalBackendRateLimiter) IncBackendProbeSuccesses(name string, ttl time.Duration) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return incLocalProbes(l.backendProbeSuccesses, name, ttl), nil
+}
+
+func (l *LocalBackendRateLimiter) ResetBackendProbes(name string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	delete(l.backendProbes, name)
+	delete(l.backendProbeSuccesses, name)
+	return nil
+}
+
+// incLocalProbes must be called with the lock held
+func incLocalProbes(counts map[string]*localProbes, name string, ttl time.Duration) int {
+	now := time.Now()
+	probes := counts[name]
+	if probes == nil || now.After(probes.expires) {
+		probes = &localProbes{expires: now.Add(ttl)}
+		counts[name] = probes
+	}
+	probes.count += 1
+	return probes.count
+}
+
 func (l *LocalBackendRateLimiter) clear() {
 	for {
 		time.Sleep(time.Second)
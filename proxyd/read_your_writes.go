@@ -0,0 +1,272 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultRecentWriteWindow = 30 * time.Second
+	replicaPollInterval      = 100 * time.Millisecond
+
+	RYWOutcomeWriteGroup     = "write_group"
+	RYWOutcomeReplica        = "replica"
+	RYWOutcomeReplicaTimeout = "replica_timeout"
+)
+
+// RecentWrites remembers the transactions proxyd forwarded recently. Entries
+// expire with their ttl.
+type RecentWrites interface {
+	Put(ctx context.Context, key string, value string, ttl time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type RedisRecentWrites struct {
+	rdb *redis.Client
+}
+
+func NewRedisRecentWrites(url string) (*RedisRecentWrites, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opts)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, wrapErr(err, "error connecting to redis")
+	}
+	return &RedisRecentWrites{rdb: rdb}, nil
+}
+
+func (r *RedisRecentWrites) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := r.rdb.SetEX(ctx, key, value, ttl).Err(); err != nil {
+		RecordRedisError("PutRecentWrite")
+		return wrapErr(err, "error putting recent write")
+	}
+	return nil
+}
+
+func (r *RedisRecentWrites) Get(ctx context.Context, key string) (string, error) {
+	val, err := r.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	} else if err != nil {
+		RecordRedisError("GetRecentWrite")
+		return "", wrapErr(err, "error getting recent write")
+	}
+	return val, nil
+}
+
+type localWrite struct {
+	value   string
+	expires time.Time
+}
+
+type LocalRecentWrites struct {
+	writes    map[string]*localWrite
+	lastSweep time.Time
+	mtx       sync.Mutex
+}
+
+func NewLocalRecentWrites() *LocalRecentWrites {
+	return &LocalRecentWrites{
+		writes:    make(map[string]*localWrite),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *LocalRecentWrites) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, write := range l.writes {
+			if now.After(write.expires) {
+				delete(l.writes, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	l.writes[key] = &localWrite{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (l *LocalRecentWrites) Get(ctx context.Context, key string) (string, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	write := l.writes[key]
+	if write == nil || time.Now().After(write.expires) {
+		return "", nil
+	}
+	return write.value, nil
+}
+
+// ReadYourWrites routes the reads that follow a write of the same client to
+// the group that took the write, usually the sequencer. It remembers the
+// hashes and senders of the transactions forwarded with
+// eth_sendRawTransaction. The receipts of these transactions and the pending
+// nonces of their senders are read from the write group while the writes are
+// recent. With a replica timeout, the reads go to the replica until it
+// reports the write, and fall back to the write group after the timeout.
+type ReadYourWrites struct {
+	writeGroup     string
+	window         time.Duration
+	replicaTimeout time.Duration
+	writes         RecentWrites
+}
+
+func NewReadYourWrites(config ReadYourWritesConfig, writeGroup string, writes RecentWrites) *ReadYourWrites {
+	window := secondsToDuration(config.WindowSeconds)
+	if window == 0 {
+		window = defaultRecentWriteWindow
+	}
+	return &ReadYourWrites{
+		writeGroup:     writeGroup,
+		window:         window,
+		replicaTimeout: secondsToDuration(config.ReplicaTimeoutSeconds),
+		writes:         writes,
+	}
+}
+
+// Record remembers the transaction of a successful eth_sendRawTransaction
+// call
+func (r *ReadYourWrites) Record(ctx context.Context, req *RPCReq, res *RPCRes) {
+	if req.Method != "eth_sendRawTransaction" || res.IsError() {
+		return
+	}
+	var params []hexutil.Bytes
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+		return
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(params[0]); err != nil {
+		return
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+
+	// The next nonce shows that a replica has seen the transaction
+	nextNonce := strconv.FormatUint(tx.Nonce()+1, 10)
+	if err := r.writes.Put(ctx, txWriteKey(tx.Hash().Hex()), nextNonce, r.window); err != nil {
+		log.Error("error recording recent write", "req_id", GetReqID(ctx), "err", err)
+		return
+	}
+	if err := r.writes.Put(ctx, senderWriteKey(sender.Hex()), nextNonce, r.window); err != nil {
+		log.Error("error recording recent write", "req_id", GetReqID(ctx), "err", err)
+	}
+}
+
+// lookup returns the key of the recent write that req reads, if any
+func (r *ReadYourWrites) lookup(ctx context.Context, req *RPCReq) (string, bool) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return "", false
+	}
+
+	var key string
+	switch req.Method {
+	case "eth_getTransactionReceipt":
+		if len(params) != 1 {
+			return "", false
+		}
+		key = txWriteKey(params[0])
+	case "eth_getTransactionCount":
+		if len(params) != 2 || params[1] != "pending" || !common.IsHexAddress(params[0]) {
+			return "", false
+		}
+		key = senderWriteKey(params[0])
+	default:
+		return "", false
+	}
+
+	val, err := r.writes.Get(ctx, key)
+	if err != nil {
+		log.Error("error looking up recent write", "req_id", GetReqID(ctx), "err", err)
+		return "", false
+	}
+	return val, val != ""
+}
+
+// Route returns the backend group of a read. When the replica timeout is
+// set, reads of recent writes are answered by waiting on the replica group
+// and res is not nil.
+func (r *ReadYourWrites) Route(ctx context.Context, rt *Routing, req *RPCReq, group string) (string, *RPCRes) {
+	if group == r.writeGroup {
+		return group, nil
+	}
+	nextNonce, ok := r.lookup(ctx, req)
+	if !ok {
+		return group, nil
+	}
+	if r.replicaTimeout == 0 {
+		readYourWritesTotal.WithLabelValues(req.Method, RYWOutcomeWriteGroup).Inc()
+		return r.writeGroup, nil
+	}
+	if res := r.waitForReplica(ctx, rt.BackendGroups[group], req, nextNonce); res != nil {
+		readYourWritesTotal.WithLabelValues(req.Method, RYWOutcomeReplica).Inc()
+		return group, res
+	}
+	readYourWritesTotal.WithLabelValues(req.Method, RYWOutcomeReplicaTimeout).Inc()
+	return r.writeGroup, nil
+}
+
+// waitForReplica polls the replica group until it reports the write, and
+// returns nil when it did not within the timeout
+func (r *ReadYourWrites) waitForReplica(ctx context.Context, group *BackendGroup, req *RPCReq, nextNonce string) *RPCRes {
+	ctx, cancel := context.WithTimeout(ctx, r.replicaTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(replicaPollInterval)
+	defer ticker.Stop()
+	for {
+		res, err := group.Forward(ctx, []*RPCReq{req}, false)
+		if err == nil && len(res) == 1 && replicaHasWrite(req, res[0], nextNonce) {
+			return res[0]
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func replicaHasWrite(req *RPCReq, res *RPCRes, nextNonce string) bool {
+	if res.IsError() || res.Result == nil {
+		return false
+	}
+	if req.Method != "eth_getTransactionCount" {
+		return true
+	}
+	count, ok := res.Result.(string)
+	if !ok {
+		return false
+	}
+	nonce, err := hexutil.DecodeUint64(count)
+	if err != nil {
+		return false
+	}
+	expected, err := strconv.ParseUint(nextNonce, 10, 64)
+	return err == nil && nonce >= expected
+}
+
+func txWriteKey(hash string) string {
+	return "ryw:tx:" + strings.ToLower(hash)
+}
+
+func senderWriteKey(sender string) string {
+	return "ryw:sender:" + strings.ToLower(sender)
+}
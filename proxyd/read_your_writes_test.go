@@ -0,0 +1,78 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadYourWritesRoute(t *testing.T) {
+	ctx := context.Background()
+	ryw := NewReadYourWrites(ReadYourWritesConfig{}, "sequencer", NewLocalRecentWrites())
+
+	key, err := crypto.HexToECDSA("8b3a350cf5c34c9194ca85829a2df0ec3153be0318b5e2d3348e872092edffba")
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	rawTx := signedRawTx(t, 5000, 7, nil)
+	tx := new(types.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(hexutil.MustDecode(rawTx)))
+
+	receiptReq := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getTransactionReceipt",
+		Params:  mustMarshalJSON([]string{tx.Hash().Hex()}),
+		ID:      []byte("1"),
+	}
+	countReq := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getTransactionCount",
+		Params:  mustMarshalJSON([]string{sender.Hex(), "pending"}),
+		ID:      []byte("1"),
+	}
+	latestCountReq := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_getTransactionCount",
+		Params:  mustMarshalJSON([]string{sender.Hex(), "latest"}),
+		ID:      []byte("1"),
+	}
+
+	route := func(req *RPCReq) string {
+		group, res := ryw.Route(ctx, &Routing{}, req, "replica")
+		require.Nil(t, res)
+		return group
+	}
+
+	require.Equal(t, "replica", route(receiptReq))
+	require.Equal(t, "replica", route(countReq))
+
+	// Failed writes are not remembered
+	ryw.Record(ctx, sendRawTxReq(rawTx), NewRPCErrorRes(json.RawMessage("1"), ErrInternal))
+	require.Equal(t, "replica", route(receiptReq))
+
+	ryw.Record(ctx, sendRawTxReq(rawTx), NewRPCRes(json.RawMessage("1"), tx.Hash().Hex()))
+	require.Equal(t, "sequencer", route(receiptReq))
+	require.Equal(t, "sequencer", route(countReq))
+	require.Equal(t, "replica", route(latestCountReq))
+
+	nextNonce, ok := ryw.lookup(ctx, countReq)
+	require.True(t, ok)
+	require.Equal(t, "8", nextNonce)
+}
+
+func TestReplicaHasWrite(t *testing.T) {
+	receiptReq := &RPCReq{Method: "eth_getTransactionReceipt"}
+	countReq := &RPCReq{Method: "eth_getTransactionCount"}
+	id := json.RawMessage("1")
+
+	require.False(t, replicaHasWrite(receiptReq, NewRPCRes(id, nil), "8"))
+	require.False(t, replicaHasWrite(receiptReq, NewRPCErrorRes(id, ErrInternal), "8"))
+	require.True(t, replicaHasWrite(receiptReq, NewRPCRes(id, map[string]interface{}{"status": "0x1"}), "8"))
+	require.False(t, replicaHasWrite(countReq, NewRPCRes(id, "0x7"), "8"))
+	require.True(t, replicaHasWrite(countReq, NewRPCRes(id, "0x8"), "8"))
+	require.True(t, replicaHasWrite(countReq, NewRPCRes(id, "0x9"), "8"))
+}
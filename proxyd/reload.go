@@ -0,0 +1,494 @@
+package proxyd
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	ConfigReloadSuccess = "success"
+	ConfigReloadError   = "error"
+)
+
+// reloader builds the routing of the server from a config, and applies new
+// configs to the running server. Backends and backend groups whose config
+// did not change are carried over, so that they keep their health and
+// consensus state.
+type reloader struct {
+	mtx                 sync.Mutex
+	srv                 *Server
+	lim                 BackendRateLimiter
+	rpcRequestSemaphore *semaphore.Weighted
+	redisURL            string
+	counter             UsageCounter
+	writes              RecentWrites
+
+	config   *Config
+	backends map[string]*Backend
+	routing  *Routing
+}
+
+func newReloader(lim BackendRateLimiter, rpcRequestSemaphore *semaphore.Weighted, redisURL string) *reloader {
+	return &reloader{
+		lim:                 lim,
+		rpcRequestSemaphore: rpcRequestSemaphore,
+		redisURL:            redisURL,
+		backends:            make(map[string]*Backend),
+	}
+}
+
+// reload applies a new config. On error, the current config stays in place.
+func (rl *reloader) reload(config *Config) error {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	if err := rl.apply(config); err != nil {
+		log.Error("error reloading config, keeping current config", "err", err)
+		configReloadsTotal.WithLabelValues(ConfigReloadError).Inc()
+		return err
+	}
+	configReloadsTotal.WithLabelValues(ConfigReloadSuccess).Inc()
+	return nil
+}
+
+func (rl *reloader) apply(config *Config) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	for _, section := range rl.restartRequired(config) {
+		log.Warn("config section changed but requires a restart", "section", section)
+	}
+
+	routing, backends, err := rl.build(config)
+	if err != nil {
+		return err
+	}
+	if err := rl.srv.SetRouting(routing); err != nil {
+		return err
+	}
+
+	var added, changed, removed []string
+	for name, be := range backends {
+		prev, ok := rl.backends[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case prev != be:
+			changed = append(changed, name)
+		}
+	}
+	for name := range rl.backends {
+		if backends[name] == nil {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	log.Info(
+		"reloaded config",
+		"added_backends", added,
+		"changed_backends", changed,
+		"removed_backends", removed,
+		"backend_groups", len(routing.BackendGroups),
+		"method_mappings", len(routing.RPCMethodMappings),
+	)
+
+	rl.commit(config, routing, backends)
+	return nil
+}
+
+// restartRequired returns the sections of the config that changed but are
+// only read at startup
+func (rl *reloader) restartRequired(config *Config) []string {
+	var sections []string
+	if !reflect.DeepEqual(rl.config.Server, config.Server) {
+		sections = append(sections, "server")
+	}
+	if !reflect.DeepEqual(rl.config.Cache, config.Cache) {
+		sections = append(sections, "cache")
+	}
+	if !reflect.DeepEqual(rl.config.Redis, config.Redis) {
+		sections = append(sections, "redis")
+	}
+	if !reflect.DeepEqual(rl.config.Metrics, config.Metrics) {
+		sections = append(sections, "metrics")
+	}
+	return sections
+}
+
+// commit makes a routing that is in use by the server the current one. It
+// starts the consensus pollers of the new backend groups and stops the ones
+// of the groups that were replaced.
+func (rl *reloader) commit(config *Config, routing *Routing, backends map[string]*Backend) {
+	for name, bg := range routing.BackendGroups {
+		if bg.Consensus == nil {
+			continue
+		}
+		if rl.routing == nil || rl.routing.BackendGroups[name] != bg {
+			bg.Consensus.Start()
+		}
+	}
+	if rl.routing != nil {
+		for name, bg := range rl.routing.BackendGroups {
+			if bg.Consensus != nil && routing.BackendGroups[name] != bg {
+				bg.Consensus.Stop()
+			}
+		}
+	}
+
+	rl.config = config
+	rl.backends = backends
+	rl.routing = routing
+}
+
+// stop stops the consensus pollers and returns the names of the backends
+func (rl *reloader) stop() []string {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	for _, bg := range rl.routing.BackendGroups {
+		if bg.Consensus != nil {
+			bg.Consensus.Stop()
+		}
+	}
+	names := make([]string, 0, len(rl.backends))
+	for name := range rl.backends {
+		names = append(names, name)
+	}
+	return names
+}
+
+// build creates the routing of a config, reusing the current backends and
+// backend groups when their config did not change
+func (rl *reloader) build(config *Config) (*Routing, map[string]*Backend, error) {
+	backends := make(map[string]*Backend)
+	for name, cfg := range config.Backends {
+		if prev := rl.backends[name]; prev != nil &&
+			reflect.DeepEqual(rl.config.Backends[name], cfg) &&
+			reflect.DeepEqual(rl.config.BackendOptions, config.BackendOptions) {
+			backends[name] = prev
+			continue
+		}
+		back, err := rl.buildBackend(config, name, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		backends[name] = back
+	}
+
+	backendGroups := make(map[string]*BackendGroup)
+	for bgName, bg := range config.BackendGroups {
+		groupBackends := make([]*Backend, 0)
+		for _, bName := range bg.Backends {
+			if backends[bName] == nil {
+				return nil, nil, fmt.Errorf("backend %s is not defined", bName)
+			}
+			groupBackends = append(groupBackends, backends[bName])
+		}
+
+		if rl.routing != nil {
+			prev := rl.routing.BackendGroups[bgName]
+			if prev != nil &&
+				reflect.DeepEqual(rl.config.BackendGroups[bgName], bg) &&
+				reflect.DeepEqual(prev.Backends, groupBackends) {
+				backendGroups[bgName] = prev
+				continue
+			}
+		}
+
+		selector, err := NewBackendSelector(bg.Strategy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("backend group %s: %w", bgName, err)
+		}
+		group := &BackendGroup{
+			Name:     bgName,
+			Backends: groupBackends,
+			Selector: selector,
+		}
+		if bg.ConsensusAware {
+			var copts []ConsensusOpt
+			if bg.ConsensusPollIntervalSeconds != 0 {
+				copts = append(copts, WithConsensusPollInterval(secondsToDuration(bg.ConsensusPollIntervalSeconds)))
+			}
+			if bg.ConsensusMaxBlockLag != 0 {
+				copts = append(copts, WithConsensusMaxBlockLag(bg.ConsensusMaxBlockLag))
+			}
+			if bg.ConsensusBanPeriodSeconds != 0 {
+				copts = append(copts, WithConsensusBanPeriod(secondsToDuration(bg.ConsensusBanPeriodSeconds)))
+			}
+			group.Consensus = NewConsensusPoller(group, copts...)
+			log.Info("configured consensus aware backend group", "name", bgName)
+		}
+		backendGroups[bgName] = group
+	}
+
+	var wsBackendGroup *BackendGroup
+	if config.WSBackendGroup != "" {
+		wsBackendGroup = backendGroups[config.WSBackendGroup]
+		if wsBackendGroup == nil {
+			return nil, nil, fmt.Errorf("ws backend group %s does not exist", config.WSBackendGroup)
+		}
+	}
+
+	if wsBackendGroup == nil && config.Server.WSPort != 0 {
+		return nil, nil, fmt.Errorf("a ws port was defined, but no ws group was defined")
+	}
+
+	// Keep the subscriptions of the hub while its group is unchanged
+	var wsHub *SubscriptionHub
+	if config.WSSharedSubscriptions && wsBackendGroup != nil {
+		if rl.routing != nil && rl.routing.WSHub != nil && rl.routing.WSHub.group == wsBackendGroup {
+			wsHub = rl.routing.WSHub
+		} else {
+			wsHub = NewSubscriptionHub(wsBackendGroup)
+		}
+	}
+
+	for _, bg := range config.RPCMethodMappings {
+		if backendGroups[bg] == nil {
+			return nil, nil, fmt.Errorf("undefined backend group %s", bg)
+		}
+	}
+
+	var resolvedAuth map[string]string
+
+	if config.Authentication != nil {
+		resolvedAuth = make(map[string]string)
+		for secret, alias := range config.Authentication {
+			resolvedSecret, err := ReadFromEnvOrConfig(secret)
+			if err != nil {
+				return nil, nil, err
+			}
+			resolvedAuth[resolvedSecret] = alias
+		}
+	}
+
+	limitsEnabled := len(config.RateLimit.KeyLimits) > 0 || len(config.RateLimit.MethodLimits) > 0
+	if (limitsEnabled || config.TxAdmission.Enabled) && rl.counter == nil {
+		if rl.redisURL == "" {
+			log.Warn("redis is not configured, using local usage counters")
+			rl.counter = NewLocalUsageCounter()
+		} else {
+			counter, err := NewRedisUsageCounter(rl.redisURL)
+			if err != nil {
+				return nil, nil, err
+			}
+			rl.counter = counter
+		}
+	}
+
+	var reqLim *RequestLimiter
+	if limitsEnabled {
+		for name, limit := range config.RateLimit.KeyLimits {
+			if limit.RatePerSecond < 0 || limit.DailyQuota < 0 {
+				return nil, nil, fmt.Errorf("limits of key %s cannot be negative", name)
+			}
+		}
+		for method, limit := range config.RateLimit.MethodLimits {
+			if limit.RatePerSecond < 0 || limit.DailyQuota < 0 {
+				return nil, nil, fmt.Errorf("limits of method %s cannot be negative", method)
+			}
+		}
+		reqLim = NewRequestLimiter(rl.counter, config.RateLimit.KeyLimits, config.RateLimit.MethodLimits)
+	}
+
+	var txAdmission *TxAdmission
+	if config.TxAdmission.Enabled {
+		if config.TxAdmission.ChainID == 0 {
+			return nil, nil, errors.New("must define a chain ID for transaction admission")
+		}
+		if config.TxAdmission.MaxCallDataSize < 0 {
+			return nil, nil, errors.New("max calldata size cannot be negative")
+		}
+		if limit := config.TxAdmission.SenderLimits; limit != nil && (limit.RatePerSecond < 0 || limit.DailyQuota < 0) {
+			return nil, nil, errors.New("sender limits cannot be negative")
+		}
+		txAdmission = NewTxAdmission(config.TxAdmission, rl.counter)
+	}
+
+	var getLogs *GetLogsGuard
+	if config.GetLogs.Enabled {
+		if config.GetLogs.MaxAddresses < 0 || config.GetLogs.MaxTopics < 0 || config.GetLogs.MaxParallelChunks < 0 {
+			return nil, nil, errors.New("eth_getLogs limits cannot be negative")
+		}
+		for name, limit := range config.GetLogs.KeyLimits {
+			if limit.MaxAddresses < 0 || limit.MaxTopics < 0 {
+				return nil, nil, fmt.Errorf("eth_getLogs limits of key %s cannot be negative", name)
+			}
+		}
+		getLogs = NewGetLogsGuard(config.GetLogs)
+	}
+
+	var readYourWrites *ReadYourWrites
+	if config.ReadYourWrites.Enabled {
+		writeGroup := config.ReadYourWrites.WriteGroup
+		if writeGroup == "" {
+			writeGroup = config.RPCMethodMappings["eth_sendRawTransaction"]
+		}
+		if backendGroups[writeGroup] == nil {
+			return nil, nil, fmt.Errorf("read your writes group %q does not exist", writeGroup)
+		}
+		if config.ReadYourWrites.WindowSeconds < 0 || config.ReadYourWrites.ReplicaTimeoutSeconds < 0 {
+			return nil, nil, errors.New("read your writes window and timeout cannot be negative")
+		}
+		if rl.writes == nil {
+			if rl.redisURL == "" {
+				log.Warn("redis is not configured, remembering recent writes locally")
+				rl.writes = NewLocalRecentWrites()
+			} else {
+				writes, err := NewRedisRecentWrites(rl.redisURL)
+				if err != nil {
+					return nil, nil, err
+				}
+				rl.writes = writes
+			}
+		}
+		readYourWrites = NewReadYourWrites(config.ReadYourWrites, writeGroup, rl.writes)
+	}
+
+	return &Routing{
+		BackendGroups:      backendGroups,
+		WSBackendGroup:     wsBackendGroup,
+		WSMethodWhitelist:  NewStringSetFromStrings(config.WSMethodWhitelist),
+		WSHub:              wsHub,
+		RPCMethodMappings:  config.RPCMethodMappings,
+		AuthenticatedPaths: resolvedAuth,
+		RateLimitConfig:    config.RateLimit,
+		RequestLimiter:     reqLim,
+		TxAdmission:        txAdmission,
+		ReadYourWrites:     readYourWrites,
+		GetLogs:            getLogs,
+	}, backends, nil
+}
+
+func (rl *reloader) buildBackend(config *Config, name string, cfg *BackendConfig) (*Backend, error) {
+	opts := make([]BackendOpt, 0)
+
+	rpcURL, err := ReadFromEnvOrConfig(cfg.RPCURL)
+	if err != nil {
+		return nil, err
+	}
+	wsURL, err := ReadFromEnvOrConfig(cfg.WSURL)
+	if err != nil {
+		return nil, err
+	}
+	if rpcURL == "" {
+		return nil, fmt.Errorf("must define an RPC URL for backend %s", name)
+	}
+	if wsURL == "" {
+		return nil, fmt.Errorf("must define a WS URL for backend %s", name)
+	}
+
+	if config.BackendOptions.ResponseTimeoutSeconds != 0 {
+		timeout := secondsToDuration(config.BackendOptions.ResponseTimeoutSeconds)
+		opts = append(opts, WithTimeout(timeout))
+	}
+	if config.BackendOptions.MaxRetries != 0 {
+		opts = append(opts, WithMaxRetries(config.BackendOptions.MaxRetries))
+	}
+	if config.BackendOptions.MaxResponseSizeBytes != 0 {
+		opts = append(opts, WithMaxResponseSize(config.BackendOptions.MaxResponseSizeBytes))
+	}
+	if config.BackendOptions.OutOfServiceSeconds != 0 {
+		opts = append(opts, WithOutOfServiceDuration(secondsToDuration(config.BackendOptions.OutOfServiceSeconds)))
+	}
+	if cb := config.BackendOptions.CircuitBreaker; cb.Enabled {
+		if cb.WindowSeconds < 0 || cb.MinRequests < 0 || cb.LatencyThresholdMs < 0 ||
+			cb.OpenSeconds < 0 || cb.HalfOpenProbes < 0 {
+			return nil, errors.New("circuit breaker settings cannot be negative")
+		}
+		if cb.ErrorRateThreshold < 0 || cb.ErrorRateThreshold > 1 ||
+			cb.SlowRateThreshold < 0 || cb.SlowRateThreshold > 1 {
+			return nil, errors.New("circuit breaker thresholds must be between 0 and 1")
+		}
+		opts = append(opts, WithCircuitBreaker(cb))
+	}
+	if cfg.MaxRPS != 0 {
+		opts = append(opts, WithMaxRPS(cfg.MaxRPS))
+	}
+	if cfg.MaxWSConns != 0 {
+		opts = append(opts, WithMaxWSConns(cfg.MaxWSConns))
+	}
+	if cfg.Password != "" {
+		passwordVal, err := ReadFromEnvOrConfig(cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithBasicAuth(cfg.Username, passwordVal))
+	}
+	tlsConfig, err := configureBackendTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		log.Info("using custom TLS config for backend", "name", name)
+		opts = append(opts, WithTLSConfig(tlsConfig))
+	}
+	if cfg.StripTrailingXFF {
+		opts = append(opts, WithStrippedTrailingXFF())
+	}
+	if cfg.Weight < 0 {
+		return nil, fmt.Errorf("weight of backend %s cannot be negative", name)
+	}
+	if cfg.Weight != 0 {
+		opts = append(opts, WithWeight(cfg.Weight))
+	}
+	opts = append(opts, WithProxydIP(os.Getenv("PROXYD_IP")))
+	back := NewBackend(name, rpcURL, wsURL, rl.lim, rl.rpcRequestSemaphore, opts...)
+	log.Info("configured backend", "name", name, "rpc_url", rpcURL, "ws_url", wsURL)
+	return back, nil
+}
+
+// ReloadConfigFile reads the config at path and applies it with reload
+func ReloadConfigFile(path string, reload func(*Config) error) error {
+	config := new(Config)
+	if _, err := toml.DecodeFile(path, config); err != nil {
+		log.Error("error reading config file, keeping current config", "err", err)
+		configReloadsTotal.WithLabelValues(ConfigReloadError).Inc()
+		return err
+	}
+	return reload(config)
+}
+
+// WatchConfigFile calls onChange when the content of the file at path
+// changes. It checks the file every interval until stop is closed.
+func WatchConfigFile(path string, interval time.Duration, stop <-chan struct{}, onChange func()) {
+	hashFile := func() [sha256.Size]byte {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Warn("error reading watched config file", "path", path, "err", err)
+			return [sha256.Size]byte{}
+		}
+		return sha256.Sum256(data)
+	}
+
+	last := hashFile()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hash := hashFile()
+			if hash == last || hash == ([sha256.Size]byte{}) {
+				continue
+			}
+			last = hash
+			log.Info("config file changed, reloading", "path", path)
+			onChange()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package proxyd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func reloadTestConfig() *Config {
+	return &Config{
+		Backends: BackendsConfig{
+			"first":  {RPCURL: "http://first", WSURL: "ws://first"},
+			"second": {RPCURL: "http://second", WSURL: "ws://second"},
+		},
+		BackendGroups: BackendGroupsConfig{
+			"main": {Backends: []string{"first"}},
+			"alt":  {Backends: []string{"second"}},
+		},
+		RPCMethodMappings: map[string]string{
+			"eth_chainId": "main",
+		},
+	}
+}
+
+func TestReloaderReusesUnchangedBackends(t *testing.T) {
+	rl := newReloader(NewLocalBackendRateLimiter(), semaphore.NewWeighted(1), "")
+	config := reloadTestConfig()
+	routing, backends, err := rl.build(config)
+	require.NoError(t, err)
+	rl.commit(config, routing, backends)
+
+	newConfig := reloadTestConfig()
+	newConfig.Backends["second"].RPCURL = "http://other"
+	newConfig.BackendGroups["alt"].Strategy = StrategyLeastOutstanding
+	newRouting, newBackends, err := rl.build(newConfig)
+	require.NoError(t, err)
+
+	require.Same(t, backends["first"], newBackends["first"])
+	require.NotSame(t, backends["second"], newBackends["second"])
+	require.Same(t, routing.BackendGroups["main"], newRouting.BackendGroups["main"])
+	require.NotSame(t, routing.BackendGroups["alt"], newRouting.BackendGroups["alt"])
+
+	// Changing the backend options rebuilds every backend
+	newConfig = reloadTestConfig()
+	newConfig.BackendOptions.MaxRetries = 5
+	newRouting, newBackends, err = rl.build(newConfig)
+	require.NoError(t, err)
+	require.NotSame(t, backends["first"], newBackends["first"])
+	require.NotSame(t, routing.BackendGroups["main"], newRouting.BackendGroups["main"])
+}
+
+func TestReloaderRejectsInvalidConfig(t *testing.T) {
+	rl := newReloader(NewLocalBackendRateLimiter(), semaphore.NewWeighted(1), "")
+	config := reloadTestConfig()
+	routing, backends, err := rl.build(config)
+	require.NoError(t, err)
+	rl.commit(config, routing, backends)
+
+	newConfig := reloadTestConfig()
+	newConfig.BackendGroups["main"].Backends = []string{"missing"}
+	require.Error(t, rl.reload(newConfig))
+	require.Same(t, config, rl.config)
+	require.Same(t, routing, rl.routing)
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxyd.toml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("a = 1"), 0600))
+
+	changes := make(chan struct{}, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		WatchConfigFile(path, 10*time.Millisecond, stop, func() {
+			changes <- struct{}{}
+		})
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, changes)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("a = 2"), 0600))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("config change not detected")
+	}
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,221 @@
+package proxyd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultReplayTimeout = 10 * time.Second
+
+type ReplayConfig struct {
+	// Target is the URL the captured requests are sent to
+	Target string
+	// Speed scales the timing of the capture. 1 replays at the original
+	// timing, 2 twice as fast. 0 sends the requests one after the other as
+	// fast as possible.
+	Speed   float64
+	Timeout time.Duration
+}
+
+// ReplayResult is the outcome of one replayed request
+type ReplayResult struct {
+	Record     *CaptureRecord
+	StatusCode int
+	Response   json.RawMessage
+	Latency    time.Duration
+	Err        error
+	// Mismatch describes how the response differs from the captured one,
+	// it is empty when they match
+	Mismatch string
+}
+
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+type ReplaySummary struct {
+	Total      int
+	Matched    int
+	Mismatched int
+	Failed     int
+	// MismatchesByMethod counts the mismatches and failures of each method
+	MismatchesByMethod map[string]int
+	CapturedLatency    LatencyPercentiles
+	ReplayedLatency    LatencyPercentiles
+	Results            []*ReplayResult
+}
+
+// Replay sends the captured requests to the target and compares the
+// responses with the captured ones
+func Replay(ctx context.Context, config ReplayConfig, records []*CaptureRecord) *ReplaySummary {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultReplayTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	results := make([]*ReplayResult, len(records))
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	if config.Speed <= 0 {
+		for i, record := range records {
+			if ctx.Err() != nil {
+				results[i] = &ReplayResult{Record: record, Err: ctx.Err()}
+				continue
+			}
+			results[i] = replayRecord(ctx, client, config.Target, record)
+		}
+		return summarizeReplay(results)
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, record := range records {
+		offset := time.Duration(float64(record.Time.Sub(records[0].Time)) / config.Speed)
+		sleepContext(ctx, time.Until(start.Add(offset)))
+		if ctx.Err() != nil {
+			results[i] = &ReplayResult{Record: record, Err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, record *CaptureRecord) {
+			defer wg.Done()
+			results[i] = replayRecord(ctx, client, config.Target, record)
+		}(i, record)
+	}
+	wg.Wait()
+	return summarizeReplay(results)
+}
+
+func replayRecord(ctx context.Context, client *http.Client, target string, record *CaptureRecord) *ReplayResult {
+	result := &ReplayResult{Record: record}
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(record.Request))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("content-type", "application/json")
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.StatusCode = res.StatusCode
+	result.Response = bytes.TrimSpace(body)
+	result.Mismatch = diffResponses(record, result)
+	return result
+}
+
+// diffResponses compares the replayed response with the captured one,
+// ignoring the formatting of the JSON
+func diffResponses(record *CaptureRecord, result *ReplayResult) string {
+	if record.StatusCode != result.StatusCode {
+		return fmt.Sprintf("status code %d, captured %d", result.StatusCode, record.StatusCode)
+	}
+	var captured, replayed interface{}
+	if err := json.Unmarshal(record.Response, &captured); err != nil {
+		return "captured response is not JSON"
+	}
+	if err := json.Unmarshal(result.Response, &replayed); err != nil {
+		return "response is not JSON"
+	}
+	if !reflect.DeepEqual(captured, replayed) {
+		return fmt.Sprintf("response %s, captured %s", truncate(string(result.Response), 200), truncate(string(record.Response), 200))
+	}
+	return ""
+}
+
+func summarizeReplay(results []*ReplayResult) *ReplaySummary {
+	summary := &ReplaySummary{
+		Total:              len(results),
+		MismatchesByMethod: make(map[string]int),
+		Results:            results,
+	}
+	var captured, replayed []time.Duration
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			summary.Failed++
+			summary.MismatchesByMethod[result.Record.Method()]++
+			continue
+		case result.Mismatch != "":
+			summary.Mismatched++
+			summary.MismatchesByMethod[result.Record.Method()]++
+		default:
+			summary.Matched++
+		}
+		captured = append(captured, time.Duration(result.Record.LatencyMs*float64(time.Millisecond)))
+		replayed = append(replayed, result.Latency)
+	}
+	summary.CapturedLatency = latencyPercentiles(captured)
+	summary.ReplayedLatency = latencyPercentiles(replayed)
+	return summary
+}
+
+func latencyPercentiles(latencies []time.Duration) LatencyPercentiles {
+	if len(latencies) == 0 {
+		return LatencyPercentiles{}
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	return LatencyPercentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99)}
+}
+
+// Print writes the summary and up to maxMismatches mismatches to w
+func (s *ReplaySummary) Print(w io.Writer, maxMismatches int) {
+	fmt.Fprintf(w, "replayed %d requests: %d matched, %d mismatched, %d failed\n", s.Total, s.Matched, s.Mismatched, s.Failed)
+	fmt.Fprintf(w, "captured latency: p50=%s p90=%s p99=%s\n", s.CapturedLatency.P50, s.CapturedLatency.P90, s.CapturedLatency.P99)
+	fmt.Fprintf(w, "replayed latency: p50=%s p90=%s p99=%s\n", s.ReplayedLatency.P50, s.ReplayedLatency.P90, s.ReplayedLatency.P99)
+
+	methods := make([]string, 0, len(s.MismatchesByMethod))
+	for method := range s.MismatchesByMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		fmt.Fprintf(w, "mismatches of %s: %d\n", method, s.MismatchesByMethod[method])
+	}
+
+	printed := 0
+	for _, result := range s.Results {
+		if printed == maxMismatches {
+			break
+		}
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(w, "%s %s: %v\n", result.Record.Time.Format(time.RFC3339Nano), result.Record.Method(), result.Err)
+		case result.Mismatch != "":
+			fmt.Fprintf(w, "%s %s: %s\n", result.Record.Time.Format(time.RFC3339Nano), result.Record.Method(), result.Mismatch)
+		default:
+			continue
+		}
+		printed++
+	}
+}
@@ -0,0 +1,261 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	LimitScopeKeyRate     = "key_rate"
+	LimitScopeKeyDaily    = "key_daily"
+	LimitScopeMethodRate  = "method_rate"
+	LimitScopeMethodDaily = "method_daily"
+	LimitScopeSenderRate  = "sender_rate"
+	LimitScopeSenderDaily = "sender_daily"
+
+	// DefaultKeyLimits are the limits of authentication keys that do not
+	// have limits of their own
+	DefaultKeyLimits = "default"
+
+	day = 24 * time.Hour
+)
+
+const UsageCounterScript = `
+local current
+current = redis.call("incr", KEYS[1])
+if current == 1 then
+    redis.call("expire", KEYS[1], ARGV[1])
+end
+return current
+`
+
+// UsageCounter counts calls in fixed windows. Counters are created on first
+// use and expire with their window.
+type UsageCounter interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type RedisUsageCounter struct {
+	rdb *redis.Client
+}
+
+func NewRedisUsageCounter(url string) (*RedisUsageCounter, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opts)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, wrapErr(err, "error connecting to redis")
+	}
+	return &RedisUsageCounter{rdb: rdb}, nil
+}
+
+func (r *RedisUsageCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	count, err := r.rdb.Eval(ctx, UsageCounterScript, []string{key}, int(ttl/time.Second)).Int()
+	if err != nil {
+		RecordRedisError("IncrUsage")
+		return 0, wrapErr(err, "error incrementing usage counter")
+	}
+	return count, nil
+}
+
+func (r *RedisUsageCounter) Delete(ctx context.Context, key string) error {
+	if err := r.rdb.Del(ctx, key).Err(); err != nil {
+		RecordRedisError("DeleteUsage")
+		return wrapErr(err, "error deleting usage counter")
+	}
+	return nil
+}
+
+type localUsage struct {
+	count   int
+	expires time.Time
+}
+
+type LocalUsageCounter struct {
+	counts    map[string]*localUsage
+	lastSweep time.Time
+	mtx       sync.Mutex
+}
+
+func NewLocalUsageCounter() *LocalUsageCounter {
+	return &LocalUsageCounter{
+		counts:    make(map[string]*localUsage),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *LocalUsageCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, usage := range l.counts {
+			if now.After(usage.expires) {
+				delete(l.counts, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	usage := l.counts[key]
+	if usage == nil || now.After(usage.expires) {
+		usage = &localUsage{expires: now.Add(ttl)}
+		l.counts[key] = usage
+	}
+	usage.count++
+	return usage.count, nil
+}
+
+func (l *LocalUsageCounter) Delete(ctx context.Context, key string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	delete(l.counts, key)
+	return nil
+}
+
+// LimitStatus describes the most restrictive limit that applied to a call
+type LimitStatus struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// merge keeps the status with the fewest remaining calls
+func (s *LimitStatus) merge(other *LimitStatus) *LimitStatus {
+	if s == nil || (other != nil && other.Remaining < s.Remaining) {
+		return other
+	}
+	return s
+}
+
+// RequestLimiter enforces per second rates and daily quotas on calls, per
+// authentication key and per method. Key limits are keyed by the alias of
+// the key. Method limits apply to each caller separately, and a method
+// ending with `*` matches every method with that prefix.
+type RequestLimiter struct {
+	counter      UsageCounter
+	keyLimits    map[string]*LimitConfig
+	methodLimits map[string]*LimitConfig
+	prefixLimits map[string]*LimitConfig
+	now          func() time.Time
+}
+
+func NewRequestLimiter(counter UsageCounter, keyLimits, methodLimits map[string]*LimitConfig) *RequestLimiter {
+	l := &RequestLimiter{
+		counter:      counter,
+		keyLimits:    keyLimits,
+		methodLimits: make(map[string]*LimitConfig),
+		prefixLimits: make(map[string]*LimitConfig),
+		now:          time.Now,
+	}
+	for method, limit := range methodLimits {
+		if strings.HasSuffix(method, "*") {
+			l.prefixLimits[strings.TrimSuffix(method, "*")] = limit
+		} else {
+			l.methodLimits[method] = limit
+		}
+	}
+	return l
+}
+
+func (l *RequestLimiter) methodLimit(method string) (string, *LimitConfig) {
+	if limit := l.methodLimits[method]; limit != nil {
+		return method, limit
+	}
+	// The longest matching prefix wins
+	var match string
+	var limit *LimitConfig
+	for prefix, prefixLimit := range l.prefixLimits {
+		if strings.HasPrefix(method, prefix) && (limit == nil || len(prefix) > len(match)) {
+			match, limit = prefix, prefixLimit
+		}
+	}
+	return match + "*", limit
+}
+
+// Take counts a call of method by the key alias or, without a key, by the
+// caller IP. It returns the most restrictive status and the scope of the
+// limit that was exceeded, which is empty when the call is allowed.
+func (l *RequestLimiter) Take(ctx context.Context, auth, caller, method string) (*LimitStatus, string) {
+	var status *LimitStatus
+	if auth != "none" {
+		limit := l.keyLimits[auth]
+		if limit == nil {
+			limit = l.keyLimits[DefaultKeyLimits]
+		}
+		if limit != nil {
+			name := "key:" + auth
+			keyStatus, scope := l.take(ctx, name, limit, LimitScopeKeyRate, LimitScopeKeyDaily)
+			status = status.merge(keyStatus)
+			if scope != "" {
+				return status, scope
+			}
+		}
+		caller = auth
+	}
+
+	if match, limit := l.methodLimit(method); limit != nil {
+		name := fmt.Sprintf("method:%s:%s", match, caller)
+		methodStatus, scope := l.take(ctx, name, limit, LimitScopeMethodRate, LimitScopeMethodDaily)
+		status = status.merge(methodStatus)
+		if scope != "" {
+			return status, scope
+		}
+	}
+	return status, ""
+}
+
+func (l *RequestLimiter) take(ctx context.Context, name string, limit *LimitConfig, rateScope, dailyScope string) (*LimitStatus, string) {
+	now := l.now()
+	var status *LimitStatus
+	if limit.RatePerSecond > 0 {
+		reset := now.Truncate(time.Second).Add(time.Second)
+		key := fmt.Sprintf("usage:%s:s:%d", name, now.Unix())
+		rateStatus, ok := l.incr(ctx, key, time.Second, limit.RatePerSecond, reset)
+		status = status.merge(rateStatus)
+		if !ok {
+			return status, rateScope
+		}
+	}
+	if limit.DailyQuota > 0 {
+		// Quotas reset at midnight UTC
+		reset := now.UTC().Truncate(day).Add(day)
+		key := fmt.Sprintf("usage:%s:d:%d", name, now.Unix()/int64(day/time.Second))
+		dailyStatus, ok := l.incr(ctx, key, day, limit.DailyQuota, reset)
+		status = status.merge(dailyStatus)
+		if !ok {
+			return status, dailyScope
+		}
+	}
+	return status, ""
+}
+
+// incr counts a call against a limit. When the counter cannot be reached
+// the call is allowed, so that an outage of Redis does not take down the
+// proxy.
+func (l *RequestLimiter) incr(ctx context.Context, key string, ttl time.Duration, limit int, reset time.Time) (*LimitStatus, bool) {
+	count, err := l.counter.Incr(ctx, key, ttl)
+	if err != nil {
+		log.Error("error counting usage, allowing request", "key", key, "err", err)
+		return nil, true
+	}
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &LimitStatus{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}, count <= limit
+}
@@ -0,0 +1,133 @@
+package proxyd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimiterKeyLimits(t *testing.T) {
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	lim := NewRequestLimiter(NewLocalUsageCounter(), map[string]*LimitConfig{
+		"alice":          {RatePerSecond: 2, DailyQuota: 3},
+		DefaultKeyLimits: {RatePerSecond: 1},
+	}, nil)
+	lim.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	status, scope := lim.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	require.Equal(t, 1, status.Remaining)
+	require.Equal(t, 2, status.Limit)
+	require.Equal(t, now.Add(time.Second), status.Reset)
+
+	_, scope = lim.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	status, scope = lim.Take(ctx, "alice", "", "eth_chainId")
+	require.Equal(t, LimitScopeKeyRate, scope)
+	require.Equal(t, 0, status.Remaining)
+
+	// The rate resets in the next second, the quota does not
+	now = now.Add(time.Second)
+	status, scope = lim.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	require.Equal(t, 0, status.Remaining)
+	require.Equal(t, 3, status.Limit)
+	require.Equal(t, time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), status.Reset)
+	_, scope = lim.Take(ctx, "alice", "", "eth_chainId")
+	require.Equal(t, LimitScopeKeyDaily, scope)
+
+	// Other keys get the default limits
+	_, scope = lim.Take(ctx, "bob", "", "eth_chainId")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "bob", "", "eth_chainId")
+	require.Equal(t, LimitScopeKeyRate, scope)
+
+	// Unauthenticated callers are not subject to key limits
+	for i := 0; i < 3; i++ {
+		status, scope = lim.Take(ctx, "none", "1.2.3.4", "eth_chainId")
+		require.Empty(t, scope)
+		require.Nil(t, status)
+	}
+}
+
+func TestRequestLimiterMethodLimits(t *testing.T) {
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	lim := NewRequestLimiter(NewLocalUsageCounter(), nil, map[string]*LimitConfig{
+		"eth_getLogs":   {RatePerSecond: 1},
+		"debug_*":       {DailyQuota: 1},
+		"debug_trace*":  {DailyQuota: 2},
+		"eth_getBlock*": {RatePerSecond: 5},
+	})
+	lim.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, scope := lim.Take(ctx, "alice", "", "eth_getLogs")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "alice", "", "eth_getLogs")
+	require.Equal(t, LimitScopeMethodRate, scope)
+	// Method limits apply to each caller separately
+	_, scope = lim.Take(ctx, "bob", "", "eth_getLogs")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "none", "1.2.3.4", "eth_getLogs")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "none", "1.2.3.4", "eth_getLogs")
+	require.Equal(t, LimitScopeMethodRate, scope)
+
+	// The longest prefix wins, and methods matching a prefix share a counter
+	_, scope = lim.Take(ctx, "alice", "", "debug_traceTransaction")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "alice", "", "debug_traceCall")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "alice", "", "debug_traceCall")
+	require.Equal(t, LimitScopeMethodDaily, scope)
+	_, scope = lim.Take(ctx, "alice", "", "debug_getRawBlock")
+	require.Empty(t, scope)
+	_, scope = lim.Take(ctx, "alice", "", "debug_getRawHeader")
+	require.Equal(t, LimitScopeMethodDaily, scope)
+
+	// Methods without limits are not counted
+	for i := 0; i < 3; i++ {
+		status, scope := lim.Take(ctx, "alice", "", "eth_chainId")
+		require.Empty(t, scope)
+		require.Nil(t, status)
+	}
+}
+
+func TestRequestLimiterSharesRedisCounters(t *testing.T) {
+	redis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer redis.Close()
+
+	newLimiter := func() *RequestLimiter {
+		counter, err := NewRedisUsageCounter(fmt.Sprintf("redis://127.0.0.1:%s", redis.Port()))
+		require.NoError(t, err)
+		return NewRequestLimiter(counter, map[string]*LimitConfig{
+			"alice": {DailyQuota: 2},
+		}, nil)
+	}
+	first := newLimiter()
+	second := newLimiter()
+	ctx := context.Background()
+
+	_, scope := first.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	_, scope = second.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	_, scope = first.Take(ctx, "alice", "", "eth_chainId")
+	require.Equal(t, LimitScopeKeyDaily, scope)
+
+	keys := redis.Keys()
+	require.Len(t, keys, 1)
+	require.Equal(t, 24*time.Hour, redis.TTL(keys[0]))
+
+	// Calls are allowed when Redis is down
+	redis.Close()
+	status, scope := second.Take(ctx, "alice", "", "eth_chainId")
+	require.Empty(t, scope)
+	require.Nil(t, status)
+}
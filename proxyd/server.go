@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sethvargo/go-limiter"
@@ -31,6 +32,9 @@ const (
 	ContextKeyXForwardedFor     = "x_forwarded_for"
 	MaxBatchRPCCalls            = 100
 	cacheStatusHdr              = "X-Proxyd-Cache-Status"
+	rateLimitLimitHdr           = "X-RateLimit-Limit"
+	rateLimitRemainingHdr       = "X-RateLimit-Remaining"
+	rateLimitResetHdr           = "X-RateLimit-Reset"
 	defaultServerTimeout        = time.Second * 10
 	maxRequestBodyLogLen        = 2000
 	defaultMaxUpstreamBatchSize = 10
@@ -38,39 +42,49 @@ const (
 
 var emptyArrayResponse = json.RawMessage("[]")
 
+// Routing holds the settings of the server that can be reloaded while it
+// runs. Each request is served with the routing that was current when it
+// arrived.
+type Routing struct {
+	BackendGroups     map[string]*BackendGroup
+	WSBackendGroup    *BackendGroup
+	WSMethodWhitelist *StringSet
+	// WSHub shares subscriptions between WS clients when it is set
+	WSHub              *SubscriptionHub
+	RPCMethodMappings  map[string]string
+	AuthenticatedPaths map[string]string
+	RateLimitConfig    RateLimitConfig
+	RequestLimiter     *RequestLimiter
+	TxAdmission        *TxAdmission
+	ReadYourWrites     *ReadYourWrites
+	GetLogs            *GetLogsGuard
+
+	lim                 limiter.Store
+	limExemptOrigins    map[string]bool
+	limExemptUserAgents map[string]bool
+}
+
 type Server struct {
-	backendGroups        map[string]*BackendGroup
-	wsBackendGroup       *BackendGroup
-	wsMethodWhitelist    *StringSet
-	rpcMethodMappings    map[string]string
+	routing              atomic.Value
 	maxBodySize          int64
 	enableRequestLog     bool
 	maxRequestBodyLogLen int
-	authenticatedPaths   map[string]string
 	timeout              time.Duration
 	maxUpstreamBatchSize int
 	upgrader             *websocket.Upgrader
-	lim                  limiter.Store
-	limConfig            RateLimitConfig
-	limExemptOrigins     map[string]bool
-	limExemptUserAgents  map[string]bool
 	rpcServer            *http.Server
 	wsServer             *http.Server
 	cache                RPCCache
+	capture              *TrafficCapture
 	srvMu                sync.Mutex
 }
 
 func NewServer(
-	backendGroups map[string]*BackendGroup,
-	wsBackendGroup *BackendGroup,
-	wsMethodWhitelist *StringSet,
-	rpcMethodMappings map[string]string,
+	routing *Routing,
 	maxBodySize int64,
-	authenticatedPaths map[string]string,
 	timeout time.Duration,
 	maxUpstreamBatchSize int,
 	cache RPCCache,
-	rateLimitConfig RateLimitConfig,
 	enableRequestLog bool,
 	maxRequestBodyLogLen int,
 ) (*Server, error) {
@@ -90,49 +104,73 @@ func NewServer(
 		maxUpstreamBatchSize = defaultMaxUpstreamBatchSize
 	}
 
-	var lim limiter.Store
+	s := &Server{
+		maxBodySize:          maxBodySize,
+		timeout:              timeout,
+		maxUpstreamBatchSize: maxUpstreamBatchSize,
+		cache:                cache,
+		enableRequestLog:     enableRequestLog,
+		maxRequestBodyLogLen: maxRequestBodyLogLen,
+		upgrader: &websocket.Upgrader{
+			HandshakeTimeout: 5 * time.Second,
+		},
+	}
+	if err := s.SetRouting(routing); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetRouting swaps the routing of the server. Requests in flight carry on
+// with the previous routing. The per IP rate limiter keeps its state when
+// its rate does not change.
+func (s *Server) SetRouting(routing *Routing) error {
+	prev := s.currentRouting()
 	limExemptOrigins := make(map[string]bool)
 	limExemptUserAgents := make(map[string]bool)
-	if rateLimitConfig.RatePerSecond > 0 {
-		var err error
-		lim, err = memorystore.New(&memorystore.Config{
+	rateLimitConfig := routing.RateLimitConfig
+	switch {
+	case rateLimitConfig.RatePerSecond <= 0:
+		routing.lim, _ = noopstore.New()
+	case prev != nil && prev.RateLimitConfig.RatePerSecond == rateLimitConfig.RatePerSecond:
+		routing.lim = prev.lim
+	default:
+		lim, err := memorystore.New(&memorystore.Config{
 			Tokens:   uint64(rateLimitConfig.RatePerSecond),
 			Interval: time.Second,
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
-
+		routing.lim = lim
+	}
+	if rateLimitConfig.RatePerSecond > 0 {
 		for _, origin := range rateLimitConfig.ExemptOrigins {
 			limExemptOrigins[strings.ToLower(origin)] = true
 		}
 		for _, agent := range rateLimitConfig.ExemptUserAgents {
 			limExemptUserAgents[strings.ToLower(agent)] = true
 		}
-	} else {
-		lim, _ = noopstore.New()
 	}
+	routing.limExemptOrigins = limExemptOrigins
+	routing.limExemptUserAgents = limExemptUserAgents
 
-	return &Server{
-		backendGroups:        backendGroups,
-		wsBackendGroup:       wsBackendGroup,
-		wsMethodWhitelist:    wsMethodWhitelist,
-		rpcMethodMappings:    rpcMethodMappings,
-		maxBodySize:          maxBodySize,
-		authenticatedPaths:   authenticatedPaths,
-		timeout:              timeout,
-		maxUpstreamBatchSize: maxUpstreamBatchSize,
-		cache:                cache,
-		enableRequestLog:     enableRequestLog,
-		maxRequestBodyLogLen: maxRequestBodyLogLen,
-		upgrader: &websocket.Upgrader{
-			HandshakeTimeout: 5 * time.Second,
-		},
-		lim:                 lim,
-		limConfig:           rateLimitConfig,
-		limExemptOrigins:    limExemptOrigins,
-		limExemptUserAgents: limExemptUserAgents,
-	}, nil
+	s.routing.Store(routing)
+
+	// Stop the previous limiter once the requests that use it are done
+	if prev != nil && prev.lim != routing.lim {
+		time.AfterFunc(s.timeout, func() {
+			if err := prev.lim.Close(context.Background()); err != nil {
+				log.Warn("error closing rate limiter", "err", err)
+			}
+		})
+	}
+	return nil
+}
+
+func (s *Server) currentRouting() *Routing {
+	routing, _ := s.routing.Load().(*Routing)
+	return routing
 }
 
 func (s *Server) RPCListenAndServe(host string, port int) error {
@@ -188,7 +226,9 @@ func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
-	ctx := s.populateContext(w, r)
+	start := time.Now()
+	rt := s.currentRouting()
+	ctx := s.populateContext(w, r, rt)
 	if ctx == nil {
 		return
 	}
@@ -196,24 +236,24 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel = context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	exemptOrigin := s.limExemptOrigins[strings.ToLower(r.Header.Get("Origin"))]
-	exemptUserAgent := s.limExemptUserAgents[strings.ToLower(r.Header.Get("User-Agent"))]
+	exemptOrigin := rt.limExemptOrigins[strings.ToLower(r.Header.Get("Origin"))]
+	exemptUserAgent := rt.limExemptUserAgents[strings.ToLower(r.Header.Get("User-Agent"))]
+	// Use XFF in context since it will automatically be replaced by the remote IP
+	xff := stripXFF(GetXForwardedFor(ctx))
 	var ok bool
 	if exemptOrigin || exemptUserAgent {
 		ok = true
 	} else {
-		// Use XFF in context since it will automatically be replaced by the remote IP
-		xff := stripXFF(GetXForwardedFor(ctx))
 		if xff == "" {
 			log.Warn("rejecting request without XFF or remote IP")
 			ok = false
 		} else {
-			_, _, _, ok, _ = s.lim.Take(ctx, xff)
+			_, _, _, ok, _ = rt.lim.Take(ctx, xff)
 		}
 	}
 	if !ok {
 		rpcErr := ErrOverRateLimit.Clone()
-		rpcErr.Message = s.limConfig.ErrorMessage
+		rpcErr.Message = rt.RateLimitConfig.ErrorMessage
 		writeRPCError(ctx, w, nil, rpcErr)
 		return
 	}
@@ -233,6 +273,16 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 	}
 	RecordRequestPayloadSize(ctx, len(body))
 
+	if s.capture != nil && s.capture.Sample() {
+		cw := newCaptureResponseWriter(w)
+		w = cw
+		defer func() {
+			if record := cw.record(start, body); record != nil {
+				s.capture.Record(record)
+			}
+		}()
+	}
+
 	if s.enableRequestLog {
 		log.Info("Raw RPC request",
 			"body", truncate(string(body), s.maxRequestBodyLogLen),
@@ -241,6 +291,16 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 		)
 	}
 
+	var limits *callLimits
+	if rt.RequestLimiter != nil && !exemptOrigin && !exemptUserAgent {
+		limits = &callLimits{
+			lim:          rt.RequestLimiter,
+			auth:         GetAuthCtx(ctx),
+			caller:       xff,
+			errorMessage: rt.RateLimitConfig.ErrorMessage,
+		}
+	}
+
 	if IsBatch(body) {
 		reqs, err := ParseBatchRPCReq(body)
 		if err != nil {
@@ -261,7 +321,7 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		batchRes, batchContainsCached, err := s.handleBatchRPC(ctx, reqs, true)
+		batchRes, batchContainsCached, err := s.handleBatchRPC(ctx, rt, reqs, true, limits)
 		if err == context.DeadlineExceeded {
 			writeRPCError(ctx, w, nil, ErrGatewayTimeout)
 			return
@@ -272,21 +332,23 @@ func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
 		}
 
 		setCacheHeader(w, batchContainsCached)
+		limits.setHeaders(w)
 		writeBatchRPCRes(ctx, w, batchRes)
 		return
 	}
 
 	rawBody := json.RawMessage(body)
-	backendRes, cached, err := s.handleBatchRPC(ctx, []json.RawMessage{rawBody}, false)
+	backendRes, cached, err := s.handleBatchRPC(ctx, rt, []json.RawMessage{rawBody}, false, limits)
 	if err != nil {
 		writeRPCError(ctx, w, nil, ErrInternal)
 		return
 	}
 	setCacheHeader(w, cached)
+	limits.setHeaders(w)
 	writeRPCRes(ctx, w, backendRes[0])
 }
 
-func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isBatch bool) ([]*RPCRes, bool, error) {
+func (s *Server) handleBatchRPC(ctx context.Context, rt *Routing, reqs []json.RawMessage, isBatch bool, limits *callLimits) ([]*RPCRes, bool, error) {
 	// A request set is transformed into groups of batches.
 	// Each batch group maps to a forwarded JSON-RPC batch request (subject to maxUpstreamBatchSize constraints)
 	// A groupID is used to decouple Requests that have duplicate ID so they're not part of the same batch that's
@@ -302,6 +364,17 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 	batches := make(map[batchGroup][]batchElem)
 	ids := make(map[string]int, len(reqs))
 
+	// Admitted transactions are released when they are not forwarded
+	// successfully
+	var admittedTxs []batchElem
+	defer func() {
+		for _, elem := range admittedTxs {
+			if res := responses[elem.Index]; res == nil || res.IsError() {
+				rt.TxAdmission.Release(ctx, elem.Req)
+			}
+		}
+	}()
+
 	for i := range reqs {
 		parsedReq, err := ParseRPCReq(reqs[i])
 		if err != nil {
@@ -322,7 +395,7 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			continue
 		}
 
-		group := s.rpcMethodMappings[parsedReq.Method]
+		group := rt.RPCMethodMappings[parsedReq.Method]
 		if group == "" {
 			// use unknown below to prevent DOS vector that fills up memory
 			// with arbitrary method names.
@@ -337,6 +410,44 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			continue
 		}
 
+		if err := limits.take(ctx, parsedReq.Method); err != nil {
+			log.Info(
+				"rate limited RPC call",
+				"source", "rpc",
+				"req_id", GetReqID(ctx),
+				"auth", GetAuthCtx(ctx),
+				"method", parsedReq.Method,
+			)
+			RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+			responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+			continue
+		}
+
+		if parsedReq.Method == "eth_sendRawTransaction" && rt.TxAdmission != nil {
+			if err := rt.TxAdmission.Admit(ctx, parsedReq); err != nil {
+				RecordRPCError(ctx, BackendProxyd, parsedReq.Method, err)
+				responses[i] = NewRPCErrorRes(parsedReq.ID, err)
+				continue
+			}
+			admittedTxs = append(admittedTxs, batchElem{parsedReq, i})
+		}
+
+		if rt.ReadYourWrites != nil {
+			var res *RPCRes
+			group, res = rt.ReadYourWrites.Route(ctx, rt, parsedReq, group)
+			if res != nil {
+				responses[i] = res
+				continue
+			}
+		}
+
+		if parsedReq.Method == "eth_getLogs" && rt.GetLogs != nil {
+			if res := rt.GetLogs.Handle(ctx, rt.BackendGroups[group], s.cache, parsedReq); res != nil {
+				responses[i] = res
+				continue
+			}
+		}
+
 		id := string(parsedReq.ID)
 		// If this is a duplicate Request ID, move the Request to a new batchGroup
 		ids[id]++
@@ -375,7 +486,7 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			start := i * s.maxUpstreamBatchSize
 			end := int(math.Min(float64(start+s.maxUpstreamBatchSize), float64(len(cacheMisses))))
 			elems := cacheMisses[start:end]
-			res, err := s.backendGroups[group.backendGroup].Forward(ctx, createBatchRequest(elems), isBatch)
+			res, err := rt.BackendGroups[group.backendGroup].Forward(ctx, createBatchRequest(elems), isBatch)
 			if err != nil {
 				log.Error(
 					"error forwarding RPC batch",
@@ -392,6 +503,10 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 			for i := range elems {
 				responses[elems[i].Index] = res[i]
 
+				if rt.ReadYourWrites != nil {
+					rt.ReadYourWrites.Record(ctx, elems[i].Req, res[i])
+				}
+
 				// TODO(inphi): batch put these
 				if res[i].Error == nil && res[i].Result != nil {
 					if err := s.cache.PutRPC(ctx, elems[i].Req, res[i]); err != nil {
@@ -410,7 +525,8 @@ func (s *Server) handleBatchRPC(ctx context.Context, reqs []json.RawMessage, isB
 }
 
 func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
-	ctx := s.populateContext(w, r)
+	rt := s.currentRouting()
+	ctx := s.populateContext(w, r, rt)
 	if ctx == nil {
 		return
 	}
@@ -423,14 +539,22 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	proxier, err := s.wsBackendGroup.ProxyWS(ctx, clientConn, s.wsMethodWhitelist)
-	if err != nil {
-		if errors.Is(err, ErrNoBackends) {
-			RecordUnserviceableRequest(ctx, RPCRequestSourceWS)
+	var proxier interface {
+		Proxy(ctx context.Context) error
+	}
+	if rt.WSHub != nil {
+		proxier = rt.WSHub.NewSession(clientConn, rt.WSMethodWhitelist)
+	} else {
+		wsProxier, err := rt.WSBackendGroup.ProxyWS(ctx, clientConn, rt.WSMethodWhitelist)
+		if err != nil {
+			if errors.Is(err, ErrNoBackends) {
+				RecordUnserviceableRequest(ctx, RPCRequestSourceWS)
+			}
+			log.Error("error dialing ws backend", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx), "err", err)
+			clientConn.Close()
+			return
 		}
-		log.Error("error dialing ws backend", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx), "err", err)
-		clientConn.Close()
-		return
+		proxier = wsProxier
 	}
 
 	activeClientWsConnsGauge.WithLabelValues(GetAuthCtx(ctx)).Inc()
@@ -445,7 +569,7 @@ func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
 	log.Info("accepted WS connection", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx))
 }
 
-func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context.Context {
+func (s *Server) populateContext(w http.ResponseWriter, r *http.Request, rt *Routing) context.Context {
 	vars := mux.Vars(r)
 	authorization := vars["authorization"]
 	xff := r.Header.Get("X-Forwarded-For")
@@ -457,7 +581,7 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 	}
 	ctx := context.WithValue(r.Context(), ContextKeyXForwardedFor, xff) // nolint:staticcheck
 
-	if s.authenticatedPaths == nil {
+	if rt.AuthenticatedPaths == nil {
 		// handle the edge case where auth is disabled
 		// but someone sends in an auth key anyway
 		if authorization != "" {
@@ -467,14 +591,14 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 			return nil
 		}
 	} else {
-		if authorization == "" || s.authenticatedPaths[authorization] == "" {
+		if authorization == "" || rt.AuthenticatedPaths[authorization] == "" {
 			log.Info("blocked unauthorized request", "authorization", authorization)
 			httpResponseCodesTotal.WithLabelValues("401").Inc()
 			w.WriteHeader(401)
 			return nil
 		}
 
-		ctx = context.WithValue(r.Context(), ContextKeyAuth, s.authenticatedPaths[authorization]) // nolint:staticcheck
+		ctx = context.WithValue(ctx, ContextKeyAuth, rt.AuthenticatedPaths[authorization]) // nolint:staticcheck
 	}
 
 	return context.WithValue(
@@ -484,6 +608,44 @@ func (s *Server) populateContext(w http.ResponseWriter, r *http.Request) context
 	)
 }
 
+// callLimits counts the calls of a request against the request limiter and
+// keeps the most restrictive status for the rate limit headers. A nil
+// callLimits does not limit calls.
+type callLimits struct {
+	lim          *RequestLimiter
+	auth         string
+	caller       string
+	errorMessage string
+	status       *LimitStatus
+}
+
+func (c *callLimits) take(ctx context.Context, method string) error {
+	if c == nil {
+		return nil
+	}
+	status, scope := c.lim.Take(ctx, c.auth, c.caller, method)
+	c.status = c.status.merge(status)
+	keyUsageTotal.WithLabelValues(c.auth, method).Inc()
+	if scope == "" {
+		return nil
+	}
+	keyRateLimitedTotal.WithLabelValues(c.auth, method, scope).Inc()
+	rpcErr := ErrOverRateLimit.Clone()
+	if c.errorMessage != "" {
+		rpcErr.Message = c.errorMessage
+	}
+	return rpcErr
+}
+
+func (c *callLimits) setHeaders(w http.ResponseWriter) {
+	if c == nil || c.status == nil {
+		return
+	}
+	w.Header().Set(rateLimitLimitHdr, strconv.Itoa(c.status.Limit))
+	w.Header().Set(rateLimitRemainingHdr, strconv.Itoa(c.status.Remaining))
+	w.Header().Set(rateLimitResetHdr, strconv.FormatInt(c.status.Reset.Unix(), 10))
+}
+
 func setCacheHeader(w http.ResponseWriter, cached bool) {
 	if cached {
 		w.Header().Set(cacheStatusHdr, "HIT")
@@ -0,0 +1,182 @@
+package proxyd
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StrategyFirstHealthy       = "first_healthy"
+	StrategyWeightedRoundRobin = "weighted_round_robin"
+	StrategyLeastOutstanding   = "least_outstanding"
+	StrategyEWMALatency        = "ewma_latency"
+
+	// ewmaAlpha is the weight of the latest latency sample
+	ewmaAlpha = 0.2
+	// ewmaFailurePenalty is the latency recorded for a failed request, so
+	// that a backend failing fast does not look like the fastest one
+	ewmaFailurePenalty = 10 * time.Second
+)
+
+// BackendSelector orders the candidate backends of a group for a request.
+// The first backend is the one picked by the strategy, the others are
+// tried in order when it fails.
+type BackendSelector interface {
+	Order(backends []*Backend) []*Backend
+}
+
+func NewBackendSelector(strategy string) (BackendSelector, error) {
+	switch strategy {
+	case "", StrategyFirstHealthy:
+		return firstHealthySelector{}, nil
+	case StrategyWeightedRoundRobin:
+		return newWeightedRoundRobinSelector(), nil
+	case StrategyLeastOutstanding:
+		return new(leastOutstandingSelector), nil
+	case StrategyEWMALatency:
+		return new(ewmaSelector), nil
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy %s", strategy)
+	}
+}
+
+// firstHealthySelector tries the backends in config order
+type firstHealthySelector struct{}
+
+func (firstHealthySelector) Order(backends []*Backend) []*Backend {
+	return backends
+}
+
+// weightedRoundRobinSelector spreads requests in proportion to the backend
+// weights using smooth weighted round-robin, so that a heavy backend does
+// not receive its share in bursts
+type weightedRoundRobinSelector struct {
+	mtx     sync.Mutex
+	current map[*Backend]int
+}
+
+func newWeightedRoundRobinSelector() *weightedRoundRobinSelector {
+	return &weightedRoundRobinSelector{
+		current: make(map[*Backend]int),
+	}
+}
+
+func (s *weightedRoundRobinSelector) Order(backends []*Backend) []*Backend {
+	if len(backends) < 2 {
+		return backends
+	}
+
+	s.mtx.Lock()
+	total := 0
+	picked := 0
+	for i, be := range backends {
+		s.current[be] += be.weight
+		total += be.weight
+		if s.current[be] > s.current[backends[picked]] {
+			picked = i
+		}
+	}
+	s.current[backends[picked]] -= total
+	s.mtx.Unlock()
+
+	return moveToFront(backends, picked)
+}
+
+// leastOutstandingSelector picks the backend with the fewest in flight
+// requests relative to its weight. Ties are broken in rotation.
+type leastOutstandingSelector struct {
+	next uint64
+}
+
+func (s *leastOutstandingSelector) Order(backends []*Backend) []*Backend {
+	if len(backends) < 2 {
+		return backends
+	}
+
+	offset := int(atomic.AddUint64(&s.next, 1) % uint64(len(backends)))
+	ordered := make([]*Backend, 0, len(backends))
+	ordered = append(ordered, backends[offset:]...)
+	ordered = append(ordered, backends[:offset]...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].load() < ordered[j].load()
+	})
+	return ordered
+}
+
+// ewmaSelector compares two random backends and picks the one with the
+// lower expected latency, which is the EWMA of its response times scaled
+// by its in flight requests and its weight. Failed requests count as
+// ewmaFailurePenalty.
+type ewmaSelector struct{}
+
+func (s *ewmaSelector) Order(backends []*Backend) []*Backend {
+	if len(backends) < 2 {
+		return backends
+	}
+
+	i := rand.Intn(len(backends))
+	j := rand.Intn(len(backends) - 1)
+	if j >= i {
+		j++
+	}
+	picked := i
+	if backends[j].ewmaScore() < backends[i].ewmaScore() {
+		picked = j
+	}
+	return moveToFront(backends, picked)
+}
+
+// moveToFront returns a copy of the backends with the picked backend first
+// and the others in their original order
+func moveToFront(backends []*Backend, picked int) []*Backend {
+	ordered := make([]*Backend, 0, len(backends))
+	ordered = append(ordered, backends[picked])
+	ordered = append(ordered, backends[:picked]...)
+	ordered = append(ordered, backends[picked+1:]...)
+	return ordered
+}
+
+// latencyTracker keeps the EWMA of the response times of a backend
+type latencyTracker struct {
+	mtx  sync.Mutex
+	ewma float64
+}
+
+func (l *latencyTracker) observe(d time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.ewma == 0 {
+		l.ewma = float64(d)
+		return
+	}
+	l.ewma = ewmaAlpha*float64(d) + (1-ewmaAlpha)*l.ewma
+}
+
+// observeFailure records a failed request that took d
+func (l *latencyTracker) observeFailure(d time.Duration) {
+	if d < ewmaFailurePenalty {
+		d = ewmaFailurePenalty
+	}
+	l.observe(d)
+}
+
+func (l *latencyTracker) value() float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ewma
+}
+
+// load is the number of in flight requests of the backend per unit of weight
+func (b *Backend) load() float64 {
+	return float64(atomic.LoadInt64(&b.outstanding)) / float64(b.weight)
+}
+
+// ewmaScore is the expected latency of the next request to the backend.
+// Backends without samples score 0 so that they get sampled.
+func (b *Backend) ewmaScore() float64 {
+	return b.latency.value() * float64(atomic.LoadInt64(&b.outstanding)+1) / float64(b.weight)
+}
@@ -0,0 +1,76 @@
+package proxyd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedRoundRobinIsSmooth(t *testing.T) {
+	a := &Backend{Name: "a", weight: 1}
+	b := &Backend{Name: "b", weight: 2}
+	selector, err := NewBackendSelector(StrategyWeightedRoundRobin)
+	require.NoError(t, err)
+
+	var picked []string
+	for i := 0; i < 6; i++ {
+		ordered := selector.Order([]*Backend{a, b})
+		require.Len(t, ordered, 2)
+		picked = append(picked, ordered[0].Name)
+	}
+	require.Equal(t, []string{"b", "a", "b", "b", "a", "b"}, picked)
+}
+
+func TestLeastOutstandingPrefersIdleBackends(t *testing.T) {
+	busy := &Backend{Name: "busy", weight: 1, outstanding: 3}
+	idle := &Backend{Name: "idle", weight: 1}
+	heavy := &Backend{Name: "heavy", weight: 4, outstanding: 2}
+	selector, err := NewBackendSelector(StrategyLeastOutstanding)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		ordered := selector.Order([]*Backend{busy, heavy, idle})
+		require.Equal(t, []*Backend{idle, heavy, busy}, ordered)
+	}
+}
+
+func TestNewBackendSelectorUnknownStrategy(t *testing.T) {
+	_, err := NewBackendSelector("random")
+	require.Error(t, err)
+}
+
+func TestEWMAAvoidsFailingBackend(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the healthy backend is slower than the failing one
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"jsonrpc": "2.0", "result": "0x1", "id": 1}`))
+	}))
+	defer healthy.Close()
+
+	sem := semaphore.NewWeighted(100)
+	lim := NewLocalBackendRateLimiter()
+	bad := NewBackend("bad", failing.URL, failing.URL, lim, sem, WithMaxRetries(0), WithStrippedTrailingXFF())
+	good := NewBackend("good", healthy.URL, healthy.URL, lim, sem, WithMaxRetries(0), WithStrippedTrailingXFF())
+
+	req := &RPCReq{JSONRPC: JSONRPCVersion, Method: "eth_chainId", ID: []byte("1")}
+	_, err := bad.Forward(context.Background(), []*RPCReq{req}, false)
+	require.Error(t, err)
+	_, err = good.Forward(context.Background(), []*RPCReq{req}, false)
+	require.NoError(t, err)
+
+	selector, err := NewBackendSelector(StrategyEWMALatency)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		ordered := selector.Order([]*Backend{bad, good})
+		require.Equal(t, "good", ordered[0].Name)
+	}
+}
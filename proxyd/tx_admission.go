@@ -0,0 +1,151 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// defaultMaxCallDataSize matches the default rollup.maxcalldatasize of
+	// l2geth
+	defaultMaxCallDataSize = 127000
+	defaultDedupeWindow    = time.Minute
+
+	TxRejectMalformed       = "malformed"
+	TxRejectCallDataSize    = "calldata_size"
+	TxRejectChainID         = "chain_id"
+	TxRejectSignature       = "signature"
+	TxRejectSenderRateLimit = "sender_rate_limit"
+	TxRejectDuplicate       = "duplicate"
+)
+
+// TxAdmission checks the transactions sent with eth_sendRawTransaction
+// before they are forwarded to the sequencer. It rejects transactions that
+// cannot be decoded, that are signed for another chain or whose calldata is
+// too large to be included in a batch. It also limits the transactions of
+// each sender and drops the transactions it has seen recently. A transaction
+// that fails to be forwarded is released so that it can be sent again.
+type TxAdmission struct {
+	chainID         *big.Int
+	signer          types.Signer
+	maxCallDataSize int
+	senderLimits    *LimitConfig
+	dedupeWindow    time.Duration
+	lim             *RequestLimiter
+	counter         UsageCounter
+}
+
+func NewTxAdmission(config TxAdmissionConfig, counter UsageCounter) *TxAdmission {
+	chainID := new(big.Int).SetUint64(config.ChainID)
+	maxCallDataSize := config.MaxCallDataSize
+	if maxCallDataSize == 0 {
+		maxCallDataSize = defaultMaxCallDataSize
+	}
+	dedupeWindow := secondsToDuration(config.DedupeWindowSeconds)
+	if dedupeWindow == 0 {
+		dedupeWindow = defaultDedupeWindow
+	}
+	return &TxAdmission{
+		chainID:         chainID,
+		signer:          types.LatestSignerForChainID(chainID),
+		maxCallDataSize: maxCallDataSize,
+		senderLimits:    config.SenderLimits,
+		dedupeWindow:    dedupeWindow,
+		lim:             NewRequestLimiter(counter, nil, nil),
+		counter:         counter,
+	}
+}
+
+// Admit returns an error when the transaction of an eth_sendRawTransaction
+// call must not be forwarded
+func (a *TxAdmission) Admit(ctx context.Context, req *RPCReq) error {
+	tx, err := a.decode(req)
+	if err != nil {
+		return a.reject(ctx, TxRejectMalformed, ErrInvalidTransaction(err.Error()))
+	}
+
+	if len(tx.Data()) > a.maxCallDataSize {
+		msg := fmt.Sprintf("calldata cannot be larger than %d, sent %d", a.maxCallDataSize, len(tx.Data()))
+		return a.reject(ctx, TxRejectCallDataSize, ErrInvalidTransaction(msg))
+	}
+	if tx.Protected() && tx.ChainId().Cmp(a.chainID) != 0 {
+		msg := fmt.Sprintf("invalid chain id %s, expected %s", tx.ChainId(), a.chainID)
+		return a.reject(ctx, TxRejectChainID, ErrInvalidTransaction(msg))
+	}
+	sender, err := types.Sender(a.signer, tx)
+	if err != nil {
+		return a.reject(ctx, TxRejectSignature, ErrInvalidTransaction(err.Error()))
+	}
+
+	if a.senderLimits != nil {
+		name := "sender:" + sender.Hex()
+		if _, scope := a.lim.take(ctx, name, a.senderLimits, LimitScopeSenderRate, LimitScopeSenderDaily); scope != "" {
+			rpcErr := ErrOverRateLimit.Clone()
+			rpcErr.Message = "sender rate limited"
+			return a.reject(ctx, TxRejectSenderRateLimit, rpcErr)
+		}
+	}
+
+	// Duplicates are only dropped when the counter can be reached
+	count, err := a.counter.Incr(ctx, seenTxKey(tx), a.dedupeWindow)
+	if err != nil {
+		log.Error("error checking for duplicate transaction", "hash", tx.Hash(), "err", err)
+	} else if count > 1 {
+		return a.reject(ctx, TxRejectDuplicate, ErrDuplicateTransaction)
+	}
+	return nil
+}
+
+// Release forgets an admitted transaction that was not forwarded, or that
+// the sequencer did not accept, so that it is not dropped as a duplicate
+// when it is sent again
+func (a *TxAdmission) Release(ctx context.Context, req *RPCReq) {
+	tx, err := a.decode(req)
+	if err != nil {
+		return
+	}
+	// the request context may be done already
+	dctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.counter.Delete(dctx, seenTxKey(tx)); err != nil {
+		log.Error("error releasing transaction", "req_id", GetReqID(ctx), "hash", tx.Hash(), "err", err)
+	}
+}
+
+func seenTxKey(tx *types.Transaction) string {
+	return "seen_tx:" + tx.Hash().Hex()
+}
+
+func (a *TxAdmission) decode(req *RPCReq) (*types.Transaction, error) {
+	var params []hexutil.Bytes
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, err
+	}
+	if len(params) != 1 {
+		return nil, fmt.Errorf("expected 1 param, got %d", len(params))
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(params[0]); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (a *TxAdmission) reject(ctx context.Context, reason string, err error) error {
+	log.Info(
+		"rejected raw transaction",
+		"req_id", GetReqID(ctx),
+		"auth", GetAuthCtx(ctx),
+		"reason", reason,
+		"err", err,
+	)
+	txAdmissionRejectionsTotal.WithLabelValues(GetAuthCtx(ctx), reason).Inc()
+	return err
+}
@@ -0,0 +1,128 @@
+package proxyd
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func signedRawTx(t *testing.T, chainID int64, nonce uint64, data []byte) string {
+	key, err := crypto.HexToECDSA("8b3a350cf5c34c9194ca85829a2df0ec3153be0318b5e2d3348e872092edffba")
+	require.NoError(t, err)
+	to := common.HexToAddress("0x01")
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1),
+		Data:     data,
+	})
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(chainID)), key)
+	require.NoError(t, err)
+	raw, err := signed.MarshalBinary()
+	require.NoError(t, err)
+	return hexutil.Encode(raw)
+}
+
+func sendRawTxReq(params ...interface{}) *RPCReq {
+	return &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_sendRawTransaction",
+		Params:  mustMarshalJSON(params),
+		ID:      []byte("1"),
+	}
+}
+
+func requireRPCErrCode(t *testing.T, code int, err error) {
+	rpcErr, ok := err.(*RPCErr)
+	require.True(t, ok, "expected an RPCErr, got %v", err)
+	require.Equal(t, code, rpcErr.Code)
+}
+
+func TestTxAdmission(t *testing.T) {
+	ctx := context.Background()
+	invalidCode := ErrInvalidTransaction("").Code
+	admission := NewTxAdmission(TxAdmissionConfig{
+		ChainID:         5000,
+		MaxCallDataSize: 10,
+	}, NewLocalUsageCounter())
+
+	t.Run("valid transaction", func(t *testing.T) {
+		require.NoError(t, admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 5000, 0, nil))))
+	})
+
+	t.Run("duplicate transaction", func(t *testing.T) {
+		raw := signedRawTx(t, 5000, 1, nil)
+		require.NoError(t, admission.Admit(ctx, sendRawTxReq(raw)))
+		require.Equal(t, ErrDuplicateTransaction, admission.Admit(ctx, sendRawTxReq(raw)))
+	})
+
+	t.Run("wrong chain ID", func(t *testing.T) {
+		err := admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 1, 0, nil)))
+		requireRPCErrCode(t, invalidCode, err)
+		require.Contains(t, err.Error(), "invalid chain id")
+	})
+
+	t.Run("oversized calldata", func(t *testing.T) {
+		err := admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 5000, 0, make([]byte, 11))))
+		requireRPCErrCode(t, invalidCode, err)
+		require.Contains(t, err.Error(), "calldata cannot be larger than 10")
+	})
+
+	t.Run("malformed signature", func(t *testing.T) {
+		to := common.HexToAddress("0x01")
+		tx := types.NewTx(&types.LegacyTx{
+			To:       &to,
+			GasPrice: big.NewInt(1),
+			Gas:      21000,
+			Value:    big.NewInt(1),
+			V:        big.NewInt(5000*2 + 35),
+			R:        big.NewInt(1),
+			S:        big.NewInt(0),
+		})
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		err = admission.Admit(ctx, sendRawTxReq(hexutil.Encode(raw)))
+		requireRPCErrCode(t, invalidCode, err)
+	})
+
+	t.Run("malformed payload", func(t *testing.T) {
+		requireRPCErrCode(t, invalidCode, admission.Admit(ctx, sendRawTxReq("0x1234")))
+		requireRPCErrCode(t, invalidCode, admission.Admit(ctx, sendRawTxReq("not hex")))
+		requireRPCErrCode(t, invalidCode, admission.Admit(ctx, sendRawTxReq()))
+	})
+}
+
+func TestTxAdmissionSenderLimits(t *testing.T) {
+	ctx := context.Background()
+	admission := NewTxAdmission(TxAdmissionConfig{
+		ChainID:      5000,
+		SenderLimits: &LimitConfig{DailyQuota: 2},
+	}, NewLocalUsageCounter())
+
+	require.NoError(t, admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 5000, 0, nil))))
+	require.NoError(t, admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 5000, 1, nil))))
+	err := admission.Admit(ctx, sendRawTxReq(signedRawTx(t, 5000, 2, nil)))
+	requireRPCErrCode(t, ErrOverRateLimit.Code, err)
+	require.Equal(t, "sender rate limited", err.Error())
+}
+
+func TestTxAdmissionRelease(t *testing.T) {
+	ctx := context.Background()
+	admission := NewTxAdmission(TxAdmissionConfig{ChainID: 5000}, NewLocalUsageCounter())
+
+	req := sendRawTxReq(signedRawTx(t, 5000, 0, nil))
+	require.NoError(t, admission.Admit(ctx, req))
+	require.Equal(t, ErrDuplicateTransaction, admission.Admit(ctx, req))
+
+	// a transaction that failed to be forwarded is not a duplicate
+	admission.Release(ctx, req)
+	require.NoError(t, admission.Admit(ctx, req))
+}
@@ -0,0 +1,611 @@
+package proxyd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsUpstreamCallTimeout = 5 * time.Second
+	wsClientWriteTimeout  = 5 * time.Second
+	wsForwardTimeout      = defaultServerTimeout
+	// wsSessionQueueSize is the number of messages queued for a client
+	// before it is considered too slow and disconnected
+	wsSessionQueueSize = 256
+)
+
+var (
+	errUpstreamClosed  = errors.New("upstream websocket closed")
+	errUpstreamTimeout = errors.New("upstream websocket call timed out")
+	errSessionClosed   = errors.New("websocket session closed")
+)
+
+type subscriptionParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+type subscriptionNotification struct {
+	JSONRPC string              `json:"jsonrpc"`
+	Method  string              `json:"method"`
+	Params  *subscriptionParams `json:"params"`
+}
+
+// upstreamMsg is either a response to a call made by the hub or a
+// subscription notification
+type upstreamMsg struct {
+	ID     json.RawMessage     `json:"id"`
+	Method string              `json:"method"`
+	Params *subscriptionParams `json:"params"`
+	Result json.RawMessage     `json:"result"`
+	Error  *RPCErr             `json:"error"`
+}
+
+// SubscriptionHub multiplexes the eth_subscribe calls of the WS clients of a
+// backend group. It keeps one upstream subscription per set of subscription
+// params, carried over a single connection per backend, and fans its events
+// out to every client subscribed with the same params. Each client gets its
+// own subscription ID. When an upstream connection drops, its subscriptions
+// are moved to another backend and the clients keep their IDs. Events are
+// queued to each client so that a slow client never holds the others up,
+// and a client whose queue is full is disconnected.
+type SubscriptionHub struct {
+	group     *BackendGroup
+	queueSize int
+
+	// subMu serializes the changes to upstream subscriptions, which
+	// involve calls to the backends. mtx protects the maps below and is
+	// never held during a call.
+	subMu      sync.Mutex
+	mtx        sync.Mutex
+	conns      map[*Backend]*upstreamConn
+	subs       map[string]*sharedSubscription
+	byUpstream map[*upstreamConn]map[string]*sharedSubscription
+	byClient   map[string]*sharedSubscription
+}
+
+type sharedSubscription struct {
+	key        string
+	params     json.RawMessage
+	upstream   *upstreamConn
+	upstreamID string
+	clients    map[string]*WSSession
+}
+
+func NewSubscriptionHub(group *BackendGroup) *SubscriptionHub {
+	return &SubscriptionHub{
+		group:      group,
+		queueSize:  wsSessionQueueSize,
+		conns:      make(map[*Backend]*upstreamConn),
+		subs:       make(map[string]*sharedSubscription),
+		byUpstream: make(map[*upstreamConn]map[string]*sharedSubscription),
+		byClient:   make(map[string]*sharedSubscription),
+	}
+}
+
+// NewSession serves a client connection through the hub
+func (h *SubscriptionHub) NewSession(clientConn *websocket.Conn, methodWhitelist *StringSet) *WSSession {
+	s := &WSSession{
+		hub:             h,
+		conn:            clientConn,
+		methodWhitelist: methodWhitelist,
+		queue:           make(chan []byte, h.queueSize),
+		done:            make(chan struct{}),
+		subIDs:          make(map[string]bool),
+	}
+	go s.writeLoop()
+	return s
+}
+
+// Subscribe adds a client subscription with the given params and returns
+// its ID
+func (h *SubscriptionHub) Subscribe(session *WSSession, params json.RawMessage) (string, error) {
+	key, err := subscriptionKey(params)
+	if err != nil {
+		return "", ErrInvalidRequest("invalid subscription params")
+	}
+
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+
+	h.mtx.Lock()
+	sub := h.subs[key]
+	h.mtx.Unlock()
+	if sub == nil {
+		upstream, upstreamID, err := h.subscribeUpstream(params, nil)
+		if err != nil {
+			return "", err
+		}
+		sub = &sharedSubscription{
+			key:     key,
+			params:  params,
+			clients: make(map[string]*WSSession),
+		}
+		h.mtx.Lock()
+		h.subs[key] = sub
+		h.attach(sub, upstream, upstreamID)
+		h.mtx.Unlock()
+	}
+
+	id := "0x" + randStr(16)
+	h.mtx.Lock()
+	sub.clients[id] = session
+	h.byClient[id] = sub
+	h.mtx.Unlock()
+	wsSubscriptionClientsGauge.Inc()
+	return id, nil
+}
+
+// Unsubscribe removes a client subscription. The upstream subscription is
+// removed with its last client.
+func (h *SubscriptionHub) Unsubscribe(session *WSSession, id string) bool {
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+
+	h.mtx.Lock()
+	sub := h.byClient[id]
+	if sub == nil || sub.clients[id] != session {
+		h.mtx.Unlock()
+		return false
+	}
+	delete(sub.clients, id)
+	delete(h.byClient, id)
+	wsSubscriptionClientsGauge.Dec()
+	if len(sub.clients) > 0 {
+		h.mtx.Unlock()
+		return true
+	}
+
+	upstream := sub.upstream
+	delete(h.subs, sub.key)
+	h.detach(sub)
+	idle := len(h.byUpstream[upstream]) == 0
+	if idle && h.conns[upstream.backend] == upstream {
+		delete(h.conns, upstream.backend)
+	}
+	h.mtx.Unlock()
+
+	if idle {
+		// Closing the connection also removes its subscriptions
+		upstream.conn.Close()
+	} else if _, err := upstream.call("eth_unsubscribe", mustMarshalJSON([]string{sub.upstreamID})); err != nil {
+		log.Warn("error unsubscribing upstream", "name", upstream.backend.Name, "err", err)
+	}
+	return true
+}
+
+// attach and detach must be called with mtx held
+func (h *SubscriptionHub) attach(sub *sharedSubscription, upstream *upstreamConn, upstreamID string) {
+	sub.upstream = upstream
+	sub.upstreamID = upstreamID
+	if h.byUpstream[upstream] == nil {
+		h.byUpstream[upstream] = make(map[string]*sharedSubscription)
+	}
+	h.byUpstream[upstream][upstreamID] = sub
+	wsSharedSubscriptionsGauge.WithLabelValues(upstream.backend.Name).Inc()
+}
+
+func (h *SubscriptionHub) detach(sub *sharedSubscription) {
+	if subs := h.byUpstream[sub.upstream]; subs != nil && subs[sub.upstreamID] == sub {
+		delete(subs, sub.upstreamID)
+		wsSharedSubscriptionsGauge.WithLabelValues(sub.upstream.backend.Name).Dec()
+	}
+}
+
+// subscribeUpstream subscribes on the first backend that accepts the
+// subscription. The avoided backend is only tried last. Must be called with
+// subMu held.
+func (h *SubscriptionHub) subscribeUpstream(params json.RawMessage, avoid *Backend) (*upstreamConn, string, error) {
+	candidates := h.group.candidates()
+	ordered := make([]*Backend, 0, len(candidates))
+	for _, be := range candidates {
+		if be != avoid {
+			ordered = append(ordered, be)
+		}
+	}
+	for _, be := range candidates {
+		if be == avoid {
+			ordered = append(ordered, be)
+		}
+	}
+
+	for _, be := range ordered {
+		upstream, err := h.connect(be)
+		if err != nil {
+			log.Warn("error connecting to ws backend", "name", be.Name, "err", err)
+			continue
+		}
+		res, err := upstream.call("eth_subscribe", params)
+		if err != nil {
+			var rpcErr *RPCErr
+			if errors.As(err, &rpcErr) {
+				// The backend rejected the params, the others would too
+				return nil, "", rpcErr
+			}
+			log.Warn("error subscribing upstream", "name", be.Name, "err", err)
+			continue
+		}
+		var upstreamID string
+		if err := json.Unmarshal(res.Result, &upstreamID); err != nil {
+			log.Warn("invalid upstream subscription ID", "name", be.Name, "err", err)
+			continue
+		}
+		return upstream, upstreamID, nil
+	}
+	return nil, "", ErrNoBackends
+}
+
+// connect returns the connection to a backend, dialing it when needed
+func (h *SubscriptionHub) connect(be *Backend) (*upstreamConn, error) {
+	h.mtx.Lock()
+	upstream := h.conns[be]
+	h.mtx.Unlock()
+	if upstream != nil {
+		return upstream, nil
+	}
+
+	conn, err := be.dialWS()
+	if err != nil {
+		return nil, err
+	}
+
+	upstream = &upstreamConn{
+		hub:     h,
+		backend: be,
+		conn:    conn,
+		pending: make(map[uint64]chan *upstreamMsg),
+		closed:  make(chan struct{}),
+	}
+	h.mtx.Lock()
+	h.conns[be] = upstream
+	h.mtx.Unlock()
+	go upstream.readLoop()
+	return upstream, nil
+}
+
+// notify fans a subscription event out to the clients. It does not wait for
+// the clients to receive it.
+func (h *SubscriptionHub) notify(upstream *upstreamConn, params *subscriptionParams) {
+	h.mtx.Lock()
+	sub := h.byUpstream[upstream][params.Subscription]
+	var clients map[string]*WSSession
+	if sub != nil {
+		clients = make(map[string]*WSSession, len(sub.clients))
+		for id, session := range sub.clients {
+			clients[id] = session
+		}
+	}
+	h.mtx.Unlock()
+
+	for id, session := range clients {
+		session.writeNotification(id, params.Result)
+	}
+}
+
+// upstreamClosed moves the subscriptions of a closed connection to another
+// backend. The clients of the subscriptions that cannot be moved are
+// disconnected.
+func (h *SubscriptionHub) upstreamClosed(upstream *upstreamConn, err error) {
+	be := upstream.backend
+	if err := be.rateLimiter.DecBackendWSConns(be.Name); err != nil {
+		log.Error("error decrementing backend ws conns", "name", be.Name, "err", err)
+	}
+	activeBackendWsConnsGauge.WithLabelValues(be.Name).Dec()
+
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+
+	h.mtx.Lock()
+	if h.conns[be] == upstream {
+		delete(h.conns, be)
+	}
+	subs := make([]*sharedSubscription, 0, len(h.byUpstream[upstream]))
+	for _, sub := range h.byUpstream[upstream] {
+		subs = append(subs, sub)
+		h.detach(sub)
+	}
+	delete(h.byUpstream, upstream)
+	h.mtx.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+
+	log.Warn("upstream websocket dropped, resubscribing", "name", be.Name, "subscriptions", len(subs), "err", err)
+	for _, sub := range subs {
+		newUpstream, upstreamID, err := h.subscribeUpstream(sub.params, be)
+		if err != nil {
+			log.Error("error resubscribing, disconnecting clients", "err", err)
+			h.mtx.Lock()
+			delete(h.subs, sub.key)
+			var sessions []*WSSession
+			for id, session := range sub.clients {
+				delete(h.byClient, id)
+				wsSubscriptionClientsGauge.Dec()
+				sessions = append(sessions, session)
+			}
+			h.mtx.Unlock()
+			for _, session := range sessions {
+				session.close()
+			}
+			continue
+		}
+		h.mtx.Lock()
+		h.attach(sub, newUpstream, upstreamID)
+		h.mtx.Unlock()
+		wsResubscriptionsTotal.WithLabelValues(newUpstream.backend.Name).Inc()
+	}
+}
+
+type upstreamConn struct {
+	hub     *SubscriptionHub
+	backend *Backend
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	pendingMu sync.Mutex
+	nextID    uint64
+	pending   map[uint64]chan *upstreamMsg
+	closed    chan struct{}
+}
+
+func (u *upstreamConn) call(method string, params json.RawMessage) (*upstreamMsg, error) {
+	ch := make(chan *upstreamMsg, 1)
+	u.pendingMu.Lock()
+	u.nextID++
+	id := u.nextID
+	u.pending[id] = ch
+	u.pendingMu.Unlock()
+	defer func() {
+		u.pendingMu.Lock()
+		delete(u.pending, id)
+		u.pendingMu.Unlock()
+	}()
+
+	req := &RPCReq{
+		JSONRPC: JSONRPCVersion,
+		Method:  method,
+		Params:  params,
+		ID:      []byte(strconv.FormatUint(id, 10)),
+	}
+	u.writeMu.Lock()
+	err := u.conn.WriteMessage(websocket.TextMessage, mustMarshalJSON(req))
+	u.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-ch:
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		return res, nil
+	case <-u.closed:
+		return nil, errUpstreamClosed
+	case <-time.After(wsUpstreamCallTimeout):
+		return nil, errUpstreamTimeout
+	}
+}
+
+func (u *upstreamConn) readLoop() {
+	for {
+		msgType, data, err := u.conn.ReadMessage()
+		if err != nil {
+			close(u.closed)
+			u.conn.Close()
+			u.hub.upstreamClosed(u, err)
+			return
+		}
+		if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
+			continue
+		}
+
+		var msg upstreamMsg
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Warn("error parsing upstream ws message", "name", u.backend.Name, "err", err)
+			continue
+		}
+		if msg.Method == "eth_subscription" && msg.Params != nil {
+			u.hub.notify(u, msg.Params)
+			continue
+		}
+
+		id, err := strconv.ParseUint(string(msg.ID), 10, 64)
+		if err != nil {
+			log.Warn("unexpected upstream ws message", "name", u.backend.Name)
+			continue
+		}
+		u.pendingMu.Lock()
+		ch := u.pending[id]
+		u.pendingMu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+	}
+}
+
+// WSSession serves a client connection through a SubscriptionHub.
+// Subscriptions are shared through the hub, the other calls are forwarded
+// to the backend group over HTTP. Its messages are written by a single
+// goroutine from a bounded queue.
+type WSSession struct {
+	hub             *SubscriptionHub
+	conn            *websocket.Conn
+	methodWhitelist *StringSet
+	queue           chan []byte
+	done            chan struct{}
+	closeOnce       sync.Once
+
+	mtx    sync.Mutex
+	subIDs map[string]bool
+}
+
+func (s *WSSession) Proxy(ctx context.Context) error {
+	defer s.cleanup()
+	for {
+		msgType, msg, err := s.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		RecordWSMessage(ctx, BackendProxyd, SourceClient)
+		if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
+			continue
+		}
+
+		rpcRequestsTotal.Inc()
+		res := s.handle(ctx, msg)
+		// Responses wait for room in the queue, the client is only
+		// waiting on its own calls
+		select {
+		case s.queue <- mustMarshalJSON(res):
+		case <-s.done:
+			return errSessionClosed
+		}
+	}
+}
+
+func (s *WSSession) handle(ctx context.Context, msg []byte) *RPCRes {
+	req, err := ParseRPCReq(msg)
+	if err != nil {
+		RecordRPCError(ctx, BackendProxyd, MethodUnknown, err)
+		return NewRPCErrorRes(nil, err)
+	}
+	if !s.methodWhitelist.Has(req.Method) {
+		RecordRPCError(ctx, BackendProxyd, req.Method, ErrMethodNotWhitelisted)
+		return NewRPCErrorRes(req.ID, ErrMethodNotWhitelisted)
+	}
+	RecordRPCForward(ctx, BackendProxyd, req.Method, RPCRequestSourceWS)
+
+	switch req.Method {
+	case "eth_accounts":
+		return NewRPCRes(req.ID, emptyArrayResponse)
+	case "eth_subscribe":
+		id, err := s.hub.Subscribe(s, req.Params)
+		if err != nil {
+			log.Info("error subscribing", "auth", GetAuthCtx(ctx), "req_id", GetReqID(ctx), "err", err)
+			return NewRPCErrorRes(req.ID, err)
+		}
+		s.mtx.Lock()
+		s.subIDs[id] = true
+		s.mtx.Unlock()
+		return NewRPCRes(req.ID, id)
+	case "eth_unsubscribe":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 1 {
+			return NewRPCErrorRes(req.ID, ErrInvalidRequest("invalid unsubscribe params"))
+		}
+		s.mtx.Lock()
+		delete(s.subIDs, params[0])
+		s.mtx.Unlock()
+		return NewRPCRes(req.ID, s.hub.Unsubscribe(s, params[0]))
+	}
+
+	// The request context ends with the upgrade, keep only its values
+	fctx, cancel := context.WithTimeout(detachedContext{ctx}, wsForwardTimeout)
+	defer cancel()
+	res, err := s.hub.group.Forward(fctx, []*RPCReq{req}, false)
+	if err != nil {
+		return NewRPCErrorRes(req.ID, err)
+	}
+	return res[0]
+}
+
+func (s *WSSession) writeNotification(id string, result json.RawMessage) {
+	msg := mustMarshalJSON(&subscriptionNotification{
+		JSONRPC: JSONRPCVersion,
+		Method:  "eth_subscription",
+		Params: &subscriptionParams{
+			Subscription: id,
+			Result:       result,
+		},
+	})
+	select {
+	case s.queue <- msg:
+	case <-s.done:
+	default:
+		log.Info("websocket client too slow, closing client", "queued", len(s.queue))
+		wsSlowClientsTotal.Inc()
+		s.close()
+	}
+}
+
+func (s *WSSession) writeLoop() {
+	for {
+		select {
+		case msg := <-s.queue:
+			if err := s.write(msg); err != nil {
+				log.Info("error writing to websocket client, closing client", "err", err)
+				s.close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *WSSession) write(msg []byte) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(wsClientWriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// close disconnects the client, which makes Proxy return and clean up
+func (s *WSSession) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+func (s *WSSession) cleanup() {
+	s.close()
+	s.mtx.Lock()
+	ids := make([]string, 0, len(s.subIDs))
+	for id := range s.subIDs {
+		ids = append(ids, id)
+	}
+	s.subIDs = make(map[string]bool)
+	s.mtx.Unlock()
+	for _, id := range ids {
+		s.hub.Unsubscribe(s, id)
+	}
+}
+
+// subscriptionKey returns the canonical encoding of subscription params, so
+// that params that only differ in formatting share a subscription
+func subscriptionKey(params json.RawMessage) (string, error) {
+	var decoded []interface{}
+	if err := json.Unmarshal(params, &decoded); err != nil {
+		return "", err
+	}
+	if len(decoded) == 0 {
+		return "", errors.New("missing subscription type")
+	}
+	return string(mustMarshalJSON(decoded)), nil
+}
+
+// detachedContext keeps the values of a context but not its cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,227 @@
+package proxyd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// upstreamBackend is a WS backend that accepts every eth_subscribe call and
+// sends the subscription events it is told to. mtx is also held while
+// writing to the connection.
+type upstreamBackend struct {
+	server *httptest.Server
+
+	mtx          sync.Mutex
+	conn         *websocket.Conn
+	subscribes   int
+	unsubscribes int
+}
+
+func newUpstreamBackend(t *testing.T) *upstreamBackend {
+	ub := new(upstreamBackend)
+	upgrader := websocket.Upgrader{}
+	ub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		ub.mtx.Lock()
+		ub.conn = conn
+		ub.mtx.Unlock()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req RPCReq
+			if err := json.Unmarshal(data, &req); err != nil {
+				continue
+			}
+			ub.mtx.Lock()
+			var result interface{} = true
+			if req.Method == "eth_subscribe" {
+				ub.subscribes++
+				result = fmt.Sprintf("0x%d", ub.subscribes)
+			} else {
+				ub.unsubscribes++
+			}
+			_ = conn.WriteJSON(NewRPCRes(req.ID, result))
+			ub.mtx.Unlock()
+		}
+	}))
+	t.Cleanup(ub.server.Close)
+	return ub
+}
+
+func (ub *upstreamBackend) notify(t *testing.T, id string, result string) {
+	ub.mtx.Lock()
+	defer ub.mtx.Unlock()
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":%s}}`, id, result)
+	require.NoError(t, ub.conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+}
+
+func (ub *upstreamBackend) counts() (int, int) {
+	ub.mtx.Lock()
+	defer ub.mtx.Unlock()
+	return ub.subscribes, ub.unsubscribes
+}
+
+func newTestHub(t *testing.T, ub *upstreamBackend) *SubscriptionHub {
+	wsURL := "ws" + strings.TrimPrefix(ub.server.URL, "http")
+	be := NewBackend("upstream", ub.server.URL, wsURL, NewLocalBackendRateLimiter(), semaphore.NewWeighted(100), WithStrippedTrailingXFF())
+	return NewSubscriptionHub(&BackendGroup{Name: "ws", Backends: []*Backend{be}})
+}
+
+// newTestSession returns a session of the hub and the client end of its
+// connection
+func newTestSession(t *testing.T, hub *SubscriptionHub) (*WSSession, *websocket.Conn) {
+	sessions := make(chan *WSSession, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		sessions <- hub.NewSession(conn, NewStringSetFromStrings([]string{"eth_subscribe"}))
+	}))
+	t.Cleanup(server.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil) // nolint:bodyclose
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	session := <-sessions
+	t.Cleanup(session.close)
+	return session, client
+}
+
+func readNotification(t *testing.T, client *websocket.Conn) *subscriptionNotification {
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var msg subscriptionNotification
+	require.NoError(t, client.ReadJSON(&msg))
+	return &msg
+}
+
+func TestSubscriptionHubSharesSubscriptions(t *testing.T) {
+	ub := newUpstreamBackend(t)
+	hub := newTestHub(t, ub)
+	alice, aliceClient := newTestSession(t, hub)
+	bob, bobClient := newTestSession(t, hub)
+
+	aliceID, err := hub.Subscribe(alice, json.RawMessage(`["newHeads"]`))
+	require.NoError(t, err)
+	bobID, err := hub.Subscribe(bob, json.RawMessage(`[ "newHeads" ]`))
+	require.NoError(t, err)
+	require.NotEqual(t, aliceID, bobID)
+	subscribes, _ := ub.counts()
+	require.Equal(t, 1, subscribes)
+
+	ub.notify(t, "0x1", `{"number":"0x1"}`)
+	msg := readNotification(t, aliceClient)
+	require.Equal(t, aliceID, msg.Params.Subscription)
+	require.JSONEq(t, `{"number":"0x1"}`, string(msg.Params.Result))
+	msg = readNotification(t, bobClient)
+	require.Equal(t, bobID, msg.Params.Subscription)
+
+	// Only a subscription's own session may remove it
+	require.False(t, hub.Unsubscribe(alice, bobID))
+	require.True(t, hub.Unsubscribe(alice, aliceID))
+	_, unsubscribes := ub.counts()
+	require.Equal(t, 0, unsubscribes)
+
+	// The upstream connection is closed with its last subscription
+	require.True(t, hub.Unsubscribe(bob, bobID))
+	require.Eventually(t, func() bool {
+		hub.mtx.Lock()
+		defer hub.mtx.Unlock()
+		return len(hub.conns) == 0 && len(hub.subs) == 0 && len(hub.byClient) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSubscriptionHubClosesSlowSession(t *testing.T) {
+	ub := newUpstreamBackend(t)
+	hub := newTestHub(t, ub)
+	hub.queueSize = 4
+	slow, _ := newTestSession(t, hub)
+	fast, fastClient := newTestSession(t, hub)
+
+	slowID, err := hub.Subscribe(slow, json.RawMessage(`["newHeads"]`))
+	require.NoError(t, err)
+	fastID, err := hub.Subscribe(fast, json.RawMessage(`["newHeads"]`))
+	require.NoError(t, err)
+
+	// The slow client never reads, its queue fills up once the socket
+	// buffers are full. The fast client reads every notification before the
+	// next one is sent, so that its own queue never fills up.
+	result := fmt.Sprintf(`"%s"`, strings.Repeat("a", 1<<20))
+	for i := 0; i < 32; i++ {
+		ub.notify(t, "0x1", result)
+		require.NoError(t, fastClient.SetReadDeadline(time.Now().Add(5*time.Second)))
+		var msg subscriptionNotification
+		require.NoError(t, fastClient.ReadJSON(&msg))
+		require.Equal(t, fastID, msg.Params.Subscription)
+	}
+
+	select {
+	case <-slow.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the slow session to be closed")
+	}
+	require.NotEmpty(t, slowID)
+}
+
+// newHalfOpenWSBackend returns a backend of ub whose circuit breaker is half
+// open with a single probe slot
+func newHalfOpenWSBackend(t *testing.T, ub *upstreamBackend, opts ...BackendOpt) *Backend {
+	wsURL := "ws" + strings.TrimPrefix(ub.server.URL, "http")
+	opts = append(opts, WithStrippedTrailingXFF(), WithCircuitBreaker(CircuitBreakerConfig{MinRequests: 1, HalfOpenProbes: 1}))
+	be := NewBackend("upstream", ub.server.URL, wsURL, NewLocalBackendRateLimiter(), semaphore.NewWeighted(100), opts...)
+	be.breaker.openDuration = 100 * time.Millisecond
+	be.breaker.record(true, 0)
+	require.True(t, be.breaker.isOpen())
+	time.Sleep(150 * time.Millisecond)
+	return be
+}
+
+func TestWSDialProbes(t *testing.T) {
+	ub := newUpstreamBackend(t)
+
+	t.Run("proxy", func(t *testing.T) {
+		be := newHalfOpenWSBackend(t, ub)
+		proxier, err := be.ProxyWS(nil, NewStringSetFromStrings([]string{"eth_subscribe"}))
+		require.NoError(t, err)
+		defer proxier.backendConn.Close()
+		// The successful dial was the probe that closes the circuit
+		require.Equal(t, CircuitClosed, be.breaker.state)
+	})
+
+	t.Run("hub", func(t *testing.T) {
+		be := newHalfOpenWSBackend(t, ub)
+		hub := NewSubscriptionHub(&BackendGroup{Name: "ws", Backends: []*Backend{be}})
+		session, _ := newTestSession(t, hub)
+		_, err := hub.Subscribe(session, json.RawMessage(`["newHeads"]`))
+		require.NoError(t, err)
+		require.Equal(t, CircuitClosed, be.breaker.state)
+	})
+
+	t.Run("saturated", func(t *testing.T) {
+		be := newHalfOpenWSBackend(t, ub, WithMaxWSConns(1))
+		incremented, err := be.rateLimiter.IncBackendWSConns(be.Name, 1)
+		require.NoError(t, err)
+		require.True(t, incremented)
+		_, err = be.ProxyWS(nil, NewStringSetFromStrings([]string{"eth_subscribe"}))
+		require.ErrorIs(t, err, ErrBackendOverCapacity)
+
+		// The probe slot was given back for the next request
+		allowed, probe := be.breaker.take(true)
+		require.True(t, allowed)
+		require.True(t, probe)
+		be.breaker.release()
+	})
+}
@@ -112,6 +112,12 @@ var (
 		Value:  "payerState",
 		EnvVar: "SUBSIDY_FILE_NAME",
 	}
+	LedgerDirFlag = cli.StringFlag{
+		Name:   "ledger-dir",
+		Usage:  "subsidy dir under the home dir for the ledger of batch costs and payouts",
+		Value:  "ledger",
+		EnvVar: "SUBSIDY_LEDGER_DIR",
+	}
 	StartBlockFlag = cli.Uint64Flag{
 		Name:   "start-block",
 		Usage:  "payer start block",
@@ -123,6 +129,12 @@ var (
 		Usage:  "payer revised block,if not empty block will be reset",
 		EnvVar: "SUBSIDY_REVISED_BLOCk",
 	}
+	ConfirmationDepthFlag = cli.Uint64Flag{
+		Name:   "confirmation-depth",
+		Usage:  "number of blocks below the L1 tip before batches are paid for and payouts are confirmed",
+		Value:  12,
+		EnvVar: "SUBSIDY_CONFIRMATION_DEPTH",
+	}
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:   "metrics",
 		Usage:  "Enable metrics collection and reporting",
@@ -171,6 +183,22 @@ var (
 	}
 )
 
+var (
+	ReconcileFromBlockFlag = cli.Uint64Flag{
+		Name:  "from-block",
+		Usage: "first L1 block to reconcile, defaults to the start block",
+	}
+	ReconcileToBlockFlag = cli.Uint64Flag{
+		Name:  "to-block",
+		Usage: "last L1 block to reconcile, defaults to the last paid block",
+	}
+)
+
+var ReconcileFlags = []cli.Flag{
+	ReconcileFromBlockFlag,
+	ReconcileToBlockFlag,
+}
+
 var Flags = []cli.Flag{
 	ReceiveAddressFlag,
 	PayerHttpUrlFlag,
@@ -187,8 +215,10 @@ var Flags = []cli.Flag{
 	HomeDirFlag,
 	CacheDirFlag,
 	FileNameFlag,
+	LedgerDirFlag,
 	StartBlockFlag,
 	RevisedBlockFlag,
+	ConfirmationDepthFlag,
 	MetricsEnabledFlag,
 	MetricsHTTPFlag,
 	MetricsPortFlag,
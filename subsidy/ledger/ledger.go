@@ -0,0 +1,216 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ErrLocked is returned when another process, usually the running subsidy
+// service, holds the lock of the ledger
+var ErrLocked = errors.New("ledger is locked by another process")
+
+var (
+	batchPrefix  = []byte("batch/")
+	payoutPrefix = []byte("payout/")
+)
+
+// BatchTx is a rollup batch transaction and what was actually paid for it
+type BatchTx struct {
+	TxHash            common.Hash `json:"tx_hash"`
+	Kind              string      `json:"kind"`
+	BlockNumber       uint64      `json:"block_number"`
+	GasUsed           uint64      `json:"gas_used"`
+	EffectiveGasPrice *big.Int    `json:"effective_gas_price"`
+	Cost              *big.Int    `json:"cost"`
+	// PayoutFromBlock is the first block of the payout range that
+	// covered this transaction
+	PayoutFromBlock uint64 `json:"payout_from_block"`
+}
+
+const (
+	// PayoutIntent is a payout whose transaction is signed but may not
+	// have been broadcast
+	PayoutIntent = "intent"
+	// PayoutBroadcast is a payout whose transaction was broadcast but is
+	// not confirmed yet
+	PayoutBroadcast = "broadcast"
+	// PayoutConfirmed is a payout whose transaction is confirmed, or that
+	// had nothing to pay
+	PayoutConfirmed = "confirmed"
+	// PayoutDropped is a payout whose nonce was used by another
+	// transaction, or whose transaction failed. Its range is paid again.
+	PayoutDropped = "dropped"
+)
+
+// Payout is a subsidy transfer covering the batch transactions of an
+// inclusive range of L1 blocks. PayTxHash is empty when nothing was owed.
+// RawTx is the signed transaction so that it can be broadcast again with
+// the same hash and nonce.
+type Payout struct {
+	FromBlock uint64        `json:"from_block"`
+	ToBlock   uint64        `json:"to_block"`
+	Amount    *big.Int      `json:"amount"`
+	Status    string        `json:"status"`
+	Nonce     uint64        `json:"nonce"`
+	PayTxHash string        `json:"pay_tx_hash"`
+	RawTx     hexutil.Bytes `json:"raw_tx,omitempty"`
+	Batches   int           `json:"batches"`
+	Time      time.Time     `json:"time"`
+}
+
+// Pending returns true when the payout was not settled yet
+func (p *Payout) Pending() bool {
+	return p.Status == PayoutIntent || p.Status == PayoutBroadcast
+}
+
+// Ledger is a persistent record of every batch transaction and the payout
+// that covered it, stored in LevelDB
+type Ledger struct {
+	db *leveldb.DB
+}
+
+// Open opens the ledger at path, creating it when it does not exist
+func Open(path string) (*Ledger, error) {
+	return open(path, nil)
+}
+
+// OpenReadOnly opens the existing ledger at path without writing to it. It
+// still needs the lock of the ledger, which the running service holds, so a
+// copy of the ledger directory must be opened while the service runs.
+func OpenReadOnly(path string) (*Ledger, error) {
+	return open(path, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+}
+
+func open(path string, o *opt.Options) (*Ledger, error) {
+	db, err := leveldb.OpenFile(path, o)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Ledger{db: db}, nil
+}
+
+// Close closes the underlying database
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
+
+// RecordPayout atomically stores a payout together with the batch
+// transactions it covers
+func (l *Ledger) RecordPayout(payout *Payout, batches []BatchTx) error {
+	batch := new(leveldb.Batch)
+	for i := range batches {
+		batches[i].PayoutFromBlock = payout.FromBlock
+		value, err := json.Marshal(&batches[i])
+		if err != nil {
+			return err
+		}
+		batch.Put(batchKey(batches[i].BlockNumber, batches[i].TxHash), value)
+	}
+	payout.Batches = len(batches)
+	value, err := json.Marshal(payout)
+	if err != nil {
+		return err
+	}
+	batch.Put(payoutKey(payout.FromBlock), value)
+	return l.db.Write(batch, nil)
+}
+
+// UpdatePayout stores a payout that was recorded before, for instance
+// when its status changes
+func (l *Ledger) UpdatePayout(payout *Payout) error {
+	value, err := json.Marshal(payout)
+	if err != nil {
+		return err
+	}
+	return l.db.Put(payoutKey(payout.FromBlock), value, nil)
+}
+
+// LatestPayout returns the payout covering the highest blocks, or nil
+// when no payout was recorded
+func (l *Ledger) LatestPayout() (*Payout, error) {
+	iter := l.db.NewIterator(util.BytesPrefix(payoutPrefix), nil)
+	defer iter.Release()
+	if !iter.Last() {
+		return nil, iter.Error()
+	}
+	var payout Payout
+	if err := json.Unmarshal(iter.Value(), &payout); err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// Payouts returns the payouts that start within the inclusive block
+// range, ordered by block
+func (l *Ledger) Payouts(fromBlock, toBlock uint64) ([]Payout, error) {
+	var payouts []Payout
+	err := l.iterate(payoutPrefix, fromBlock, toBlock, func(value []byte) error {
+		var payout Payout
+		if err := json.Unmarshal(value, &payout); err != nil {
+			return err
+		}
+		payouts = append(payouts, payout)
+		return nil
+	})
+	return payouts, err
+}
+
+// Batches returns the batch transactions included within the inclusive
+// block range, ordered by block
+func (l *Ledger) Batches(fromBlock, toBlock uint64) ([]BatchTx, error) {
+	var batches []BatchTx
+	err := l.iterate(batchPrefix, fromBlock, toBlock, func(value []byte) error {
+		var tx BatchTx
+		if err := json.Unmarshal(value, &tx); err != nil {
+			return err
+		}
+		batches = append(batches, tx)
+		return nil
+	})
+	return batches, err
+}
+
+func (l *Ledger) iterate(prefix []byte, fromBlock, toBlock uint64, fn func([]byte) error) error {
+	limit := util.BytesPrefix(prefix).Limit
+	if toBlock < ^uint64(0) {
+		limit = blockKey(prefix, toBlock+1)
+	}
+	iter := l.db.NewIterator(&util.Range{Start: blockKey(prefix, fromBlock), Limit: limit}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(iter.Value()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// blockKey prefixes a big endian block number so that keys sort by block
+func blockKey(prefix []byte, number uint64) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], number)
+	return key
+}
+
+func batchKey(number uint64, txHash common.Hash) []byte {
+	return append(blockKey(batchPrefix, number), txHash.Bytes()...)
+}
+
+func payoutKey(fromBlock uint64) []byte {
+	return blockKey(payoutPrefix, fromBlock)
+}
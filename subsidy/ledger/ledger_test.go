@@ -0,0 +1,104 @@
+package ledger
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordPayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger")
+	l, err := Open(path)
+	require.NoError(t, err)
+
+	batches := []BatchTx{
+		{TxHash: common.HexToHash("0x01"), Kind: "ctc", BlockNumber: 105, GasUsed: 100, EffectiveGasPrice: big.NewInt(2), Cost: big.NewInt(200)},
+		{TxHash: common.HexToHash("0x02"), Kind: "scc", BlockNumber: 105, GasUsed: 50, EffectiveGasPrice: big.NewInt(2), Cost: big.NewInt(100)},
+	}
+	payout := &Payout{FromBlock: 100, ToBlock: 110, Amount: big.NewInt(300), PayTxHash: "0xabc"}
+	require.NoError(t, l.RecordPayout(payout, batches))
+	require.NoError(t, l.RecordPayout(&Payout{FromBlock: 111, ToBlock: 120, Amount: big.NewInt(0)}, nil))
+	require.NoError(t, l.Close())
+
+	// The records survive reopening
+	l, err = Open(path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	payouts, err := l.Payouts(0, ^uint64(0))
+	require.NoError(t, err)
+	require.Len(t, payouts, 2)
+	require.Equal(t, uint64(110), payouts[0].ToBlock)
+	require.Equal(t, 2, payouts[0].Batches)
+	require.Equal(t, "0xabc", payouts[0].PayTxHash)
+	require.Equal(t, 0, payouts[1].Amount.Sign())
+
+	payouts, err = l.Payouts(101, 200)
+	require.NoError(t, err)
+	require.Len(t, payouts, 1)
+	require.Equal(t, uint64(111), payouts[0].FromBlock)
+
+	stored, err := l.Batches(105, 105)
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	require.Equal(t, uint64(100), stored[0].PayoutFromBlock)
+	require.Equal(t, big.NewInt(200), stored[0].Cost)
+
+	stored, err = l.Batches(106, 200)
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func TestLatestPayout(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "ledger"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	latest, err := l.LatestPayout()
+	require.NoError(t, err)
+	require.Nil(t, latest)
+
+	require.NoError(t, l.RecordPayout(&Payout{FromBlock: 100, ToBlock: 110, Amount: big.NewInt(0), Status: PayoutConfirmed}, nil))
+	payout := &Payout{FromBlock: 111, ToBlock: 120, Amount: big.NewInt(5), Status: PayoutIntent, Nonce: 7, RawTx: []byte{1, 2, 3}}
+	require.NoError(t, l.RecordPayout(payout, nil))
+
+	latest, err = l.LatestPayout()
+	require.NoError(t, err)
+	require.True(t, latest.Pending())
+	require.Equal(t, uint64(7), latest.Nonce)
+	require.Equal(t, []byte{1, 2, 3}, []byte(latest.RawTx))
+
+	payout.Status = PayoutConfirmed
+	require.NoError(t, l.UpdatePayout(payout))
+	latest, err = l.LatestPayout()
+	require.NoError(t, err)
+	require.False(t, latest.Pending())
+	require.Equal(t, uint64(111), latest.FromBlock)
+}
+
+func TestOpenLockedLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger")
+	l, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.RecordPayout(&Payout{FromBlock: 100, ToBlock: 110, Amount: big.NewInt(0)}, nil))
+
+	// the service holds the lock
+	_, err = OpenReadOnly(path)
+	require.ErrorIs(t, err, ErrLocked)
+	_, err = Open(path)
+	require.ErrorIs(t, err, ErrLocked)
+	require.NoError(t, l.Close())
+
+	l, err = OpenReadOnly(path)
+	require.NoError(t, err)
+	defer l.Close()
+	latest, err := l.LatestPayout()
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), latest.FromBlock)
+
+	_, err = OpenReadOnly(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -10,6 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	ometrics "github.com/mantlenetworkio/mantle/gas-oracle/metrics"
 	flags "github.com/mantlenetworkio/mantle/subsidy/flags"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
 	"github.com/mantlenetworkio/mantle/subsidy/payer"
 	"github.com/urfave/cli"
 )
@@ -66,8 +69,66 @@ func main() {
 
 		return nil
 	}
+	app.Commands = []cli.Command{
+		{
+			Name:   "reconcile",
+			Usage:  "Compare the recorded payouts with the rollup cost worked out from receipts",
+			Flags:  flags.ReconcileFlags,
+			Action: reconcileAction,
+		},
+	}
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Crit("application failed", "message", err)
 	}
 }
+
+// reconcileAction prints a report of every payout in the range and of the
+// ranges that were never paid. Logs go to stderr so that the report can be
+// piped.
+func reconcileAction(ctx *cli.Context) error {
+	loglevel := ctx.GlobalUint64(flags.LogLevelFlag.Name)
+	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(loglevel), log.StreamHandler(os.Stderr, log.TerminalFormat(true))))
+
+	config := payer.NewConfig(ctx)
+	ob, err := payer.NewReconcilePayer(config)
+	if errors.Is(err, ledger.ErrLocked) {
+		return fmt.Errorf("%w, stop the subsidy service or reconcile a copy of the ledger with --%s", err, flags.LedgerDirFlag.Name)
+	}
+	if err != nil {
+		return err
+	}
+	defer ob.Close()
+
+	fromBlock := config.StartBlock
+	if ctx.IsSet(flags.ReconcileFromBlockFlag.Name) {
+		fromBlock = ctx.Uint64(flags.ReconcileFromBlockFlag.Name)
+	}
+	toBlock := ob.EndBlock()
+	if ctx.IsSet(flags.ReconcileToBlockFlag.Name) {
+		toBlock = ctx.Uint64(flags.ReconcileToBlockFlag.Name)
+	}
+	if fromBlock > toBlock {
+		return fmt.Errorf("from block %d is after to block %d", fromBlock, toBlock)
+	}
+
+	reports, err := ob.Reconcile(fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FROM\tTO\tSTATUS\tPAID\tCOST\tDIFFERENCE\tPAY TX")
+	var mismatched int
+	for _, report := range reports {
+		if report.Status != payer.RangeOK {
+			mismatched++
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", report.FromBlock, report.ToBlock, report.Status,
+			report.Paid, report.Cost, report.Difference, report.PayTxHash)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	log.Info("Reconciliation complete", "from", fromBlock, "to", toBlock, "ranges", len(reports), "mismatched", mismatched)
+	return nil
+}
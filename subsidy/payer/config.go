@@ -29,8 +29,10 @@ type Config struct {
 	HomeDir                   string
 	CacheDir                  string
 	FileName                  string
+	LedgerDir                 string
 	RevisedBlock              uint64
 	StartBlock                uint64
+	ConfirmationDepth         uint64
 	//receivable                common.Address
 	// Metrics config
 	MetricsEnabled          bool
@@ -61,8 +63,10 @@ func NewConfig(ctx *cli.Context) *Config {
 	cfg.HomeDir = ctx.GlobalString(flags.HomeDirFlag.Name)
 	cfg.CacheDir = ctx.GlobalString(flags.CacheDirFlag.Name)
 	cfg.FileName = ctx.GlobalString(flags.FileNameFlag.Name)
+	cfg.LedgerDir = ctx.GlobalString(flags.LedgerDirFlag.Name)
 	cfg.StartBlock = ctx.GlobalUint64(flags.StartBlockFlag.Name)
 	cfg.RevisedBlock = ctx.GlobalUint64(flags.RevisedBlockFlag.Name)
+	cfg.ConfirmationDepth = ctx.GlobalUint64(flags.ConfirmationDepthFlag.Name)
 
 	if ctx.GlobalIsSet(flags.PrivateKeyFlag.Name) {
 		hex := ctx.GlobalString(flags.PrivateKeyFlag.Name)
@@ -0,0 +1,160 @@
+package payer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+)
+
+// receiptCost is what was actually paid for a transaction
+type receiptCost struct {
+	BlockNumber       uint64
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+}
+
+// receiptFetcher fetches what was paid for a transaction from its receipt
+type receiptFetcher interface {
+	ReceiptCost(ctx context.Context, txHash common.Hash) (*receiptCost, error)
+}
+
+// rpcReceipt holds the receipt fields that are needed to work out the
+// cost of a transaction. The receipt type of go-ethereum does not decode
+// the effective gas price, so the receipt is fetched with a raw call.
+type rpcReceipt struct {
+	BlockNumber       *hexutil.Big   `json:"blockNumber"`
+	BlockHash         common.Hash    `json:"blockHash"`
+	GasUsed           hexutil.Uint64 `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+}
+
+type rpcReceiptFetcher struct {
+	rpc    *rpc.Client
+	client *ethclient.Client
+}
+
+func (f *rpcReceiptFetcher) ReceiptCost(ctx context.Context, txHash common.Hash) (*receiptCost, error) {
+	var receipt *rpcReceipt
+	if err := f.rpc.CallContext(ctx, &receipt, "eth_getTransactionReceipt", txHash); err != nil {
+		return nil, err
+	}
+	if receipt == nil || receipt.BlockNumber == nil {
+		return nil, fmt.Errorf("receipt of %s not found", txHash.Hex())
+	}
+	cost := &receiptCost{
+		BlockNumber: receipt.BlockNumber.ToInt().Uint64(),
+		GasUsed:     uint64(receipt.GasUsed),
+	}
+	if receipt.EffectiveGasPrice != nil {
+		cost.EffectiveGasPrice = receipt.EffectiveGasPrice.ToInt()
+		return cost, nil
+	}
+
+	// Nodes that predate the effectiveGasPrice field: work it out from
+	// the transaction and the base fee of its block
+	tx, _, err := f.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	header, err := f.client.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	cost.EffectiveGasPrice, err = effectiveGasPrice(tx, header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	return cost, nil
+}
+
+// effectiveGasPrice returns the gas price paid by a transaction included
+// in a block with the given base fee
+func effectiveGasPrice(tx *ethtypes.Transaction, baseFee *big.Int) (*big.Int, error) {
+	if baseFee == nil {
+		return tx.GasPrice(), nil
+	}
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		return nil, err
+	}
+	return tip.Add(tip, baseFee), nil
+}
+
+// CalculateCost returns what was paid for the transactions that emitted the
+// logs, as gas used times the effective gas price of their receipts. A
+// transaction that emitted several logs is counted once. When a receipt
+// cannot be fetched the whole range fails, so that it is never paid short.
+func (ob *Payer) CalculateCost(kind string, logs []ethtypes.Log) (*big.Int, []ledger.BatchTx, error) {
+	totalFee := big.NewInt(0)
+	var batches []ledger.BatchTx
+	seen := make(map[common.Hash]bool)
+	for _, l := range logs {
+		if seen[l.TxHash] {
+			continue
+		}
+		seen[l.TxHash] = true
+		receipt, err := ob.receipts.ReceiptCost(context.Background(), l.TxHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot get cost of %s: %w", l.TxHash.Hex(), err)
+		}
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+		totalFee.Add(totalFee, cost)
+		batches = append(batches, ledger.BatchTx{
+			TxHash:            l.TxHash,
+			Kind:              kind,
+			BlockNumber:       receipt.BlockNumber,
+			GasUsed:           receipt.GasUsed,
+			EffectiveGasPrice: receipt.EffectiveGasPrice,
+			Cost:              cost,
+		})
+	}
+	return totalFee, batches, nil
+}
+
+// RangeCost returns what was paid for the state and transaction batches
+// appended within the inclusive block range. Logs are queried at most
+// maxBlockRange blocks at a time.
+func (ob *Payer) RangeCost(fromBlock, toBlock uint64) (*big.Int, []ledger.BatchTx, error) {
+	totalFee := big.NewInt(0)
+	var batches []ledger.BatchTx
+	for from := fromBlock; from <= toBlock; from += maxBlockRange + 1 {
+		to := toBlock
+		if to-from > maxBlockRange {
+			to = from + maxBlockRange
+		}
+		fee, rangeBatches, err := ob.rangeCost(from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		totalFee.Add(totalFee, fee)
+		batches = append(batches, rangeBatches...)
+	}
+	return totalFee, batches, nil
+}
+
+func (ob *Payer) rangeCost(fromBlock, toBlock uint64) (*big.Int, []ledger.BatchTx, error) {
+	sccLogs, err := ob.getLogs(ob.sccAddrStr, ob.sccTopic, fromBlock, toBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctcLogs, err := ob.getLogs(ob.ctcAddrStr, ob.ctcTopic, fromBlock, toBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+	sccFee, sccBatches, err := ob.CalculateCost(BatchKindSCC, sccLogs)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctcFee, ctcBatches, err := ob.CalculateCost(BatchKindCTC, ctcLogs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sccFee.Add(sccFee, ctcFee), append(sccBatches, ctcBatches...), nil
+}
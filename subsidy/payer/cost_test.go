@@ -0,0 +1,113 @@
+package payer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+	"github.com/stretchr/testify/require"
+)
+
+type mockReceiptFetcher map[common.Hash]*receiptCost
+
+func (m mockReceiptFetcher) ReceiptCost(ctx context.Context, txHash common.Hash) (*receiptCost, error) {
+	receipt, ok := m[txHash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return receipt, nil
+}
+
+func TestCalculateCost(t *testing.T) {
+	first, second := common.HexToHash("0x01"), common.HexToHash("0x02")
+	ob := &Payer{receipts: mockReceiptFetcher{
+		first:  {BlockNumber: 10, GasUsed: 100_000, EffectiveGasPrice: big.NewInt(3)},
+		second: {BlockNumber: 11, GasUsed: 50_000, EffectiveGasPrice: big.NewInt(4)},
+	}}
+
+	// The first transaction emitted two logs and is counted once
+	logs := []ethtypes.Log{{TxHash: first}, {TxHash: first}, {TxHash: second}}
+	totalFee, batches, err := ob.CalculateCost(BatchKindCTC, logs)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(500_000), totalFee)
+	require.Len(t, batches, 2)
+	require.Equal(t, ledger.BatchTx{
+		TxHash:            first,
+		Kind:              BatchKindCTC,
+		BlockNumber:       10,
+		GasUsed:           100_000,
+		EffectiveGasPrice: big.NewInt(3),
+		Cost:              big.NewInt(300_000),
+	}, batches[0])
+
+	// A missing receipt fails the whole range instead of paying nothing
+	logs = append(logs, ethtypes.Log{TxHash: common.HexToHash("0x03")})
+	_, _, err = ob.CalculateCost(BatchKindCTC, logs)
+	require.Error(t, err)
+}
+
+func TestEffectiveGasPrice(t *testing.T) {
+	legacy := ethtypes.NewTx(&ethtypes.LegacyTx{GasPrice: big.NewInt(10)})
+	price, err := effectiveGasPrice(legacy, nil)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10), price)
+
+	dynamic := ethtypes.NewTx(&ethtypes.DynamicFeeTx{GasFeeCap: big.NewInt(10), GasTipCap: big.NewInt(2)})
+	price, err = effectiveGasPrice(dynamic, big.NewInt(5))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(7), price)
+
+	// The tip is capped by the fee cap
+	price, err = effectiveGasPrice(dynamic, big.NewInt(9))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10), price)
+}
+
+func TestReconcile(t *testing.T) {
+	costs := map[[2]uint64]*big.Int{
+		{100, 109}: big.NewInt(1000),
+		{110, 119}: big.NewInt(500),
+		{120, 129}: big.NewInt(700),
+		{130, 139}: big.NewInt(0),
+		{140, 150}: big.NewInt(300),
+	}
+	costFn := func(from, to uint64) (*big.Int, error) {
+		cost, ok := costs[[2]uint64{from, to}]
+		if !ok {
+			return nil, errors.New("unexpected range")
+		}
+		return cost, nil
+	}
+	payouts := []ledger.Payout{
+		{FromBlock: 100, ToBlock: 109, Amount: big.NewInt(1000), PayTxHash: "0x1"},
+		{FromBlock: 110, ToBlock: 119, Amount: big.NewInt(400), PayTxHash: "0x2"},
+		{FromBlock: 130, ToBlock: 139, Amount: big.NewInt(0)},
+	}
+	reports, err := reconcile(payouts, 100, 150, costFn)
+	require.NoError(t, err)
+
+	statuses := make([]string, len(reports))
+	for i, report := range reports {
+		statuses[i] = report.Status
+	}
+	require.Equal(t, []string{RangeOK, RangeUnderpaid, RangeUnrecorded, RangeOK, RangeUnrecorded}, statuses)
+	require.Equal(t, big.NewInt(-100), reports[1].Difference)
+	require.Equal(t, uint64(120), reports[2].FromBlock)
+	require.Equal(t, uint64(129), reports[2].ToBlock)
+	require.Equal(t, big.NewInt(-300), reports[4].Difference)
+
+	payouts[1].Status = ledger.PayoutBroadcast
+	reports, err = reconcile(payouts[1:2], 110, 119, costFn)
+	require.NoError(t, err)
+	require.Equal(t, RangePending, reports[0].Status)
+
+	payouts[0].Amount = big.NewInt(1200)
+	reports, err = reconcile(payouts[:1], 100, 109, costFn)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, RangeOverpaid, reports[0].Status)
+}
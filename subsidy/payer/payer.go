@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"path"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -13,15 +14,28 @@ import (
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mantlenetworkio/mantle/subsidy/cache-file"
-	"github.com/mantlenetworkio/mantle/subsidy/types"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+)
+
+const (
+	// BatchKindSCC marks batch transactions of the StateCommitmentChain
+	BatchKindSCC = "scc"
+	// BatchKindCTC marks batch transactions of the CanonicalTransactionChain
+	BatchKindCTC = "ctc"
+	// maxBlockRange is the maximum number of blocks after the first one
+	// that are covered by a single payout or log query
+	maxBlockRange = 1000
 )
 
 type Payer struct {
 	ctx                       context.Context
 	config                    *Config
 	queryClient               *ethclient.Client
-	payClient                 *ethclient.Client
+	receipts                  receiptFetcher
+	ledger                    *ledger.Ledger
+	payClient                 payBackend
 	payerStateFileWriter      *cache_file.PayerStateFileWriter
 	l1QueryEpochLengthSeconds uint64
 	waitForReceipt            bool
@@ -34,18 +48,42 @@ type Payer struct {
 }
 
 func NewPayer(cfg *Config) *Payer {
-	queryClient, err := ethclient.Dial(cfg.queryerHttpUrl)
+	ob, err := newPayer(cfg, ledger.Open)
 	if err != nil {
 		panic(err)
 	}
+	return ob
+}
+
+// NewReconcilePayer creates a Payer that only reads the ledger, for the
+// reconcile command. It fails with ledger.ErrLocked while the service runs
+// on the same ledger.
+func NewReconcilePayer(cfg *Config) (*Payer, error) {
+	return newPayer(cfg, ledger.OpenReadOnly)
+}
+
+func newPayer(cfg *Config, openLedger func(string) (*ledger.Ledger, error)) (*Payer, error) {
+	l, err := openLedger(path.Join(cfg.HomeDir, cfg.LedgerDir))
+	if err != nil {
+		return nil, err
+	}
+	queryRPC, err := rpc.Dial(cfg.queryerHttpUrl)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	queryClient := ethclient.NewClient(queryRPC)
 	payClient, err := ethclient.Dial(cfg.payerHttpUrl)
 	if err != nil {
-		panic(err)
+		l.Close()
+		return nil, err
 	}
 	state := cache_file.NewPayerStateFileWriter(cfg.HomeDir, cfg.CacheDir, cfg.FileName)
 	return &Payer{
 		payClient:                 payClient,
 		queryClient:               queryClient,
+		receipts:                  &rpcReceiptFetcher{rpc: queryRPC, client: queryClient},
+		ledger:                    l,
 		config:                    cfg,
 		l1QueryEpochLengthSeconds: cfg.l1QueryEpochLengthSeconds,
 		ctx:                       context.Background(),
@@ -57,7 +95,7 @@ func NewPayer(cfg *Config) *Payer {
 		waitForReceipt:            cfg.waitForReceipt,
 		stop:                      make(chan struct{}),
 		receiveAddress:            cfg.receiverAddr,
-	}
+	}, nil
 }
 
 func (ob *Payer) getLogs(address common.Address, topic string, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
@@ -70,66 +108,89 @@ func (ob *Payer) getLogs(address common.Address, topic string, fromBlock, toBloc
 	return ob.queryClient.FilterLogs(context.Background(), filter)
 }
 
-// PayRollupCost from block
+// PayRollupCost pays for the batches appended since the last payout, up to
+// the confirmation depth below the L1 tip. A payout that is not settled yet
+// is driven forward instead, so that there is at most one payout in flight.
 func (ob *Payer) PayRollupCost() error {
-	endBlock := ob.EndBlock()
-	fromBlock := endBlock + 1
+	pending, err := ob.ledger.LatestPayout()
+	if err != nil {
+		return err
+	}
+	if pending != nil && pending.Pending() {
+		return ob.settlePayout(pending)
+	}
+
+	fromBlock := ob.EndBlock() + 1
 	tip, err := ob.queryClient.HeaderByNumber(context.Background(), nil)
 	if err != nil {
 		return err
 	}
-	toBlock := tip.Number.Uint64()
+	if tip.Number.Uint64() < ob.config.ConfirmationDepth {
+		return nil
+	}
+	toBlock := tip.Number.Uint64() - ob.config.ConfirmationDepth
 	if fromBlock > toBlock {
-		log.Info(fmt.Sprintf("to:%v less than from:%v,no new block\n", toBlock, fromBlock))
+		log.Info(fmt.Sprintf("to:%v less than from:%v,no new confirmed block\n", toBlock, fromBlock))
 		return nil
 	}
-	if toBlock-fromBlock > 1000 {
-		toBlock = fromBlock + 1000
+	if toBlock-fromBlock > maxBlockRange {
+		toBlock = fromBlock + maxBlockRange
 	}
-	totalFee := big.NewInt(0)
-	sccLogs, err := ob.getLogs(ob.sccAddrStr, ob.sccTopic, fromBlock, toBlock)
+	totalFee, batches, err := ob.RangeCost(fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
-	ctcLogs, err := ob.getLogs(ob.ctcAddrStr, ob.ctcTopic, fromBlock, toBlock)
-	if err != nil {
-		return err
+	payout := &ledger.Payout{
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+		Amount:    totalFee,
+		Time:      time.Now(),
 	}
-	totalFee = totalFee.Add(ob.CalculateCost(sccLogs), ob.CalculateCost(ctcLogs))
-	var hash string
-	if totalFee.Cmp(big.NewInt(0)) == 0 {
+	if totalFee.Sign() == 0 {
 		log.Info(fmt.Sprintf("block height form %v to %v totalFee is zero", fromBlock, toBlock))
-	} else {
-		hash, err = ob.Transfer(totalFee)
-		if err != nil {
+		payout.Status = ledger.PayoutConfirmed
+		if err := ob.ledger.RecordPayout(payout, batches); err != nil {
 			return err
 		}
+		ob.writeState(payout)
+		return nil
 	}
-	log.Info(fmt.Sprintf("block height form %v to %v,amount:%v,transfer hash:%v,reveiver:%v", fromBlock, toBlock, totalFee, hash, ob.config.receiverAddr))
-	payerState := types.PayerState{
-		LastPayTime: time.Now(),
-		EndBlock:    toBlock,
-		PayTxHash:   hash,
+
+	// Record the signed transaction before it is broadcast. After a crash
+	// the same transaction is broadcast again, so the range cannot be
+	// paid twice.
+	tx, err := ob.signTransfer(totalFee)
+	if err != nil {
+		return err
 	}
-	if err := ob.payerStateFileWriter.Write(&payerState); err != nil {
-		panic(err)
+	rawTx, err := tx.MarshalBinary()
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-func (ob *Payer) CalculateCost(logs []ethtypes.Log) *big.Int {
-	totalFee := big.NewInt(0)
-	for _, l := range logs {
-		tx, _, err := ob.queryClient.TransactionByHash(context.Background(), l.TxHash)
-		if err != nil {
-			return big.NewInt(0)
-		}
-		totalFee = totalFee.Add(totalFee, tx.Cost())
+	payout.Status = ledger.PayoutIntent
+	payout.Nonce = tx.Nonce()
+	payout.PayTxHash = tx.Hash().Hex()
+	payout.RawTx = rawTx
+	if err := ob.ledger.RecordPayout(payout, batches); err != nil {
+		return err
 	}
-	return totalFee
+	log.Info(fmt.Sprintf("block height form %v to %v,amount:%v,transfer hash:%v,nonce:%v,reveiver:%v", fromBlock, toBlock, totalFee, payout.PayTxHash, payout.Nonce, ob.config.receiverAddr))
+	return ob.settlePayout(payout)
 }
 
+// EndBlock returns the last block covered by a payout. A payout that is
+// still in flight covers its range, a dropped payout does not.
 func (ob *Payer) EndBlock() uint64 {
+	latest, err := ob.ledger.LatestPayout()
+	if err != nil {
+		log.Error("cannot read latest payout", "message", err)
+	}
+	if latest != nil {
+		if latest.Status == ledger.PayoutDropped {
+			return latest.FromBlock - 1
+		}
+		return latest.ToBlock
+	}
 	endBlock := ob.payerStateFileWriter.LoadCache().EndBlock
 	if endBlock == 0 {
 		endBlock = ob.config.StartBlock
@@ -137,8 +198,11 @@ func (ob *Payer) EndBlock() uint64 {
 	return endBlock
 }
 
-// Start runs the Payer
+// Start recovers a payout left in flight by a previous run and runs the Payer
 func (ob *Payer) Start() error {
+	if err := ob.recoverPayout(); err != nil {
+		return err
+	}
 	ob.payLoop()
 	return nil
 }
@@ -147,22 +211,54 @@ func (ob *Payer) Stop() {
 	close(ob.stop)
 }
 
+// Close releases the ledger
+func (ob *Payer) Close() error {
+	return ob.ledger.Close()
+}
+
 func (ob *Payer) Wait() {
 	<-ob.stop
 }
 
+// Transfer sends amount to the receiver address right away
 func (ob *Payer) Transfer(amount *big.Int) (string, error) {
+	signedTx, err := ob.signTransfer(amount)
+	if err != nil {
+		return "", err
+	}
+	err = ob.payClient.SendTransaction(context.Background(), signedTx)
+	if err != nil {
+		log.Error("SendTransaction error:", err)
+		return "", err
+	}
+
+	log.Info(fmt.Sprintf("tx sent: %s", signedTx.Hash().Hex()))
+	if ob.waitForReceipt {
+		// Wait for the receipt
+		receipt, err := waitForReceipt(ob.payClient, signedTx)
+		if err != nil {
+			return signedTx.Hash().Hex(), err
+		}
+		log.Info("L1 transaction confirmed", "hash", signedTx.Hash().Hex(),
+			"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
+	}
+	return signedTx.Hash().Hex(), nil
+}
+
+// signTransfer signs a transfer of amount to the receiver address with the
+// next pending nonce of the payer
+func (ob *Payer) signTransfer(amount *big.Int) (*ethtypes.Transaction, error) {
 	senderAddr := ethcrypto.PubkeyToAddress(ob.config.privateKey.PublicKey)
 	nonce, err := ob.payClient.PendingNonceAt(context.Background(), senderAddr)
 	if err != nil {
 		log.Error("PendingNonceAt error:", err)
-		return "", err
+		return nil, err
 	}
 	gasLimit := uint64(21000) // in units
 	gasPrice, err := ob.payClient.SuggestGasPrice(context.Background())
 	if err != nil {
 		log.Error("SuggestGasPrice error:", err)
-		return "", err
+		return nil, err
 	}
 	baseTx := &ethtypes.LegacyTx{
 		To:       &ob.config.receiverAddr,
@@ -173,33 +269,17 @@ func (ob *Payer) Transfer(amount *big.Int) (string, error) {
 		Data:     nil,
 	}
 	tx := ethtypes.NewTx(baseTx)
-	chainID, err := ob.payClient.NetworkID(context.Background())
+	chainID, err := ob.payClient.ChainID(context.Background())
 	if err != nil {
-		log.Error("payClient.NetworkID error:", err)
-		return "", err
+		log.Error("payClient.ChainID error:", err)
+		return nil, err
 	}
 	signedTx, err := ethtypes.SignTx(tx, ethtypes.NewLondonSigner(chainID), ob.config.privateKey)
 	if err != nil {
 		log.Error("ethtypes.SignTx error:", err)
-		return "", err
-	}
-	err = ob.payClient.SendTransaction(context.Background(), signedTx)
-	if err != nil {
-		log.Error("SendTransaction error:", err)
-		return "", err
+		return nil, err
 	}
-
-	log.Info(fmt.Sprintf("tx sent: %s", signedTx.Hash().Hex()))
-	if ob.waitForReceipt {
-		// Wait for the receipt
-		receipt, err := waitForReceipt(ob.payClient, tx)
-		if err != nil {
-			return signedTx.Hash().Hex(), err
-		}
-		log.Info("L1 transaction confirmed", "hash", tx.Hash().Hex(),
-			"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
-	}
-	return signedTx.Hash().Hex(), nil
+	return signedTx, nil
 }
 
 func (ob *Payer) payLoop() {
@@ -219,7 +299,7 @@ func (ob *Payer) payLoop() {
 	}
 }
 
-func waitForReceipt(backend *ethclient.Client, tx *ethtypes.Transaction) (*ethtypes.Receipt, error) {
+func waitForReceipt(backend payBackend, tx *ethtypes.Transaction) (*ethtypes.Receipt, error) {
 	t := time.NewTicker(300 * time.Millisecond)
 	receipt := new(ethtypes.Receipt)
 	var err error
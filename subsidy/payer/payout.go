@@ -0,0 +1,135 @@
+package payer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+	"github.com/mantlenetworkio/mantle/subsidy/types"
+)
+
+// payBackend is the part of the L1 client used to send and track payouts
+type payBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *ethtypes.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error)
+}
+
+// recoverPayout settles a payout that a previous run recorded but did not
+// see confirmed, so that its range is not paid again
+func (ob *Payer) recoverPayout() error {
+	pending, err := ob.ledger.LatestPayout()
+	if err != nil {
+		return err
+	}
+	if pending == nil || !pending.Pending() {
+		return nil
+	}
+	log.Warn("recovering payout left in flight", "from", pending.FromBlock, "to", pending.ToBlock,
+		"status", pending.Status, "hash", pending.PayTxHash, "nonce", pending.Nonce)
+	return ob.settlePayout(pending)
+}
+
+// settlePayout moves a payout forward. A payout whose transaction is
+// included is confirmed once it is ConfirmationDepth blocks deep. A payout
+// whose transaction is not included is broadcast again with the same hash,
+// unless its nonce was used by another transaction, in which case it is
+// dropped and its range is paid again. A reorg that removes the transaction
+// makes it not included again.
+func (ob *Payer) settlePayout(payout *ledger.Payout) error {
+	ctx := context.Background()
+	txHash := common.HexToHash(payout.PayTxHash)
+
+	// Read the nonce before the receipt. A transaction included in
+	// between is then seen as included rather than as dropped.
+	sender := ethcrypto.PubkeyToAddress(ob.config.privateKey.PublicKey)
+	nonce, err := ob.payClient.NonceAt(ctx, sender, nil)
+	if err != nil {
+		return err
+	}
+	receipt, err := ob.payClient.TransactionReceipt(ctx, txHash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return err
+	}
+
+	if receipt != nil {
+		if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+			log.Error("payout transaction failed", "hash", payout.PayTxHash, "from", payout.FromBlock, "to", payout.ToBlock)
+			return ob.setPayoutStatus(payout, ledger.PayoutDropped)
+		}
+		tip, err := ob.payClient.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if receipt.BlockNumber.Uint64()+ob.config.ConfirmationDepth > tip.Number.Uint64() {
+			log.Info("waiting for payout confirmation", "hash", payout.PayTxHash,
+				"included", receipt.BlockNumber, "tip", tip.Number, "depth", ob.config.ConfirmationDepth)
+			if payout.Status == ledger.PayoutIntent {
+				return ob.setPayoutStatus(payout, ledger.PayoutBroadcast)
+			}
+			return nil
+		}
+		log.Info("L1 transaction confirmed", "hash", payout.PayTxHash,
+			"gas-used", receipt.GasUsed, "blocknumber", receipt.BlockNumber)
+		if err := ob.setPayoutStatus(payout, ledger.PayoutConfirmed); err != nil {
+			return err
+		}
+		ob.writeState(payout)
+		return nil
+	}
+
+	if nonce > payout.Nonce {
+		log.Warn("payout nonce used by another transaction, paying range again", "hash", payout.PayTxHash,
+			"nonce", payout.Nonce, "from", payout.FromBlock, "to", payout.ToBlock)
+		return ob.setPayoutStatus(payout, ledger.PayoutDropped)
+	}
+
+	tx := new(ethtypes.Transaction)
+	if err := tx.UnmarshalBinary(payout.RawTx); err != nil {
+		return err
+	}
+	if err := ob.payClient.SendTransaction(ctx, tx); err != nil && !isKnownTransaction(err) {
+		return err
+	}
+	if payout.Status == ledger.PayoutIntent {
+		log.Info("payout broadcast", "hash", payout.PayTxHash, "nonce", payout.Nonce)
+		return ob.setPayoutStatus(payout, ledger.PayoutBroadcast)
+	}
+	return nil
+}
+
+func (ob *Payer) setPayoutStatus(payout *ledger.Payout, status string) error {
+	payout.Status = status
+	return ob.ledger.UpdatePayout(payout)
+}
+
+// writeState keeps the state file in step with the ledger. The ledger is
+// the source of truth, so a failure is only logged.
+func (ob *Payer) writeState(payout *ledger.Payout) {
+	payerState := types.PayerState{
+		LastPayTime: payout.Time,
+		EndBlock:    payout.ToBlock,
+		PayTxHash:   payout.PayTxHash,
+	}
+	if err := ob.payerStateFileWriter.Write(&payerState); err != nil {
+		log.Error("cannot write payer state", "message", err)
+	}
+}
+
+// isKnownTransaction returns true when a node rejects a transaction
+// because it already has it
+func isKnownTransaction(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
@@ -0,0 +1,174 @@
+package payer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	cache_file "github.com/mantlenetworkio/mantle/subsidy/cache-file"
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPayBackend is an L1 where transactions are included on demand
+type mockPayBackend struct {
+	tip      uint64
+	nonce    uint64
+	sent     []*ethtypes.Transaction
+	receipts map[common.Hash]*ethtypes.Receipt
+}
+
+func (m *mockPayBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(5), nil
+}
+
+func (m *mockPayBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*ethtypes.Header, error) {
+	return &ethtypes.Header{Number: new(big.Int).SetUint64(m.tip)}, nil
+}
+
+func (m *mockPayBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return m.nonce, nil
+}
+
+func (m *mockPayBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return m.nonce, nil
+}
+
+func (m *mockPayBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (m *mockPayBackend) SendTransaction(ctx context.Context, tx *ethtypes.Transaction) error {
+	for _, sent := range m.sent {
+		if sent.Hash() == tx.Hash() {
+			return errors.New("already known")
+		}
+	}
+	m.sent = append(m.sent, tx)
+	return nil
+}
+
+func (m *mockPayBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*ethtypes.Receipt, error) {
+	receipt, ok := m.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+// include mines a transaction in the tip block
+func (m *mockPayBackend) include(tx *ethtypes.Transaction) {
+	m.nonce = tx.Nonce() + 1
+	m.receipts[tx.Hash()] = &ethtypes.Receipt{
+		Status:      ethtypes.ReceiptStatusSuccessful,
+		BlockNumber: new(big.Int).SetUint64(m.tip),
+	}
+}
+
+func newTestPayer(t *testing.T, backend *mockPayBackend) *Payer {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	l, err := ledger.Open(filepath.Join(dir, "ledger"))
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return &Payer{
+		config: &Config{
+			privateKey:        key,
+			receiverAddr:      common.HexToAddress("0x01"),
+			ConfirmationDepth: 2,
+			StartBlock:        99,
+		},
+		payClient:            backend,
+		ledger:               l,
+		payerStateFileWriter: cache_file.NewPayerStateFileWriter(dir, "cache", "state"),
+	}
+}
+
+func recordIntent(t *testing.T, ob *Payer) *ledger.Payout {
+	tx, err := ob.signTransfer(big.NewInt(1000))
+	require.NoError(t, err)
+	rawTx, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	payout := &ledger.Payout{
+		FromBlock: 100,
+		ToBlock:   110,
+		Amount:    big.NewInt(1000),
+		Status:    ledger.PayoutIntent,
+		Nonce:     tx.Nonce(),
+		PayTxHash: tx.Hash().Hex(),
+		RawTx:     rawTx,
+	}
+	require.NoError(t, ob.ledger.RecordPayout(payout, nil))
+	return payout
+}
+
+func latestPayout(t *testing.T, ob *Payer) *ledger.Payout {
+	payout, err := ob.ledger.LatestPayout()
+	require.NoError(t, err)
+	return payout
+}
+
+func TestSettlePayoutConfirms(t *testing.T) {
+	backend := &mockPayBackend{tip: 50, nonce: 3, receipts: make(map[common.Hash]*ethtypes.Receipt)}
+	ob := newTestPayer(t, backend)
+	payout := recordIntent(t, ob)
+
+	// The intent is broadcast
+	require.NoError(t, ob.settlePayout(payout))
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, ledger.PayoutBroadcast, latestPayout(t, ob).Status)
+	require.Equal(t, uint64(110), ob.EndBlock())
+
+	// Broadcasting again is harmless
+	require.NoError(t, ob.settlePayout(payout))
+	require.Len(t, backend.sent, 1)
+
+	// It is not confirmed until it is deep enough
+	backend.include(backend.sent[0])
+	backend.tip++
+	require.NoError(t, ob.settlePayout(payout))
+	require.Equal(t, ledger.PayoutBroadcast, latestPayout(t, ob).Status)
+
+	backend.tip++
+	require.NoError(t, ob.settlePayout(payout))
+	require.Equal(t, ledger.PayoutConfirmed, latestPayout(t, ob).Status)
+	require.Equal(t, uint64(110), ob.payerStateFileWriter.LoadCache().EndBlock)
+}
+
+func TestRecoverPayoutAlreadyBroadcast(t *testing.T) {
+	backend := &mockPayBackend{tip: 50, nonce: 3, receipts: make(map[common.Hash]*ethtypes.Receipt)}
+	ob := newTestPayer(t, backend)
+	payout := recordIntent(t, ob)
+
+	// The transaction was broadcast and included before a crash, but the
+	// ledger still has the intent
+	tx := new(ethtypes.Transaction)
+	require.NoError(t, tx.UnmarshalBinary(payout.RawTx))
+	backend.sent = append(backend.sent, tx)
+	backend.include(tx)
+	backend.tip += 2
+
+	require.NoError(t, ob.recoverPayout())
+	require.Len(t, backend.sent, 1)
+	require.Equal(t, ledger.PayoutConfirmed, latestPayout(t, ob).Status)
+}
+
+func TestSettlePayoutDropsReplacedNonce(t *testing.T) {
+	backend := &mockPayBackend{tip: 50, nonce: 3, receipts: make(map[common.Hash]*ethtypes.Receipt)}
+	ob := newTestPayer(t, backend)
+	payout := recordIntent(t, ob)
+
+	// Another transaction used the nonce of the payout
+	backend.nonce++
+	require.NoError(t, ob.settlePayout(payout))
+	require.Empty(t, backend.sent)
+	require.Equal(t, ledger.PayoutDropped, latestPayout(t, ob).Status)
+	require.Equal(t, uint64(99), ob.EndBlock())
+}
@@ -0,0 +1,111 @@
+package payer
+
+import (
+	"math/big"
+
+	"github.com/mantlenetworkio/mantle/subsidy/ledger"
+)
+
+const (
+	// RangeOK is a range that was paid exactly what it cost
+	RangeOK = "ok"
+	// RangeUnderpaid is a range that was paid less than it cost
+	RangeUnderpaid = "underpaid"
+	// RangeOverpaid is a range that was paid more than it cost
+	RangeOverpaid = "overpaid"
+	// RangeUnrecorded is a range that has no payout in the ledger
+	RangeUnrecorded = "unrecorded"
+	// RangePending is a range whose payout is not confirmed yet
+	RangePending = "pending"
+)
+
+// RangeReport compares what was paid for a block range with what the
+// batches in it actually cost. Difference is paid minus cost.
+type RangeReport struct {
+	FromBlock  uint64
+	ToBlock    uint64
+	PayTxHash  string
+	Paid       *big.Int
+	Cost       *big.Int
+	Difference *big.Int
+	Status     string
+}
+
+// Reconcile compares every payout of the ledger within the inclusive block
+// range with the cost worked out again from the chain. Gaps between payouts
+// are reported as unrecorded along with their cost.
+func (ob *Payer) Reconcile(fromBlock, toBlock uint64) ([]RangeReport, error) {
+	payouts, err := ob.ledger.Payouts(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return reconcile(payouts, fromBlock, toBlock, func(from, to uint64) (*big.Int, error) {
+		cost, _, err := ob.RangeCost(from, to)
+		return cost, err
+	})
+}
+
+func reconcile(payouts []ledger.Payout, fromBlock, toBlock uint64, costFn func(from, to uint64) (*big.Int, error)) ([]RangeReport, error) {
+	var reports []RangeReport
+	next := fromBlock
+	for _, payout := range payouts {
+		if payout.FromBlock > next {
+			report, err := unrecordedRange(next, payout.FromBlock-1, costFn)
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, *report)
+		}
+		cost, err := costFn(payout.FromBlock, payout.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		paid := payout.Amount
+		if paid == nil || payout.Status == ledger.PayoutDropped {
+			paid = new(big.Int)
+		}
+		report := RangeReport{
+			FromBlock:  payout.FromBlock,
+			ToBlock:    payout.ToBlock,
+			PayTxHash:  payout.PayTxHash,
+			Paid:       paid,
+			Cost:       cost,
+			Difference: new(big.Int).Sub(paid, cost),
+		}
+		switch {
+		case payout.Pending():
+			report.Status = RangePending
+		case report.Difference.Sign() < 0:
+			report.Status = RangeUnderpaid
+		case report.Difference.Sign() > 0:
+			report.Status = RangeOverpaid
+		default:
+			report.Status = RangeOK
+		}
+		reports = append(reports, report)
+		next = payout.ToBlock + 1
+	}
+	if next <= toBlock {
+		report, err := unrecordedRange(next, toBlock, costFn)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+func unrecordedRange(fromBlock, toBlock uint64, costFn func(from, to uint64) (*big.Int, error)) (*RangeReport, error) {
+	cost, err := costFn(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return &RangeReport{
+		FromBlock:  fromBlock,
+		ToBlock:    toBlock,
+		Paid:       new(big.Int),
+		Cost:       cost,
+		Difference: new(big.Int).Neg(cost),
+		Status:     RangeUnrecorded,
+	}, nil
+}
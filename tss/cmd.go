@@ -35,8 +35,11 @@ func main() {
 
 	rootCmd.AddCommand(
 		manager.Command(),
+		manager.QueryCommand(),
 		tssnode.Command(),
 		tssnode.PeerIDCommand(),
+		tssnode.RotateKeystoreCommand(),
+		tssnode.RecoverShareCommand(),
 	)
 
 	rootCmd.PersistentFlags().StringP("config", "c", "config", "configuration file with extension")
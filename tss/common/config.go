@@ -16,6 +16,7 @@ type Configuration struct {
 	Node                           NodeConfig    `json:"node"`
 	L1Url                          string        `json:"l1_url" mapstructure:"l1_url"`
 	SccContractAddress             string        `json:"scc_contract_address" mapstructure:"scc_contract_address"`
+	CtcContractAddress             string        `json:"ctc_contract_address" mapstructure:"ctc_contract_address"`
 	TssGroupContractAddress        string        `json:"tss_group_contract_address" mapstructure:"tss_group_contract_address"`
 	TssStakingSlashContractAddress string        `json:"tss_staking_slash_contract_address" mapstructure:"tss_staking_slash_contract_address"`
 	TimedTaskInterval              string        `json:"timed_task_interval" mapstructure:"timed_task_interval"`
@@ -34,6 +35,25 @@ type ManagerConfig struct {
 	CPKConfirmTimeout string `json:"cpk_confirm_timeout" mapstructure:"cpk_confirm_timeout"`
 	AskTimeout        string `json:"ask_timeout" mapstructure:"ask_timeout"`
 	SignTimeout       string `json:"sign_timeout" mapstructure:"sign_timeout"`
+	DisableReshare    bool   `json:"disable_reshare" mapstructure:"disable_reshare"`
+
+	MemberCheckInterval string      `json:"member_check_interval" mapstructure:"member_check_interval"`
+	WsTLS               WsTLSConfig `json:"ws_tls" mapstructure:"ws_tls"`
+
+	Election ElectionConfig `json:"election" mapstructure:"election"`
+}
+
+// ElectionConfig runs the manager as one of several active/standby replicas,
+// only the elected leader serves the nodes and the batch submitter. Backend is
+// "file" for a lock on LockFile or "redis" for a lease on RedisKey, empty for a
+// single manager.
+type ElectionConfig struct {
+	Backend   string `json:"backend" mapstructure:"backend"`
+	LockFile  string `json:"lock_file" mapstructure:"lock_file"`
+	RedisUrl  string `json:"redis_url" mapstructure:"redis_url"`
+	RedisKey  string `json:"redis_key" mapstructure:"redis_key"`
+	LeaseTTL  string `json:"lease_ttl" mapstructure:"lease_ttl"`
+	RetryWait string `json:"retry_wait" mapstructure:"retry_wait"`
 }
 
 type NodeConfig struct {
@@ -44,6 +64,10 @@ type NodeConfig struct {
 	DisableHTTP2 bool   `json:"disable_http2" mapstructure:"disable_http2"`
 	PrivateKey   string `json:"private_key" mapstructure:"private_key"`
 
+	L2EthRpcs      string `json:"l2_eth_rpcs" mapstructure:"l2_eth_rpcs"`
+	L2VerifyQuorum int    `json:"l2_verify_quorum" mapstructure:"l2_verify_quorum"`
+	VerifyCtcQueue bool   `json:"verify_ctc_queue" mapstructure:"verify_ctc_queue"`
+
 	PreParamFile    string        `json:"pre_param_file" mapstructure:"pre_param_file"`
 	P2PPort         string        `json:"p2p_port" mapstructure:"p2p_port"`
 	BootstrapPeers  string        `json:"bootstrap_peers" mapstructure:"bootstrap_peers"`
@@ -52,8 +76,32 @@ type NodeConfig struct {
 	KeySignTimeout  time.Duration `json:"key_sign_timeout" mapstructure:"key_sign_timeout"`
 	PreParamTimeout time.Duration `json:"pre_param_timeout" mapstructure:"pre_param_timeout"`
 
-	Secrets SecretsManagerConfig `json:"secrets" mapstructure:"secrets"`
-	Shamir  ShamirConfig         `json:"shamir" mapstructure:"shamir"`
+	PreParamsPoolSize    int           `json:"pre_params_pool_size" mapstructure:"pre_params_pool_size"`
+	PreParamsPoolTimeout time.Duration `json:"pre_params_pool_timeout" mapstructure:"pre_params_pool_timeout"`
+
+	Secrets  SecretsManagerConfig `json:"secrets" mapstructure:"secrets"`
+	Shamir   ShamirConfig         `json:"shamir" mapstructure:"shamir"`
+	Keystore KeystoreConfig       `json:"keystore" mapstructure:"keystore"`
+	WsTLS    WsTLSConfig          `json:"ws_tls" mapstructure:"ws_tls"`
+}
+
+// WsTLSConfig enables mutual TLS on the websocket between the manager and the
+// nodes. Each side presents CertFile and verifies the other against CAFile.
+type WsTLSConfig struct {
+	Enable   bool   `json:"enable" mapstructure:"enable"`
+	CertFile string `json:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `json:"key_file" mapstructure:"key_file"`
+	CAFile   string `json:"ca_file" mapstructure:"ca_file"`
+}
+
+// KeystoreConfig encrypts the local key shares with a key derived from the
+// passphrase. The local key shares are stored in plaintext when the
+// passphrase is empty. KeyVersion is increased by every rotation of the
+// passphrase.
+type KeystoreConfig struct {
+	Passphrase string `json:"-" mapstructure:"passphrase"`
+	ScryptN    int    `json:"scrypt_n" mapstructure:"scrypt_n"`
+	KeyVersion int    `json:"key_version" mapstructure:"key_version"`
 }
 
 type SecretsManagerConfig struct {
@@ -61,12 +109,22 @@ type SecretsManagerConfig struct {
 	SecretId string `json:"secret_id" mapstructure:"secret_id"`
 }
 
+// ShamirConfig splits the local key shares into N Shamir shares, K of which
+// rebuild the local key share. The shares are spread over the S3 buckets,
+// the Secrets Manager secrets and the local directories, none of which may
+// hold K shares. Their data key is
+// wrapped by KMS when a KMS key is set, or by a key derived from the
+// passphrase otherwise.
 type ShamirConfig struct {
-	Enable bool      `json:"enable" mapstructure:"enable"`
-	Kms    KmsConfig `json:"kms" mapstructure:"kms"`
-	S3     S3Config  `json:"s3" mapstructure:"s3"`
-	Sm     SMConfig  `json:"sm" mapstructure:"sm"`
-	Xor    string    `json:"xor" mapstructure:"xor"`
+	Enable     bool      `json:"enable" mapstructure:"enable"`
+	N          int       `json:"n" mapstructure:"n"`
+	K          int       `json:"k" mapstructure:"k"`
+	Kms        KmsConfig `json:"kms" mapstructure:"kms"`
+	S3         S3Config  `json:"s3" mapstructure:"s3"`
+	Sm         SMConfig  `json:"sm" mapstructure:"sm"`
+	LocalDirs  string    `json:"local_dirs" mapstructure:"local_dirs"`
+	Passphrase string    `json:"-" mapstructure:"passphrase"`
+	Xor        string    `json:"xor" mapstructure:"xor"`
 }
 
 type KmsConfig struct {
@@ -79,6 +137,9 @@ type S3Config struct {
 	Region  string     `json:"region" mapstructure:"region"`
 	Buckets string     `json:"buckets" mapstructure:"buckets"`
 	Aksk    AKSKConfig `json:"aksk" mapstructure:"aksk"`
+	// Endpoint of an S3 compatible service, such as MinIO
+	Endpoint       string `json:"endpoint" mapstructure:"endpoint"`
+	ForcePathStyle bool   `json:"force_path_style" mapstructure:"force_path_style"`
 }
 
 type SMConfig struct {
@@ -105,12 +166,21 @@ func DefaultConfiguration() Configuration {
 			CPKConfirmTimeout: "2h",
 			AskTimeout:        "60s",
 			SignTimeout:       "60s",
+
+			MemberCheckInterval: "1m",
+			Election: ElectionConfig{
+				RedisKey:  "tss-manager-leader",
+				LeaseTTL:  "15s",
+				RetryWait: "1s",
+			},
 		},
 		Node: NodeConfig{
 			P2PPort:         "8000",
 			KeyGenTimeout:   10 * time.Second,
 			KeySignTimeout:  10 * time.Second,
 			PreParamTimeout: 5 * time.Minute,
+
+			PreParamsPoolTimeout: 30 * time.Minute,
 		},
 	}
 }
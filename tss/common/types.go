@@ -15,6 +15,9 @@ const (
 	AskSlash       Method = "askSlash"
 	SignSlash      Method = "signSlash"
 	SignRollBack   Method = "signRollBack"
+	Reshare        Method = "reshare"
+	AskTxBatch     Method = "askTxBatch"
+	SignTxBatch    Method = "signTxBatch"
 
 	SlashTypeLiveness byte = 1
 	SlashTypeCulprit  byte = 2
@@ -41,6 +44,43 @@ func (ssr SignStateRequest) String() string {
 	return fmt.Sprintf("start_block: %s, offset_starts_at_index: %s, election_id: %d, state_roots: %s", ssr.StartBlock, ssr.OffsetStartsAtIndex, ssr.ElectionId, srs)
 }
 
+// SignTxBatchRequest describes the elements appended to the CTC by an
+// appendSequencerBatch call. StartBlock is the L2 block of the first element
+// and TxHashes are the hashes of the lone transactions of the consecutive L2
+// blocks, queued transactions included.
+type SignTxBatchRequest struct {
+	StartBlock           string     `json:"start_block"`
+	ShouldStartAtElement string     `json:"should_start_at_element"`
+	TxHashes             [][32]byte `json:"tx_hashes"`
+	ElectionId           uint64     `json:"election_id"`
+}
+
+func (str SignTxBatchRequest) String() string {
+	var hashes string
+	for _, h := range str.TxHashes {
+		hashes = hashes + hex.EncodeToString(h[:]) + " "
+	}
+	return fmt.Sprintf("start_block: %s, should_start_at_element: %s, election_id: %d, tx_hashes: %s", str.StartBlock, str.ShouldStartAtElement, str.ElectionId, hashes)
+}
+
+// Range parses the start block and the first element of the batch. The L2
+// block n holds the CTC element n-1, so the start block must be the element
+// plus one.
+func (str SignTxBatchRequest) Range() (startBlock *big.Int, shouldStartAtElement *big.Int, err error) {
+	startBlock, ok := new(big.Int).SetString(str.StartBlock, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("wrong StartBlock %q, can not be converted to number", str.StartBlock)
+	}
+	shouldStartAtElement, ok = new(big.Int).SetString(str.ShouldStartAtElement, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("wrong ShouldStartAtElement %q, can not be converted to number", str.ShouldStartAtElement)
+	}
+	if new(big.Int).Sub(startBlock, shouldStartAtElement).Cmp(big.NewInt(1)) != 0 {
+		return nil, nil, fmt.Errorf("start block %s does not follow should start at element %s", str.StartBlock, str.ShouldStartAtElement)
+	}
+	return startBlock, shouldStartAtElement, nil
+}
+
 type SlashRequest struct {
 	Address    common.Address `json:"address"`
 	BatchIndex uint64         `json:"batch_index"`
@@ -51,8 +91,28 @@ type RollBackRequest struct {
 	StartBlock string `json:"start_block"`
 }
 
+// VerifyReason tells why a node refused to approve an ask request.
+type VerifyReason string
+
+const (
+	ReasonInvalidRequest    VerifyReason = "invalid_request"
+	ReasonStateRootMismatch VerifyReason = "state_root_mismatch"
+	ReasonTxMismatch        VerifyReason = "tx_mismatch"
+	// the node could not come to a verdict, it is not a vote against the batch
+	ReasonNoQuorum      VerifyReason = "no_quorum"
+	ReasonL1Unavailable VerifyReason = "l1_unavailable"
+	ReasonL2Unavailable VerifyReason = "l2_unavailable"
+)
+
+// Inconclusive reports whether the node failed to verify the request rather
+// than finding it wrong.
+func (r VerifyReason) Inconclusive() bool {
+	return r == ReasonNoQuorum || r == ReasonL1Unavailable || r == ReasonL2Unavailable
+}
+
 type AskResponse struct {
-	Result bool `json:"result"`
+	Result bool         `json:"result"`
+	Reason VerifyReason `json:"reason,omitempty"`
 }
 
 type NodeSignRequest struct {
@@ -79,6 +139,24 @@ type KeygenResponse struct {
 	ClusterPublicKey string `json:"cluster_public_key"`
 }
 
+// ReshareRequest asks the old committee to hand the key of ClusterPublicKey
+// over to the new committee of ElectionId. KeyEpoch is the number of
+// resharings the key has been through.
+type ReshareRequest struct {
+	ClusterPublicKey string   `json:"cluster_public_key"`
+	ElectionId       uint64   `json:"election_id"`
+	OldNodes         []string `json:"old_nodes"`
+	OldThreshold     int      `json:"old_threshold"`
+	NewNodes         []string `json:"new_nodes"`
+	NewThreshold     int      `json:"new_threshold"`
+	KeyEpoch         int      `json:"key_epoch"`
+	Timestamp        int64    `json:"timestamp"`
+}
+
+type ReshareResponse struct {
+	ClusterPublicKey string `json:"cluster_public_key"`
+}
+
 type SignatureData struct {
 	// Ethereum-style recovery byte; only the first byte is relevant
 	SignatureRecovery []byte `json:"signature_recovery,omitempty"`
@@ -93,3 +171,19 @@ type BatchSubmitterResponse struct {
 	Signature []byte `json:"signature"`
 	RollBack  bool   `json:"roll_back"`
 }
+
+// WsChallenge is sent by the manager to a connecting node, which proves the
+// possession of its key by signing the nonce.
+type WsChallenge struct {
+	Nonce string `json:"nonce"`
+}
+
+type WsChallengeResponse struct {
+	PubKey    string `json:"pub_key"`
+	Signature string `json:"signature"`
+}
+
+type WsAuthResult struct {
+	Result bool   `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
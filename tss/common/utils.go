@@ -12,17 +12,23 @@ import (
 )
 
 var (
+	typByte32               abi.Type
 	typByte32Array          abi.Type
 	typUint256              abi.Type
 	typSlashMsg             abi.Type
 	typBytes                abi.Type
 	stateBatchArguments     abi.Arguments
+	txBatchArguments        abi.Arguments
 	slashMsgArguments       abi.Arguments
 	groupPublicKeyArguments abi.Arguments
 	slashArguments          abi.Arguments
 	rollBackArguments       abi.Arguments
 )
 
+// txBatchDomain separates the tx batch digest from the state batch digest,
+// whose encoding is otherwise the same.
+var txBatchDomain = crypto.Keccak256Hash([]byte("MANTLE_TX_BATCH"))
+
 type SlashMsg struct {
 	BatchIndex *big.Int
 	JailNode   common.Address
@@ -31,6 +37,7 @@ type SlashMsg struct {
 }
 
 func init() {
+	typByte32, _ = abi.NewType("bytes32", "bytes32", nil)
 	typByte32Array, _ = abi.NewType("bytes32[]", "bytes32[]", nil)
 	typUint256, _ = abi.NewType("uint256", "uint256", nil)
 	typBytes, _ = abi.NewType("bytes", "bytes", nil)
@@ -41,6 +48,15 @@ func init() {
 			Type: typUint256,
 		},
 	}
+	txBatchArguments = abi.Arguments{
+		{
+			Type: typByte32,
+		}, {
+			Type: typByte32Array,
+		}, {
+			Type: typUint256,
+		},
+	}
 
 	typSlashMsg, _ = abi.NewType(
 		"tuple", "",
@@ -97,6 +113,21 @@ func StateBatchHash(stateRoots [][32]byte, offsetStartsAtIndex *big.Int) ([]byte
 	return crypto.Keccak256Hash(abiEncodedRaw).Bytes(), nil
 }
 
+// TxBatchHash is the digest signed for an appendSequencerBatch call,
+// keccak256(abi.encode(keccak256("MANTLE_TX_BATCH"), txHashes, shouldStartAtElement)).
+func TxBatchHash(txHashes [][32]byte, shouldStartAtElement *big.Int) ([]byte, error) {
+	abiEncodedRaw, err := txBatchArguments.Pack([32]byte(txBatchDomain), txHashes, shouldStartAtElement)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256Hash(abiEncodedRaw).Bytes(), nil
+}
+
+// WsChallengeDigest is the digest a node signs to answer the WsChallenge.
+func WsChallengeDigest(nonce string) []byte {
+	return crypto.Keccak256Hash([]byte("tss-ws-auth:" + nonce)).Bytes()
+}
+
 func SlashMsgBytes(batchIndex uint64, jailNode common.Address, tssNodes []common.Address, slashType byte) ([]byte, error) {
 	return slashMsgArguments.Pack(SlashMsg{
 		SlashType:  new(big.Int).SetUint64(uint64(slashType)),
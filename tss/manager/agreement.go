@@ -76,6 +76,22 @@ func (m Manager) agreement(ctx types.Context, request interface{}, method tss.Me
 					}
 					continue
 				}
+				if !askResponse.Result && askResponse.Reason.Inconclusive() {
+					// the node failed to verify, it neither approves nor disapproves
+					log.Warn("node could not verify the request", "node", resp.SourceNode, "reason", askResponse.Reason)
+					errResp[resp.SourceNode] = struct{}{}
+					if len(errResp)+errSend > maxAllowedLostCount {
+						log.Error("maxAllowedLostCount exceed.")
+						return
+					}
+					if len(errResp)+len(results) == expectedResponseCount {
+						return
+					}
+					continue
+				}
+				if !askResponse.Result {
+					log.Warn("node disapproves the request", "node", resp.SourceNode, "reason", askResponse.Reason)
+				}
 				results[resp.SourceNode] = askResponse.Result
 				if len(errResp)+len(results) == expectedResponseCount {
 					return
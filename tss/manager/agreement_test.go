@@ -192,3 +192,33 @@ func TestTimeoutAgreement(t *testing.T) {
 	require.True(t, costTime.Seconds() >= manager.askTimeout.Seconds())
 	require.EqualValues(t, 3, len(ctx.Approvers()))
 }
+
+func TestInconclusiveAgreement(t *testing.T) {
+	// one can not verify, one disapproves, others return true
+	afterMsgSent := func(request server.RequestMsg, respCh chan server.ResponseMsg) error {
+		askResp := tss.AskResponse{
+			Result: true,
+		}
+		if request.TargetNode == "c" {
+			askResp = tss.AskResponse{Result: false, Reason: tss.ReasonNoQuorum}
+		} else if request.TargetNode == "d" {
+			askResp = tss.AskResponse{Result: false, Reason: tss.ReasonStateRootMismatch}
+		}
+		rpcResp := tmtypes.NewRPCSuccessResponse(request.RpcRequest.ID, askResp)
+		respCh <- server.ResponseMsg{
+			RpcResponse: rpcResp,
+			SourceNode:  request.TargetNode,
+		}
+		return nil
+	}
+	manager, request := setup(afterMsgSent, nil)
+	ctx := types.NewContext().
+		WithAvailableNodes([]string{"a", "b", "c", "d"}).
+		WithTssInfo(types.TssCommitteeInfo{
+			Threshold: 1,
+		})
+	ctx, err := manager.agreement(ctx, request, "ask")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b"}, ctx.Approvers())
+	require.EqualValues(t, []string{"d"}, ctx.UnApprovers())
+}
@@ -2,8 +2,10 @@ package manager
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"github.com/mantlenetworkio/mantle/tss/index"
+	"github.com/mantlenetworkio/mantle/tss/manager/election"
 	"github.com/mantlenetworkio/mantle/tss/manager/l1chain"
 	"github.com/mantlenetworkio/mantle/tss/manager/store"
 	"github.com/mantlenetworkio/mantle/tss/slash"
@@ -35,11 +37,59 @@ func Command() *cobra.Command {
 func run(cmd *cobra.Command) error {
 	config := common.GetConfigFromCmd(cmd)
 	log.Info("config info print", "SignedBatchesWindow", config.SignedBatchesWindow, "MinSignedInWindow", config.MinSignedInWindow)
-	wsServer, err := server.NewWSServer(config.Manager.WsAddr)
+	memberCheckInterval, err := time.ParseDuration(config.Manager.MemberCheckInterval)
 	if err != nil {
 		return err
 	}
-	managerStore, err := store.NewStorage(config.Manager.DBDir)
+	var wsTLSConfig *tls.Config
+	if config.Manager.WsTLS.Enable {
+		if wsTLSConfig, err = server.LoadServerTLSConfig(config.Manager.WsTLS.CertFile, config.Manager.WsTLS.KeyFile, config.Manager.WsTLS.CAFile); err != nil {
+			return err
+		}
+	}
+
+	elector, err := election.NewElector(config.Manager.Election)
+	if err != nil {
+		return err
+	}
+	// kill (no param) default send syscall.SIGTERM
+	// kill -2 is syscall.SIGINT
+	// kill -9 is syscall.SIGKILL but can't be caught, so don't need to add it
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// a standby manager serves nothing until it is elected
+	campaignCtx, cancelCampaign := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-quit:
+			cancelCampaign()
+		case <-campaignCtx.Done():
+		}
+	}()
+	log.Info("campaigning for the manager leadership", "backend", config.Manager.Election.Backend)
+	err = elector.Campaign(campaignCtx)
+	cancelCampaign()
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Info("stopped campaigning")
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		if err := elector.Resign(); err != nil {
+			log.Error("failed to resign the leadership", "err", err)
+		}
+	}()
+
+	// the former leader may not have released the store yet
+	managerStore, err := openStore(config.Manager.DBDir, elector.Lost())
+	if err != nil {
+		return err
+	}
+	defer managerStore.Close()
+	wsServer, err := server.NewWSServer(config.Manager.WsAddr, wsTLSConfig)
 	if err != nil {
 		return err
 	}
@@ -51,13 +101,14 @@ func run(cmd *cobra.Command) error {
 	observer.Start()
 
 	queryService := l1chain.NewQueryService(config.L1Url, config.TssGroupContractAddress, config.L1ConfirmBlocks, managerStore)
+	wsServer.SetMemberSource(queryService.QueryTssMembers, memberCheckInterval)
 	manager, err := NewManager(wsServer, queryService, managerStore, config)
 	if err != nil {
 		return err
 	}
 	manager.Start()
 
-	registry := router.NewRegistry(manager, managerStore)
+	registry := router.NewRegistry(manager, managerStore, manager)
 	r := gin.Default()
 	registry.Register(r)
 
@@ -72,18 +123,20 @@ func run(cmd *cobra.Command) error {
 		}
 	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 10 seconds.
-	quit := make(chan os.Signal)
-	// kill (no param) default send syscall.SIGTERM
-	// kill -2 is syscall.SIGINT
-	// kill -9 is syscall.SIGKILL but can't be caught, so don't need to add it
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Wait for interrupt signal or the lost leadership to gracefully shutdown
+	// the server with a timeout of 10 seconds.
+	var lostErr error
+	select {
+	case <-quit:
+	case <-elector.Lost():
+		lostErr = errors.New("lost the manager leadership")
+		log.Error("lost the manager leadership, stop serving")
+	}
 	log.Info("Shutting down server...")
 
 	manager.Stop()
 	observer.Stop()
+	wsServer.Stop()
 
 	// The context is used to inform the server it has 10 seconds to finish
 	// the request it is currently handling
@@ -97,5 +150,25 @@ func run(cmd *cobra.Command) error {
 
 	log.Info("Server exiting")
 
-	return nil
+	return lostErr
+}
+
+// openStore opens the store in dir, retrying while the store is still locked
+// by another process until the leadership is lost.
+func openStore(dir string, lost <-chan struct{}) (*store.Storage, error) {
+	for {
+		managerStore, err := store.NewStorage(dir)
+		if err == nil {
+			return managerStore, nil
+		}
+		if len(dir) == 0 || !errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, err
+		}
+		log.Warn("the store is locked, retry to open it", "dir", dir, "err", err)
+		select {
+		case <-lost:
+			return nil, errors.New("lost the manager leadership")
+		case <-time.After(time.Second):
+		}
+	}
 }
@@ -0,0 +1,91 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	BackendFile  = "file"
+	BackendRedis = "redis"
+)
+
+var leaderGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "Tss",
+		Subsystem: "Manager",
+		Name:      "leader",
+		Help:      "1 when the manager is the elected leader",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(leaderGauge)
+}
+
+// Elector elects a single leader among the manager replicas sharing the same
+// backend.
+type Elector interface {
+	// Campaign blocks until the manager is the leader or ctx is done.
+	Campaign(ctx context.Context) error
+	// Lost is closed once the leader loses the leadership, the manager must
+	// stop serving then.
+	Lost() <-chan struct{}
+	// Resign gives the leadership up.
+	Resign() error
+}
+
+func NewElector(cfg tss.ElectionConfig) (Elector, error) {
+	if len(cfg.Backend) == 0 {
+		return standalone{}, nil
+	}
+	retryWait, err := time.ParseDuration(cfg.RetryWait)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case BackendFile:
+		if len(cfg.LockFile) == 0 {
+			return nil, fmt.Errorf("lock_file is required by the %s election backend", BackendFile)
+		}
+		return newFileElector(cfg.LockFile, retryWait), nil
+	case BackendRedis:
+		leaseTTL, err := time.ParseDuration(cfg.LeaseTTL)
+		if err != nil {
+			return nil, err
+		}
+		return newRedisElector(cfg.RedisUrl, cfg.RedisKey, leaseTTL, retryWait)
+	default:
+		return nil, fmt.Errorf("unknown election backend %s", cfg.Backend)
+	}
+}
+
+// standalone is the elector of a single manager, which is always the leader.
+type standalone struct{}
+
+func (standalone) Campaign(ctx context.Context) error {
+	leaderGauge.Set(1)
+	return nil
+}
+
+func (standalone) Lost() <-chan struct{} {
+	return nil
+}
+
+func (standalone) Resign() error {
+	leaderGauge.Set(0)
+	return nil
+}
+
+func waitRetry(ctx context.Context, retryWait time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(retryWait):
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package election
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileElector(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "manager.lock")
+	leader := newFileElector(lockFile, 10*time.Millisecond)
+	standby := newFileElector(lockFile, 10*time.Millisecond)
+
+	require.NoError(t, leader.Campaign(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, standby.Campaign(ctx), context.DeadlineExceeded)
+
+	require.NoError(t, leader.Resign())
+	require.NoError(t, standby.Campaign(context.Background()))
+	require.NoError(t, standby.Resign())
+}
+
+func TestRedisElector(t *testing.T) {
+	rs, err := miniredis.Run()
+	require.NoError(t, err)
+	defer rs.Close()
+
+	url := "redis://" + rs.Addr()
+	leader, err := newRedisElector(url, "leader", 300*time.Millisecond, 10*time.Millisecond)
+	require.NoError(t, err)
+	standby, err := newRedisElector(url, "leader", 300*time.Millisecond, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	require.NoError(t, leader.Campaign(context.Background()))
+	owner, err := rs.Get("leader")
+	require.NoError(t, err)
+	require.Equal(t, leader.id, owner)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, standby.Campaign(ctx), context.DeadlineExceeded)
+
+	// the leader renews the lease
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-leader.Lost():
+		t.Fatal("the leader lost the lease")
+	default:
+	}
+
+	require.NoError(t, leader.Resign())
+	require.False(t, rs.Exists("leader"))
+
+	require.NoError(t, standby.Campaign(context.Background()))
+	// another manager took the lease over
+	require.NoError(t, rs.Set("leader", "another"))
+	select {
+	case <-standby.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the leadership is not lost")
+	}
+	require.NoError(t, standby.Resign())
+	owner, err = rs.Get("leader")
+	require.NoError(t, err)
+	require.Equal(t, "another", owner)
+}
+
+// stallingProxy forwards the connections to addr until stall, from then on
+// the requests are held without an answer
+type stallingProxy struct {
+	listener net.Listener
+	addr     string
+
+	lock    sync.Mutex
+	stalled bool
+}
+
+func newStallingProxy(t *testing.T, addr string) *stallingProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	p := &stallingProxy{listener: listener, addr: addr}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.forward(conn)
+		}
+	}()
+	return p
+}
+
+func (p *stallingProxy) forward(conn net.Conn) {
+	defer conn.Close()
+	upstream, err := net.Dial("tcp", p.addr)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	go io.Copy(conn, upstream) // nolint:errcheck
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		p.lock.Lock()
+		stalled := p.stalled
+		p.lock.Unlock()
+		if stalled {
+			continue
+		}
+		if _, err := upstream.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+func (p *stallingProxy) stall() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stalled = true
+}
+
+func TestRedisElectorStalled(t *testing.T) {
+	rs, err := miniredis.Run()
+	require.NoError(t, err)
+	defer rs.Close()
+	proxy := newStallingProxy(t, rs.Addr())
+
+	leaseTTL := 600 * time.Millisecond
+	leader, err := newRedisElector("redis://"+proxy.listener.Addr().String(), "leader", leaseTTL, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, leader.Campaign(context.Background()))
+
+	// wait for a renewal, the lease is then held until leaseTTL after it
+	rs.SetTTL("leader", time.Hour)
+	require.Eventually(t, func() bool {
+		return rs.TTL("leader") == leaseTTL
+	}, leaseTTL, time.Millisecond)
+	renewedAt := time.Now()
+
+	// the leader steps down two thirds of the ttl after the renewal, well
+	// before the lease may expire, even though its next renewals hang
+	proxy.stall()
+	select {
+	case <-leader.Lost():
+		require.Less(t, time.Since(renewedAt), leaseTTL*5/6)
+	case <-time.After(2 * leaseTTL):
+		t.Fatal("the leadership is not lost")
+	}
+	owner, err := rs.Get("leader")
+	require.NoError(t, err)
+	require.Equal(t, leader.id, owner)
+	require.Error(t, leader.Resign())
+}
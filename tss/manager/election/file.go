@@ -0,0 +1,81 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/l2geth/log"
+)
+
+// fileElector elects the manager holding an exclusive lock on a file of the
+// volume the replicas share. The lock is released by the kernel when the
+// leader exits, so the leadership is never lost while the leader runs.
+type fileElector struct {
+	path      string
+	retryWait time.Duration
+
+	lock sync.Mutex
+	file *os.File
+}
+
+func newFileElector(path string, retryWait time.Duration) *fileElector {
+	return &fileElector{
+		path:      path,
+		retryWait: retryWait,
+	}
+}
+
+func (e *fileElector) Campaign(ctx context.Context) error {
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("fail to open lock file %s: %w", e.path, err)
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return fmt.Errorf("fail to lock %s: %w", e.path, err)
+		}
+		if err := waitRetry(ctx, e.retryWait); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	// leave the holder in the file for the operators
+	hostname, _ := os.Hostname()
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(fmt.Sprintf("%s %d\n", hostname, os.Getpid())), 0)
+	}
+
+	e.lock.Lock()
+	e.file = file
+	e.lock.Unlock()
+	leaderGauge.Set(1)
+	log.Info("elected as the leader", "lock_file", e.path)
+	return nil
+}
+
+func (e *fileElector) Lost() <-chan struct{} {
+	return nil
+}
+
+func (e *fileElector) Resign() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	leaderGauge.Set(0)
+	err := syscall.Flock(int(e.file.Fd()), syscall.LOCK_UN)
+	e.file.Close()
+	e.file = nil
+	return err
+}
@@ -0,0 +1,197 @@
+package election
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mantlenetworkio/mantle/l2geth/log"
+)
+
+// the lease is only renewed or released by its holder
+const renewLeaseScript = `
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`
+
+const releaseLeaseScript = `
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`
+
+var errLeaseTaken = errors.New("the lease is held by another manager")
+
+// redisElector elects the manager holding a lease on a redis key. The leader
+// renews the lease every third of its ttl, and gives the leadership up once it
+// can not renew the lease before another manager may take it.
+type redisElector struct {
+	rdb       *redis.Client
+	key       string
+	id        string
+	leaseTTL  time.Duration
+	retryWait time.Duration
+
+	lock   sync.Mutex
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRedisElector(url, key string, leaseTTL, retryWait time.Duration) (*redisElector, error) {
+	if leaseTTL < 3*time.Millisecond {
+		return nil, fmt.Errorf("lease ttl %s is too short", leaseTTL)
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &redisElector{
+		rdb:       redis.NewClient(opts),
+		key:       key,
+		id:        fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(nonce)),
+		leaseTTL:  leaseTTL,
+		retryWait: retryWait,
+		lost:      make(chan struct{}),
+	}, nil
+}
+
+func (e *redisElector) Campaign(ctx context.Context) error {
+	var acquiredAt time.Time
+	for {
+		acquiredAt = time.Now()
+		acquired, err := e.rdb.SetNX(ctx, e.key, e.id, e.leaseTTL).Result()
+		if err != nil {
+			log.Warn("failed to acquire the leader lease", "key", e.key, "err", err)
+		}
+		if acquired {
+			break
+		}
+		if err := waitRetry(ctx, e.retryWait); err != nil {
+			return err
+		}
+	}
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	e.lock.Lock()
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	e.lock.Unlock()
+	go e.renew(renewCtx, acquiredAt)
+	leaderGauge.Set(1)
+	log.Info("elected as the leader", "key", e.key, "id", e.id)
+	return nil
+}
+
+// renew renews the lease every third of its ttl. The leader steps down two
+// thirds of the ttl after the lease was last renewed, whether a renewal is
+// still in flight or not, so that it is gone before another manager may take
+// the lease. renewedAt is when the last renewal was sent.
+func (e *redisElector) renew(ctx context.Context, renewedAt time.Time) {
+	defer close(e.done)
+	ticker := time.NewTicker(e.leaseTTL / 3)
+	defer ticker.Stop()
+	stepDownAt := renewedAt.Add(e.leaseTTL * 2 / 3)
+	stepDown := time.NewTimer(time.Until(stepDownAt))
+	defer stepDown.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stepDown.C:
+			e.stepDown("the leader lease was not renewed in time")
+			return
+		case <-ticker.C:
+		}
+
+		sentAt := time.Now()
+		result := make(chan error, 1)
+		go func(deadline time.Time) {
+			result <- e.renewLease(ctx, deadline)
+		}(stepDownAt)
+		select {
+		case <-ctx.Done():
+			return
+		case <-stepDown.C:
+			e.stepDown("the leader lease was not renewed in time")
+			return
+		case err := <-result:
+			if err == nil {
+				stepDownAt = sentAt.Add(e.leaseTTL * 2 / 3)
+				if !stepDown.Stop() {
+					<-stepDown.C
+				}
+				stepDown.Reset(time.Until(stepDownAt))
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error("failed to renew the leader lease", "key", e.key, "err", err)
+			if errors.Is(err, errLeaseTaken) {
+				e.stepDown("the leader lease is taken")
+				return
+			}
+		}
+	}
+}
+
+func (e *redisElector) stepDown(reason string) {
+	log.Error("step down as the leader", "key", e.key, "reason", reason)
+	leaderGauge.Set(0)
+	close(e.lost)
+}
+
+// renewLease renews the lease, the call gives up at deadline, when the leader
+// steps down
+func (e *redisElector) renewLease(ctx context.Context, deadline time.Time) error {
+	cctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	renewed, err := e.rdb.Eval(cctx, renewLeaseScript, []string{e.key}, e.id, e.leaseTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if renewed == 0 {
+		return errLeaseTaken
+	}
+	return nil
+}
+
+func (e *redisElector) Lost() <-chan struct{} {
+	return e.lost
+}
+
+func (e *redisElector) Resign() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.cancel == nil {
+		return e.rdb.Close()
+	}
+	e.cancel()
+	<-e.done
+	e.cancel = nil
+	leaderGauge.Set(0)
+
+	// the lease expires on its own after its ttl, do not wait longer on redis
+	ctx, cancel := context.WithTimeout(context.Background(), e.leaseTTL)
+	defer cancel()
+	err := e.rdb.Eval(ctx, releaseLeaseScript, []string{e.key}, e.id).Err()
+	if closeErr := e.rdb.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -54,7 +54,7 @@ func (m Manager) observeElection() {
 					if len(cpkData.Cpk) != 0 && time.Now().Sub(cpkData.CreationTime).Hours() < m.cpkConfirmTimeout.Hours() { // cpk is generated, but has not been confirmed yet
 						return
 					}
-					cpk, err := m.generateKey(tssInfo.TssMembers, tssInfo.Threshold)
+					cpk, keyEpoch, err := m.reshareOrGenerateKey(tssInfo)
 					if err != nil {
 						log.Error("failed to generate key", "err", err)
 						return
@@ -64,6 +64,7 @@ func (m Manager) observeElection() {
 						Cpk:          cpk,
 						ElectionId:   tssInfo.ElectionId,
 						CreationTime: time.Now(),
+						KeyEpoch:     keyEpoch,
 					}); err != nil {
 						log.Error("failed to get cpk from storage", "err", err)
 					}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -136,3 +137,30 @@ func (q QueryService) QueryInactiveInfo() (types.TssCommitteeInfo, error) {
 		TssMembers: tssMembers,
 	}, nil
 }
+
+// QueryTssMembers returns the members of the active and the inactive tss
+// groups, jailed members and culprits included.
+func (q QueryService) QueryTssMembers() ([]string, error) {
+	currentBlockNumber, err := q.ethClient.BlockNumber(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{BlockNumber: new(big.Int).SetUint64(currentBlockNumber - q.confirmBlocks)}
+	_, _, _, activeTssMembers, err := q.tssGroupManagerCaller.GetTssGroupInfo(opts)
+	if err != nil {
+		return nil, err
+	}
+	_, _, inactiveTssMembers, err := q.tssGroupManagerCaller.GetTssInactiveGroupInfo(opts)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(activeTssMembers)+len(inactiveTssMembers))
+	for _, m := range append(activeTssMembers, inactiveTssMembers...) {
+		unmarshalled, err := crypto.UnmarshalPubkey(append([]byte{0x04}, m...))
+		if err != nil {
+			return nil, fmt.Errorf("fail to unmarshal tss member: %w", err)
+		}
+		members = append(members, hex.EncodeToString(crypto.CompressPubkey(unmarshalled)))
+	}
+	return members, nil
+}
@@ -12,7 +12,6 @@ import (
 	"math/big"
 	"math/rand"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -33,6 +32,7 @@ type Manager struct {
 	tssStakingSlashingCaller *tsh.TssStakingSlashingCaller
 	tssGroupManagerCaller    *tgm.TssGroupManagerCaller
 	l1ConfirmBlocks          int
+	signedBatchesWindow      int
 
 	taskInterval          time.Duration
 	confirmReceiptTimeout time.Duration
@@ -41,10 +41,9 @@ type Manager struct {
 	askTimeout            time.Duration
 	signTimeout           time.Duration
 
-	stateSignatureCache map[[32]byte][]byte
-	sigCacheLock        *sync.RWMutex
-	stopGenKey          bool
-	stopChan            chan struct{}
+	stopGenKey     bool
+	disableReshare bool
+	stopChan       chan struct{}
 }
 
 func NewManager(wsServer server.IWebsocketManager,
@@ -94,6 +93,7 @@ func NewManager(wsServer server.IWebsocketManager,
 		store:                    store,
 		l1Cli:                    l1Cli,
 		l1ConfirmBlocks:          config.L1ConfirmBlocks,
+		signedBatchesWindow:      config.SignedBatchesWindow,
 		tssStakingSlashingCaller: tssStakingSlashingCaller,
 		tssGroupManagerCaller:    tssGroupManagerCaller,
 
@@ -104,9 +104,8 @@ func NewManager(wsServer server.IWebsocketManager,
 		askTimeout:            askTimeoutDur,
 		signTimeout:           signTimeoutDur,
 
-		stateSignatureCache: make(map[[32]byte][]byte),
-		sigCacheLock:        &sync.RWMutex{},
-		stopChan:            make(chan struct{}),
+		disableReshare: config.Manager.DisableReshare,
+		stopChan:       make(chan struct{}),
 	}, nil
 }
 
@@ -196,20 +195,7 @@ func (m Manager) SignStateBatch(request tss.SignStateRequest) ([]byte, error) {
 	}
 
 	if err != nil {
-		for _, culprit := range culprits {
-			addr, err := tss.NodeToAddress(culprit)
-			if err != nil {
-				log.Error("failed to convert node to address", "public key", culprit, "err", err)
-				continue
-			}
-			m.store.SetSlashingInfo(slash.SlashingInfo{
-				Address:    addr,
-				ElectionId: tssInfo.ElectionId,
-				BatchIndex: math.MaxUint64, // not real, just for identifying the specific slashing info.
-				SlashType:  tss.SlashTypeCulprit,
-			})
-		}
-		m.store.AddCulprits(culprits)
+		m.recordCulprits(tssInfo.ElectionId, culprits)
 		return nil, err
 	}
 
@@ -240,8 +226,69 @@ func (m Manager) SignStateBatch(request tss.SignStateRequest) ([]byte, error) {
 	return responseBytes, nil
 }
 
-func (m Manager) SignTxBatch() error {
-	return errors.New("not support for now")
+func (m Manager) SignTxBatch(request tss.SignTxBatchRequest) ([]byte, error) {
+	log.Info("received sign tx batch request", "request", request.String())
+	_, shouldStartAtElement, err := request.Range()
+	if err != nil {
+		return nil, err
+	}
+	digestBz, err := tss.TxBatchHash(request.TxHashes, shouldStartAtElement)
+	if err != nil {
+		return nil, err
+	}
+	if sig := m.getStateSignature(digestBz); len(sig) > 0 {
+		log.Info("get stored signature ", "digest", hex.EncodeToString(digestBz), "sig", hex.EncodeToString(sig))
+		return json.Marshal(tss.BatchSubmitterResponse{Signature: sig})
+	}
+
+	tssInfo, err := m.tssQueryService.QueryActiveInfo()
+	if err != nil {
+		return nil, err
+	}
+	availableNodes := m.availableNodes(tssInfo.TssMembers)
+	if len(availableNodes) < tssInfo.Threshold+1 {
+		return nil, errors.New("not enough available nodes to sign tx batch")
+	}
+	ctx := types.NewContext().
+		WithAvailableNodes(availableNodes).
+		WithTssInfo(tssInfo).
+		WithRequestId(randomRequestId()).
+		WithElectionId(tssInfo.ElectionId)
+
+	// ask tss nodes whether the batch matches their l2 chain
+	ctx, err = m.agreement(ctx, request, tss.AskTxBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(ctx.Approvers()) < ctx.TssInfos().Threshold+1 {
+		return nil, errors.New("failed to sign tx batch, approvals " + strings.Join(ctx.Approvers(), ",") + " ,unApprovals " + strings.Join(ctx.UnApprovers(), ","))
+	}
+
+	request.ElectionId = tssInfo.ElectionId
+	resp, culprits, err := m.sign(ctx, request, digestBz, tss.SignTxBatch)
+	if err != nil {
+		m.recordCulprits(tssInfo.ElectionId, culprits)
+		return nil, err
+	}
+	m.setStateSignature(digestBz, resp.Signature)
+	return json.Marshal(tss.BatchSubmitterResponse{Signature: resp.Signature})
+}
+
+func (m Manager) recordCulprits(electionId uint64, culprits []string) {
+	for _, culprit := range culprits {
+		addr, err := tss.NodeToAddress(culprit)
+		if err != nil {
+			log.Error("failed to convert node to address", "public key", culprit, "err", err)
+			continue
+		}
+		m.store.SetSlashingInfo(slash.SlashingInfo{
+			Address:    addr,
+			ElectionId: electionId,
+			BatchIndex: math.MaxUint64, // not real, just for identifying the specific slashing info.
+			SlashType:  tss.SlashTypeCulprit,
+		})
+	}
+	m.store.AddCulprits(culprits)
 }
 
 func (m Manager) availableNodes(tssMembers []string) []string {
@@ -281,17 +328,19 @@ func (m Manager) afterSignStateBatch(ctx types.Context, stateBatch [][32]byte, a
 }
 
 func (m Manager) getStateSignature(digestBz []byte) []byte {
-	m.sigCacheLock.RLock()
-	defer m.sigCacheLock.RUnlock()
 	var key [32]byte
 	copy(key[:], digestBz)
-	return m.stateSignatureCache[key]
+	sig, err := m.store.GetSignature(key)
+	if err != nil {
+		log.Error("failed to get stored signature", "digest", hex.EncodeToString(digestBz), "err", err)
+	}
+	return sig
 }
 
 func (m Manager) setStateSignature(digestBz []byte, sig []byte) {
-	m.sigCacheLock.Lock()
-	defer m.sigCacheLock.Unlock()
 	var key [32]byte
 	copy(key[:], digestBz)
-	m.stateSignatureCache[key] = sig
+	if err := m.store.SetSignature(key, sig); err != nil {
+		log.Error("failed to store signature", "digest", hex.EncodeToString(digestBz), "err", err)
+	}
 }
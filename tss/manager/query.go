@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mantlenetworkio/mantle/tss/index"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/slash"
+)
+
+const slashingStatusPending = "pending"
+
+func (m Manager) GetSigningInfo(address common.Address) (bool, slash.SigningInfo) {
+	return m.store.GetSigningInfo(address)
+}
+
+func (m Manager) GetMissedBatches(address common.Address) (bool, types.MissedBatches) {
+	found, signingInfo := m.store.GetSigningInfo(address)
+	if !found || m.signedBatchesWindow <= 0 {
+		return false, types.MissedBatches{}
+	}
+	window := uint64(m.signedBatchesWindow)
+	// the window ends at the latest batch, which is at IndexOffset
+	var first uint64
+	if signingInfo.IndexOffset >= window {
+		first = signingInfo.IndexOffset - window + 1
+	}
+	missed := make([]bool, 0, signingInfo.IndexOffset-first+1)
+	for offset := first; offset <= signingInfo.IndexOffset; offset++ {
+		missed = append(missed, m.store.GetNodeMissedBatchBitArray(address, offset%window))
+	}
+	return true, types.MissedBatches{
+		Address:             address,
+		StartBatchIndex:     signingInfo.StartBatchIndex,
+		IndexOffset:         signingInfo.IndexOffset,
+		MissedBlocksCounter: signingInfo.MissedBlocksCounter,
+		Window:              m.signedBatchesWindow,
+		Missed:              missed,
+	}
+}
+
+func (m Manager) ListSlashing() []types.SlashingStatus {
+	slashingInfos := m.store.ListSlashingInfo()
+	statuses := make([]types.SlashingStatus, len(slashingInfos))
+	for i, si := range slashingInfos {
+		status := sendState.get(si.Address, si.BatchIndex)
+		if len(status) == 0 {
+			status = slashingStatusPending
+		}
+		statuses[i] = types.SlashingStatus{SlashingInfo: si, Status: status}
+	}
+	return statuses
+}
+
+func (m Manager) ListSubmittedSlashing() []types.SubmittedSlashing {
+	return m.store.ListSubmittedSlashing()
+}
+
+func (m Manager) GetCulprits() []string {
+	return m.store.GetCulprits()
+}
+
+func (m Manager) GetStateBatch(root [32]byte) (bool, index.StateBatchInfo) {
+	return m.store.GetStateBatch(root)
+}
+
+func (m Manager) GetIndexStateBatch(index uint64) (bool, [32]byte) {
+	return m.store.GetIndexStateBatch(index)
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// QueryCommand queries the signing and slashing records of a running manager.
+func QueryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "query the signing and slashing records of the tss manager",
+	}
+	cmd.PersistentFlags().String("url", "http://127.0.0.1:8080", "http url of the tss manager")
+
+	cmd.AddCommand(
+		queryPathCommand("signing [node]", "signing info of a node, by its address or public key", 1,
+			func(args []string) string { return "/query/signing/" + args[0] }),
+		queryPathCommand("missed [node]", "missed batches of a node in the current signing window", 1,
+			func(args []string) string { return "/query/missed/" + args[0] }),
+		queryPathCommand("slashing", "slashings waiting to be confirmed on layer1", 0,
+			func([]string) string { return "/query/slashing" }),
+		queryPathCommand("submitted-slashing", "slashings confirmed on layer1", 0,
+			func([]string) string { return "/query/slashing/submitted" }),
+		queryPathCommand("culprits", "culprits of the failed signings", 0,
+			func([]string) string { return "/query/culprits" }),
+		queryPathCommand("batch [index|root]", "state batch by its index on layer1 or its 0x prefixed root", 1,
+			func(args []string) string {
+				if strings.HasPrefix(args[0], "0x") {
+					return "/query/batch/root/" + args[0]
+				}
+				return "/query/batch/index/" + args[0]
+			}),
+	)
+	return cmd
+}
+
+func queryPathCommand(use, short string, nArgs int, path func([]string) string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(nArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url, _ := cmd.Flags().GetString("url")
+			body, err := queryManager(strings.TrimSuffix(url, "/") + "/api/v1" + path(args))
+			if err != nil {
+				return err
+			}
+			var out bytes.Buffer
+			if err := json.Indent(&out, body, "", "  "); err != nil {
+				return err
+			}
+			fmt.Println(out.String())
+			return nil
+		},
+	}
+}
+
+func queryManager(url string) ([]byte, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query the manager: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read the response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/slash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissedBatches(t *testing.T) {
+	manager, _ := setup(nil, nil)
+	manager.signedBatchesWindow = 4
+	address := common.HexToAddress("0x1")
+
+	found, _ := manager.GetMissedBatches(address)
+	require.False(t, found)
+
+	// offsets 0..6 were signed, offsets 3 and 5 were missed
+	manager.store.SetSigningInfo(slash.SigningInfo{Address: address, StartBatchIndex: 10, IndexOffset: 6, MissedBlocksCounter: 2})
+	manager.store.SetNodeMissedBatchBitArray(address, 3%4, true)
+	manager.store.SetNodeMissedBatchBitArray(address, 5%4, true)
+
+	found, missedBatches := manager.GetMissedBatches(address)
+	require.True(t, found)
+	require.EqualValues(t, 4, missedBatches.Window)
+	require.EqualValues(t, 2, missedBatches.MissedBlocksCounter)
+	require.EqualValues(t, []bool{true, false, true, false}, missedBatches.Missed)
+
+	// a node with fewer batches than the window
+	manager.store.SetSigningInfo(slash.SigningInfo{Address: address, StartBatchIndex: 10, IndexOffset: 1})
+	_, missedBatches = manager.GetMissedBatches(address)
+	require.EqualValues(t, []bool{false, true}, missedBatches.Missed)
+}
+
+func TestListSlashing(t *testing.T) {
+	manager, _ := setup(nil, nil)
+	pending := slash.SlashingInfo{Address: common.HexToAddress("0x1111111111111111111111111111111111111111"), BatchIndex: 1, ElectionId: 1}
+	sending := slash.SlashingInfo{Address: common.HexToAddress("0x2222222222222222222222222222222222222222"), BatchIndex: 2, ElectionId: 1}
+	manager.store.SetSlashingInfo(pending)
+	manager.store.SetSlashingInfo(sending)
+	sendState.set(sending.Address, sending.BatchIndex, "has not minted")
+	defer sendState.remove(sending.Address, sending.BatchIndex)
+
+	require.ElementsMatch(t, []types.SlashingStatus{
+		{SlashingInfo: pending, Status: slashingStatusPending},
+		{SlashingInfo: sending, Status: "has not minted"},
+	}, manager.ListSlashing())
+
+	submitted := types.SubmittedSlashing{SlashingInfo: sending, TxHash: common.HexToHash("0x3")}
+	manager.store.AddSubmittedSlashing(submitted)
+	submittedList := manager.ListSubmittedSlashing()
+	require.Len(t, submittedList, 1)
+	require.Equal(t, submitted.TxHash, submittedList[0].TxHash)
+	require.Equal(t, sending, submittedList[0].SlashingInfo)
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/slices"
+	"github.com/mantlenetworkio/mantle/l2geth/log"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/ws/server"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+// reshareOrGenerateKey hands the confirmed CPK over to the new committee,
+// so that the proposer keeps signing with the same CPK. It falls back to
+// generating a new CPK when there is no confirmed CPK or the resharing fails.
+func (m Manager) reshareOrGenerateKey(newInfo types.TssCommitteeInfo) (string, int, error) {
+	if !m.disableReshare {
+		oldInfo, err := m.tssQueryService.QueryActiveInfo()
+		if err != nil || len(oldInfo.ClusterPubKey) == 0 {
+			log.Warn("no confirmed cpk to reshare, start to generate a new one", "err", err)
+		} else {
+			keyEpoch := m.keyEpoch(oldInfo)
+			cpk, err := m.reshareKey(oldInfo, newInfo, keyEpoch)
+			if err == nil {
+				return cpk, keyEpoch + 1, nil
+			}
+			log.Error("failed to reshare key, start to generate a new one", "err", err)
+		}
+	}
+	cpk, err := m.generateKey(newInfo.TssMembers, newInfo.Threshold)
+	return cpk, 0, err
+}
+
+// keyEpoch returns the number of resharings of the confirmed CPK. A CPK the
+// manager does not know was generated by a keygen.
+func (m Manager) keyEpoch(oldInfo types.TssCommitteeInfo) int {
+	cpkData, err := m.store.GetByElectionId(oldInfo.ElectionId)
+	if err != nil || cpkData.Cpk != oldInfo.ClusterPubKey {
+		return 0
+	}
+	return cpkData.KeyEpoch
+}
+
+func (m Manager) reshareKey(oldInfo, newInfo types.TssCommitteeInfo, keyEpoch int) (string, error) {
+	oldNodes := m.availableNodes(oldInfo.TssMembers)
+	if len(oldNodes) < oldInfo.Threshold+1 {
+		return "", errors.New("not enough available old members to reshare CPK")
+	}
+	newNodes := m.availableNodes(newInfo.TssMembers)
+	if len(newNodes) < len(newInfo.TssMembers) {
+		return "", errors.New("not enough available nodes to reshare CPK")
+	}
+	participants := append([]string{}, oldNodes...)
+	for _, node := range newNodes {
+		if !slices.Exists(participants, node) {
+			participants = append(participants, node)
+		}
+	}
+
+	requestId := randomRequestId()
+	respChan := make(chan server.ResponseMsg)
+	stopChan := make(chan struct{})
+	if err := m.wsServer.RegisterResChannel(requestId, respChan, stopChan); err != nil {
+		log.Error("failed to register response channel", "err", err)
+		return "", err
+	}
+
+	sendError := make(chan struct{})
+	clusterPublicKeys := make(map[string]string, 0)
+	var anyError error
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		cctx, cancel := context.WithTimeout(context.Background(), m.keygenTimeout)
+		defer func() {
+			log.Info("exit accept reshare response goroutine")
+			cancel()
+			close(stopChan)
+			wg.Done()
+		}()
+		for {
+			select {
+			case <-sendError:
+				anyError = errors.New("failed to send request to node")
+				log.Error("failed to send request to node")
+				return
+			case <-cctx.Done():
+				anyError = errors.New("wait nodes for reshare response timeout")
+				log.Error("wait nodes for reshare response timeout")
+				return
+			case resp := <-respChan:
+				log.Info("received reshare response", "response", resp.RpcResponse.String(), "node", resp.SourceNode)
+				if resp.RpcResponse.Error != nil {
+					anyError = errors.New(resp.RpcResponse.Error.Error())
+					log.Error("returns error", "node", resp.SourceNode)
+					return
+				}
+				var reshareResp tss.ReshareResponse
+				if err := tmjson.Unmarshal(resp.RpcResponse.Result, &reshareResp); err != nil {
+					anyError = err
+					log.Error("failed to Unmarshal ReshareResponse", "err", err)
+					return
+				}
+				clusterPublicKeys[resp.SourceNode] = reshareResp.ClusterPublicKey
+			default:
+				if len(clusterPublicKeys) == len(participants) {
+					return
+				}
+			}
+		}
+	}()
+
+	nodeRequest := tss.ReshareRequest{
+		ClusterPublicKey: oldInfo.ClusterPubKey,
+		ElectionId:       newInfo.ElectionId,
+		OldNodes:         oldNodes,
+		OldThreshold:     oldInfo.Threshold,
+		NewNodes:         newNodes,
+		NewThreshold:     newInfo.Threshold,
+		KeyEpoch:         keyEpoch,
+	}
+	m.callReshare(participants, nodeRequest, requestId, sendError)
+	wg.Wait()
+
+	if anyError != nil {
+		return "", anyError
+	}
+	if len(clusterPublicKeys) != len(participants) {
+		return "", errors.New("timeout")
+	}
+	// the CPK must be unchanged on every member
+	for node, cpk := range clusterPublicKeys {
+		if cpk != oldInfo.ClusterPubKey {
+			return "", fmt.Errorf("node %s reshared CPK %s instead of %s", node, cpk, oldInfo.ClusterPubKey)
+		}
+	}
+	return oldInfo.ClusterPubKey, nil
+}
+
+func (m Manager) callReshare(participants []string, nodeRequest tss.ReshareRequest, requestId string, sendError chan struct{}) {
+	nodeRequest.Timestamp = time.Now().UnixMilli()
+	requestBz, _ := json.Marshal(nodeRequest)
+	for _, node := range participants {
+		go func(node string) {
+			requestMsg := server.RequestMsg{
+				TargetNode: node,
+				RpcRequest: tmtypes.NewRPCRequest(tmtypes.JSONRPCStringID(requestId), tss.Reshare.String(), requestBz),
+			}
+			if err := m.wsServer.SendMsg(requestMsg); err != nil {
+				sendError <- struct{}{}
+			}
+		}(node)
+	}
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/ws/server"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+func TestReshare(t *testing.T) {
+	var lock sync.Mutex
+	targets := make(map[string]tss.ReshareRequest)
+	var afterMsgSent afterMsgSendFunc = func(request server.RequestMsg, respCh chan server.ResponseMsg) error {
+		var reshareRequest tss.ReshareRequest
+		if err := json.Unmarshal(request.RpcRequest.Params, &reshareRequest); err != nil {
+			return err
+		}
+		lock.Lock()
+		targets[request.TargetNode] = reshareRequest
+		lock.Unlock()
+		rpcResp := tmtypes.NewRPCSuccessResponse(request.RpcRequest.ID, tss.ReshareResponse{ClusterPublicKey: "abcd"})
+		respCh <- server.ResponseMsg{
+			RpcResponse: rpcResp,
+			SourceNode:  request.TargetNode,
+		}
+		return nil
+	}
+	var queryAliveNodes queryAliveNodesFunc = func() []string {
+		return []string{"a", "b", "c", "d", "e"}
+	}
+	manager, _ := setup(afterMsgSent, queryAliveNodes)
+	oldInfo := types.TssCommitteeInfo{ClusterPubKey: "abcd", TssMembers: []string{"a", "b", "c", "x"}, Threshold: 2}
+	newInfo := types.TssCommitteeInfo{ElectionId: 2, TssMembers: []string{"c", "d", "e"}, Threshold: 1}
+	cpk, err := manager.reshareKey(oldInfo, newInfo, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, "abcd", cpk)
+	require.EqualValues(t, 5, len(targets))
+	request := targets["c"]
+	require.EqualValues(t, "abcd", request.ClusterPublicKey)
+	require.EqualValues(t, 2, request.ElectionId)
+	require.EqualValues(t, []string{"a", "b", "c"}, request.OldNodes)
+	require.EqualValues(t, 2, request.OldThreshold)
+	require.EqualValues(t, []string{"c", "d", "e"}, request.NewNodes)
+	require.EqualValues(t, 1, request.NewThreshold)
+	require.EqualValues(t, 1, request.KeyEpoch)
+}
+
+func TestInConsistCPKReshare(t *testing.T) {
+	var afterMsgSent afterMsgSendFunc = func(request server.RequestMsg, respCh chan server.ResponseMsg) error {
+		reshareResp := tss.ReshareResponse{ClusterPublicKey: "abcd"}
+		if request.TargetNode == "d" {
+			reshareResp.ClusterPublicKey = "abc"
+		}
+		rpcResp := tmtypes.NewRPCSuccessResponse(request.RpcRequest.ID, reshareResp)
+		respCh <- server.ResponseMsg{
+			RpcResponse: rpcResp,
+			SourceNode:  request.TargetNode,
+		}
+		return nil
+	}
+	var queryAliveNodes queryAliveNodesFunc = func() []string {
+		return []string{"a", "b", "c", "d"}
+	}
+	manager, _ := setup(afterMsgSent, queryAliveNodes)
+	oldInfo := types.TssCommitteeInfo{ClusterPubKey: "abcd", TssMembers: []string{"a", "b", "c"}, Threshold: 1}
+	newInfo := types.TssCommitteeInfo{TssMembers: []string{"b", "c", "d"}, Threshold: 1}
+	cpk, err := manager.reshareKey(oldInfo, newInfo, 0)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "instead of abcd")
+	require.EqualValues(t, 0, len(cpk))
+}
+
+func TestNotEnoughOldNodesReshare(t *testing.T) {
+	var queryAliveNodes queryAliveNodesFunc = func() []string {
+		return []string{"a", "c", "d"}
+	}
+	manager, _ := setup(nil, queryAliveNodes)
+	oldInfo := types.TssCommitteeInfo{ClusterPubKey: "abcd", TssMembers: []string{"a", "b"}, Threshold: 1}
+	newInfo := types.TssCommitteeInfo{TssMembers: []string{"c", "d"}, Threshold: 1}
+	cpk, err := manager.reshareKey(oldInfo, newInfo, 0)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "not enough available old members")
+	require.EqualValues(t, 0, len(cpk))
+}
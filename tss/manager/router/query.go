@@ -0,0 +1,121 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/index"
+)
+
+// stateBatchResponse is a state batch with its root encoded in hex
+type stateBatchResponse struct {
+	index.StateBatchInfo
+	BatchRoot string `json:"batch_root"`
+}
+
+// nodeAddress accepts the address of a node, or its public key
+func nodeAddress(node string) (common.Address, bool) {
+	if common.IsHexAddress(node) {
+		return common.HexToAddress(node), true
+	}
+	address, err := tss.NodeToAddress(node)
+	if err != nil {
+		return common.Address{}, false
+	}
+	return address, true
+}
+
+func (registry *Registry) SigningInfoHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address, ok := nodeAddress(c.Param("node"))
+		if !ok {
+			c.String(http.StatusBadRequest, "wrong format node address or public key")
+			return
+		}
+		found, signingInfo := registry.queryService.GetSigningInfo(address)
+		if !found {
+			c.String(http.StatusNotFound, "no signing info of "+address.String())
+			return
+		}
+		c.JSON(http.StatusOK, signingInfo)
+	}
+}
+
+func (registry *Registry) MissedBatchesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address, ok := nodeAddress(c.Param("node"))
+		if !ok {
+			c.String(http.StatusBadRequest, "wrong format node address or public key")
+			return
+		}
+		found, missedBatches := registry.queryService.GetMissedBatches(address)
+		if !found {
+			c.String(http.StatusNotFound, "no signing info of "+address.String())
+			return
+		}
+		c.JSON(http.StatusOK, missedBatches)
+	}
+}
+
+func (registry *Registry) SlashingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, registry.queryService.ListSlashing())
+	}
+}
+
+func (registry *Registry) SubmittedSlashingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, registry.queryService.ListSubmittedSlashing())
+	}
+}
+
+func (registry *Registry) CulpritsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, registry.queryService.GetCulprits())
+	}
+}
+
+func (registry *Registry) StateBatchByIndexHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batchIndex, err := strconv.ParseUint(c.Param("index"), 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "wrong format index")
+			return
+		}
+		found, root := registry.queryService.GetIndexStateBatch(batchIndex)
+		if !found {
+			c.String(http.StatusNotFound, "no state batch at index "+c.Param("index"))
+			return
+		}
+		registry.writeStateBatch(c, root)
+	}
+}
+
+func (registry *Registry) StateBatchByRootHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rootBz, err := hexutil.Decode(c.Param("root"))
+		if err != nil || len(rootBz) != 32 {
+			c.String(http.StatusBadRequest, "wrong format root, a 0x prefixed 32 bytes hex is expected")
+			return
+		}
+		var root [32]byte
+		copy(root[:], rootBz)
+		registry.writeStateBatch(c, root)
+	}
+}
+
+func (registry *Registry) writeStateBatch(c *gin.Context, root [32]byte) {
+	found, stateBatch := registry.queryService.GetStateBatch(root)
+	if !found {
+		c.String(http.StatusNotFound, "no state batch of root "+hexutil.Encode(root[:]))
+		return
+	}
+	c.JSON(http.StatusOK, stateBatchResponse{
+		StateBatchInfo: stateBatch,
+		BatchRoot:      hexutil.Encode(root[:]),
+	})
+}
@@ -16,12 +16,14 @@ import (
 type Registry struct {
 	signService  types.SignService
 	adminService types.AdminService
+	queryService types.QueryService
 }
 
-func NewRegistry(signService types.SignService, adminService types.AdminService) *Registry {
+func NewRegistry(signService types.SignService, adminService types.AdminService, queryService types.QueryService) *Registry {
 	return &Registry{
 		signService:  signService,
 		adminService: adminService,
+		queryService: queryService,
 	}
 }
 
@@ -56,6 +58,35 @@ func (registry *Registry) SignStateHandler() gin.HandlerFunc {
 	}
 }
 
+func (registry *Registry) SignTxBatchHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request tss.SignTxBatchRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, errors.New("invalid request body"))
+			return
+		}
+
+		if _, _, err := request.Range(); err != nil {
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+		if len(request.TxHashes) == 0 {
+			c.JSON(http.StatusBadRequest, errors.New("empty TxHashes"))
+			return
+		}
+		signature, err := registry.signService.SignTxBatch(request)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to sign tx batch")
+			log.Error("failed to sign tx batch", "error", err)
+			return
+		}
+
+		if _, err = c.Writer.Write(signature); err != nil {
+			log.Error("failed to write signature to response writer", "error", err)
+		}
+	}
+}
+
 func (registry *Registry) ResetHeightHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		heightStr := c.Param("height")
@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 )
 
@@ -9,11 +10,21 @@ func (registry *Registry) Register(r *gin.Engine) {
 	r.GET("/ping", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	v1Router := r.Group("/api/v1")
 	v1Router.POST("/sign/state", registry.SignStateHandler())
+	v1Router.POST("/sign/tx", registry.SignTxBatchHandler())
 
 	v1Router.GET("/admin/height", registry.GetHeightHandler())
 	v1Router.POST("/admin/reset/height", registry.ResetHeightHandler())
 	v1Router.DELETE("/admin/delete/slash", registry.DeleteSlashHandler())
+
+	v1Router.GET("/query/signing/:node", registry.SigningInfoHandler())
+	v1Router.GET("/query/missed/:node", registry.MissedBatchesHandler())
+	v1Router.GET("/query/slashing", registry.SlashingHandler())
+	v1Router.GET("/query/slashing/submitted", registry.SubmittedSlashingHandler())
+	v1Router.GET("/query/culprits", registry.CulpritsHandler())
+	v1Router.GET("/query/batch/index/:index", registry.StateBatchByIndexHandler())
+	v1Router.GET("/query/batch/root/:root", registry.StateBatchByRootHandler())
 }
@@ -1,8 +1,10 @@
 package manager
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +23,30 @@ func TestSendState(t *testing.T) {
 	actualStatus = sendState.get(address, batchIndex)
 	require.EqualValues(t, status, actualStatus)
 }
+
+func TestSendStateKey(t *testing.T) {
+	// addresses that only differ in their last bytes and the batch indexes
+	// all have their own send state
+	addresses := []common.Address{common.HexToAddress("0x1"), common.HexToAddress("0x2"), common.HexToAddress("0x100000002")}
+	batchIndexes := []uint64{1, 2}
+	for _, address := range addresses {
+		for _, batchIndex := range batchIndexes {
+			sendState.set(address, batchIndex, fmt.Sprintf("%s-%d", address.Hex(), batchIndex))
+		}
+	}
+	for _, address := range addresses {
+		for _, batchIndex := range batchIndexes {
+			require.Equal(t, fmt.Sprintf("%s-%d", address.Hex(), batchIndex), sendState.get(address, batchIndex))
+		}
+	}
+
+	sendState.remove(addresses[0], batchIndexes[0])
+	require.Empty(t, sendState.get(addresses[0], batchIndexes[0]))
+	require.NotEmpty(t, sendState.get(addresses[0], batchIndexes[1]))
+	require.NotEmpty(t, sendState.get(addresses[1], batchIndexes[0]))
+	for _, address := range addresses {
+		for _, batchIndex := range batchIndexes {
+			sendState.remove(address, batchIndex)
+		}
+	}
+}
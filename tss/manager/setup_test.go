@@ -5,6 +5,7 @@ import (
 
 	tss "github.com/mantlenetworkio/mantle/tss/common"
 	"github.com/mantlenetworkio/mantle/tss/manager/store"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
 	"github.com/mantlenetworkio/mantle/tss/ws/server"
 )
 
@@ -58,3 +59,15 @@ func setup(afterMsgSent afterMsgSendFunc, queryAliveNodes queryAliveNodesFunc) (
 	}
 	return manager, request
 }
+
+type mockQueryService struct {
+	activeInfo types.TssCommitteeInfo
+}
+
+func (mock mockQueryService) QueryActiveInfo() (types.TssCommitteeInfo, error) {
+	return mock.activeInfo, nil
+}
+
+func (mock mockQueryService) QueryInactiveInfo() (types.TssCommitteeInfo, error) {
+	return types.TssCommitteeInfo{}, nil
+}
@@ -55,6 +55,7 @@ func (m Manager) handleSlashing(si slash.SlashingInfo) {
 			return
 		}
 		if found { // this slashing is confirmed on ethereum
+			m.store.AddSubmittedSlashing(types.SubmittedSlashing{SlashingInfo: si, ConfirmedAt: time.Now()})
 			m.store.RemoveSlashingInfo(si.Address, si.BatchIndex)
 		}
 		return
@@ -193,7 +194,13 @@ func (m Manager) submitSlashing(signResp tss.SignResponse, si slash.SlashingInfo
 					log.Info("Transaction confirmed",
 						"txHash", txHash,
 						"reverted", reverted)
-					// remove submitted slashing info
+					// move the slashing info to the submitted ones
+					m.store.AddSubmittedSlashing(types.SubmittedSlashing{
+						SlashingInfo: si,
+						TxHash:       txHash,
+						Reverted:     reverted,
+						ConfirmedAt:  time.Now(),
+					})
 					m.store.RemoveSlashingInfo(si.Address, si.BatchIndex)
 					return receipt
 				}
@@ -221,29 +228,25 @@ type SendState struct {
 func (ss SendState) set(address common.Address, batchIndex uint64, status string) {
 	ss.lock.Lock()
 	defer ss.lock.Unlock()
-	indexBz := make([]byte, 8)
-	binary.BigEndian.PutUint64(indexBz, batchIndex)
-	var key [28]byte
-	copy(key[:], append(address.Hash().Bytes(), indexBz...))
-	ss.states[key] = status
+	ss.states[sendStateKey(address, batchIndex)] = status
 }
 
 func (ss SendState) get(address common.Address, batchIndex uint64) string {
 	ss.lock.Lock()
 	defer ss.lock.Unlock()
-	indexBz := make([]byte, 8)
-	binary.BigEndian.PutUint64(indexBz, batchIndex)
-	var key [28]byte
-	copy(key[:], append(address.Hash().Bytes(), indexBz...))
-	return ss.states[key]
+	return ss.states[sendStateKey(address, batchIndex)]
 }
 
 func (ss SendState) remove(address common.Address, batchIndex uint64) {
 	ss.lock.Lock()
 	defer ss.lock.Unlock()
-	indexBz := make([]byte, 8)
-	binary.BigEndian.PutUint64(indexBz, batchIndex)
+	delete(ss.states, sendStateKey(address, batchIndex))
+}
+
+// key: address + batchIndex
+func sendStateKey(address common.Address, batchIndex uint64) [28]byte {
 	var key [28]byte
-	copy(key[:], append(address.Hash().Bytes(), indexBz...))
-	delete(ss.states, key)
+	copy(key[:], address.Bytes())
+	binary.BigEndian.PutUint64(key[common.AddressLength:], batchIndex)
+	return key
 }
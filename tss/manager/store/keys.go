@@ -14,6 +14,8 @@ var (
 	SlashingInfoKeyPrefix            = []byte{0x06}
 	ScannedHeightKeyPrefix           = []byte{0x07}
 	CulpritsKeyPrefix                = []byte{0x08}
+	SignatureKeyPrefix               = []byte{0x09}
+	SubmittedSlashingKeyPrefix       = []byte{0x0a}
 )
 
 func getCPKDataKey(electionId uint64) []byte {
@@ -65,3 +67,14 @@ func getScannedHeightKey() []byte {
 func getCulpritsKey() []byte {
 	return CulpritsKeyPrefix
 }
+
+func getSignatureKey(digest [32]byte) []byte {
+	return append(SignatureKeyPrefix, digest[:]...)
+}
+
+// key: prefix + address + batchIndex
+func getSubmittedSlashingKey(address common.Address, batchIndex uint64) []byte {
+	indexBz := make([]byte, 8)
+	binary.BigEndian.PutUint64(indexBz, batchIndex)
+	return append(append(SubmittedSlashingKeyPrefix, address.Bytes()...), indexBz...)
+}
@@ -0,0 +1,13 @@
+package store
+
+func (s *Storage) SetSignature(digest [32]byte, sig []byte) error {
+	return s.db.Put(getSignatureKey(digest), sig, nil)
+}
+
+func (s *Storage) GetSignature(digest [32]byte) ([]byte, error) {
+	bz, err := s.db.Get(getSignatureKey(digest), nil)
+	if err != nil {
+		return handleError([]byte(nil), err)
+	}
+	return bz, nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
 	"github.com/mantlenetworkio/mantle/tss/slash"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -155,3 +156,26 @@ func (s *Storage) GetCulprits() []string {
 	}
 	return ret
 }
+
+func (s *Storage) AddSubmittedSlashing(submitted types.SubmittedSlashing) {
+	bz, err := json.Marshal(submitted)
+	if err != nil {
+		panic(err)
+	}
+	if err = s.db.Put(getSubmittedSlashingKey(submitted.Address, submitted.BatchIndex), bz, nil); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Storage) ListSubmittedSlashing() (submitted []types.SubmittedSlashing) {
+	iterator := s.db.NewIterator(util.BytesPrefix(SubmittedSlashingKeyPrefix), nil)
+	defer iterator.Release()
+	for iterator.Next() {
+		var slashing types.SubmittedSlashing
+		if err := json.Unmarshal(iterator.Value(), &slashing); err != nil {
+			panic(err)
+		}
+		submitted = append(submitted, slashing)
+	}
+	return
+}
@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/mantlenetworkio/mantle/l2geth/crypto"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/ws/server"
+	"github.com/stretchr/testify/require"
+	tmtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+func TestSignTxBatch(t *testing.T) {
+	request := tss.SignTxBatchRequest{
+		StartBlock:           "11",
+		ShouldStartAtElement: "10",
+		TxHashes:             [][32]byte{crypto.Keccak256Hash([]byte("tx1")), crypto.Keccak256Hash([]byte("tx2"))},
+	}
+	digest, err := tss.TxBatchHash(request.TxHashes, big.NewInt(10))
+	require.NoError(t, err)
+	stateDigest, err := tss.StateBatchHash(request.TxHashes, big.NewInt(10))
+	require.NoError(t, err)
+	require.NotEqualValues(t, stateDigest, digest)
+
+	priK, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	pubKey := btcec.PublicKey(priK.PublicKey)
+	signature, err := crypto.Sign(digest, priK)
+	require.NoError(t, err)
+
+	methods := make(map[string]string)
+	afterMsgSent := func(request server.RequestMsg, respCh chan server.ResponseMsg) error {
+		var result interface{} = tss.AskResponse{Result: true}
+		if request.RpcRequest.Method == tss.SignTxBatch.String() {
+			result = tss.SignResponse{Signature: signature}
+		}
+		methods[request.RpcRequest.Method] = request.TargetNode
+		respCh <- server.ResponseMsg{
+			RpcResponse: tmtypes.NewRPCSuccessResponse(request.RpcRequest.ID, result),
+			SourceNode:  request.TargetNode,
+		}
+		return nil
+	}
+	queryAliveNodes := func() []string {
+		return []string{"a"}
+	}
+	manager, _ := setup(afterMsgSent, queryAliveNodes)
+	manager.tssQueryService = mockQueryService{activeInfo: types.TssCommitteeInfo{
+		ElectionId:    1,
+		ClusterPubKey: hex.EncodeToString(pubKey.SerializeCompressed()),
+		TssMembers:    []string{"a"},
+		Threshold:     0,
+	}}
+
+	respBz, err := manager.SignTxBatch(request)
+	require.NoError(t, err)
+	var resp tss.BatchSubmitterResponse
+	require.NoError(t, json.Unmarshal(respBz, &resp))
+	require.EqualValues(t, signature, resp.Signature)
+	require.False(t, resp.RollBack)
+	require.Contains(t, methods, tss.AskTxBatch.String())
+	require.Contains(t, methods, tss.SignTxBatch.String())
+
+	// the second request is served from the signature cache
+	manager.tssQueryService = nil
+	respBz, err = manager.SignTxBatch(request)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(respBz, &resp))
+	require.EqualValues(t, signature, resp.Signature)
+}
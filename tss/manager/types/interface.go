@@ -9,7 +9,7 @@ import (
 
 type SignService interface {
 	SignStateBatch(request tss.SignStateRequest) ([]byte, error)
-	SignTxBatch() error
+	SignTxBatch(request tss.SignTxBatchRequest) ([]byte, error)
 }
 
 type AdminService interface {
@@ -18,6 +18,18 @@ type AdminService interface {
 	RemoveSlashingInfo(common.Address, uint64)
 }
 
+// QueryService serves the read-only queries of the signing and slashing
+// records.
+type QueryService interface {
+	GetSigningInfo(address common.Address) (bool, slash.SigningInfo)
+	GetMissedBatches(address common.Address) (bool, MissedBatches)
+	ListSlashing() []SlashingStatus
+	ListSubmittedSlashing() []SubmittedSlashing
+	GetCulprits() []string
+	GetStateBatch(root [32]byte) (bool, index.StateBatchInfo)
+	GetIndexStateBatch(index uint64) (bool, [32]byte)
+}
+
 type TssQueryService interface {
 	QueryActiveInfo() (TssCommitteeInfo, error)
 	QueryInactiveInfo() (TssCommitteeInfo, error)
@@ -28,8 +40,22 @@ type CPKStore interface {
 	GetByElectionId(uint64) (CpkData, error)
 }
 
+// SignatureStore keeps the signatures of the batches, so that a manager
+// taking the leadership over returns the signatures the former leader made.
+type SignatureStore interface {
+	SetSignature(digest [32]byte, sig []byte) error
+	GetSignature(digest [32]byte) ([]byte, error)
+}
+
+type SubmittedSlashingStore interface {
+	AddSubmittedSlashing(SubmittedSlashing)
+	ListSubmittedSlashing() []SubmittedSlashing
+}
+
 type ManagerStore interface {
 	CPKStore
+	SignatureStore
+	SubmittedSlashingStore
 	index.StateBatchStore
 	index.ScanHeightStore
 	slash.SlashingStore
@@ -3,6 +3,9 @@ package types
 import (
 	"context"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mantlenetworkio/mantle/tss/slash"
 )
 
 type TssCommitteeInfo struct {
@@ -16,6 +19,34 @@ type CpkData struct {
 	Cpk          string    `json:"cpk"`
 	ElectionId   uint64    `json:"election_id"`
 	CreationTime time.Time `json:"creation_time"`
+	KeyEpoch     int       `json:"key_epoch,omitempty"` // the number of resharings of the cpk
+}
+
+// SlashingStatus is a slashing the manager has not seen confirmed on layer1
+// yet, Status is "pending" until its transaction is sent.
+type SlashingStatus struct {
+	slash.SlashingInfo
+	Status string `json:"status"`
+}
+
+// SubmittedSlashing is a slashing confirmed on layer1, TxHash is empty when
+// the transaction was sent by a former run of the manager.
+type SubmittedSlashing struct {
+	slash.SlashingInfo
+	TxHash      common.Hash `json:"tx_hash"`
+	Reverted    bool        `json:"reverted"`
+	ConfirmedAt time.Time   `json:"confirmed_at"`
+}
+
+// MissedBatches is the missed batches of a node in the current signing
+// window, Missed is ordered from the oldest batch to the latest one.
+type MissedBatches struct {
+	Address             common.Address `json:"address"`
+	StartBatchIndex     uint64         `json:"start_batch_index"`
+	IndexOffset         uint64         `json:"index_offset"`
+	MissedBlocksCounter uint64         `json:"missed_blocks_counter"`
+	Window              int            `json:"window"`
+	Missed              []bool         `json:"missed"`
 }
 
 // Context ---------------------------------------------
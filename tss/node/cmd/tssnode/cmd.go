@@ -87,12 +87,16 @@ func runNode(cmd *cobra.Command) error {
 			KeyGenTimeout:   cfg.Node.KeyGenTimeout,
 			KeySignTimeout:  cfg.Node.KeySignTimeout,
 			EnableMonitor:   false,
+
+			PreParamsPoolSize:    cfg.Node.PreParamsPoolSize,
+			PreParamsPoolTimeout: cfg.Node.PreParamsPoolTimeout,
 		},
 		cfg.Node.PreParamFile,
 		cfg.Node.ExternalIP,
 		cfg.Node.Secrets.Enable,
 		cfg.Node.Secrets.SecretId,
 		cfg.Node.Shamir,
+		cfg.Node.Keystore,
 	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("fail to create tss server instance")
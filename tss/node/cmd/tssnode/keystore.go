@@ -0,0 +1,52 @@
+package tssnode
+
+import (
+	"errors"
+	"os"
+
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const newKeystorePassphraseEnv = "TSS_NODE_KEYSTORE_NEW_PASSPHRASE"
+
+func RotateKeystoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-keystore",
+		Short: "encrypt the local key shares with the passphrase in " + newKeystorePassphraseEnv,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg := tss.GetConfigFromCmd(cmd)
+			scryptN, _ := cmd.Flags().GetInt("scrypt-n")
+			if scryptN == 0 {
+				scryptN = cfg.Node.Keystore.ScryptN
+			}
+
+			newPassphrase := os.Getenv(newKeystorePassphraseEnv)
+			if len(newPassphrase) == 0 {
+				return errors.New(newKeystorePassphraseEnv + " is not set")
+			}
+			kek, err := storage.NewKeyEncryptionProvider(cfg.Node.Keystore)
+			if err != nil {
+				return err
+			}
+			stateManager, err := storage.NewFileStateMgr(cfg.Node.BaseDir, kek)
+			if err != nil {
+				return err
+			}
+			keyVersion := cfg.Node.Keystore.KeyVersion + 1
+			newKEK, err := storage.NewPassphraseProvider(newPassphrase, scryptN, keyVersion)
+			if err != nil {
+				return err
+			}
+			if err := stateManager.RotateKeyEncryption(newKEK); err != nil {
+				return err
+			}
+			log.Info().Int("key_version", keyVersion).Msg("keystore rotated, update the keystore passphrase and key version of the node config")
+			return nil
+		},
+	}
+	cmd.Flags().Int("scrypt-n", 0, "scrypt cost of the new passphrase, defaults to the node config")
+	return cmd
+}
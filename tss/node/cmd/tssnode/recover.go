@@ -0,0 +1,75 @@
+package tssnode
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/mantlenetworkio/mantle/l2geth/crypto"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func RecoverShareCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recover-share",
+		Short: "rebuild a local key share from its shamir shares and save it to the base dir",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg := tss.GetConfigFromCmd(cmd)
+			pubKey, _ := cmd.Flags().GetString("pub-key")
+			keyEpoch, _ := cmd.Flags().GetInt("key-epoch")
+			partyKey, _ := cmd.Flags().GetString("party-key")
+			locations, _ := cmd.Flags().GetString("locations")
+			baseDir, _ := cmd.Flags().GetString("base-dir")
+
+			if len(pubKey) == 0 {
+				return errors.New("need to specify the pub-key of the key share")
+			}
+			if len(partyKey) == 0 {
+				if len(cfg.Node.PrivateKey) == 0 {
+					return errors.New("need to specify party-key or to config private key")
+				}
+				privKey, err := crypto.HexToECDSA(cfg.Node.PrivateKey)
+				if err != nil {
+					return err
+				}
+				partyKey = hex.EncodeToString(crypto.CompressPubkey(&privKey.PublicKey))
+			}
+			if len(baseDir) == 0 {
+				baseDir = cfg.Node.BaseDir
+			}
+
+			shamirManager, err := storage.NewShamirMgrWithLocations(cfg.Node.Shamir, locations)
+			if err != nil {
+				return err
+			}
+			state, err := shamirManager.GetKeyFile(pubKey, keyEpoch, partyKey)
+			if err != nil {
+				return err
+			}
+			kek, err := storage.NewKeyEncryptionProvider(cfg.Node.Keystore)
+			if err != nil {
+				return err
+			}
+			stateManager, err := storage.NewFileStateMgr(baseDir, kek)
+			if err != nil {
+				return err
+			}
+			if err := stateManager.SaveLocalState(state); err != nil {
+				return err
+			}
+			if err := stateManager.SetKeyEpoch(pubKey, keyEpoch); err != nil {
+				return err
+			}
+			log.Info().Str("pub_key", pubKey).Str("base_dir", baseDir).Msg("key share recovered")
+			return nil
+		},
+	}
+	cmd.Flags().String("pub-key", "", "cluster public key of the key share")
+	cmd.Flags().Int("key-epoch", 0, "number of resharings of the key share")
+	cmd.Flags().String("party-key", "", "public key of the node owning the key share, defaults to the one of the config private key")
+	cmd.Flags().String("locations", "", "comma separated share locations, such as dir:/mnt/backup,s3:bucket,sm:secret-id, defaults to the ones of the config")
+	cmd.Flags().String("base-dir", "", "directory to save the key share to, defaults to the base dir of the config")
+	return cmd
+}
@@ -3,6 +3,7 @@ package signer
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/tls"
 	"math/big"
 
 	ethc "github.com/ethereum/go-ethereum/common"
@@ -35,6 +36,7 @@ type Processor struct {
 	tssServer                 tsslib.Server
 	wsClient                  *client.WSClients
 	l2Client                  *l2ethclient.Client
+	stateVerifier             *stateVerifier
 	l1Client                  *ethclient.Client
 	ctx                       context.Context
 	cancel                    func()
@@ -45,9 +47,14 @@ type Processor struct {
 	askSlashChan              chan tdtypes.RPCRequest
 	signSlashChan             chan tdtypes.RPCRequest
 	keygenRequestChan         chan tdtypes.RPCRequest
+	reshareRequestChan        chan tdtypes.RPCRequest
 	signRollBachChan          chan tdtypes.RPCRequest
+	askTxBatchChan            chan tdtypes.RPCRequest
+	signTxBatchChan           chan tdtypes.RPCRequest
 	waitSignLock              *sync.Mutex
 	waitSignMsgs              map[string]common.SignStateRequest
+	waitSignTxBatchLock       *sync.Mutex
+	waitSignTxBatchMsgs       map[string]common.SignTxBatchRequest
 	waitSignSlashLock         *sync.Mutex
 	waitSignSlashMsgs         map[string]map[uint64]common.SlashRequest
 	cacheVerifyLock           *sync.Mutex
@@ -87,11 +94,24 @@ func NewProcessor(cfg common.Configuration, contx context.Context, tssInstance t
 		return nil, err
 	}
 
-	wsClient, err := client.NewWSClient(cfg.Node.WsAddr, "/ws", privKey, pubKeyHex)
+	var wsTLSConfig *tls.Config
+	if cfg.Node.WsTLS.Enable {
+		if wsTLSConfig, err = client.LoadClientTLSConfig(cfg.Node.WsTLS.CertFile, cfg.Node.WsTLS.KeyFile, cfg.Node.WsTLS.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	wsClient, err := client.NewWSClient(cfg.Node.WsAddr, "/ws", privKey, pubKeyHex, wsTLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	l2RpcClient, err := DialL2RpcClientWithTimeout(ctx, cfg.Node.L2EthRpc, cfg.Node.DisableHTTP2)
+	if err != nil {
+		return nil, err
+	}
+	stateVerifier, err := newStateVerifier(ctx, cfg, l2RpcClient, l1Cli)
 	if err != nil {
 		return nil, err
 	}
-	l2Client, err := DialL2EthClientWithTimeout(ctx, cfg.Node.L2EthRpc, cfg.Node.DisableHTTP2)
 	tssStakingSlashingCaller, err := tsh.NewTssStakingSlashingCaller(ethc.HexToAddress(cfg.TssStakingSlashContractAddress), l1Cli)
 	if err != nil {
 		return nil, err
@@ -110,7 +130,8 @@ func NewProcessor(cfg common.Configuration, contx context.Context, tssInstance t
 		wg:                        &sync.WaitGroup{},
 		logger:                    log.With().Str("module", "signer").Logger(),
 		wsClient:                  wsClient,
-		l2Client:                  l2Client,
+		l2Client:                  l2ethclient.NewClient(l2RpcClient),
+		stateVerifier:             stateVerifier,
 		l1Client:                  l1Cli,
 		ctx:                       ctx,
 		cancel:                    cancel,
@@ -119,9 +140,14 @@ func NewProcessor(cfg common.Configuration, contx context.Context, tssInstance t
 		askSlashChan:              make(chan tdtypes.RPCRequest, 1),
 		signSlashChan:             make(chan tdtypes.RPCRequest, 1),
 		keygenRequestChan:         make(chan tdtypes.RPCRequest, 1),
+		reshareRequestChan:        make(chan tdtypes.RPCRequest, 1),
 		signRollBachChan:          make(chan tdtypes.RPCRequest, 1),
+		askTxBatchChan:            make(chan tdtypes.RPCRequest, 100),
+		signTxBatchChan:           make(chan tdtypes.RPCRequest, 100),
 		waitSignLock:              &sync.Mutex{},
 		waitSignMsgs:              make(map[string]common.SignStateRequest),
+		waitSignTxBatchLock:       &sync.Mutex{},
+		waitSignTxBatchMsgs:       make(map[string]common.SignTxBatchRequest),
 		waitSignSlashLock:         &sync.Mutex{},
 		waitSignSlashMsgs:         make(map[string]map[uint64]common.SlashRequest),
 		cacheVerifyLock:           &sync.Mutex{},
@@ -143,7 +169,7 @@ func NewProcessor(cfg common.Configuration, contx context.Context, tssInstance t
 
 func (p *Processor) Start() {
 	p.logger.Info().Msg("Signer is starting")
-	p.wg.Add(8)
+	p.wg.Add(11)
 	p.run()
 }
 
@@ -154,6 +180,7 @@ func (p *Processor) Stop() {
 	p.wsClient.Cli.Stop()
 	p.cancel()
 	p.l2Client.Close()
+	p.stateVerifier.Close()
 	p.l1Client.Close()
 	p.wg.Wait()
 }
@@ -165,6 +192,9 @@ func (p *Processor) run() {
 	go p.Sign()
 	go p.SignSlash()
 	go p.Keygen()
+	go p.Reshare()
 	go p.deleteSlashing()
 	go p.SignRollBack()
+	go p.VerifyTxBatch()
+	go p.SignTxBatch()
 }
@@ -0,0 +1,121 @@
+package signer
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/slices"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/common"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/resharing"
+	tdtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+func (p *Processor) Reshare() {
+	defer p.wg.Done()
+	logger := p.logger.With().Str("step", "reshare").Logger()
+
+	logger.Info().Msg("start to reshare ")
+
+	go func() {
+		defer func() {
+			logger.Info().Msg("exit reshare process")
+		}()
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			case req := <-p.reshareRequestChan:
+				var resId = req.ID.(tdtypes.JSONRPCStringID).String()
+				logger.Info().Msgf("dealing resId (%s) ", resId)
+
+				var reshareR tsscommon.ReshareRequest
+				if err := json.Unmarshal(req.Params, &reshareR); err != nil {
+					logger.Error().Msg("failed to unmarshal reshare request")
+					RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 201, "failed", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+					continue
+				}
+
+				reshareReq := resharing.NewRequest(
+					reshareR.ClusterPublicKey,
+					reshareR.OldNodes,
+					reshareR.OldThreshold,
+					reshareR.NewNodes,
+					reshareR.NewThreshold,
+					reshareR.KeyEpoch,
+				)
+				resp, err := p.tssServer.Reshare(reshareReq)
+
+				if err != nil {
+					logger.Err(err).Msg("failed to reshare !")
+					RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 202, "failed", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+				} else {
+					if resp.Status == common.Success {
+						reshareResponse := tsscommon.ReshareResponse{
+							ClusterPublicKey: resp.PubKey,
+						}
+						RpcResponse := tdtypes.NewRPCSuccessResponse(tdtypes.JSONRPCStringID(resId), reshareResponse)
+						p.wsClient.SendMsg(RpcResponse)
+						p.wg.Add(1)
+						go p.waitReshareConfirmed(reshareR)
+						// only the members of the new committee confirm the
+						// unchanged group public key on layer one
+						if !slices.Exists(reshareR.NewNodes, p.localPubkey) {
+							continue
+						}
+						logger.Info().Msgf("reshare start to set group publickey for l1 contract")
+						err := p.setGroupPublicKey(p.localPubKeyByte, resp.PubKeyByte)
+						if err != nil {
+							logger.Err(err).Msg("failed to send tss group manager transactionx")
+						}
+					} else {
+						RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 202, "failed", resp.FailReason)
+						p.wsClient.SendMsg(RpcResponse)
+					}
+				}
+
+			}
+		}
+	}()
+}
+
+// waitReshareConfirmed switches to the reshared key share once the election
+// of the resharing is active on layer one with the unchanged CPK, and
+// deletes the key share it supersedes. The reshared key share is discarded
+// when the election is active with another CPK, as the manager fell back to
+// a keygen.
+func (p *Processor) waitReshareConfirmed(req tsscommon.ReshareRequest) {
+	defer p.wg.Done()
+	logger := p.logger.With().Str("step", "confirm reshare").Uint64("election id", req.ElectionId).Logger()
+	keyEpoch := req.KeyEpoch + 1
+	queryTicker := time.NewTicker(p.taskInterval)
+	defer queryTicker.Stop()
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-queryTicker.C:
+		}
+		tssInfo, err := p.tssQueryService.QueryActiveInfo()
+		if err != nil {
+			logger.Err(err).Msg("failed to query active tss info")
+			continue
+		}
+		if tssInfo.ElectionId < req.ElectionId {
+			continue
+		}
+		if tssInfo.ClusterPubKey == req.ClusterPublicKey {
+			err = p.tssServer.ActivateReshare(req.ClusterPublicKey, keyEpoch)
+		} else {
+			logger.Warn().Msg("the election is active with another cpk, discard the reshared key share")
+			err = p.tssServer.DiscardReshare(req.ClusterPublicKey, keyEpoch)
+		}
+		if err != nil {
+			logger.Err(err).Msg("failed to switch key share after resharing")
+			continue
+		}
+		return
+	}
+}
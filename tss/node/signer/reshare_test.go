@@ -0,0 +1,111 @@
+package signer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	managertypes "github.com/mantlenetworkio/mantle/tss/manager/types"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/resharing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReshareServer records the key epochs switched to and discarded
+type fakeReshareServer struct {
+	tsslib.Server
+	lock      sync.Mutex
+	activated []int
+	discarded []int
+}
+
+func (f *fakeReshareServer) Reshare(resharing.Request) (resharing.Response, error) {
+	return resharing.Response{}, nil
+}
+
+func (f *fakeReshareServer) ActivateReshare(_ string, keyEpoch int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.activated = append(f.activated, keyEpoch)
+	return nil
+}
+
+func (f *fakeReshareServer) DiscardReshare(_ string, keyEpoch int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.discarded = append(f.discarded, keyEpoch)
+	return nil
+}
+
+// fakeQueryService returns the active committee it is set to
+type fakeQueryService struct {
+	lock   sync.Mutex
+	active managertypes.TssCommitteeInfo
+}
+
+func (f *fakeQueryService) QueryActiveInfo() (managertypes.TssCommitteeInfo, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.active, nil
+}
+
+func (f *fakeQueryService) QueryInactiveInfo() (managertypes.TssCommitteeInfo, error) {
+	return managertypes.TssCommitteeInfo{}, nil
+}
+
+func (f *fakeQueryService) setActive(info managertypes.TssCommitteeInfo) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.active = info
+}
+
+func TestWaitReshareConfirmed(t *testing.T) {
+	req := tsscommon.ReshareRequest{ClusterPublicKey: "abcd", ElectionId: 2, KeyEpoch: 1}
+	tests := []struct {
+		name      string
+		confirmed managertypes.TssCommitteeInfo
+		activated []int
+		discarded []int
+	}{
+		{"confirmed", managertypes.TssCommitteeInfo{ElectionId: 2, ClusterPubKey: "abcd"}, []int{2}, nil},
+		{"keygen", managertypes.TssCommitteeInfo{ElectionId: 2, ClusterPubKey: "ef01"}, nil, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := new(fakeReshareServer)
+			queryService := &fakeQueryService{active: managertypes.TssCommitteeInfo{ElectionId: 1, ClusterPubKey: "abcd"}}
+			p := &Processor{
+				tssServer:       server,
+				tssQueryService: queryService,
+				taskInterval:    10 * time.Millisecond,
+				stopChan:        make(chan struct{}),
+				wg:              &sync.WaitGroup{},
+				logger:          zerolog.Nop(),
+			}
+			p.wg.Add(1)
+			done := make(chan struct{})
+			go func() {
+				p.waitReshareConfirmed(req)
+				close(done)
+			}()
+
+			// The former election keeps the key share in use
+			time.Sleep(50 * time.Millisecond)
+			server.lock.Lock()
+			require.Empty(t, server.activated)
+			require.Empty(t, server.discarded)
+			server.lock.Unlock()
+
+			queryService.setActive(tt.confirmed)
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("expected the resharing to be settled")
+			}
+			require.Equal(t, tt.activated, server.activated)
+			require.Equal(t, tt.discarded, server.discarded)
+		})
+	}
+}
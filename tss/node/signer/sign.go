@@ -112,7 +112,7 @@ func (p *Processor) SignGo(resId tdtypes.JSONRPCStringID, sign tsscommon.NodeSig
 			return err
 		}
 		bol := p.CacheSign(hashStr, signData)
-		logger.Info().Msgf("cache sign byte behavior %s ", bol)
+		logger.Info().Msgf("cache sign byte behavior %t ", bol)
 		data = signData
 	} else {
 		data = signByte
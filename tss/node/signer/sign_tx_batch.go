@@ -0,0 +1,129 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	tdtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+func (p *Processor) SignTxBatch() {
+	defer p.wg.Done()
+	logger := p.logger.With().Str("step", "sign tx batch Message").Logger()
+
+	logger.Info().Msg("start to sign tx batch message ")
+
+	go func() {
+		defer func() {
+			logger.Info().Msg("exit sign tx batch process")
+		}()
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			case req := <-p.signTxBatchChan:
+				var resId = req.ID.(tdtypes.JSONRPCStringID).String()
+				logger.Info().Msgf("dealing resId (%s) ", resId)
+
+				var nodeSignRequest tsscommon.NodeSignRequest
+				rawMsg := json.RawMessage{}
+				nodeSignRequest.RequestBody = &rawMsg
+
+				if err := json.Unmarshal(req.Params, &nodeSignRequest); err != nil {
+					logger.Error().Msg("failed to unmarshal tx batch request")
+					RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 201, "failed", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+					continue
+				}
+				var requestBody tsscommon.SignTxBatchRequest
+				if err := json.Unmarshal(rawMsg, &requestBody); err != nil {
+					logger.Error().Msg("failed to umarshal tx batch params request body")
+					RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 201, "failed", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+					continue
+				}
+				nodeSignRequest.RequestBody = requestBody
+
+				hash, signByte, err := p.checkTxBatch(requestBody)
+				if err != nil {
+					logger.Err(err).Msg("check tx batch failed")
+					RpcResponse := tdtypes.NewRPCErrorResponse(req.ID, 201, "failed", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+					continue
+				}
+				hashStr := hexutil.Encode(hash)
+
+				if signByte == nil {
+					data, culprits, err := p.handleSign(nodeSignRequest, hash, logger)
+					if err != nil {
+						logger.Error().Msgf("tx batch %s sign failed ", hashStr)
+						var errorRes tdtypes.RPCResponse
+						if len(culprits) > 0 {
+							respData := strings.Join(culprits, ",")
+							errorRes = tdtypes.NewRPCErrorResponse(req.ID, tsscommon.CulpritErrorCode, err.Error(), respData)
+							p.nodeStore.AddCulprits(culprits)
+						} else {
+							errorRes = tdtypes.NewRPCErrorResponse(req.ID, 201, "sign failed", err.Error())
+						}
+						er := p.wsClient.SendMsg(errorRes)
+						if er != nil {
+							logger.Err(er).Msg("failed to send msg to tss manager")
+						}
+						continue
+					}
+					bol := p.CacheSign(hashStr, data)
+					logger.Info().Msgf("cache sign byte behavior %t ", bol)
+					signByte = data
+				}
+
+				signResponse := tsscommon.SignResponse{
+					Signature: signByte,
+				}
+				RpcResponse := tdtypes.NewRPCSuccessResponse(req.ID, signResponse)
+				if err = p.wsClient.SendMsg(RpcResponse); err != nil {
+					logger.Err(err).Msg("failed to sendMsg to tss manager ")
+				} else {
+					logger.Info().Msg("send tx batch sign response successfully")
+					p.removeWaitTxBatchEvent(hashStr)
+				}
+			}
+		}
+	}()
+}
+
+func (p *Processor) checkTxBatch(msg tsscommon.SignTxBatchRequest) (hashByte, signByte []byte, err error) {
+	hashByte, err = txBatchMsgToHash(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashStr := hexutil.Encode(hashByte)
+
+	signByte, ok := p.cacheSign.Get(hashStr)
+	if ok {
+		return hashByte, signByte, nil
+	}
+	p.waitSignTxBatchLock.Lock()
+	_, ok = p.waitSignTxBatchMsgs[hashStr]
+	p.waitSignTxBatchLock.Unlock()
+	if !ok {
+		return nil, nil, errors.New("sign request has the unverified tx batch")
+	}
+	return hashByte, nil, nil
+}
+
+func txBatchMsgToHash(msg tsscommon.SignTxBatchRequest) ([]byte, error) {
+	_, shouldStartAtElement, err := msg.Range()
+	if err != nil {
+		return nil, err
+	}
+	return tsscommon.TxBatchHash(msg.TxHashes, shouldStartAtElement)
+}
+
+func (p *Processor) removeWaitTxBatchEvent(key string) {
+	p.waitSignTxBatchLock.Lock()
+	defer p.waitSignTxBatchLock.Unlock()
+	delete(p.waitSignTxBatchMsgs, key)
+}
@@ -0,0 +1,244 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethc "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/mantlenetworkio/mantle/l2geth/common"
+	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
+	l2types "github.com/mantlenetworkio/mantle/l2geth/core/types"
+	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
+	"github.com/mantlenetworkio/mantle/l2geth/rpc"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+)
+
+const getQueueElementABI = `[{"inputs":[{"internalType":"uint256","name":"_index","type":"uint256"}],"name":"getQueueElement","outputs":[{"components":[{"internalType":"bytes32","name":"transactionHash","type":"bytes32"},{"internalType":"uint40","name":"timestamp","type":"uint40"},{"internalType":"uint40","name":"blockNumber","type":"uint40"}],"internalType":"struct Lib_BVMCodec.QueueElement","name":"_element","type":"tuple"}],"stateMutability":"view","type":"function"}]`
+
+// maxQueueCheckBlocks is the number of blocks whose l1 to l2 transactions are
+// checked at the same time
+const maxQueueCheckBlocks = 8
+
+var (
+	errQueueTxMismatch = errors.New("l1 to l2 transaction doesn't match the CTC queue")
+	errL2Unavailable   = errors.New("fail to get the l2 block")
+
+	ctcABI, _          = abi.JSON(strings.NewReader(getQueueElementABI))
+	typAddress, _      = abi.NewType("address", "address", nil)
+	typUint256, _      = abi.NewType("uint256", "uint256", nil)
+	typBytes, _        = abi.NewType("bytes", "bytes", nil)
+	enqueueTxArguments = abi.Arguments{{Type: typAddress}, {Type: typAddress}, {Type: typUint256}, {Type: typBytes}}
+)
+
+type queueElement struct {
+	TransactionHash [32]byte
+	Timestamp       *big.Int
+	BlockNumber     *big.Int
+}
+
+// rpcHeader is the part of an l2 header the state roots are verified with.
+type rpcHeader struct {
+	Root common.Hash `json:"stateRoot"`
+}
+
+// stateVerifier verifies state roots against several l2 endpoints, a state
+// root is trusted once quorum endpoints return it.
+type stateVerifier struct {
+	sources  []*rpc.Client
+	quorum   int
+	l2Client *l2ethclient.Client
+
+	// the CTC queue check is disabled when l1Client is nil
+	l1Client   *ethclient.Client
+	ctcAddress ethc.Address
+}
+
+func newStateVerifier(ctx context.Context, cfg tsscommon.Configuration, l2RpcClient *rpc.Client, l1Client *ethclient.Client) (*stateVerifier, error) {
+	sources := []*rpc.Client{l2RpcClient}
+	for _, url := range strings.Split(cfg.Node.L2EthRpcs, ",") {
+		url = strings.TrimSpace(url)
+		if len(url) == 0 {
+			continue
+		}
+		source, err := DialL2RpcClientWithTimeout(ctx, url, cfg.Node.DisableHTTP2)
+		if err != nil {
+			return nil, fmt.Errorf("fail to dial l2 endpoint %s: %w", url, err)
+		}
+		sources = append(sources, source)
+	}
+	quorum := cfg.Node.L2VerifyQuorum
+	if quorum == 0 {
+		quorum = len(sources)/2 + 1
+	}
+	// a minority quorum could be reached by two different roots
+	if quorum <= len(sources)/2 || quorum > len(sources) {
+		return nil, fmt.Errorf("l2 verify quorum %d is not a majority of the %d l2 endpoints", quorum, len(sources))
+	}
+	verifier := &stateVerifier{
+		sources:  sources,
+		quorum:   quorum,
+		l2Client: l2ethclient.NewClient(l2RpcClient),
+	}
+	if cfg.Node.VerifyCtcQueue {
+		if !ethc.IsHexAddress(cfg.CtcContractAddress) {
+			return nil, errors.New("ctc_contract_address is required to verify the CTC queue")
+		}
+		verifier.l1Client = l1Client
+		verifier.ctcAddress = ethc.HexToAddress(cfg.CtcContractAddress)
+	}
+	return verifier, nil
+}
+
+func (v *stateVerifier) Close() {
+	for _, source := range v.sources[1:] {
+		source.Close()
+	}
+}
+
+// agreedRoots returns the state roots of the blocks the quorum agrees on, nil
+// for a block without a quorum.
+func (v *stateVerifier) agreedRoots(ctx context.Context, numbers []*big.Int) []*common.Hash {
+	votes := make([][]*common.Hash, len(numbers))
+	for i := range votes {
+		votes[i] = make([]*common.Hash, len(v.sources))
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(len(v.sources))
+	for i, source := range v.sources {
+		// each goroutine writes its own column only
+		go func(index int, source *rpc.Client) {
+			defer wg.Done()
+			heads := make([]*rpcHeader, len(numbers))
+			batch := make([]rpc.BatchElem, len(numbers))
+			for j, number := range numbers {
+				batch[j] = rpc.BatchElem{
+					Method: "eth_getBlockByNumber",
+					Args:   []interface{}{hexutil.EncodeBig(number), false},
+					Result: &heads[j],
+				}
+			}
+			if err := source.BatchCallContext(ctx, batch); err != nil {
+				return
+			}
+			for j := range batch {
+				if batch[j].Error == nil && heads[j] != nil {
+					root := heads[j].Root
+					votes[j][index] = &root
+				}
+			}
+		}(i, source)
+	}
+	wg.Wait()
+
+	roots := make([]*common.Hash, len(numbers))
+	for i := range numbers {
+		roots[i] = quorumRoot(votes[i], v.quorum)
+	}
+	return roots
+}
+
+// quorumRoot returns the root at least quorum votes are for, a nil vote is an
+// endpoint that did not answer.
+func quorumRoot(votes []*common.Hash, quorum int) *common.Hash {
+	counts := make(map[common.Hash]int)
+	for _, vote := range votes {
+		if vote == nil {
+			continue
+		}
+		counts[*vote]++
+		if counts[*vote] >= quorum {
+			root := *vote
+			return &root
+		}
+	}
+	return nil
+}
+
+// checkQueueTxs checks the l1 to l2 transactions of the blocks against the
+// elements the CTC enqueued on l1. It returns errQueueTxMismatch for a
+// transaction the CTC does not have, and errL2Unavailable for a block it
+// could not read. Sequencer transactions are not checked, and the blocks are
+// read from the primary l2 endpoint only, not through the quorum sources.
+func (v *stateVerifier) checkQueueTxs(ctx context.Context, numbers []*big.Int) error {
+	if v.l1Client == nil {
+		return nil
+	}
+	errs := make([]error, len(numbers))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(numbers))
+	sem := make(chan struct{}, maxQueueCheckBlocks)
+	for i, number := range numbers {
+		sem <- struct{}{}
+		go func(index int, number *big.Int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			block, err := v.l2Client.BlockByNumber(ctx, number)
+			if err != nil {
+				errs[index] = fmt.Errorf("%w %d: %v", errL2Unavailable, number, err)
+				return
+			}
+			for _, tx := range block.Transactions() {
+				if tx.QueueOrigin() != l2types.QueueOriginL1ToL2 {
+					continue
+				}
+				if err := v.checkQueueTx(ctx, tx); err != nil {
+					errs[index] = fmt.Errorf("block %d: %w", number, err)
+					return
+				}
+			}
+		}(i, number)
+	}
+	wg.Wait()
+
+	var anyErr error
+	for _, err := range errs {
+		if errors.Is(err, errQueueTxMismatch) {
+			return err
+		}
+		if err != nil {
+			anyErr = err
+		}
+	}
+	return anyErr
+}
+
+func (v *stateVerifier) checkQueueTx(ctx context.Context, tx *l2types.Transaction) error {
+	meta := tx.GetMeta()
+	if meta.QueueIndex == nil || tx.L1MessageSender() == nil || tx.To() == nil || tx.L1BlockNumber() == nil {
+		return fmt.Errorf("tx %s: %w", tx.Hash().Hex(), errQueueTxMismatch)
+	}
+	input, err := ctcABI.Pack("getQueueElement", new(big.Int).SetUint64(*meta.QueueIndex))
+	if err != nil {
+		return err
+	}
+	output, err := v.l1Client.CallContract(ctx, ethereum.CallMsg{To: &v.ctcAddress, Data: input}, nil)
+	if err != nil {
+		return fmt.Errorf("fail to get queue element %d: %w", *meta.QueueIndex, err)
+	}
+	values, err := ctcABI.Unpack("getQueueElement", output)
+	if err != nil {
+		return fmt.Errorf("fail to unpack queue element %d: %w", *meta.QueueIndex, err)
+	}
+	element := *abi.ConvertType(values[0], new(queueElement)).(*queueElement)
+
+	encoded, err := enqueueTxArguments.Pack(ethc.Address(*tx.L1MessageSender()), ethc.Address(*tx.To()), new(big.Int).SetUint64(tx.Gas()), tx.Data())
+	if err != nil {
+		return err
+	}
+	if crypto.Keccak256Hash(encoded) != element.TransactionHash ||
+		element.Timestamp.Uint64() != tx.L1Timestamp() ||
+		element.BlockNumber.Cmp(tx.L1BlockNumber()) != 0 {
+		return fmt.Errorf("tx %s, queue index %d: %w", tx.Hash().Hex(), *meta.QueueIndex, errQueueTxMismatch)
+	}
+	return nil
+}
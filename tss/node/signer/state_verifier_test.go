@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/mantlenetworkio/mantle/l2geth/common"
+	l2ethclient "github.com/mantlenetworkio/mantle/l2geth/ethclient"
+	"github.com/mantlenetworkio/mantle/l2geth/rpc"
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuorumRoot(t *testing.T) {
+	a, b := common.HexToHash("0x0a"), common.HexToHash("0x0b")
+
+	require.EqualValues(t, &a, quorumRoot([]*common.Hash{&a}, 1))
+	require.EqualValues(t, &a, quorumRoot([]*common.Hash{&a, &b, &a}, 2))
+	require.Nil(t, quorumRoot([]*common.Hash{&a, &b, nil}, 2))
+	require.Nil(t, quorumRoot([]*common.Hash{nil, nil, nil}, 1))
+	require.EqualValues(t, &b, quorumRoot([]*common.Hash{nil, &b, &b}, 2))
+}
+
+func TestStateVerifierQuorum(t *testing.T) {
+	primary, err := rpc.DialHTTP("http://127.0.0.1:1")
+	require.NoError(t, err)
+	var cfg tsscommon.Configuration
+	cfg.Node.L2EthRpcs = "http://127.0.0.1:2, http://127.0.0.1:3,http://127.0.0.1:4"
+
+	verifier, err := newStateVerifier(context.Background(), cfg, primary, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, verifier.quorum)
+	verifier.Close()
+
+	// a minority of the endpoints could agree on two roots
+	for _, quorum := range []int{-1, 1, 2, 5} {
+		cfg.Node.L2VerifyQuorum = quorum
+		_, err = newStateVerifier(context.Background(), cfg, primary, nil)
+		require.Error(t, err, "quorum %d", quorum)
+	}
+	cfg.Node.L2VerifyQuorum = 4
+	verifier, err = newStateVerifier(context.Background(), cfg, primary, nil)
+	require.NoError(t, err)
+	verifier.Close()
+}
+
+func TestCheckQueueTxsL2Unavailable(t *testing.T) {
+	var lock sync.Mutex
+	var active, maxActive int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		active--
+		lock.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	l2RpcClient, err := rpc.DialHTTP(server.URL)
+	require.NoError(t, err)
+	l1Client, err := ethclient.Dial("http://127.0.0.1:1")
+	require.NoError(t, err)
+	verifier := &stateVerifier{l2Client: l2ethclient.NewClient(l2RpcClient), l1Client: l1Client}
+
+	numbers := make([]*big.Int, 4*maxQueueCheckBlocks)
+	for i := range numbers {
+		numbers[i] = big.NewInt(int64(i + 1))
+	}
+	err = verifier.checkQueueTxs(context.Background(), numbers)
+	require.ErrorIs(t, err, errL2Unavailable)
+	require.NotErrorIs(t, err, errQueueTxMismatch)
+	lock.Lock()
+	defer lock.Unlock()
+	require.LessOrEqual(t, maxActive, maxQueueCheckBlocks)
+	require.Greater(t, maxActive, 1)
+}
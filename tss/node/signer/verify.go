@@ -2,8 +2,8 @@ package signer
 
 import (
 	"encoding/json"
+	"errors"
 	"math/big"
-	"sync"
 
 	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
 	"github.com/mantlenetworkio/mantle/tss/common"
@@ -34,20 +34,8 @@ func (p *Processor) Verify() {
 					continue
 				}
 				var resId = req.ID
-				offset := len(askRequest.StateRoots)
-
-				wg := &sync.WaitGroup{}
-				wg.Add(offset)
-				var result = true
-				for index, stateRoot := range askRequest.StateRoots {
-					go func(fIndex int, fStateRoot [32]byte) {
-						resultTmp := p.verify(askRequest.StartBlock, fIndex, fStateRoot, logger, wg)
-						if !resultTmp {
-							result = resultTmp
-						}
-					}(index, stateRoot)
-				}
-				wg.Wait()
+
+				result, reason := p.verify(askRequest, logger)
 				if result {
 					hash, err := signMsgToHash(askRequest)
 					if err != nil {
@@ -62,6 +50,7 @@ func (p *Processor) Verify() {
 				}
 				askResponse := common.AskResponse{
 					Result: result,
+					Reason: reason,
 				}
 				RpcResponse = tdtypes.NewRPCSuccessResponse(resId, askResponse)
 				p.wsClient.SendMsg(RpcResponse)
@@ -73,37 +62,71 @@ func (p *Processor) Verify() {
 
 }
 
-func (p *Processor) verify(start string, index int, stateRoot [32]byte, logger zerolog.Logger, wg *sync.WaitGroup) bool {
-	defer wg.Done()
-	defer logger.Info().Msgf("start block number:(%s),index (%d), verify done", start, index)
+// verify checks the state roots against the quorum of the l2 endpoints and,
+// when enabled, the l1 to l2 transactions of the blocks against the CTC queue.
+func (p *Processor) verify(request common.SignStateRequest, logger zerolog.Logger) (bool, common.VerifyReason) {
+	startBlock, ok := new(big.Int).SetString(request.StartBlock, 10)
+	if !ok || len(request.StateRoots) == 0 {
+		logger.Error().Msgf("invalid state batch, start block (%s), %d state roots", request.StartBlock, len(request.StateRoots))
+		return false, common.ReasonInvalidRequest
+	}
 
-	offset := new(big.Int).SetInt64(int64(index))
-	startBig, _ := new(big.Int).SetString(start, 10)
-	blockNumber := offset.Add(offset, startBig)
-	logger.Info().Msgf("verify block number %d", blockNumber)
+	numbers := make([]*big.Int, 0, len(request.StateRoots))
+	stateRoots := make([][32]byte, 0, len(request.StateRoots))
+	for index, stateRoot := range request.StateRoots {
+		blockNumber := new(big.Int).Add(startBlock, big.NewInt(int64(index)))
+		value, ok := p.getCacheVerify(blockNumber.String())
+		if !ok {
+			numbers = append(numbers, blockNumber)
+			stateRoots = append(stateRoots, stateRoot)
+			continue
+		}
+		if !value {
+			logger.Info().Msgf("block number (%d) state root verified as wrong before", blockNumber)
+			return false, common.ReasonStateRootMismatch
+		}
+	}
+	if len(numbers) == 0 {
+		return true, ""
+	}
+	logger.Info().Msgf("verify block number %d to %d", numbers[0], numbers[len(numbers)-1])
 
-	value, ok := p.cacheVerify.Get(blockNumber.String())
-	if ok {
-		return value
+	var noQuorum bool
+	roots := p.stateVerifier.agreedRoots(p.ctx, numbers)
+	for i, root := range roots {
+		if root == nil {
+			logger.Warn().Msgf("block number (%d) state root has no quorum of l2 endpoints", numbers[i])
+			noQuorum = true
+			continue
+		}
+		if *root != stateRoots[i] {
+			logger.Info().Msgf("block number (%d) state root doesn't same, state root (%s) , block root (%s)", numbers[i], hexutil.Encode(stateRoots[i][:]), root.String())
+			bol := p.CacheVerify(numbers[i].String(), false)
+			logger.Info().Msgf("cache verify behavior %t ", bol)
+			return false, common.ReasonStateRootMismatch
+		}
+	}
+	if noQuorum {
+		return false, common.ReasonNoQuorum
 	}
 
-	block, err := p.l2Client.BlockByNumber(p.ctx, blockNumber)
-	if err != nil {
-		logger.Err(err).Msgf("failed to get block by (%d) ", blockNumber)
-		return false
-	} else {
-		if hexutil.Encode(stateRoot[:]) != block.Root().String() {
-			logger.Info().Msgf("block number (%d) state root doesn't same, state root (%s) , block root (%s)", blockNumber, hexutil.Encode(stateRoot[:]), block.Root().String())
-			bol := p.CacheVerify(blockNumber.String(), false)
-			logger.Info().Msgf("cache verify behavior %s ", bol)
-			return false
-		} else {
-			logger.Info().Msgf("block number (%d) verify success", blockNumber)
-			bol := p.CacheVerify(blockNumber.String(), true)
-			logger.Info().Msgf("cache verify behavior %s ", bol)
-			return true
+	if err := p.stateVerifier.checkQueueTxs(p.ctx, numbers); err != nil {
+		logger.Err(err).Msg("failed to check the CTC queue")
+		if errors.Is(err, errQueueTxMismatch) {
+			return false, common.ReasonTxMismatch
+		}
+		if errors.Is(err, errL2Unavailable) {
+			return false, common.ReasonL2Unavailable
 		}
+		return false, common.ReasonL1Unavailable
+	}
+
+	for _, number := range numbers {
+		bol := p.CacheVerify(number.String(), true)
+		logger.Info().Msgf("cache verify behavior %t ", bol)
 	}
+	logger.Info().Msgf("block number %d to %d verify success", numbers[0], numbers[len(numbers)-1])
+	return true, ""
 }
 
 func (p *Processor) UpdateWaitSignEvents(uniqueId string, msg common.SignStateRequest) {
@@ -117,3 +140,9 @@ func (p *Processor) CacheVerify(key string, value bool) bool {
 	defer p.cacheVerifyLock.Unlock()
 	return p.cacheVerify.Set(key, value)
 }
+
+func (p *Processor) getCacheVerify(key string) (bool, bool) {
+	p.cacheVerifyLock.Lock()
+	defer p.cacheVerifyLock.Unlock()
+	return p.cacheVerify.Get(key)
+}
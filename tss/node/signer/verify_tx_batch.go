@@ -0,0 +1,109 @@
+package signer
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/mantlenetworkio/mantle/l2geth/common/hexutil"
+	"github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/rs/zerolog"
+	tdtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
+)
+
+func (p *Processor) VerifyTxBatch() {
+	defer p.wg.Done()
+	logger := p.logger.With().Str("step", "verify tx batch event").Logger()
+	logger.Info().Msg("start to verify tx batch events ")
+
+	go func() {
+		defer func() {
+			logger.Info().Msg("exit verify tx batch event process")
+		}()
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			case req := <-p.askTxBatchChan:
+				var askRequest common.SignTxBatchRequest
+				var RpcResponse tdtypes.RPCResponse
+				var resId = req.ID
+				if err := json.Unmarshal(req.Params, &askRequest); err != nil {
+					logger.Error().Msg("failed to unmarshal ask tx batch request")
+					RpcResponse = tdtypes.NewRPCErrorResponse(resId, 201, "failed to unmarshal", err.Error())
+					p.wsClient.SendMsg(RpcResponse)
+					continue
+				}
+
+				result := p.verifyTxBatch(askRequest, logger)
+				if result {
+					hash, err := txBatchMsgToHash(askRequest)
+					if err != nil {
+						logger.Err(err).Msg("failed to conv tx batch msg to hash")
+						RpcResponse = tdtypes.NewRPCErrorResponse(resId, 201, "failed to conv msg to hash", err.Error())
+						p.wsClient.SendMsg(RpcResponse)
+						continue
+					}
+					p.UpdateWaitSignTxBatchMsgs(hexutil.Encode(hash), askRequest)
+				}
+				askResponse := common.AskResponse{
+					Result: result,
+				}
+				RpcResponse = tdtypes.NewRPCSuccessResponse(resId, askResponse)
+				p.wsClient.SendMsg(RpcResponse)
+			}
+		}
+	}()
+}
+
+// verifyTxBatch checks that the batch starts at the block of its first element
+// and that every tx hash of the batch is the lone transaction of the matching
+// block of the local l2 chain.
+func (p *Processor) verifyTxBatch(request common.SignTxBatchRequest, logger zerolog.Logger) bool {
+	startBlock, _, err := request.Range()
+	if err != nil {
+		logger.Err(err).Msg("invalid tx batch range")
+		return false
+	}
+	if len(request.TxHashes) == 0 {
+		logger.Error().Msg("invalid tx batch, no tx hashes")
+		return false
+	}
+	results := make([]bool, len(request.TxHashes))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(request.TxHashes))
+	for i, txHash := range request.TxHashes {
+		go func(index int, txHash [32]byte) {
+			defer wg.Done()
+			blockNumber := new(big.Int).Add(startBlock, big.NewInt(int64(index)))
+			block, err := p.l2Client.BlockByNumber(p.ctx, blockNumber)
+			if err != nil {
+				logger.Err(err).Msgf("failed to get block by (%d) ", blockNumber)
+				return
+			}
+			txs := block.Transactions()
+			if len(txs) != 1 {
+				logger.Error().Msgf("block number (%d) has %d txs instead of 1", blockNumber, len(txs))
+				return
+			}
+			if txs[0].Hash() != txHash {
+				logger.Info().Msgf("block number (%d) tx hash doesn't same, tx hash (%s) , block tx hash (%s)", blockNumber, hexutil.Encode(txHash[:]), txs[0].Hash().String())
+				return
+			}
+			results[index] = true
+		}(i, txHash)
+	}
+	wg.Wait()
+	for _, result := range results {
+		if !result {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Processor) UpdateWaitSignTxBatchMsgs(uniqueId string, msg common.SignTxBatchRequest) {
+	p.waitSignTxBatchLock.Lock()
+	defer p.waitSignTxBatchLock.Unlock()
+	p.waitSignTxBatchMsgs[uniqueId] = msg
+}
@@ -0,0 +1,43 @@
+package signer
+
+import (
+	"testing"
+
+	tsscommon "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTxBatchRange(t *testing.T) {
+	txHashes := [][32]byte{{1}, {2}}
+	tests := []struct {
+		name                 string
+		startBlock           string
+		shouldStartAtElement string
+	}{
+		{"same block and element", "10", "10"},
+		{"element ahead", "10", "11"},
+		{"block too far", "12", "10"},
+		{"invalid start block", "x", "10"},
+		{"invalid element", "11", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tsscommon.SignTxBatchRequest{
+				StartBlock:           tt.startBlock,
+				ShouldStartAtElement: tt.shouldStartAtElement,
+				TxHashes:             txHashes,
+			}
+			// No l2 client is set, the batch is rejected before it is read
+			p := &Processor{logger: zerolog.Nop()}
+			require.False(t, p.verifyTxBatch(request, zerolog.Nop()))
+			_, err := txBatchMsgToHash(request)
+			require.Error(t, err)
+		})
+	}
+
+	request := tsscommon.SignTxBatchRequest{StartBlock: "11", ShouldStartAtElement: "10", TxHashes: txHashes}
+	hash, err := txBatchMsgToHash(request)
+	require.NoError(t, err)
+	require.NotEmpty(t, hash)
+}
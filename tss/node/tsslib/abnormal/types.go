@@ -28,6 +28,25 @@ var (
 type PartyInfo struct {
 	Party      tss.Party
 	PartyIDMap map[string]*tss.PartyID
+	// NewParty is the party of the new committee of a resharing, when the
+	// local node is in both the old and the new committees. Party is then
+	// the party of the old committee.
+	NewParty tss.Party
+}
+
+// RecipientParties returns the local parties a message is routed to
+func (p *PartyInfo) RecipientParties(routing *tss.MessageRouting) []tss.Party {
+	if p.NewParty == nil {
+		return []tss.Party{p.Party}
+	}
+	var parties []tss.Party
+	if routing.IsToOldCommittee || routing.IsToOldAndNewCommittees {
+		parties = append(parties, p.Party)
+	}
+	if !routing.IsToOldCommittee || routing.IsToOldAndNewCommittees {
+		parties = append(parties, p.NewParty)
+	}
+	return parties
 }
 
 type Node struct {
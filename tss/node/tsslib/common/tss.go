@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/binance-chain/tss-lib/tss"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -274,7 +275,7 @@ func (t *TssCommon) updateLocal(wireMsg *messages.WireMessage) error {
 	//	t.logger.Error().Msg("cannot find the party to this wired msg")
 	//	return errors.New("cannot find the party")
 	//}
-	localMsgParty := partyInfo.Party
+	localMsgParties := partyInfo.RecipientParties(bulkMsg.Routing)
 	rPartyID, ok := partyInfo.PartyIDMap[bulkMsg.Routing.From.Id]
 	if !ok {
 		t.logger.Error().Msg("error in find the partyID")
@@ -308,13 +309,15 @@ func (t *TssCommon) updateLocal(wireMsg *messages.WireMessage) error {
 		}
 		t.culpritsLock.RLock()
 		if len(t.culprits) != 0 && partyInlist(partyID, t.culprits) {
-			t.logger.Error().Msgf("the malicious party (party ID:%s) try to send incorrect message to me (party ID:%s)", partyID.Id, localMsgParty.PartyID().Id)
+			t.logger.Error().Msgf("the malicious party (party ID:%s) try to send incorrect message to me (party ID:%s)", partyID.Id, partyInfo.Party.PartyID().Id)
 			t.culpritsLock.RUnlock()
 			return errors.New("tss share verification failed")
 		}
 		t.culpritsLock.RUnlock()
-		job := newJob(localMsgParty, bulkMsg.WiredBulkMsg, round.MsgIdentifier, partyID, bulkMsg.Routing.IsBroadcast)
-		tssJobChan <- job
+		for _, localMsgParty := range localMsgParties {
+			job := newJob(localMsgParty, bulkMsg.WiredBulkMsg, round.MsgIdentifier, partyID, bulkMsg.Routing.IsBroadcast)
+			tssJobChan <- job
+		}
 	}
 
 	close(tssJobChan)
@@ -360,12 +363,24 @@ func (t *TssCommon) sendBulkMsg(wiredMsgType string, tssMsgType messages.TSSMess
 		peerIDs = t.P2PPeers
 		t.P2PPeersLock.RUnlock()
 	} else {
+		sentToLocal := false
 		for _, each := range r.To {
 			peerID, ok := t.PartyIDtoP2PID[each.Id]
 			if !ok {
 				t.logger.Error().Msg("error in find the P2P ID")
 				continue
 			}
+			// the message is for the other local party of a resharing, which
+			// the p2p layer does not send to
+			if peerID.String() == t.localPeerID {
+				if !sentToLocal {
+					sentToLocal = true
+					if err := t.sendToLocal(peerID, wrappedMsg); err != nil {
+						return err
+					}
+				}
+				continue
+			}
 			peerIDs = append(peerIDs, peerID)
 		}
 	}
@@ -377,6 +392,23 @@ func (t *TssCommon) sendBulkMsg(wiredMsgType string, tssMsgType messages.TSSMess
 	return nil
 }
 
+func (t *TssCommon) sendToLocal(localPeerID peer.ID, wrappedMsg messages.WrappedMessage) error {
+	payload, err := json.Marshal(wrappedMsg)
+	if err != nil {
+		return fmt.Errorf("fail to marshal the wrapped message: %w", err)
+	}
+	// the inbound messages are processed by another goroutine, which may be
+	// waiting for the local party to produce this message
+	go func() {
+		select {
+		case t.TssMsg <- &p2p.Message{PeerID: localPeerID, Payload: payload}:
+		case <-time.After(t.conf.KeyGenTimeout):
+			t.logger.Error().Msg("fail to send the message to the local party")
+		}
+	}()
+	return nil
+}
+
 func (t *TssCommon) ProcessOutCh(msg tss.Message, msgType messages.TSSMessageTpe) error {
 	msgData, r, err := msg.WireBytes()
 	// if we cannot get the wire share, the tss will fail, we just quit.
@@ -548,7 +580,10 @@ func (t *TssCommon) ProcessOneMessage(wrappedMsg *messages.WrappedMessage, peerI
 				return fmt.Errorf("duplicated notification from peer %s ignored", peerID)
 			}
 			t.finishedPeers[peerID] = true
-			if len(t.finishedPeers) == len(t.partyInfo.PartyIDMap)-1 {
+			t.P2PPeersLock.RLock()
+			allFinished := len(t.finishedPeers) == len(t.P2PPeers)
+			t.P2PPeersLock.RUnlock()
+			if allFinished {
 				t.logger.Debug().Msg("we get the confirm of the nodes that generate the signature")
 				close(t.taskDone)
 			}
@@ -580,7 +615,7 @@ func (t *TssCommon) processTSSMsg(wireMsg *messages.WireMessage, msgType message
 		t.logger.Error().Msg("error in find the data owner")
 		return errors.New("error in find the data owner")
 	}
-	keyBytes := dataOwner.GetKey()
+	keyBytes := conversion.PartyPubKey(dataOwner.GetKey())
 
 	ok = verifySignature(keyBytes, wireMsg.Message, wireMsg.Sig, t.msgID)
 	if !ok {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/binance-chain/tss-lib/ecdsa/resharing"
 	"github.com/binance-chain/tss-lib/ecdsa/signing"
 	"github.com/binance-chain/tss-lib/tss"
 	"github.com/btcsuite/btcd/btcec"
@@ -107,6 +108,42 @@ func GetMsgRound(msg []byte, partyID *tss.PartyID, isBroadcast bool) (abnormal.R
 			RoundMsg: messages2.KEYSIGN9,
 		}, nil
 
+	case *resharing.DGRound1Message:
+		return abnormal.RoundInfo{
+			Index:    0,
+			RoundMsg: messages2.RESHARE1,
+		}, nil
+
+	case *resharing.DGRound2Message1:
+		return abnormal.RoundInfo{
+			Index:    1,
+			RoundMsg: messages2.RESHARE2a,
+		}, nil
+
+	case *resharing.DGRound2Message2:
+		return abnormal.RoundInfo{
+			Index:    2,
+			RoundMsg: messages2.RESHARE2b,
+		}, nil
+
+	case *resharing.DGRound3Message1:
+		return abnormal.RoundInfo{
+			Index:    3,
+			RoundMsg: messages2.RESHARE3aUnicast,
+		}, nil
+
+	case *resharing.DGRound3Message2:
+		return abnormal.RoundInfo{
+			Index:    4,
+			RoundMsg: messages2.RESHARE3b,
+		}, nil
+
+	case *resharing.DGRound4Message:
+		return abnormal.RoundInfo{
+			Index:    5,
+			RoundMsg: messages2.RESHARE4,
+		}, nil
+
 	default:
 		return abnormal.RoundInfo{}, errors.New("unknown round")
 	}
@@ -125,6 +162,10 @@ func checkUnicast(round abnormal.RoundInfo) bool {
 		}
 		return false
 	}
+	// resharing unicast blame
+	if strings.Contains(round.RoundMsg, "DGR") {
+		return index == 3
+	}
 	// keysign unicast blame
 	if index < 5 {
 		return true
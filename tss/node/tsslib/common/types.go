@@ -13,4 +13,8 @@ type TssConfig struct {
 	PreParamTimeout time.Duration
 	// enable the tss monitor
 	EnableMonitor bool
+	// PreParamsPoolSize is the number of pre-parameters generated ahead of the keygens
+	PreParamsPoolSize int
+	// PreParamsPoolTimeout defines the timeout of the pre-parameter generations of the pool
+	PreParamsPoolTimeout time.Duration
 }
@@ -16,7 +16,33 @@ import (
 	"strconv"
 )
 
+// partyKeyEpochPrefix is prepended to the public key of the parties of the
+// odd key epochs. Resharing needs the parties of the old and the new
+// committees to have distinct keys, even when a node is in both of them, so
+// the party keys alternate between the plain and the prefixed public keys
+// with every resharing.
+const partyKeyEpochPrefix = 0x01
+
 func GetParties(keys []string, localPartyKey string) ([]*tss.PartyID, *tss.PartyID, error) {
+	return GetEpochParties(keys, localPartyKey, 0, "")
+}
+
+// GetEpochParties returns the parties of the keys in the key epoch, with ids
+// prefixed by idPrefix
+func GetEpochParties(keys []string, localPartyKey string, epoch int, idPrefix string) ([]*tss.PartyID, *tss.PartyID, error) {
+	partiesID, localPartyID, err := GetCommitteeParties(keys, localPartyKey, epoch, idPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if localPartyID == nil {
+		return nil, nil, errors.New("local party is not in the list")
+	}
+	return partiesID, localPartyID, nil
+}
+
+// GetCommitteeParties is GetEpochParties for a resharing committee, which the
+// local party may not be in. The local party is nil then.
+func GetCommitteeParties(keys []string, localPartyKey string, epoch int, idPrefix string) ([]*tss.PartyID, *tss.PartyID, error) {
 	var localPartyID *tss.PartyID
 	var unSortedPartiesID []*tss.PartyID
 	sort.Strings(keys)
@@ -25,25 +51,38 @@ func GetParties(keys []string, localPartyKey string) ([]*tss.PartyID, *tss.Party
 		if err != nil {
 			return nil, nil, fmt.Errorf("fail to get account pub key (%s): %w", item, err)
 		}
-		key := new(big.Int).SetBytes(pkBytes)
+		key := PartyKey(pkBytes, epoch)
 		// Set up the parameters
 		// Note: The `id` and `moniker` fields are for convenience to allow you to easily track participants.
 		// The `id` should be a unique string representing this party in the network and `moniker` can be anything (even left blank).
 		// The `uniqueKey` is a unique identifying key for this peer (such as its p2p public key) as a big.Int.
-		partyID := tss.NewPartyID(strconv.Itoa(idx), "", key)
+		partyID := tss.NewPartyID(idPrefix+strconv.Itoa(idx), "", key)
 		if item == localPartyKey {
 			localPartyID = partyID
 		}
 		unSortedPartiesID = append(unSortedPartiesID, partyID)
 	}
-	if localPartyID == nil {
-		return nil, nil, errors.New("local party is not in the list")
-	}
 
 	partiesID := tss.SortPartyIDs(unSortedPartiesID)
 	return partiesID, localPartyID, nil
 }
 
+// PartyKey returns the party key of a node public key in the key epoch
+func PartyKey(pkBytes []byte, epoch int) *big.Int {
+	if epoch%2 == 1 {
+		pkBytes = append([]byte{partyKeyEpochPrefix}, pkBytes...)
+	}
+	return new(big.Int).SetBytes(pkBytes)
+}
+
+// PartyPubKey returns the node public key of a party key of any key epoch
+func PartyPubKey(partyKey []byte) []byte {
+	if len(partyKey) == btcec.PubKeyBytesLenCompressed+1 && partyKey[0] == partyKeyEpochPrefix {
+		return partyKey[1:]
+	}
+	return partyKey
+}
+
 func SetupPartyIDMap(partiesID []*tss.PartyID) map[string]*tss.PartyID {
 	partyIDMap := make(map[string]*tss.PartyID)
 	for _, id := range partiesID {
@@ -67,7 +106,7 @@ func GetPeerIDFromPartyID(partyID *tss.PartyID) (peer.ID, error) {
 	if partyID == nil || !partyID.ValidateBasic() {
 		return "", errors.New("invalid partyID")
 	}
-	pkBytes := partyID.KeyInt().Bytes()
+	pkBytes := PartyPubKey(partyID.KeyInt().Bytes())
 
 	return GetPeerIDFromSecp256PubKey(pkBytes)
 }
@@ -89,10 +128,13 @@ func GetPeersID(partyIDtoP2PID map[string]peer.ID, localPeerID string) []peer.ID
 		return nil
 	}
 	peerIDs := make([]peer.ID, 0, len(partyIDtoP2PID)-1)
+	seen := make(map[peer.ID]bool)
 	for _, value := range partyIDtoP2PID {
-		if value.String() == localPeerID {
+		// a node has two parties when it is in both committees of a resharing
+		if value.String() == localPeerID || seen[value] {
 			continue
 		}
+		seen[value] = true
 		peerIDs = append(peerIDs, value)
 	}
 	return peerIDs
@@ -130,7 +172,7 @@ func PartyIDtoPubKey(party *tss.PartyID) (string, error) {
 	if party == nil || !party.ValidateBasic() {
 		return "", errors.New("invalid party")
 	}
-	partyKeyBytes := party.GetKey()
+	partyKeyBytes := PartyPubKey(party.GetKey())
 	pubKey := hex.EncodeToString(partyKeyBytes)
 
 	return pubKey, nil
@@ -28,13 +28,20 @@ func (t *TssServer) Keygen(req keygen2.Request) (keygen2.Response, error) {
 		Str("threshold", strconv.Itoa(req.ThresHold)).
 		Msg("received keygen request")
 
+	preParams := t.preParams
+	if t.preParamsPool != nil {
+		if preParams, err = t.preParamsPool.Take(t.conf.PreParamTimeout); err != nil {
+			return keygen2.Response{}, err
+		}
+	}
+
 	keygenInstance := keygen2.NewTssKeyGen(
 		t.p2pCommunication.GetLocalPeerID(),
 		t.conf,
 		t.localNodePubKey,
 		t.p2pCommunication.BroadcastMsgChan,
 		t.stopChan,
-		t.preParams,
+		preParams,
 		msgID,
 		t.stateManager,
 		t.secretsEnable,
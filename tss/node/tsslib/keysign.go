@@ -86,22 +86,9 @@ func (t *TssServer) KeySign(req keysign2.Request) (keysign2.Response, error) {
 		return emptyResp, err
 	}
 
-	var localStateItem storage.KeygenLocalState
-	if t.shamirEnable {
-		localStateItem, err = t.shamirManager.GetKeyFile(req.PoolPubKey, t.localNodePubKey)
-		if err != nil {
-			return emptyResp, fmt.Errorf("fail to get local keygen state from shamir manager: %w", err)
-		}
-	} else if t.secretsEnable {
-		localStateItem, err = t.secretsManager.GetKeyFile(req.PoolPubKey)
-		if err != nil {
-			return emptyResp, fmt.Errorf("fail to get local keygen state from secrets manager: %w", err)
-		}
-	} else {
-		localStateItem, err = t.stateManager.GetLocalState(req.PoolPubKey)
-		if err != nil {
-			return emptyResp, fmt.Errorf("fail to get local keygen state from local drive: %w", err)
-		}
+	localStateItem, err := t.getLocalState(req.PoolPubKey)
+	if err != nil {
+		return emptyResp, err
 	}
 
 	_, ok := t.participants[req.PoolPubKey]
@@ -191,6 +178,48 @@ func (t *TssServer) isContainPubkeys(signerPubKeys, participants []string, poolP
 	return nil
 }
 
+// getLocalState returns the key share of poolPubKey in use
+func (t *TssServer) getLocalState(poolPubKey string) (storage.KeygenLocalState, error) {
+	keyEpoch, err := t.stateManager.GetKeyEpoch(poolPubKey)
+	if err != nil {
+		return storage.KeygenLocalState{}, fmt.Errorf("fail to get key epoch: %w", err)
+	}
+	return t.getEpochLocalState(poolPubKey, keyEpoch)
+}
+
+func (t *TssServer) getEpochLocalState(poolPubKey string, keyEpoch int) (storage.KeygenLocalState, error) {
+	if t.shamirEnable {
+		localStateItem, err := t.shamirManager.GetKeyFile(poolPubKey, keyEpoch, t.localNodePubKey)
+		if err != nil {
+			return storage.KeygenLocalState{}, fmt.Errorf("fail to get local keygen state from shamir manager: %w", err)
+		}
+		return localStateItem, nil
+	} else if t.secretsEnable {
+		localStateItem, err := t.secretsManager.GetKeyFile(poolPubKey, keyEpoch)
+		if err != nil {
+			return storage.KeygenLocalState{}, fmt.Errorf("fail to get local keygen state from secrets manager: %w", err)
+		}
+		return localStateItem, nil
+	}
+	localStateItem, err := t.stateManager.GetEpochLocalState(poolPubKey, keyEpoch)
+	if err != nil {
+		return storage.KeygenLocalState{}, fmt.Errorf("fail to get local keygen state from local drive: %w", err)
+	}
+	return localStateItem, nil
+}
+
+func (t *TssServer) deleteLocalState(poolPubKey string, keyEpoch int) error {
+	if t.shamirEnable {
+		return t.shamirManager.DeleteKeyFile(poolPubKey, keyEpoch, t.localNodePubKey)
+	} else if t.secretsEnable {
+		if err := t.secretsManager.DeleteKeyFile(poolPubKey, keyEpoch); err != nil {
+			return err
+		}
+		return t.secretsManager.Save()
+	}
+	return t.stateManager.DeleteLocalState(poolPubKey, keyEpoch)
+}
+
 func (t *TssServer) GetParticipants(poolPubkey string) ([]string, error) {
 	value, ok := t.participants[poolPubkey]
 	if !ok {
@@ -61,7 +61,7 @@ func (tKeySign *TssKeySign) GetTssCommonStruct() *common2.TssCommon {
 
 // signMessage
 func (tKeySign *TssKeySign) SignMessage(msgToSign []byte, localStateItem storage.KeygenLocalState, parties []string) (tsscommon.SignatureData, error) {
-	partiesID, localPartyID, err := conversion.GetParties(parties, localStateItem.LocalPartyKey)
+	partiesID, localPartyID, err := conversion.GetEpochParties(parties, localStateItem.LocalPartyKey, localStateItem.KeyEpoch, "")
 	var emptySignatureData tsscommon.SignatureData
 	if err != nil {
 		return emptySignatureData, fmt.Errorf("fail to form key sign party: %w", err)
@@ -81,7 +81,7 @@ func (tKeySign *TssKeySign) SignMessage(msgToSign []byte, localStateItem storage
 		return emptySignatureData, fmt.Errorf("fail to convert msg to hash int: %w", err)
 	}
 	moniker := m.String()
-	partiesID, eachLocalPartyID, err := conversion.GetParties(parties, localStateItem.LocalPartyKey)
+	partiesID, eachLocalPartyID, err := conversion.GetEpochParties(parties, localStateItem.LocalPartyKey, localStateItem.KeyEpoch, "")
 	ctx := tss.NewPeerContext(partiesID)
 	if err != nil {
 		return emptySignatureData, fmt.Errorf("error to create parties in batch signging %w\n", err)
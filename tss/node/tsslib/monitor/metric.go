@@ -10,9 +10,13 @@ import (
 type Metric struct {
 	keygenCounter  *prometheus.CounterVec
 	keysignCounter *prometheus.CounterVec
+	reshareCounter *prometheus.CounterVec
 	keySignTime    prometheus.Gauge
 	keyGenTime     prometheus.Gauge
+	reshareTime    prometheus.Gauge
 	joinPartyTime  *prometheus.GaugeVec
+	preParamsDepth prometheus.Gauge
+	preParamsTaken *prometheus.CounterVec
 	logger         zerolog.Logger
 }
 
@@ -34,12 +38,35 @@ func (m *Metric) UpdateKeySign(keysignTime time.Duration, success bool) {
 	}
 }
 
+func (m *Metric) UpdateReshare(reshareTime time.Duration, success bool) {
+	if success {
+		m.reshareTime.Set(float64(reshareTime))
+		m.reshareCounter.WithLabelValues("success").Inc()
+	} else {
+		m.reshareCounter.WithLabelValues("failure").Inc()
+	}
+}
+
+func (m *Metric) UpdatePreParamsPoolDepth(depth int) {
+	m.preParamsDepth.Set(float64(depth))
+}
+
+// UpdatePreParamsTaken counts the pre-params taken by keygens, from the pool
+// or generated inline when the pool is empty
+func (m *Metric) UpdatePreParamsTaken(source string) {
+	m.preParamsTaken.WithLabelValues(source).Inc()
+}
+
 func (m *Metric) Enable() {
 	prometheus.MustRegister(m.keygenCounter)
 	prometheus.MustRegister(m.keysignCounter)
 	prometheus.MustRegister(m.keyGenTime)
 	prometheus.MustRegister(m.keySignTime)
+	prometheus.MustRegister(m.reshareCounter)
+	prometheus.MustRegister(m.reshareTime)
 	prometheus.MustRegister(m.joinPartyTime)
+	prometheus.MustRegister(m.preParamsDepth)
+	prometheus.MustRegister(m.preParamsTaken)
 }
 
 func NewMetric() *Metric {
@@ -66,6 +93,16 @@ func NewMetric() *Metric {
 			[]string{"status"},
 		),
 
+		reshareCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "Tss",
+				Subsystem: "Tss",
+				Name:      "reshare",
+				Help:      "Tss resharing success and failure counter",
+			},
+			[]string{"status"},
+		),
+
 		keyGenTime: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: "Tss",
@@ -84,6 +121,15 @@ func NewMetric() *Metric {
 			},
 		),
 
+		reshareTime: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: "Tss",
+				Subsystem: "Tss",
+				Name:      "reshare_time",
+				Help:      "the time spend for the latest resharing",
+			},
+		),
+
 		joinPartyTime: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: "Tss",
@@ -92,6 +138,25 @@ func NewMetric() *Metric {
 				Help:      "the time spend for the latest keysign/keygen join party",
 			}, []string{"type"}),
 
+		preParamsDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: "Tss",
+				Subsystem: "Tss",
+				Name:      "preparams_pool_depth",
+				Help:      "the number of pre-params sets in the pool",
+			},
+		),
+
+		preParamsTaken: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "Tss",
+				Subsystem: "Tss",
+				Name:      "preparams_taken",
+				Help:      "Tss keygen pre-params counter, from the pool or generated inline",
+			},
+			[]string{"source"},
+		),
+
 		logger: log.With().Str("module", "tssMonitor").Logger(),
 	}
 	return &metrics
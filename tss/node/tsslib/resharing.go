@@ -0,0 +1,184 @@
+package tsslib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/abnormal"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/common"
+	conversion2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/conversion"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/messages"
+	resharing2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/resharing"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
+)
+
+func (t *TssServer) Reshare(req resharing2.Request) (resharing2.Response, error) {
+	t.tssKeyGenLocker.Lock()
+	defer t.tssKeyGenLocker.Unlock()
+	msgID, err := t.requestToMsgId(req)
+	if err != nil {
+		return resharing2.Response{}, err
+	}
+	if err = t.requestCheck(req); err != nil {
+		return resharing2.Response{}, err
+	}
+	t.logger.Info().
+		Str("pub key", req.PubKey).
+		Str("old keys", strings.Join(req.OldKeys, ",")).
+		Str("old threshold", strconv.Itoa(req.OldThreshold)).
+		Str("new keys", strings.Join(req.NewKeys, ",")).
+		Str("new threshold", strconv.Itoa(req.NewThreshold)).
+		Int("key epoch", req.KeyEpoch).
+		Msg("received resharing request")
+
+	// the old committee hands over the key share it holds
+	var localState *storage.KeygenLocalState
+	if containsKey(req.OldKeys, t.localNodePubKey) {
+		// the manager only reshares a confirmed key, so a node restarted
+		// before it saw the confirmation of the last resharing switches to
+		// the key share of that resharing
+		if err := t.activateKeyEpoch(req.PubKey, req.KeyEpoch, true); err != nil {
+			return resharing2.Response{}, err
+		}
+		localStateItem, err := t.getLocalState(req.PubKey)
+		if err != nil {
+			return resharing2.Response{}, err
+		}
+		if localStateItem.KeyEpoch != req.KeyEpoch {
+			return resharing2.Response{}, fmt.Errorf("local key epoch %d does not match the resharing key epoch %d", localStateItem.KeyEpoch, req.KeyEpoch)
+		}
+		localState = &localStateItem
+	}
+	// and the new committee generates its key shares with its own pre-params
+	isNewMember := containsKey(req.NewKeys, t.localNodePubKey)
+	preParams := t.preParams
+	if isNewMember && t.preParamsPool != nil {
+		if preParams, err = t.preParamsPool.Take(t.conf.PreParamTimeout); err != nil {
+			return resharing2.Response{}, err
+		}
+	}
+
+	resharingInstance := resharing2.NewTssResharing(
+		t.p2pCommunication.GetLocalPeerID(),
+		t.conf,
+		t.localNodePubKey,
+		t.p2pCommunication.BroadcastMsgChan,
+		t.stopChan,
+		preParams,
+		msgID,
+		t.stateManager,
+		t.secretsEnable,
+		t.secretsManager,
+		t.shamirEnable,
+		t.shamirManager,
+		t.privateKey,
+		t.p2pCommunication,
+		req.NewThreshold,
+	)
+
+	resharingMsgChannel := resharingInstance.GetTssResharingChannels()
+	t.p2pCommunication.SetSubscribe(messages.TSSKeyGenMsg, msgID, resharingMsgChannel)
+	t.p2pCommunication.SetSubscribe(messages.TSSTaskDone, msgID, resharingMsgChannel)
+
+	defer func() {
+		t.p2pCommunication.CancelSubscribe(messages.TSSKeyGenMsg, msgID)
+		t.p2pCommunication.CancelSubscribe(messages.TSSTaskDone, msgID)
+
+		t.p2pCommunication.ReleaseStream(msgID)
+	}()
+	abnormalMgr := resharingInstance.GetTssCommonStruct().GetAbnormalMgr()
+
+	beforeReshare := time.Now()
+	k, err := resharingInstance.ReshareKey(req, localState)
+	reshareTime := time.Since(beforeReshare)
+	if err != nil {
+		t.tssMetrics.UpdateReshare(reshareTime, false)
+		t.logger.Error().Err(err).Msg("err in resharing")
+		return resharing2.NewResponse(
+			"", nil, common.Fail,
+			abnormal.GenerateNewKeyError,
+			abnormalMgr.GetAbnormalNodePubKeys()), err
+	}
+	t.tssMetrics.UpdateReshare(reshareTime, true)
+
+	pubkey, _, pubkeyByte, err := conversion2.GetTssPubKey(k)
+	if err != nil {
+		return resharing2.NewResponse(
+			"",
+			nil,
+			common.Fail,
+			abnormal.GenerateNewKeyError,
+			abnormalMgr.GetAbnormalNodePubKeys()), err
+	}
+
+	return resharing2.NewResponse(
+		pubkey,
+		pubkeyByte,
+		common.Success,
+		"",
+		abnormalMgr.GetAbnormalNodePubKeys(),
+	), nil
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivateReshare switches to the key share of keyEpoch once the resharing
+// of pubKey to keyEpoch is confirmed on layer one, and deletes the key share
+// it supersedes. A node left out of the new committee only deletes its key
+// share.
+func (t *TssServer) ActivateReshare(pubKey string, keyEpoch int) error {
+	t.tssKeyGenLocker.Lock()
+	defer t.tssKeyGenLocker.Unlock()
+	return t.activateKeyEpoch(pubKey, keyEpoch, false)
+}
+
+// DiscardReshare deletes the key share of keyEpoch saved by a resharing of
+// pubKey that was not confirmed, unless it is in use
+func (t *TssServer) DiscardReshare(pubKey string, keyEpoch int) error {
+	t.tssKeyGenLocker.Lock()
+	defer t.tssKeyGenLocker.Unlock()
+	current, err := t.stateManager.GetKeyEpoch(pubKey)
+	if err != nil {
+		return err
+	}
+	if current >= keyEpoch {
+		return nil
+	}
+	return t.deleteLocalState(pubKey, keyEpoch)
+}
+
+// activateKeyEpoch switches the key share of pubKey in use to keyEpoch. With
+// onlyHeld, it does nothing unless the node holds the key share of keyEpoch.
+func (t *TssServer) activateKeyEpoch(pubKey string, keyEpoch int, onlyHeld bool) error {
+	current, err := t.stateManager.GetKeyEpoch(pubKey)
+	if err != nil {
+		return err
+	}
+	if current >= keyEpoch {
+		return nil
+	}
+	if onlyHeld {
+		if _, err := t.getEpochLocalState(pubKey, keyEpoch); err != nil {
+			return nil
+		}
+	}
+	if err := t.stateManager.SetKeyEpoch(pubKey, keyEpoch); err != nil {
+		return fmt.Errorf("fail to switch to key epoch %d: %w", keyEpoch, err)
+	}
+	// keysign reloads the participants of the new committee
+	delete(t.participants, pubKey)
+	if err := t.deleteLocalState(pubKey, current); err != nil {
+		return fmt.Errorf("fail to delete the key share of key epoch %d: %w", current, err)
+	}
+	t.logger.Info().Str("pub key", pubKey).Int("key epoch", keyEpoch).Msg("switched to the reshared key share")
+	return nil
+}
@@ -0,0 +1,21 @@
+package resharing
+
+type Request struct {
+	PubKey       string   `json:"pub_key"`
+	OldKeys      []string `json:"old_keys"`
+	OldThreshold int      `json:"old_threshold"`
+	NewKeys      []string `json:"new_keys"`
+	NewThreshold int      `json:"new_threshold"`
+	KeyEpoch     int      `json:"key_epoch"`
+}
+
+func NewRequest(pubKey string, oldKeys []string, oldThreshold int, newKeys []string, newThreshold int, keyEpoch int) Request {
+	return Request{
+		PubKey:       pubKey,
+		OldKeys:      oldKeys,
+		OldThreshold: oldThreshold,
+		NewKeys:      newKeys,
+		NewThreshold: newThreshold,
+		KeyEpoch:     keyEpoch,
+	}
+}
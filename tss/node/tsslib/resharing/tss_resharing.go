@@ -0,0 +1,295 @@
+package resharing
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	bcrypto "github.com/binance-chain/tss-lib/crypto"
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/binance-chain/tss-lib/ecdsa/resharing"
+	"github.com/binance-chain/tss-lib/tss"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/abnormal"
+	common2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/common"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/conversion"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/messages"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/p2p"
+	storage2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	oldPartyIDPrefix = "old-"
+	newPartyIDPrefix = "new-"
+)
+
+// TssResharing hands the key shares of the old committee over to the new
+// committee, keeping the cluster public key. The local node may be in the
+// old committee, the new committee or both; it runs one party per committee.
+type TssResharing struct {
+	logger          zerolog.Logger
+	localNodePubKey string
+	preParams       *keygen.LocalPreParams
+	tssCommonStruct *common2.TssCommon
+	stopChan        chan struct{} // channel to indicate whether we should stop
+	stateManager    storage2.LocalStateManager
+	secretsEnable   bool
+	secretsManager  storage2.SecretsManager
+	shamirEnable    bool
+	shamirManager   storage2.ShamirManager
+	commStopChan    chan struct{}
+	p2pComm         *p2p.Communication
+}
+
+func NewTssResharing(localP2PID string,
+	conf common2.TssConfig,
+	localNodePubKey string,
+	broadcastChan chan *messages.BroadcastMsgChan,
+	stopChan chan struct{},
+	preParam *keygen.LocalPreParams,
+	msgID string,
+	stateManager storage2.LocalStateManager,
+	secretsEnable bool,
+	secretsManager storage2.SecretsManager,
+	shamirEnable bool,
+	shamirManager storage2.ShamirManager,
+	privateKey *ecdsa.PrivateKey,
+	p2pComm *p2p.Communication,
+	thresHold int) *TssResharing {
+	return &TssResharing{
+		logger: log.With().
+			Str("module", "resharing").
+			Str("msgID", msgID).Logger(),
+		localNodePubKey: localNodePubKey,
+		preParams:       preParam,
+		tssCommonStruct: common2.NewTssCommon(localP2PID, broadcastChan, conf, msgID, privateKey, thresHold),
+		stopChan:        stopChan,
+		stateManager:    stateManager,
+		secretsEnable:   secretsEnable,
+		secretsManager:  secretsManager,
+		shamirEnable:    shamirEnable,
+		shamirManager:   shamirManager,
+		commStopChan:    make(chan struct{}),
+		p2pComm:         p2pComm,
+	}
+}
+
+func (tReshare *TssResharing) GetTssResharingChannels() chan *p2p.Message {
+	return tReshare.tssCommonStruct.TssMsg
+}
+
+func (tReshare *TssResharing) GetTssCommonStruct() *common2.TssCommon {
+	return tReshare.tssCommonStruct
+}
+
+// ReshareKey runs the resharing. localState is the key share of the local
+// node, which is only needed in the old committee.
+func (tReshare *TssResharing) ReshareKey(req Request, localState *storage2.KeygenLocalState) (*bcrypto.ECPoint, error) {
+	// the parties of the new committee are one key epoch ahead, so that they
+	// never share a key with the parties of the old committee
+	oldPartiesID, oldLocalPartyID, err := conversion.GetCommitteeParties(req.OldKeys, tReshare.localNodePubKey, req.KeyEpoch, oldPartyIDPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get old committee parties: %w", err)
+	}
+	newPartiesID, newLocalPartyID, err := conversion.GetCommitteeParties(req.NewKeys, tReshare.localNodePubKey, req.KeyEpoch+1, newPartyIDPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get new committee parties: %w", err)
+	}
+	if oldLocalPartyID == nil && newLocalPartyID == nil {
+		return nil, errors.New("local party is in neither the old nor the new committee")
+	}
+
+	oldCtx := tss.NewPeerContext(oldPartiesID)
+	newCtx := tss.NewPeerContext(newPartiesID)
+	outCh := make(chan tss.Message, 2*(len(oldPartiesID)+len(newPartiesID)))
+	oldEndCh := make(chan keygen.LocalPartySaveData, 1)
+	newEndCh := make(chan keygen.LocalPartySaveData, 1)
+	errChan := make(chan struct{})
+
+	var localParties []tss.Party
+	partyInfo := &abnormal.PartyInfo{}
+	if oldLocalPartyID != nil {
+		if localState == nil {
+			return nil, errors.New("error, empty local state of the old committee")
+		}
+		params := tss.NewReSharingParameters(btcec.S256(), oldCtx, newCtx, oldLocalPartyID,
+			len(oldPartiesID), req.OldThreshold, len(newPartiesID), req.NewThreshold)
+		partyInfo.Party = resharing.NewLocalParty(params, localState.LocalData, outCh, oldEndCh)
+		localParties = append(localParties, partyInfo.Party)
+	}
+	if newLocalPartyID != nil {
+		if tReshare.preParams == nil {
+			return nil, errors.New("error, empty pre-parameters")
+		}
+		save := keygen.NewLocalPartySaveData(len(newPartiesID))
+		save.LocalPreParams = *tReshare.preParams
+		params := tss.NewReSharingParameters(btcec.S256(), oldCtx, newCtx, newLocalPartyID,
+			len(oldPartiesID), req.OldThreshold, len(newPartiesID), req.NewThreshold)
+		newParty := resharing.NewLocalParty(params, save, outCh, newEndCh)
+		if partyInfo.Party == nil {
+			partyInfo.Party = newParty
+		} else {
+			partyInfo.NewParty = newParty
+		}
+		localParties = append(localParties, newParty)
+	}
+
+	abnormalMgr := tReshare.tssCommonStruct.GetAbnormalMgr()
+	allPartiesID := append(append([]*tss.PartyID{}, oldPartiesID...), newPartiesID...)
+	partyIDMap := conversion.SetupPartyIDMap(allPartiesID)
+	if err := conversion.SetupIDMaps(partyIDMap, tReshare.tssCommonStruct.PartyIDtoP2PID); err != nil {
+		tReshare.logger.Error().Msgf("error in creating mapping between partyID and P2P ID")
+		return nil, err
+	}
+	partyInfo.PartyIDMap = partyIDMap
+	tReshare.tssCommonStruct.SetPartyInfo(partyInfo)
+	abnormalMgr.SetPartyInfo(partyInfo.Party, partyIDMap)
+	tReshare.tssCommonStruct.P2PPeersLock.Lock()
+	tReshare.tssCommonStruct.P2PPeers = conversion.GetPeersID(tReshare.tssCommonStruct.PartyIDtoP2PID, tReshare.tssCommonStruct.GetLocalPeerID())
+	tReshare.tssCommonStruct.P2PPeersLock.Unlock()
+
+	var reshareWg sync.WaitGroup
+	reshareWg.Add(len(localParties) + 1)
+	var errOnce sync.Once
+	for _, party := range localParties {
+		go func(party tss.Party) {
+			defer reshareWg.Done()
+			if err := party.Start(); nil != err {
+				tReshare.logger.Error().Err(err).Msg("fail to start resharing party")
+				errOnce.Do(func() { close(errChan) })
+			}
+		}(party)
+	}
+	go tReshare.tssCommonStruct.ProcessInboundMessages(tReshare.commStopChan, &reshareWg)
+
+	var oldEnd, newEnd <-chan keygen.LocalPartySaveData
+	if oldLocalPartyID != nil {
+		oldEnd = oldEndCh
+	}
+	if newLocalPartyID != nil {
+		newEnd = newEndCh
+	}
+	r, err := tReshare.processReshare(req, errChan, outCh, oldEnd, newEnd, localState)
+	if err != nil {
+		close(tReshare.commStopChan)
+		return nil, fmt.Errorf("fail to process resharing: %w", err)
+	}
+	select {
+	case <-time.After(time.Second * 5):
+		close(tReshare.commStopChan)
+
+	case <-tReshare.tssCommonStruct.GetTaskDone():
+		close(tReshare.commStopChan)
+	}
+
+	reshareWg.Wait()
+	return r, nil
+}
+
+// processReshare routes the messages of the local parties until every one
+// of them is done. A nil end channel is for a committee the local node is not
+// in.
+func (tReshare *TssResharing) processReshare(req Request,
+	errChan chan struct{},
+	outCh <-chan tss.Message,
+	oldEndCh, newEndCh <-chan keygen.LocalPartySaveData,
+	localState *storage2.KeygenLocalState) (*bcrypto.ECPoint, error) {
+	defer tReshare.logger.Debug().Msg("finished resharing process")
+	tReshare.logger.Debug().Msg("start to read messages from local parties")
+	tssConf := tReshare.tssCommonStruct.GetConf()
+	abnormalMgr := tReshare.tssCommonStruct.GetAbnormalMgr()
+	var pubKey *bcrypto.ECPoint
+	if localState != nil {
+		pubKey = localState.LocalData.ECDSAPub
+	}
+	for oldEndCh != nil || newEndCh != nil {
+		select {
+		case <-errChan: // when a resharing party return
+			tReshare.logger.Error().Msg("resharing failed")
+			return nil, errors.New("error channel closed fail to start local party")
+
+		case <-tReshare.stopChan: // when TSS processor receive signal to quit
+			return nil, errors.New("received exit signal")
+
+		case <-time.After(tssConf.KeyGenTimeout):
+			// we bail out after KeyGenTimeoutSeconds
+			tReshare.logger.Error().Msgf("fail to reshare message with %s", tssConf.KeyGenTimeout.String())
+			if abnormalMgr.GetLastMsg() == nil {
+				tReshare.logger.Error().Msg("fail to start the resharing, the last produced message of this node is none")
+				return nil, errors.New("timeout before shared message is generated")
+			}
+			return nil, abnormal.ErrTssTimeOut
+
+		case msg := <-outCh:
+			abnormalMgr.SetLastMsg(msg)
+			err := tReshare.tssCommonStruct.ProcessOutCh(msg, messages.TSSKeyGenMsg)
+			if err != nil {
+				tReshare.logger.Error().Err(err).Msg("fail to process the message")
+				return nil, err
+			}
+
+		case <-oldEndCh:
+			// the share of the old committee is wiped from memory, the stored
+			// one is deleted once the resharing is confirmed on layer one
+			tReshare.logger.Debug().Msg("resharing of the old committee finished successfully")
+			oldEndCh = nil
+
+		case msg := <-newEndCh:
+			newEndCh = nil
+			tReshare.logger.Debug().Msgf("resharing of the new committee finished successfully: %s", msg.ECDSAPub.Y().String())
+			pubKeyHex, _, _, err := conversion.GetTssPubKey(msg.ECDSAPub)
+			if err != nil {
+				return nil, fmt.Errorf("fail to get tss pubkey: %w", err)
+			}
+			if pubKeyHex != req.PubKey {
+				return nil, fmt.Errorf("reshared pub key %s differs from the requested pub key %s", pubKeyHex, req.PubKey)
+			}
+			if err := tReshare.saveLocalState(storage2.KeygenLocalState{
+				PubKey:          pubKeyHex,
+				LocalData:       msg,
+				ParticipantKeys: req.NewKeys,
+				LocalPartyKey:   tReshare.localNodePubKey,
+				Threshold:       req.NewThreshold,
+				KeyEpoch:        req.KeyEpoch + 1,
+			}); err != nil {
+				return nil, err
+			}
+			pubKey = msg.ECDSAPub
+		}
+	}
+	if err := tReshare.tssCommonStruct.NotifyTaskDone(); err != nil {
+		tReshare.logger.Error().Err(err).Msg("fail to broadcast the resharing done")
+	}
+	return pubKey, nil
+}
+
+// saveLocalState saves the key share of the new key epoch next to the one in
+// use, which the node keeps signing with until the resharing is confirmed
+func (tReshare *TssResharing) saveLocalState(state storage2.KeygenLocalState) error {
+	if tReshare.shamirEnable {
+		if err := tReshare.shamirManager.PutKeyFile(state); err != nil {
+			return fmt.Errorf("fail to put resharing result with shamir manager : %w", err)
+		}
+	} else if tReshare.secretsEnable {
+		if err := tReshare.secretsManager.PutKeyFile(state); err != nil {
+			return fmt.Errorf("fail to put resharing result to secrets manager map : %w", err)
+		}
+		if err := tReshare.secretsManager.Save(); err != nil {
+			return fmt.Errorf("fail to put resharing result to secrets manager :%w", err)
+		}
+	} else {
+		if err := tReshare.stateManager.SaveLocalState(state); err != nil {
+			return fmt.Errorf("fail to save resharing result to storage: %w", err)
+		}
+	}
+
+	address := tReshare.p2pComm.ExportPeerAddress()
+	if err := tReshare.stateManager.SaveAddressBook(address); err != nil {
+		tReshare.logger.Error().Err(err).Msg("fail to save the peer addresses")
+	}
+	return nil
+}
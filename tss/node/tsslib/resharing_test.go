@@ -0,0 +1,90 @@
+package tsslib
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/mantlenetworkio/mantle/l2geth/crypto"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTssServer(t *testing.T) *TssServer {
+	stateManager, err := storage.NewFileStateMgr(t.TempDir(), nil)
+	require.NoError(t, err)
+	return &TssServer{
+		logger:          zerolog.Nop(),
+		participants:    make(map[string][]string),
+		tssKeyGenLocker: &sync.Mutex{},
+		stateManager:    stateManager,
+	}
+}
+
+func newTestKeyShare(t *testing.T, pubKey string, keyEpoch int, participants ...string) storage.KeygenLocalState {
+	return storage.KeygenLocalState{
+		PubKey:          pubKey,
+		ParticipantKeys: participants,
+		LocalPartyKey:   "b",
+		Threshold:       1,
+		KeyEpoch:        keyEpoch,
+	}
+}
+
+func TestActivateReshare(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	pubKey := hex.EncodeToString(crypto.CompressPubkey(&key.PublicKey))
+
+	// A member of both committees signs with its former key share until the
+	// resharing is confirmed
+	tssServer := newTestTssServer(t)
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 0, "a", "b", "c")))
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 1, "b", "c", "d")))
+	tssServer.participants[pubKey] = []string{"a", "b", "c"}
+	localState, err := tssServer.getLocalState(pubKey)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, localState.ParticipantKeys)
+
+	require.NoError(t, tssServer.ActivateReshare(pubKey, 1))
+	localState, err = tssServer.getLocalState(pubKey)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c", "d"}, localState.ParticipantKeys)
+	require.NotContains(t, tssServer.participants, pubKey)
+	_, err = tssServer.getEpochLocalState(pubKey, 0)
+	require.Error(t, err)
+	require.NoError(t, tssServer.ActivateReshare(pubKey, 1))
+
+	// An unconfirmed resharing is discarded, but never the key share in use
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 2, "c", "d", "e")))
+	require.NoError(t, tssServer.DiscardReshare(pubKey, 2))
+	_, err = tssServer.getEpochLocalState(pubKey, 2)
+	require.Error(t, err)
+	require.NoError(t, tssServer.DiscardReshare(pubKey, 1))
+	_, err = tssServer.getLocalState(pubKey)
+	require.NoError(t, err)
+
+	// A member left out of the new committee deletes its key share
+	tssServer = newTestTssServer(t)
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 0, "a", "b", "c")))
+	require.NoError(t, tssServer.ActivateReshare(pubKey, 1))
+	_, err = tssServer.getEpochLocalState(pubKey, 0)
+	require.Error(t, err)
+	_, err = tssServer.getLocalState(pubKey)
+	require.Error(t, err)
+
+	// A resharing request of the next key epoch tells a restarted node that
+	// the resharing it holds the key share of was confirmed
+	tssServer = newTestTssServer(t)
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 0, "a", "b", "c")))
+	require.NoError(t, tssServer.activateKeyEpoch(pubKey, 1, true))
+	localState, err = tssServer.getLocalState(pubKey)
+	require.NoError(t, err)
+	require.Equal(t, 0, localState.KeyEpoch)
+	require.NoError(t, tssServer.stateManager.SaveLocalState(newTestKeyShare(t, pubKey, 1, "b", "c", "d")))
+	require.NoError(t, tssServer.activateKeyEpoch(pubKey, 1, true))
+	localState, err = tssServer.getLocalState(pubKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, localState.KeyEpoch)
+}
@@ -3,6 +3,7 @@ package tsslib
 import (
 	keygen2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/keygen"
 	keysign2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/keysign"
+	resharing2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/resharing"
 )
 
 type Server interface {
@@ -11,6 +12,9 @@ type Server interface {
 	GetLocalPeerID() string
 	Keygen(req keygen2.Request) (keygen2.Response, error)
 	KeySign(req keysign2.Request) (keysign2.Response, error)
+	Reshare(req resharing2.Request) (resharing2.Response, error)
+	ActivateReshare(pubKey string, keyEpoch int) error
+	DiscardReshare(pubKey string, keyEpoch int) error
 	ExportPeerAddress() map[string]string
 	GetParticipants(poolPubkey string) ([]string, error)
 }
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	nodeconfig "github.com/mantlenetworkio/mantle/tss/common"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeystoreVersion is the version of the encrypted local state format.
+	// Local state files without a version are plaintext.
+	KeystoreVersion = 1
+
+	DefaultScryptN = 1 << 18
+	maxScryptN     = 1 << 22
+	scryptR        = 8
+	scryptP        = 1
+	scryptSaltLen  = 32
+	dataKeyLen     = 32
+)
+
+var (
+	ErrKeystoreCorrupted = errors.New("keystore is corrupted or was encrypted with another key")
+	ErrKeystoreLocked    = errors.New("keystore is encrypted but no key encryption provider is configured")
+)
+
+// KeyEncryptionProvider wraps the data keys that encrypt the local state
+// files. Implementations can derive their key from a passphrase or keep it
+// in an external key management service.
+type KeyEncryptionProvider interface {
+	Name() string
+	WrapKey(dataKey []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// encryptedKeystore is the content of an encrypted local state file. The
+// state is sealed with a random data key, and the data key is wrapped by the
+// key encryption provider, so that rotating the provider only rewrites the
+// wrapped key.
+type encryptedKeystore struct {
+	Version    int    `json:"version"`
+	PubKey     string `json:"pub_key"`
+	Provider   string `json:"provider"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func keystoreAAD(version int, pubKey string) []byte {
+	return []byte(fmt.Sprintf("tss-keystore-v%d:%s", version, pubKey))
+}
+
+func sealKeystore(kek KeyEncryptionProvider, pubKey string, plaintext []byte) (*encryptedKeystore, error) {
+	dataKey := make([]byte, dataKeyLen)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("fail to generate data key: %w", err)
+	}
+	wrapped, err := kek.WrapKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("fail to wrap data key: %w", err)
+	}
+	nonce, ciphertext, err := aesGCMSeal(dataKey, plaintext, keystoreAAD(KeystoreVersion, pubKey))
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedKeystore{
+		Version:    KeystoreVersion,
+		PubKey:     pubKey,
+		Provider:   kek.Name(),
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+func openKeystore(kek KeyEncryptionProvider, ks *encryptedKeystore) ([]byte, error) {
+	if ks.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if kek == nil {
+		return nil, ErrKeystoreLocked
+	}
+	if ks.Provider != kek.Name() {
+		return nil, fmt.Errorf("keystore was encrypted by provider %s, configured provider is %s", ks.Provider, kek.Name())
+	}
+	dataKey, err := kek.UnwrapKey(ks.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCMOpen(dataKey, ks.Nonce, ks.Ciphertext, keystoreAAD(ks.Version, ks.PubKey))
+}
+
+func aesGCMSeal(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("fail to generate nonce: %w", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func aesGCMOpen(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrKeystoreCorrupted
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, ErrKeystoreCorrupted
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrKeystoreCorrupted
+	}
+	return plaintext, nil
+}
+
+// writeFileAtomic writes a file readable by its owner only, so that a crash
+// never leaves a truncated key share behind
+func writeFileAtomic(filePathName string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filePathName), filepath.Base(filePathName)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePathName)
+}
+
+// NewKeyEncryptionProvider returns the provider configured by config, or nil
+// when the local states are stored in plaintext
+func NewKeyEncryptionProvider(config nodeconfig.KeystoreConfig) (KeyEncryptionProvider, error) {
+	if len(config.Passphrase) == 0 {
+		return nil, nil
+	}
+	return NewPassphraseProvider(config.Passphrase, config.ScryptN, config.KeyVersion)
+}
+
+// PassphraseProvider derives the key encryption key from a passphrase with
+// scrypt. Every wrapped key has its own salt. The key version tells the
+// successive passphrases apart.
+type PassphraseProvider struct {
+	passphrase []byte
+	scryptN    int
+	keyVersion int
+	kekCache   map[string][]byte
+	lock       sync.Mutex
+}
+
+type scryptWrappedKey struct {
+	Salt       []byte `json:"salt"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewPassphraseProvider returns a provider deriving its keys with the scrypt
+// cost scryptN, or DefaultScryptN when scryptN is 0. keyVersion is increased
+// by every rotation of the passphrase.
+func NewPassphraseProvider(passphrase string, scryptN int, keyVersion int) (*PassphraseProvider, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("keystore passphrase is empty")
+	}
+	if scryptN == 0 {
+		scryptN = DefaultScryptN
+	}
+	if scryptN < 2 || scryptN&(scryptN-1) != 0 {
+		return nil, fmt.Errorf("scrypt N must be a power of 2, got %d", scryptN)
+	}
+	if keyVersion < 0 {
+		return nil, fmt.Errorf("key version must not be negative, got %d", keyVersion)
+	}
+	return &PassphraseProvider{
+		passphrase: []byte(passphrase),
+		scryptN:    scryptN,
+		keyVersion: keyVersion,
+		kekCache:   make(map[string][]byte),
+	}, nil
+}
+
+// Name is versioned by the key version, the keystores written before the
+// key version existed are named "scrypt"
+func (p *PassphraseProvider) Name() string {
+	if p.keyVersion == 0 {
+		return "scrypt"
+	}
+	return fmt.Sprintf("scrypt-v%d", p.keyVersion)
+}
+
+// deriveKey caches the derived keys, as the local state is read for every
+// signature
+func (p *PassphraseProvider) deriveKey(salt []byte, n, r, parallel int) ([]byte, error) {
+	cacheKey := fmt.Sprintf("%s:%d:%d:%d", hex.EncodeToString(salt), n, r, parallel)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if kek, ok := p.kekCache[cacheKey]; ok {
+		return kek, nil
+	}
+	kek, err := scrypt.Key(p.passphrase, salt, n, r, parallel, dataKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("fail to derive key encryption key: %w", err)
+	}
+	p.kekCache[cacheKey] = kek
+	return kek, nil
+}
+
+func (p *PassphraseProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	salt := make([]byte, scryptSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("fail to generate salt: %w", err)
+	}
+	kek, err := p.deriveKey(salt, p.scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	nonce, ciphertext, err := aesGCMSeal(kek, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(scryptWrappedKey{
+		Salt:       salt,
+		N:          p.scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	})
+}
+
+func (p *PassphraseProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	var key scryptWrappedKey
+	if err := json.Unmarshal(wrapped, &key); err != nil {
+		return nil, ErrKeystoreCorrupted
+	}
+	// Bound the cost read from the file
+	if key.N > maxScryptN || key.R < 1 || key.P < 1 || key.R*key.P > 64 {
+		return nil, ErrKeystoreCorrupted
+	}
+	kek, err := p.deriveKey(key.Salt, key.N, key.R, key.P)
+	if err != nil {
+		return nil, ErrKeystoreCorrupted
+	}
+	return aesGCMOpen(kek, key.Nonce, key.Ciphertext, nil)
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/binance-chain/tss-lib/crypto/paillier"
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// testScryptN keeps the key derivations of the tests fast
+const testScryptN = 1 << 10
+
+func newTestLocalState(t *testing.T) KeygenLocalState {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return KeygenLocalState{
+		PubKey:          hex.EncodeToString(crypto.CompressPubkey(&key.PublicKey)),
+		ParticipantKeys: []string{"a", "b", "c"},
+		LocalPartyKey:   "a",
+		Threshold:       2,
+	}
+}
+
+func newTestProvider(t *testing.T, passphrase string) *PassphraseProvider {
+	return newTestProviderVersion(t, passphrase, 0)
+}
+
+func newTestProviderVersion(t *testing.T, passphrase string, keyVersion int) *PassphraseProvider {
+	kek, err := NewPassphraseProvider(passphrase, testScryptN, keyVersion)
+	require.NoError(t, err)
+	return kek
+}
+
+func newTestPreParams() *keygen.LocalPreParams {
+	return &keygen.LocalPreParams{PaillierSK: &paillier.PrivateKey{}, NTildei: big.NewInt(7), H1i: big.NewInt(2), H2i: big.NewInt(3)}
+}
+
+func TestKeystoreLoad(t *testing.T) {
+	dir := t.TempDir()
+	fsm, err := NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, fsm.SaveLocalState(state))
+
+	filePathName, err := fsm.getFilePathName(state.PubKey, 0)
+	require.NoError(t, err)
+	info, err := os.Stat(filePathName)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	buf, err := ioutil.ReadFile(filePathName)
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "participant_keys")
+
+	// A restarted node reads the state with the same passphrase
+	fsm, err = NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	loaded, err := fsm.GetLocalState(state.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, state.ParticipantKeys, loaded.ParticipantKeys)
+	require.Equal(t, state.Threshold, loaded.Threshold)
+
+	_, err = NewFileStateMgr(dir, newTestProvider(t, "wrong"))
+	require.ErrorIs(t, err, ErrKeystoreCorrupted)
+
+	fsm, err = NewFileStateMgr(dir, nil)
+	require.NoError(t, err)
+	_, err = fsm.GetLocalState(state.PubKey)
+	require.ErrorIs(t, err, ErrKeystoreLocked)
+}
+
+func TestKeystoreMigration(t *testing.T) {
+	dir := t.TempDir()
+	plainMgr, err := NewFileStateMgr(dir, nil)
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, plainMgr.SaveLocalState(state))
+	filePathName, err := plainMgr.getFilePathName(state.PubKey, 0)
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(filePathName, 0o655))
+
+	// Plaintext states are encrypted when the provider is configured
+	fsm, err := NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	var ks encryptedKeystore
+	buf, err := ioutil.ReadFile(filePathName)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(buf, &ks))
+	require.Equal(t, KeystoreVersion, ks.Version)
+	info, err := os.Stat(filePathName)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := fsm.GetLocalState(state.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, state.LocalPartyKey, loaded.LocalPartyKey)
+
+	// And so are the plaintext states copied afterwards
+	copied := newTestLocalState(t)
+	require.NoError(t, plainMgr.SaveLocalState(copied))
+	_, err = fsm.GetLocalState(copied.PubKey)
+	require.NoError(t, err)
+	_, err = plainMgr.GetLocalState(copied.PubKey)
+	require.ErrorIs(t, err, ErrKeystoreLocked)
+}
+
+func TestKeystoreRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKEK := newTestProvider(t, "old")
+	fsm, err := NewFileStateMgr(dir, oldKEK)
+	require.NoError(t, err)
+	states := []KeygenLocalState{newTestLocalState(t), newTestLocalState(t)}
+	for _, state := range states {
+		require.NoError(t, fsm.SaveLocalState(state))
+	}
+	require.NoError(t, fsm.SavePreParams(newTestPreParams()))
+
+	// The new provider needs another key version
+	require.ErrorContains(t, fsm.RotateKeyEncryption(newTestProvider(t, "new")), "increase the key version")
+
+	newKEK := newTestProviderVersion(t, "new", 1)
+	require.NoError(t, fsm.RotateKeyEncryption(newKEK))
+	for _, state := range states {
+		_, err := fsm.GetLocalState(state.PubKey)
+		require.NoError(t, err)
+	}
+	preParams, err := fsm.GetLocalPreParams("")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), preParams.NTildei.Int64())
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	_, err = NewFileStateMgr(dir, oldKEK)
+	require.ErrorContains(t, err, "keystore was encrypted by provider scrypt-v1, configured provider is scrypt")
+
+	// Rotating without the current provider leaves the files untouched
+	lockedMgr, err := NewFileStateMgr(dir, nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, lockedMgr.RotateKeyEncryption(newTestProviderVersion(t, "other", 2)), ErrKeystoreLocked)
+	_, err = fsm.GetLocalState(states[1].PubKey)
+	require.NoError(t, err)
+}
+
+func TestKeystoreInterruptedRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKEK := newTestProvider(t, "old")
+	newKEK := newTestProviderVersion(t, "new", 1)
+	fsm, err := NewFileStateMgr(dir, oldKEK)
+	require.NoError(t, err)
+	states := []KeygenLocalState{newTestLocalState(t), newTestLocalState(t)}
+	for _, state := range states {
+		require.NoError(t, fsm.SaveLocalState(state))
+	}
+	files, err := fsm.getKeystoreFiles("")
+	require.NoError(t, err)
+
+	// stage rotates the files as RotateKeyEncryption does, up to the renames
+	stage := func(commit bool) {
+		for _, filePathName := range files {
+			buf, name, _, err := fsm.readKeystoreFile(filePathName, oldKEK)
+			require.NoError(t, err)
+			require.NoError(t, fsm.writeKeystoreFile(filePathName+rotationSuffix, name, buf, newKEK))
+		}
+		// The crash happens after the first rename
+		if commit {
+			require.NoError(t, writeFileAtomic(fsm.rotationMarker(), []byte(newKEK.Name())))
+			require.NoError(t, os.Rename(files[0]+rotationSuffix, files[0]))
+		}
+	}
+
+	// A rotation crashed before its commit is discarded
+	stage(false)
+	fsm, err = NewFileStateMgr(dir, oldKEK)
+	require.NoError(t, err)
+	staged, err := fsm.getKeystoreFiles(rotationSuffix)
+	require.NoError(t, err)
+	require.Empty(t, staged)
+
+	// And one crashed after its commit is completed
+	stage(true)
+	fsm, err = NewFileStateMgr(dir, newKEK)
+	require.NoError(t, err)
+	for _, state := range states {
+		_, err := fsm.GetLocalState(state.PubKey)
+		require.NoError(t, err)
+	}
+	all, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.ElementsMatch(t, files, all)
+}
+
+func TestKeystorePreParams(t *testing.T) {
+	dir := t.TempDir()
+	plainMgr, err := NewFileStateMgr(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, plainMgr.SavePreParams(newTestPreParams()))
+
+	// Plaintext pre-params are encrypted at start
+	fsm, err := NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	buf, err := ioutil.ReadFile(fsm.getPreParamsFilePathName())
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "NTildei")
+	preParams, err := fsm.GetLocalPreParams("")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), preParams.NTildei.Int64())
+
+	require.NoError(t, fsm.SavePreParams(newTestPreParams()))
+	buf, err = ioutil.ReadFile(fsm.getPreParamsFilePathName())
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "NTildei")
+	_, err = plainMgr.GetLocalPreParams("")
+	require.ErrorIs(t, err, ErrKeystoreLocked)
+}
+
+func TestKeystoreCorruption(t *testing.T) {
+	dir := t.TempDir()
+	fsm, err := NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, fsm.SaveLocalState(state))
+	filePathName, err := fsm.getFilePathName(state.PubKey, 0)
+	require.NoError(t, err)
+	buf, err := ioutil.ReadFile(filePathName)
+	require.NoError(t, err)
+	var original encryptedKeystore
+	require.NoError(t, json.Unmarshal(buf, &original))
+
+	tests := []struct {
+		name    string
+		corrupt func(ks *encryptedKeystore)
+	}{
+		{"ciphertext", func(ks *encryptedKeystore) { ks.Ciphertext[0] ^= 1 }},
+		{"nonce", func(ks *encryptedKeystore) { ks.Nonce = ks.Nonce[1:] }},
+		{"wrapped key", func(ks *encryptedKeystore) { ks.WrappedKey = []byte("{}") }},
+		{"pub key", func(ks *encryptedKeystore) { ks.PubKey = "00" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := original
+			ks.Ciphertext = append([]byte(nil), original.Ciphertext...)
+			tt.corrupt(&ks)
+			buf, err := json.Marshal(ks)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(filePathName, buf, 0o600))
+			_, err = fsm.GetLocalState(state.PubKey)
+			require.ErrorIs(t, err, ErrKeystoreCorrupted)
+		})
+	}
+
+	require.NoError(t, ioutil.WriteFile(filePathName, []byte(`{"version":2}`), 0o600))
+	_, err = fsm.GetLocalState(state.PubKey)
+	require.ErrorContains(t, err, "unsupported keystore version 2")
+
+	require.NoError(t, ioutil.WriteFile(filePathName, buf[:len(buf)/2], 0o600))
+	_, err = fsm.GetLocalState(state.PubKey)
+	require.Error(t, err)
+
+	// No temporary file is left behind by the writes
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filePathName}, files)
+}
@@ -20,7 +20,25 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-const PreParams = "pre_params"
+const (
+	PreParams = "pre_params"
+
+	// rotationMarker commits a keystore rotation, whose files are staged
+	// with rotationSuffix
+	rotationMarker = "keystore_rotation"
+	rotationSuffix = ".rotate"
+
+	keyEpochsFile = "key_epochs.json"
+)
+
+// KeyShareID identifies the key share of pubKey at keyEpoch in the storages.
+// The key shares of a keygen keep the pub key alone.
+func KeyShareID(pubKey string, keyEpoch int) string {
+	if keyEpoch == 0 {
+		return pubKey
+	}
+	return fmt.Sprintf("%s-%d", pubKey, keyEpoch)
+}
 
 type KeygenLocalState struct {
 	PubKey          string                    `json:"pub_key"`
@@ -28,11 +46,20 @@ type KeygenLocalState struct {
 	ParticipantKeys []string                  `json:"participant_keys"` // the paticipant of last key gen
 	LocalPartyKey   string                    `json:"local_party_key"`
 	Threshold       int                       `json:"threshold"`
+	KeyEpoch        int                       `json:"key_epoch,omitempty"` // the number of resharings of the key
 }
 
+// LocalStateManager keeps the key shares by pub key and key epoch. A
+// resharing saves the key share of the next key epoch next to the one in
+// use, and SetKeyEpoch switches to it once the resharing is confirmed.
 type LocalStateManager interface {
 	SaveLocalState(state KeygenLocalState) error
+	// GetLocalState returns the key share of pubKey in use
 	GetLocalState(pubKey string) (KeygenLocalState, error)
+	GetEpochLocalState(pubKey string, keyEpoch int) (KeygenLocalState, error)
+	DeleteLocalState(pubKey string, keyEpoch int) error
+	GetKeyEpoch(pubKey string) (int, error)
+	SetKeyEpoch(pubKey string, keyEpoch int) error
 	SaveAddressBook(addressBook map[peer.ID]p2p.AddrList) error
 	RetrieveP2PAddresses() (p2p.AddrList, error)
 	SavePreParams(preParams *keygen.LocalPreParams) error
@@ -43,9 +70,14 @@ type FileStateMgr struct {
 	folder    string
 	logger    zerolog.Logger
 	writeLock *sync.RWMutex
+	kek       KeyEncryptionProvider
 }
 
-func NewFileStateMgr(folder string) (*FileStateMgr, error) {
+// NewFileStateMgr returns a state manager encrypting the local states with
+// kek. The local states found in folder are checked against kek, so that a
+// wrong passphrase fails at start, and the plaintext ones are encrypted
+// right away. A nil kek keeps the local states in plaintext.
+func NewFileStateMgr(folder string, kek KeyEncryptionProvider) (*FileStateMgr, error) {
 	if len(folder) > 0 {
 		_, err := os.Stat(folder)
 		if err != nil && os.IsNotExist(err) {
@@ -54,14 +86,26 @@ func NewFileStateMgr(folder string) (*FileStateMgr, error) {
 			}
 		}
 	}
-	return &FileStateMgr{
+	fsm := &FileStateMgr{
 		folder:    folder,
 		logger:    log.With().Str("module", "storage").Logger(),
 		writeLock: &sync.RWMutex{},
-	}, nil
+		kek:       kek,
+	}
+	if err := fsm.recoverRotation(); err != nil {
+		return nil, err
+	}
+	if kek == nil {
+		fsm.logger.Warn().Msg("no key encryption provider configured, local states are stored in plaintext")
+		return fsm, nil
+	}
+	if err := fsm.migrateLocalStates(); err != nil {
+		return nil, err
+	}
+	return fsm, nil
 }
 
-func (fsm *FileStateMgr) getFilePathName(pubKey string) (string, error) {
+func (fsm *FileStateMgr) getFilePathName(pubKey string, keyEpoch int) (string, error) {
 	ret, err := conversion.CheckKeyOnCurve(pubKey)
 	if err != nil {
 		return "", err
@@ -70,19 +114,23 @@ func (fsm *FileStateMgr) getFilePathName(pubKey string) (string, error) {
 		return "", errors.New("invalid pubkey for file name")
 	}
 
-	localFileName := fmt.Sprintf("localstate-%s.json", pubKey)
+	localFileName := fmt.Sprintf("localstate-%s.json", KeyShareID(pubKey, keyEpoch))
 	if len(fsm.folder) > 0 {
 		return filepath.Join(fsm.folder, localFileName), nil
 	}
 	return localFileName, nil
 }
 
-func (fsm *FileStateMgr) getOneFilePathName() (string, error) {
+func (fsm *FileStateMgr) getAllFilePathNames() ([]string, error) {
 	var pattern = "localstate*.json"
 	if len(fsm.folder) > 0 {
 		pattern = filepath.Join(fsm.folder, pattern)
 	}
-	files, err := filepath.Glob(pattern)
+	return filepath.Glob(pattern)
+}
+
+func (fsm *FileStateMgr) getOneFilePathName() (string, error) {
+	files, err := fsm.getAllFilePathNames()
 	if err != nil {
 		return "", err
 	}
@@ -93,23 +141,251 @@ func (fsm *FileStateMgr) getOneFilePathName() (string, error) {
 
 }
 
-func (fsm *FileStateMgr) SaveLocalState(state KeygenLocalState) error {
+// readKeystoreFile reads a file sealed by sealKeystore, or a plaintext one,
+// and returns its content, the name the content is bound to, and whether it
+// was plaintext
+func (fsm *FileStateMgr) readKeystoreFile(filePathName string, kek KeyEncryptionProvider) ([]byte, string, bool, error) {
+	fsm.writeLock.RLock()
+	buf, err := ioutil.ReadFile(filePathName)
+	fsm.writeLock.RUnlock()
+	if err != nil {
+		return nil, "", false, fmt.Errorf("file to read from file(%s): %w", filePathName, err)
+	}
+	var ks encryptedKeystore
+	if err := json.Unmarshal(buf, &ks); err != nil {
+		return nil, "", false, fmt.Errorf("fail to unmarshal keystore file(%s): %w", filePathName, err)
+	}
+	if ks.Version == 0 {
+		return buf, "", true, nil
+	}
+	if buf, err = openKeystore(kek, &ks); err != nil {
+		return nil, "", false, fmt.Errorf("fail to decrypt keystore file(%s): %w", filePathName, err)
+	}
+	return buf, ks.PubKey, false, nil
+}
+
+// writeKeystoreFile seals buf, bound to name, with kek when it is set
+func (fsm *FileStateMgr) writeKeystoreFile(filePathName string, name string, buf []byte, kek KeyEncryptionProvider) error {
+	if kek != nil {
+		ks, err := sealKeystore(kek, name, buf)
+		if err != nil {
+			return fmt.Errorf("fail to encrypt keystore file(%s): %w", filePathName, err)
+		}
+		if buf, err = json.Marshal(ks); err != nil {
+			return fmt.Errorf("fail to marshal keystore to json: %w", err)
+		}
+	}
+	fsm.writeLock.Lock()
+	defer fsm.writeLock.Unlock()
+	return writeFileAtomic(filePathName, buf)
+}
+
+// readLocalState reads a local state file, which is either plaintext or
+// encrypted, and reports whether it was plaintext
+func (fsm *FileStateMgr) readLocalState(filePathName string) (KeygenLocalState, bool, error) {
+	buf, _, plaintext, err := fsm.readKeystoreFile(filePathName, fsm.kek)
+	if err != nil {
+		return KeygenLocalState{}, false, err
+	}
+	var localState KeygenLocalState
+	if err := json.Unmarshal(buf, &localState); nil != err {
+		return KeygenLocalState{}, false, fmt.Errorf("fail to unmarshal KeygenLocalState: %w", err)
+	}
+	return localState, plaintext, nil
+}
+
+func (fsm *FileStateMgr) writeLocalState(filePathName string, state KeygenLocalState, kek KeyEncryptionProvider) error {
 	buf, err := json.Marshal(state)
 	if err != nil {
 		return fmt.Errorf("fail to marshal KeygenLocalState to json: %w", err)
 	}
-	filePathName, err := fsm.getFilePathName(state.PubKey)
+	return fsm.writeKeystoreFile(filePathName, state.PubKey, buf, kek)
+}
+
+func (fsm *FileStateMgr) getPreParamsFilePathName() string {
+	return filepath.Join(fsm.folder, fmt.Sprintf("%s.json", PreParams))
+}
+
+// getKeystoreFiles returns the files encrypted with the key encryption
+// provider: the local states, the pre-params and the pre-params pool
+func (fsm *FileStateMgr) getKeystoreFiles(suffix string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(fsm.folder, "localstate*.json"+suffix))
+	if err != nil {
+		return nil, err
+	}
+	poolFiles, err := filepath.Glob(filepath.Join(fsm.folder, preParamsPoolFolder, "*.json"+suffix))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, poolFiles...)
+	preParamsFile := fsm.getPreParamsFilePathName() + suffix
+	if _, err := os.Stat(preParamsFile); err == nil {
+		files = append(files, preParamsFile)
+	}
+	return files, nil
+}
+
+// migrateLocalStates decrypts the encrypted local states and encrypts the
+// plaintext ones, as well as the plaintext pre-params
+func (fsm *FileStateMgr) migrateLocalStates() error {
+	files, err := fsm.getAllFilePathNames()
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filePathName, buf, 0o655)
+	for _, filePathName := range files {
+		state, plaintext, err := fsm.readLocalState(filePathName)
+		if err != nil {
+			return err
+		}
+		if !plaintext {
+			continue
+		}
+		if err := fsm.writeLocalState(filePathName, state, fsm.kek); err != nil {
+			return fmt.Errorf("fail to encrypt local state file(%s): %w", filePathName, err)
+		}
+		fsm.logger.Info().Str("file", filePathName).Msg("encrypted plaintext local state")
+	}
+
+	preParamsFile := fsm.getPreParamsFilePathName()
+	if _, err := os.Stat(preParamsFile); err != nil {
+		return nil
+	}
+	buf, _, plaintext, err := fsm.readKeystoreFile(preParamsFile, fsm.kek)
+	if err != nil {
+		return err
+	}
+	if plaintext {
+		if err := fsm.writeKeystoreFile(preParamsFile, PreParams, buf, fsm.kek); err != nil {
+			return fmt.Errorf("fail to encrypt pre params file(%s): %w", preParamsFile, err)
+		}
+		fsm.logger.Info().Str("file", preParamsFile).Msg("encrypted plaintext pre params")
+	}
+	return nil
+}
+
+// RotateKeyEncryption wraps the data keys of all the keystore files with
+// newKEK, which must have another id than the current provider. The files
+// are rewritten next to the current ones first, then the rotation is
+// committed by the rotation marker and the files are renamed into place.
+// A rotation interrupted before the marker is discarded at the next start,
+// and one interrupted after it is completed.
+func (fsm *FileStateMgr) RotateKeyEncryption(newKEK KeyEncryptionProvider) error {
+	if newKEK == nil {
+		return errors.New("new key encryption provider is nil")
+	}
+	if fsm.kek != nil && fsm.kek.Name() == newKEK.Name() {
+		return fmt.Errorf("new key encryption provider has the id %s of the current one, increase the key version", newKEK.Name())
+	}
+	files, err := fsm.getKeystoreFiles("")
+	if err != nil {
+		return err
+	}
+	// Read everything first, so that a wrong provider fails before any
+	// file is written
+	contents := make([][]byte, len(files))
+	names := make([]string, len(files))
+	for i, filePathName := range files {
+		if contents[i], names[i], _, err = fsm.readKeystoreFile(filePathName, fsm.kek); err != nil {
+			return err
+		}
+		if len(names[i]) > 0 {
+			continue
+		}
+		// Plaintext files are bound to their pub key, or to PreParams for
+		// the pre-params
+		names[i] = PreParams
+		if strings.HasPrefix(filepath.Base(filePathName), "localstate") {
+			var state KeygenLocalState
+			if err := json.Unmarshal(contents[i], &state); err != nil {
+				return fmt.Errorf("fail to unmarshal KeygenLocalState: %w", err)
+			}
+			names[i] = state.PubKey
+		}
+	}
+	for i, filePathName := range files {
+		if err := fsm.writeKeystoreFile(filePathName+rotationSuffix, names[i], contents[i], newKEK); err != nil {
+			fsm.discardRotation()
+			return fmt.Errorf("fail to rotate keystore file(%s): %w", filePathName, err)
+		}
+	}
+	if err := writeFileAtomic(fsm.rotationMarker(), []byte(newKEK.Name())); err != nil {
+		fsm.discardRotation()
+		return fmt.Errorf("fail to commit keystore rotation: %w", err)
+	}
+	if err := fsm.completeRotation(); err != nil {
+		return err
+	}
+	fsm.kek = newKEK
+	fsm.logger.Info().Int("files", len(files)).Str("provider", newKEK.Name()).Msg("rotated key encryption of the keystore")
+	return nil
+}
+
+func (fsm *FileStateMgr) rotationMarker() string {
+	return filepath.Join(fsm.folder, rotationMarker)
+}
+
+// recoverRotation completes or discards a rotation interrupted by a crash
+func (fsm *FileStateMgr) recoverRotation() error {
+	if _, err := os.Stat(fsm.rotationMarker()); err != nil {
+		return fsm.discardRotation()
+	}
+	fsm.logger.Warn().Msg("completing an interrupted keystore rotation")
+	return fsm.completeRotation()
+}
+
+func (fsm *FileStateMgr) completeRotation() error {
+	files, err := fsm.getKeystoreFiles(rotationSuffix)
+	if err != nil {
+		return err
+	}
+	fsm.writeLock.Lock()
+	defer fsm.writeLock.Unlock()
+	for _, filePathName := range files {
+		if err := os.Rename(filePathName, strings.TrimSuffix(filePathName, rotationSuffix)); err != nil {
+			return fmt.Errorf("fail to complete keystore rotation: %w", err)
+		}
+	}
+	return os.Remove(fsm.rotationMarker())
+}
+
+func (fsm *FileStateMgr) discardRotation() error {
+	files, err := fsm.getKeystoreFiles(rotationSuffix)
+	if err != nil {
+		return err
+	}
+	for _, filePathName := range files {
+		if err := os.Remove(filePathName); err != nil {
+			return fmt.Errorf("fail to discard keystore rotation: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveLocalState saves the key share of state.PubKey at state.KeyEpoch
+func (fsm *FileStateMgr) SaveLocalState(state KeygenLocalState) error {
+	filePathName, err := fsm.getFilePathName(state.PubKey, state.KeyEpoch)
+	if err != nil {
+		return err
+	}
+	return fsm.writeLocalState(filePathName, state, fsm.kek)
 }
 
 func (fsm *FileStateMgr) GetLocalState(pubKey string) (KeygenLocalState, error) {
 	if len(pubKey) == 0 {
 		return KeygenLocalState{}, errors.New("pub key is empty")
 	}
-	filePathName, err := fsm.getFilePathName(pubKey)
+	keyEpoch, err := fsm.GetKeyEpoch(pubKey)
+	if err != nil {
+		return KeygenLocalState{}, err
+	}
+	return fsm.GetEpochLocalState(pubKey, keyEpoch)
+}
+
+func (fsm *FileStateMgr) GetEpochLocalState(pubKey string, keyEpoch int) (KeygenLocalState, error) {
+	if len(pubKey) == 0 {
+		return KeygenLocalState{}, errors.New("pub key is empty")
+	}
+	filePathName, err := fsm.getFilePathName(pubKey, keyEpoch)
 	if err != nil {
 		return KeygenLocalState{}, err
 	}
@@ -117,17 +393,77 @@ func (fsm *FileStateMgr) GetLocalState(pubKey string) (KeygenLocalState, error)
 		return KeygenLocalState{}, err
 	}
 
-	buf, err := ioutil.ReadFile(filePathName)
+	localState, plaintext, err := fsm.readLocalState(filePathName)
 	if err != nil {
-		return KeygenLocalState{}, fmt.Errorf("file to read from file(%s): %w", filePathName, err)
+		return KeygenLocalState{}, err
 	}
-	var localState KeygenLocalState
-	if err := json.Unmarshal(buf, &localState); nil != err {
-		return KeygenLocalState{}, fmt.Errorf("fail to unmarshal KeygenLocalState: %w", err)
+	// Local states copied into the folder after the start are encrypted
+	// on first use
+	if plaintext && fsm.kek != nil {
+		if err := fsm.writeLocalState(filePathName, localState, fsm.kek); err != nil {
+			fsm.logger.Error().Err(err).Str("file", filePathName).Msg("fail to encrypt plaintext local state")
+		}
 	}
 	return localState, nil
 }
 
+// DeleteLocalState deletes the key share of pubKey at keyEpoch, if any
+func (fsm *FileStateMgr) DeleteLocalState(pubKey string, keyEpoch int) error {
+	filePathName, err := fsm.getFilePathName(pubKey, keyEpoch)
+	if err != nil {
+		return err
+	}
+	fsm.writeLock.Lock()
+	defer fsm.writeLock.Unlock()
+	if err := os.Remove(filePathName); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("fail to delete local state file(%s): %w", filePathName, err)
+	}
+	return nil
+}
+
+func (fsm *FileStateMgr) readKeyEpochs() (map[string]int, error) {
+	var keyEpochs = map[string]int{}
+	buf, err := ioutil.ReadFile(filepath.Join(fsm.folder, keyEpochsFile))
+	if os.IsNotExist(err) {
+		return keyEpochs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &keyEpochs); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal key epochs: %w", err)
+	}
+	return keyEpochs, nil
+}
+
+// GetKeyEpoch returns the key epoch of the key share of pubKey in use, 0
+// until a resharing of the key is confirmed
+func (fsm *FileStateMgr) GetKeyEpoch(pubKey string) (int, error) {
+	fsm.writeLock.RLock()
+	defer fsm.writeLock.RUnlock()
+	keyEpochs, err := fsm.readKeyEpochs()
+	if err != nil {
+		return 0, err
+	}
+	return keyEpochs[pubKey], nil
+}
+
+// SetKeyEpoch switches the key share of pubKey in use to the one of keyEpoch
+func (fsm *FileStateMgr) SetKeyEpoch(pubKey string, keyEpoch int) error {
+	fsm.writeLock.Lock()
+	defer fsm.writeLock.Unlock()
+	keyEpochs, err := fsm.readKeyEpochs()
+	if err != nil {
+		return err
+	}
+	keyEpochs[pubKey] = keyEpoch
+	buf, err := json.Marshal(keyEpochs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(fsm.folder, keyEpochsFile), buf)
+}
+
 func (fsm *FileStateMgr) GetOneLocalPreParams() (*keygen.LocalPreParams, error) {
 	filePathName, err := fsm.getOneFilePathName()
 	if err != nil {
@@ -139,13 +475,9 @@ func (fsm *FileStateMgr) GetOneLocalPreParams() (*keygen.LocalPreParams, error)
 	if _, err := os.Stat(filePathName); os.IsNotExist(err) {
 		return nil, err
 	}
-	buf, err := ioutil.ReadFile(filePathName)
+	localState, _, err := fsm.readLocalState(filePathName)
 	if err != nil {
-		return nil, fmt.Errorf("file to read from file(%s): %w", filePathName, err)
-	}
-	var localState KeygenLocalState
-	if err := json.Unmarshal(buf, &localState); nil != err {
-		return nil, fmt.Errorf("fail to unmarshal KeygenLocalState: %w", err)
+		return nil, err
 	}
 	return &localState.LocalData.LocalPreParams, nil
 
@@ -214,19 +546,16 @@ func (fsm *FileStateMgr) SavePreParams(preParams *keygen.LocalPreParams) error {
 	if err != nil {
 		return fmt.Errorf("fail to marshal keygen local preparams to json: %w", err)
 	}
-	localFileName := fmt.Sprintf("%s.json", PreParams)
-	var filePathName = localFileName
-	if len(fsm.folder) > 0 {
-		filePathName = filepath.Join(fsm.folder, localFileName)
-	}
-	return ioutil.WriteFile(filePathName, buf, 0o655)
+	return fsm.writeKeystoreFile(fsm.getPreParamsFilePathName(), PreParams, buf, fsm.kek)
 }
 
+// GetLocalPreParams reads the pre-params of filePath, or the ones saved by
+// SavePreParams when it is empty. The file is either plaintext or encrypted.
 func (fsm *FileStateMgr) GetLocalPreParams(filePath string) (*keygen.LocalPreParams, error) {
 	if len(filePath) == 0 {
-		filePath = filepath.Join(fsm.folder, fmt.Sprintf("%s.json", PreParams))
+		filePath = fsm.getPreParamsFilePathName()
 	}
-	buf, err := ioutil.ReadFile(filePath)
+	buf, _, _, err := fsm.readKeystoreFile(filePath, fsm.kek)
 	if err != nil {
 		return nil, err
 	}
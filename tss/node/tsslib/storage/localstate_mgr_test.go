@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStateKeyEpochs(t *testing.T) {
+	dir := t.TempDir()
+	fsm, err := NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, fsm.SaveLocalState(state))
+
+	// The key share of a resharing is kept next to the one in use
+	reshared := state
+	reshared.ParticipantKeys = []string{"b", "c", "d"}
+	reshared.KeyEpoch = 1
+	require.NoError(t, fsm.SaveLocalState(reshared))
+	loaded, err := fsm.GetLocalState(state.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, state.ParticipantKeys, loaded.ParticipantKeys)
+	loaded, err = fsm.GetEpochLocalState(state.PubKey, 1)
+	require.NoError(t, err)
+	require.Equal(t, reshared.ParticipantKeys, loaded.ParticipantKeys)
+
+	// until it is switched to, which survives a restart
+	require.NoError(t, fsm.SetKeyEpoch(state.PubKey, 1))
+	fsm, err = NewFileStateMgr(dir, newTestProvider(t, "passphrase"))
+	require.NoError(t, err)
+	keyEpoch, err := fsm.GetKeyEpoch(state.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, keyEpoch)
+	loaded, err = fsm.GetLocalState(state.PubKey)
+	require.NoError(t, err)
+	require.Equal(t, reshared.ParticipantKeys, loaded.ParticipantKeys)
+
+	require.NoError(t, fsm.DeleteLocalState(state.PubKey, 0))
+	require.NoError(t, fsm.DeleteLocalState(state.PubKey, 0))
+	_, err = fsm.GetEpochLocalState(state.PubKey, 0)
+	require.True(t, os.IsNotExist(err))
+	keyEpoch, err = fsm.GetKeyEpoch(newTestLocalState(t).PubKey)
+	require.NoError(t, err)
+	require.Equal(t, 0, keyEpoch)
+}
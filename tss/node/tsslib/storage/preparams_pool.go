@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/monitor"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	preParamsPoolFolder = "pre_params_pool"
+	// backgroundConcurrency is the lowest concurrency of the pre-params
+	// generation, so that the pool does not slow down the signatures
+	backgroundConcurrency = 1
+	generateRetryInterval = 10 * time.Second
+)
+
+// GeneratePreParamsFn generates a set of pre-params
+type GeneratePreParamsFn func(timeout time.Duration, concurrency ...int) (*keygen.LocalPreParams, error)
+
+// PreParamsPool keeps pre-generated pre-params in a folder, so that a
+// keygen does not wait for their generation. Every set is handed out once
+// and deleted. The pool is refilled in the background.
+type PreParamsPool struct {
+	folder          string
+	size            int
+	generateTimeout time.Duration
+	kek             KeyEncryptionProvider
+	generate        GeneratePreParamsFn
+	metrics         *monitor.Metric
+	logger          zerolog.Logger
+	lock            sync.Mutex
+	files           []string
+	seq             int64
+	takeChan        chan struct{}
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewPreParamsPool returns a pool of size sets of pre-params stored in the
+// pre-params pool folder of baseFolder, encrypted with kek when it is set
+func NewPreParamsPool(baseFolder string, size int, generateTimeout time.Duration, kek KeyEncryptionProvider, metrics *monitor.Metric) (*PreParamsPool, error) {
+	folder := filepath.Join(baseFolder, preParamsPoolFolder)
+	if err := os.MkdirAll(folder, 0o700); err != nil {
+		return nil, fmt.Errorf("fail to create pre params pool folder: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(folder, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// The sets are handed out in the order they were generated
+	sort.Strings(files)
+	pool := &PreParamsPool{
+		folder:          folder,
+		size:            size,
+		generateTimeout: generateTimeout,
+		kek:             kek,
+		generate:        keygen.GeneratePreParams,
+		metrics:         metrics,
+		logger:          log.With().Str("module", "preParamsPool").Logger(),
+		files:           files,
+		seq:             time.Now().UnixNano(),
+		takeChan:        make(chan struct{}, 1),
+		stopChan:        make(chan struct{}),
+	}
+	pool.metrics.UpdatePreParamsPoolDepth(len(files))
+	return pool, nil
+}
+
+func (p *PreParamsPool) Start() {
+	p.wg.Add(1)
+	go p.fill()
+}
+
+func (p *PreParamsPool) Stop() {
+	close(p.stopChan)
+	p.wg.Wait()
+}
+
+func (p *PreParamsPool) Depth() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.files)
+}
+
+// fill generates pre-params one at a time until the pool is full, and waits
+// for a set to be taken to generate the next one
+func (p *PreParamsPool) fill() {
+	defer p.wg.Done()
+	for {
+		if p.Depth() >= p.size {
+			select {
+			case <-p.takeChan:
+				continue
+			case <-p.stopChan:
+				return
+			}
+		}
+		start := time.Now()
+		preParams, stopped, err := p.generateUntilStop()
+		if stopped {
+			return
+		}
+		if err == nil {
+			err = p.add(preParams)
+		}
+		if err != nil {
+			p.logger.Error().Err(err).Msg("fail to generate pre params for the pool")
+			select {
+			case <-time.After(generateRetryInterval):
+				continue
+			case <-p.stopChan:
+				return
+			}
+		}
+		p.logger.Info().Dur("took", time.Since(start)).Int("depth", p.Depth()).Msg("generated pre params for the pool")
+		select {
+		case <-p.stopChan:
+			return
+		default:
+		}
+	}
+}
+
+// generateUntilStop generates a set of pre-params, unless the pool is
+// stopped first. The generation cannot be interrupted, so it is left to
+// finish in the background and its result is discarded.
+func (p *PreParamsPool) generateUntilStop() (*keygen.LocalPreParams, bool, error) {
+	type result struct {
+		preParams *keygen.LocalPreParams
+		err       error
+	}
+	generated := make(chan result, 1)
+	go func() {
+		preParams, err := p.generate(p.generateTimeout, backgroundConcurrency)
+		generated <- result{preParams, err}
+	}()
+	select {
+	case r := <-generated:
+		return r.preParams, false, r.err
+	case <-p.stopChan:
+		return nil, true, nil
+	}
+}
+
+func (p *PreParamsPool) add(preParams *keygen.LocalPreParams) error {
+	buf, err := json.Marshal(preParams)
+	if err != nil {
+		return fmt.Errorf("fail to marshal keygen local preparams to json: %w", err)
+	}
+	if p.kek != nil {
+		ks, err := sealKeystore(p.kek, PreParams, buf)
+		if err != nil {
+			return fmt.Errorf("fail to encrypt keygen local preparams: %w", err)
+		}
+		if buf, err = json.Marshal(ks); err != nil {
+			return err
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.seq++
+	filePathName := filepath.Join(p.folder, fmt.Sprintf("%020d.json", p.seq))
+	if err := writeFileAtomic(filePathName, buf); err != nil {
+		return err
+	}
+	p.files = append(p.files, filePathName)
+	p.metrics.UpdatePreParamsPoolDepth(len(p.files))
+	return nil
+}
+
+// Take removes the oldest set of pre-params from the pool. It generates a
+// set with all the cores, within timeout, when the pool is empty.
+func (p *PreParamsPool) Take(timeout time.Duration) (*keygen.LocalPreParams, error) {
+	for {
+		filePathName, ok := p.pop()
+		if !ok {
+			break
+		}
+		preParams, err := p.read(filePathName)
+		// The set is deleted even when unreadable, so that it is never
+		// handed out twice
+		if rmErr := os.Remove(filePathName); rmErr != nil {
+			p.logger.Error().Err(rmErr).Str("file", filePathName).Msg("fail to delete pre params")
+		}
+		if err != nil {
+			p.logger.Error().Err(err).Str("file", filePathName).Msg("fail to read pre params of the pool")
+			continue
+		}
+		p.metrics.UpdatePreParamsTaken("pool")
+		return preParams, nil
+	}
+
+	p.logger.Warn().Msg("pre params pool is empty, start to generate pre params...")
+	preParams, err := p.generate(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("fail to generate pre parameters: %w", err)
+	}
+	p.metrics.UpdatePreParamsTaken("inline")
+	return preParams, nil
+}
+
+func (p *PreParamsPool) pop() (string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.files) == 0 {
+		return "", false
+	}
+	filePathName := p.files[0]
+	p.files = p.files[1:]
+	p.metrics.UpdatePreParamsPoolDepth(len(p.files))
+	select {
+	case p.takeChan <- struct{}{}:
+	default:
+	}
+	return filePathName, true
+}
+
+func (p *PreParamsPool) read(filePathName string) (*keygen.LocalPreParams, error) {
+	buf, err := ioutil.ReadFile(filePathName)
+	if err != nil {
+		return nil, err
+	}
+	var ks encryptedKeystore
+	if err := json.Unmarshal(buf, &ks); err != nil {
+		return nil, err
+	}
+	if ks.Version != 0 {
+		if buf, err = openKeystore(p.kek, &ks); err != nil {
+			return nil, err
+		}
+	}
+	var preParams keygen.LocalPreParams
+	if err := json.Unmarshal(buf, &preParams); err != nil {
+		return nil, err
+	}
+	if !preParams.Validate() {
+		return nil, errors.New("invalid pre params")
+	}
+	return &preParams, nil
+}
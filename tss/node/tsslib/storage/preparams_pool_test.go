@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/binance-chain/tss-lib/crypto/paillier"
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/monitor"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGenerator numbers the pre-params it generates and records the
+// concurrency of the generations. The generations wait for block when it
+// is set.
+type fakeGenerator struct {
+	lock        sync.Mutex
+	count       int64
+	concurrency []int
+	block       chan struct{}
+}
+
+func (f *fakeGenerator) generate(_ time.Duration, concurrency ...int) (*keygen.LocalPreParams, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.count++
+	f.concurrency = append(f.concurrency, concurrency...)
+	n := big.NewInt(f.count)
+	return &keygen.LocalPreParams{
+		PaillierSK: &paillier.PrivateKey{PublicKey: paillier.PublicKey{N: n}, LambdaN: n, PhiN: n},
+		NTildei:    n,
+		H1i:        n,
+		H2i:        n,
+	}, nil
+}
+
+func newTestPool(t *testing.T, dir string, size int, generator *fakeGenerator) *PreParamsPool {
+	pool, err := NewPreParamsPool(dir, size, time.Minute, newTestProvider(t, "passphrase"), monitor.NewMetric())
+	require.NoError(t, err)
+	pool.generate = generator.generate
+	return pool
+}
+
+func TestPreParamsPool(t *testing.T) {
+	dir := t.TempDir()
+	generator := new(fakeGenerator)
+	pool := newTestPool(t, dir, 3, generator)
+	pool.Start()
+	require.Eventually(t, func() bool { return pool.Depth() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	// The sets are encrypted and handed out once, oldest first
+	files, err := filepath.Glob(filepath.Join(dir, preParamsPoolFolder, "*.json"))
+	require.NoError(t, err)
+	require.Equal(t, 3, len(files))
+	buf, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"version":1`)
+
+	preParams, err := pool.Take(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), preParams.NTildei.Int64())
+	_, err = os.Stat(files[0])
+	require.True(t, os.IsNotExist(err))
+
+	// The pool is refilled in the background, at the lowest concurrency
+	require.Eventually(t, func() bool { return pool.Depth() == 3 }, 5*time.Second, 10*time.Millisecond)
+	pool.Stop()
+	generator.lock.Lock()
+	require.Equal(t, int64(4), generator.count)
+	require.Equal(t, []int{1, 1, 1, 1}, generator.concurrency)
+	generator.lock.Unlock()
+
+	// The sets are kept across restarts, and an unreadable set is deleted
+	require.NoError(t, ioutil.WriteFile(files[1], []byte("{}"), 0o600))
+	pool = newTestPool(t, dir, 3, generator)
+	require.Equal(t, 3, pool.Depth())
+	preParams, err = pool.Take(time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), preParams.NTildei.Int64())
+	require.Equal(t, 1, pool.Depth())
+	_, err = os.Stat(files[1])
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestPreParamsPoolEmpty(t *testing.T) {
+	generator := new(fakeGenerator)
+	pool := newTestPool(t, t.TempDir(), 1, generator)
+
+	// A keygen generates its pre-params with all the cores when the pool is
+	// empty
+	preParams, err := pool.Take(time.Minute)
+	require.NoError(t, err)
+	require.True(t, preParams.Validate())
+	require.Equal(t, int64(1), generator.count)
+	require.Empty(t, generator.concurrency)
+	require.Equal(t, 0, pool.Depth())
+}
+
+func TestPreParamsPoolStopDuringGeneration(t *testing.T) {
+	dir := t.TempDir()
+	generator := &fakeGenerator{block: make(chan struct{})}
+	pool := newTestPool(t, dir, 1, generator)
+	pool.Start()
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the pool to stop during a generation")
+	}
+
+	// The generation finishing after the stop is discarded
+	close(generator.block)
+	require.Eventually(t, func() bool {
+		generator.lock.Lock()
+		defer generator.lock.Unlock()
+		return generator.count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, pool.Depth())
+	files, err := filepath.Glob(filepath.Join(dir, preParamsPoolFolder, "*.json"))
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -14,10 +15,13 @@ import (
 
 const CtxTimeout = 30 * time.Second
 
+// SecretsManager keeps the key shares by pub key and key epoch, see
+// KeyShareID. Save writes them all to the secret.
 type SecretsManager interface {
 	Save() error
 	PutKeyFile(state KeygenLocalState) error
-	GetKeyFile(pubKey string) (KeygenLocalState, error)
+	GetKeyFile(pubKey string, keyEpoch int) (KeygenLocalState, error)
+	DeleteKeyFile(pubKey string, keyEpoch int) error
 }
 
 type SecretsMgr struct {
@@ -42,12 +46,12 @@ func NewSecretsMgr(secretId string) (*SecretsMgr, error) {
 	result, err := client.GetSecretValue(ctx, params)
 	if err != nil {
 
-		log.Error().Err(err).Msgf("fail to get secret value form aws secrets manager :%w", err)
+		log.Error().Err(err).Msgf("fail to get secret value form aws secrets manager :%v", err)
 		return nil, err
 	}
 	var keys map[string]KeygenLocalState
 	if err := json.Unmarshal(result.SecretBinary, &keys); err != nil {
-		log.Error().Err(err).Msgf("fail to unmarshal data to map :%w", err)
+		log.Error().Err(err).Msgf("fail to unmarshal data to map :%v", err)
 		keys = map[string]KeygenLocalState{}
 	}
 
@@ -60,21 +64,21 @@ func NewSecretsMgr(secretId string) (*SecretsMgr, error) {
 }
 
 func (sm *SecretsMgr) PutKeyFile(stat KeygenLocalState) error {
-	_, ok := sm.keys[stat.PubKey]
-	if !ok {
-		sm.keys[stat.PubKey] = stat
-	}
+	sm.keys[KeyShareID(stat.PubKey, stat.KeyEpoch)] = stat
 	return nil
 }
 
+func (sm *SecretsMgr) DeleteKeyFile(pubKey string, keyEpoch int) error {
+	delete(sm.keys, KeyShareID(pubKey, keyEpoch))
+	return nil
+}
+
+// Save writes the key shares to the secret, even when none is left, so that
+// the deleted key shares are gone from it too
 func (sm *SecretsMgr) Save() error {
-	if len(sm.keys) == 0 {
-		log.Warn().Msg("secrets storage size is 0 ,don't need to put to aws secrets manager")
-		return nil
-	}
 	buf, err := json.Marshal(sm.keys)
 	if err != nil {
-		log.Error().Err(err).Msgf("fail to marshal secrets keys map to json: %w", err)
+		log.Error().Err(err).Msgf("fail to marshal secrets keys map to json: %v", err)
 		return err
 	}
 	params := &secretsmanager.UpdateSecretInput{
@@ -83,18 +87,18 @@ func (sm *SecretsMgr) Save() error {
 	}
 	output, err := sm.client.UpdateSecret(context.TODO(), params)
 	if err != nil {
-		log.Error().Err(err).Msgf("fail to put data to aws's secrets manager : %w", err)
+		log.Error().Err(err).Msgf("fail to put data to aws's secrets manager : %v", err)
 		return err
 	}
-	log.Info().Msgf("put data to aws's secrets manager success, version is:%s", output.VersionId)
+	log.Info().Msgf("put data to aws's secrets manager success, version is:%s", aws.ToString(output.VersionId))
 	return nil
 }
 
-func (sm *SecretsMgr) GetKeyFile(pubKey string) (KeygenLocalState, error) {
-	value, ok := sm.keys[pubKey]
+func (sm *SecretsMgr) GetKeyFile(pubKey string, keyEpoch int) (KeygenLocalState, error) {
+	value, ok := sm.keys[KeyShareID(pubKey, keyEpoch)]
 	if !ok {
-		log.Warn().Msgf("can not find keygenlocalstate from storage by this pubKey (%s)", pubKey)
-		return KeygenLocalState{}, nil
+		log.Warn().Msgf("can not find keygenlocalstate from storage by this pubKey (%s) and key epoch (%d)", pubKey, keyEpoch)
+		return KeygenLocalState{}, fmt.Errorf("no key share of pub key %s at key epoch %d", pubKey, keyEpoch)
 	}
 	return value, nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,38 +20,90 @@ import (
 	"github.com/google/uuid"
 	nodeconfig "github.com/mantlenetworkio/mantle/tss/common"
 	"github.com/rs/zerolog/log"
-	"sort"
 	"strconv"
-	"strings"
 )
 
 const (
-	n = 5
-	k = 4
+	defaultShamirN = 5
+	defaultShamirK = 4
 )
 
+// ShamirManager keeps the key shares by pub key and key epoch, see
+// KeyShareID
 type ShamirManager interface {
 	PutKeyFile(state KeygenLocalState) error
-	GetKeyFile(pubKey, localPartyKey string) (KeygenLocalState, error)
+	GetKeyFile(pubKey string, keyEpoch int, localPartyKey string) (KeygenLocalState, error)
+	DeleteKeyFile(pubKey string, keyEpoch int, localPartyKey string) error
 }
 
 type (
 	ShamirMgr struct {
 		shamirConfig nodeconfig.ShamirConfig
+		n            int
+		k            int
+		stores       []ShareStore
+		kek          KeyEncryptionProvider
 		keys         map[string]KeygenLocalState
 	}
 
+	// Share is a Shamir share encrypted with a data key. Shares without a
+	// nonce were encrypted with a zero nonce, and shares without a provider
+	// have their data key wrapped by KMS.
 	Share struct {
 		DexCipher   []byte `json:"dex_cipher"`
 		ShareCipher []byte `json:"share_cipher"`
+		Nonce       []byte `json:"nonce,omitempty"`
+		Provider    string `json:"provider,omitempty"`
 	}
 )
 
+// NewShamirMgr returns a manager spreading the local states over the share
+// locations of config. No location may hold k shares, as it would rebuild
+// the local states by itself.
 func NewShamirMgr(config nodeconfig.ShamirConfig) (*ShamirMgr, error) {
+	sh, err := NewShamirMgrWithLocations(config, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.checkSharePlacement(); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// NewShamirMgrWithLocations returns a manager using the share locations
+// instead of the ones of config, see NewShareStores. It can read from fewer
+// locations than NewShamirMgr accepts, to recover the local states, but it
+// does not write to them.
+func NewShamirMgrWithLocations(config nodeconfig.ShamirConfig, locations string) (*ShamirMgr, error) {
 	log.Debug().Msg("create shamir instance ")
+	n, k := config.N, config.K
+	if n == 0 {
+		n = defaultShamirN
+	}
+	if k == 0 {
+		k = defaultShamirK
+	}
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("invalid shamir threshold %d of %d shares", k, n)
+	}
+	stores, err := NewShareStores(config, locations)
+	if err != nil {
+		return nil, err
+	}
+	var kek KeyEncryptionProvider
+	if len(config.Kms.KeyId) > 0 {
+		kek = &kmsProvider{config: config.Kms}
+	} else if kek, err = NewPassphraseProvider(config.Passphrase, 0, 0); err != nil {
+		return nil, errors.New("shamir needs a kms key id or a passphrase")
+	}
 	var keys = map[string]KeygenLocalState{}
 	return &ShamirMgr{
 		shamirConfig: config,
+		n:            n,
+		k:            k,
+		stores:       stores,
+		kek:          kek,
 		keys:         keys,
 	}, nil
 
@@ -58,253 +111,225 @@ func NewShamirMgr(config nodeconfig.ShamirConfig) (*ShamirMgr, error) {
 
 func (sh *ShamirMgr) PutKeyFile(stat KeygenLocalState) error {
 	log.Info().Msg("start to storage new keygen ")
-	_, ok := sh.keys[stat.PubKey]
-	if !ok {
-		sh.keys[stat.PubKey] = stat
-		err := sh.SaveEncrypt(stat)
-		if err != nil {
-			log.Error().Err(err).Msg("put key file failed")
-			return err
-		}
+	if err := sh.SaveEncrypt(stat); err != nil {
+		log.Error().Err(err).Msg("put key file failed")
+		return err
 	}
+	sh.keys[KeyShareID(stat.PubKey, stat.KeyEpoch)] = stat
 	return nil
 }
 
-func (sh *ShamirMgr) GetKeyFile(pubKey, localPartyKey string) (KeygenLocalState, error) {
-	value, ok := sh.keys[pubKey]
+func (sh *ShamirMgr) GetKeyFile(pubKey string, keyEpoch int, localPartyKey string) (KeygenLocalState, error) {
+	id := KeyShareID(pubKey, keyEpoch)
+	value, ok := sh.keys[id]
 	if !ok {
-		log.Warn().Msgf("can not find keygenlocalstate from memory storage by this pubKey (%s),need to get from aws", pubKey)
-		keygen, err := sh.GetDecrypt(pubKey, localPartyKey)
+		log.Warn().Msgf("can not find keygenlocalstate from memory storage by this pubKey (%s) and key epoch (%d),need to get from share locations", pubKey, keyEpoch)
+		keygen, err := sh.GetDecrypt(id, localPartyKey)
 		if err != nil {
-			log.Error().Err(err).Msg("failed to get keygen local state from aws")
+			log.Error().Err(err).Msg("failed to get keygen local state from share locations")
 			return KeygenLocalState{}, err
 		}
 		var keygenlocalstate KeygenLocalState
 		if err := json.Unmarshal([]byte(keygen), &keygenlocalstate); err != nil {
-			log.Error().Err(err).Msgf("fail to unmarshal data to map :%w", err)
+			log.Error().Err(err).Msgf("fail to unmarshal data to map :%v", err)
 			return KeygenLocalState{}, err
 		}
 		//缓存在内存中
-		sh.keys[pubKey] = keygenlocalstate
+		sh.keys[id] = keygenlocalstate
 
 		return keygenlocalstate, nil
 	}
 	return value, nil
 }
 
+// DeleteKeyFile deletes the shares of the key share of pubKey at keyEpoch
+// from all the share locations
+func (sh *ShamirMgr) DeleteKeyFile(pubKey string, keyEpoch int, localPartyKey string) error {
+	id := KeyShareID(pubKey, keyEpoch)
+	delete(sh.keys, id)
+	for i := 0; i < sh.n; i++ {
+		for _, store := range sh.stores {
+			if err := store.DeleteShare(id, localPartyKey, i); err != nil {
+				return fmt.Errorf("fail to delete share %d from %s: %w", i, store, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSharePlacement fails when a location would hold k shares. The same
+// location listed twice counts once.
+func (sh *ShamirMgr) checkSharePlacement() error {
+	var counts = map[string]int{}
+	for i := 0; i < sh.n; i++ {
+		counts[sh.stores[i%len(sh.stores)].String()]++
+	}
+	for location, count := range counts {
+		if count >= sh.k {
+			return fmt.Errorf("share location %s would hold %d of %d shares, reaching the threshold %d", location, count, sh.n, sh.k)
+		}
+	}
+	return nil
+}
+
 func (sh *ShamirMgr) SaveEncrypt(stat KeygenLocalState) error {
-	log.Info().Msg("start to save keygen to aws")
+	log.Info().Msg("start to save keygen to share locations")
+	if err := sh.checkSharePlacement(); err != nil {
+		return err
+	}
 	bytes, err := json.Marshal(stat)
 	if err != nil {
 		log.Error().Err(err).Msg("keygen localstate json marshal failed ")
 		return err
 	}
 	//1-通过Shamir密钥分片算法将私钥分成k/n份
-	log.Info().Msg("1-start to use shamir to split keygen")
-	shares, err := sssas.Create(k, n, string(bytes))
+	log.Info().Msgf("1-start to use shamir to split keygen into %d shares, threshold %d", sh.n, sh.k)
+	shares, err := sssas.Create(sh.k, sh.n, string(bytes))
 	if err != nil {
 		log.Error().Err(err).Msg("shamir create failed! ")
 		return err
 	}
 
-	//2-将分片好的私钥进行异或，异或的key可以随机生成
-	log.Info().Msg("2-start to xor the shares.")
-	var shares_xor []string
-	if len(sh.shamirConfig.Xor) == 0 {
-		log.Error().Msg("Shamir.Xor value is empty ,please set it! ")
-		return errors.New("Shamir.Xor value is empty ,please set it! ")
+	//2-生成aes密文秘钥
+	log.Info().Msg("2-start to generate data key")
+	dataKey := make([]byte, dataKeyLen)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("fail to generate data key: %w", err)
 	}
-	for i := 0; i < n; i++ {
-		xor := ByXOR(shares[i], sh.shamirConfig.Xor)
-		shares_xor = append(shares_xor, xor)
-	}
-	//3-生成aes密文秘钥
-	log.Info().Msg("3-start to generate data key")
-	dataKey, err := sh.GenerateDataKey()
+	dexCipher, err := sh.kek.WrapKey(dataKey)
 	if err != nil {
+		log.Error().Err(err).Msg("fail to wrap data key")
 		return err
 	}
 
-	//4-将异或后的分片私钥通过aes加密
-	log.Info().Msg("4-start to encrypt shares")
-	var shares_xor_encrypted [][]byte
-	for i := 0; i < n; i++ {
-		cipher, err := AesEncrypt([]byte(shares_xor[i]), dataKey.Plaintext)
+	//3-将异或后的分片私钥通过aes加密，按顺序存入各个位置
+	log.Info().Msg("3-start to encrypt and store shares")
+	for i := 0; i < sh.n; i++ {
+		nonce, cipher, err := aesGCMSeal(dataKey, []byte(ByXOR(shares[i], sh.shamirConfig.Xor)), nil)
 		if err != nil {
 			return err
 		}
 		var share = Share{
-			DexCipher:   dataKey.CiphertextBlob,
+			DexCipher:   dexCipher,
 			ShareCipher: cipher,
+			Nonce:       nonce,
+			Provider:    sh.kek.Name(),
 		}
 		share_bytes, err := json.Marshal(share)
 		if err != nil {
 			log.Error().Err(err).Msg("share struct json marshal failed ")
 			return err
 		}
-		shares_xor_encrypted = append(shares_xor_encrypted, share_bytes)
+		store := sh.stores[i%len(sh.stores)]
+		if err := store.PutShare(KeyShareID(stat.PubKey, stat.KeyEpoch), stat.LocalPartyKey, i, share_bytes); err != nil {
+			log.Error().Err(err).Msgf("fail to put share %d to %s", i, store)
+			return err
+		}
 	}
+	return nil
+}
 
-	//6-将加密的分片私钥密文存入S3和SM，分片索引值奇数存S3,偶数存SM。
-
-	var shares_s3 [][]byte
-	var shares_sm [][]byte
-
-	var buckets = strings.Split(sh.shamirConfig.S3.Buckets, ",")
-	var secrets = strings.Split(sh.shamirConfig.Sm.SecretIds, ",")
-	sort.Strings(buckets)
-	sort.Strings(secrets)
-	if len(buckets) == 0 && len(secrets) == 0 {
-		log.Error().Msg("S3 config doesn't have buckets value,Sm config doesn't have secrets value! ")
-		return errors.New("S3 config doesn't have buckets value,Sm config doesn't have secrets value! ")
-	} else if len(buckets) == 0 {
-		shares_sm = shares_xor_encrypted
-	} else if len(secrets) == 0 {
-		shares_s3 = shares_xor_encrypted
-	} else {
-		for i := 0; i < n; i++ {
-			if i%2 == 0 {
-				shares_s3 = append(shares_s3, shares_xor_encrypted[i])
+// GetDecrypt combines the first k shares of the key share pubKey, a
+// KeyShareID, found in the share locations. The
+// locations are searched for every share index, so that the shares are
+// found whatever location they were written to.
+func (sh *ShamirMgr) GetDecrypt(pubKey, localPartyKey string) (string, error) {
+	type shareLocation struct {
+		store ShareStore
+		index int
+	}
+	// The locations the shares are written to come first
+	var locations, others []shareLocation
+	for i := 0; i < sh.n; i++ {
+		for j, store := range sh.stores {
+			if j == i%len(sh.stores) {
+				locations = append(locations, shareLocation{store, i})
 			} else {
-				shares_sm = append(shares_sm, shares_xor_encrypted[i])
+				others = append(others, shareLocation{store, i})
 			}
 		}
 	}
+	locations = append(locations, others...)
 
-	if shares_s3_len := len(shares_s3); shares_s3_len > 0 {
-		buckets_len := len(buckets)
-		log.Info().Msg("6-start to new s3 uploader ")
-		s3_uploader, err := sh.NewS3Uploader()
-		if err != nil {
-			return err
+	//1-从各个位置获取分片私钥密文，AES解密后异或还原
+	var shares_recover []string
+	var seen = map[string]bool{}
+	var plainDex = map[string][]byte{}
+	for _, location := range locations {
+		if len(shares_recover) == sh.k {
+			break
 		}
-		log.Info().Msg("6-start to uploader ")
-
-		for i := 0; i < shares_s3_len; i++ {
-			var filename = stat.PubKey + ":" + stat.LocalPartyKey + ":" + strconv.Itoa(i)
-			err = uploadToS3(buckets[i%buckets_len], filename, shares_s3[i], *s3_uploader)
-			if err != nil {
-				return err
+		value, err := location.store.GetShare(pubKey, localPartyKey, location.index)
+		if err != nil {
+			if !errors.Is(err, ErrShareNotFound) {
+				log.Warn().Err(err).Msgf("fail to get share %d from %s", location.index, location.store)
 			}
+			continue
 		}
-	}
-
-	if shares_sm_len := len(shares_sm); shares_sm_len > 0 {
-		secrets_len := len(secrets)
-		log.Info().Msg("6-start to new s3 secrets manager ")
-
-		svc, err := sh.NewSecretsManager()
+		recovered, err := sh.decryptShare(value, plainDex)
 		if err != nil {
-			return err
+			log.Warn().Err(err).Msgf("fail to decrypt share %d from %s", location.index, location.store)
+			continue
 		}
-		log.Info().Msg("6-start to put value to sm ")
-
-		for i := 0; i < shares_sm_len; i++ {
-			var key = stat.PubKey + ":" + strconv.Itoa(i)
-			err := putSecretValue(secrets[i%secrets_len], key, shares_sm[i], *svc)
-			if err != nil {
-				return err
-			}
+		// The same share can be found in several locations
+		if !sssas.IsValidShare(recovered) || seen[recovered[:44]] {
+			continue
 		}
+		seen[recovered[:44]] = true
+		shares_recover = append(shares_recover, recovered)
+	}
+	if len(shares_recover) < sh.k {
+		return "", errors.New("shares number " + strconv.Itoa(len(shares_recover)) + " is smaller than shamir threshold " + strconv.Itoa(sh.k))
 	}
-	return nil
-}
 
-func (sh *ShamirMgr) GetDecrypt(pubKey, localPartyKey string) (string, error) {
-	var buckets = strings.Split(sh.shamirConfig.S3.Buckets, ",")
-	var secrets = strings.Split(sh.shamirConfig.Sm.SecretIds, ",")
-	sort.Strings(buckets)
-	sort.Strings(secrets)
-	var sm_len, s3_len int
-	if len(buckets) == 0 && len(secrets) == 0 {
-		log.Error().Msg("S3 config doesn't have buckets value,Sm config doesn't have secrets value! ")
-		return "", errors.New("S3 config doesn't have buckets value,Sm config doesn't have secrets value! ")
-	} else if len(buckets) == 0 {
-		sm_len = n
-	} else if len(secrets) == 0 {
-		s3_len = n
-	} else {
-		s3_len = n/2 + n%2
-		sm_len = n / 2
+	//2-将k个分片私钥通过Shamir算法恢复成原有私钥
+	privateKey_recover, err := sssas.Combine(shares_recover)
+	if err != nil {
+		log.Error().Err(err).Msg("shamir combine failed!")
+		return "", err
 	}
+	return privateKey_recover, nil
+}
 
-	//1-从SM、S3获取分片私钥密文
-	var shares_xor_encrypted [][]byte
-	if s3_len > 0 {
-		buckets_len := len(buckets)
-		s3_downloader, err := sh.NewS3Downloader()
-		if err != nil {
-			return "", err
-		}
-		for i := 0; i < s3_len; i++ {
-			var filename = pubKey + ":" + localPartyKey + ":" + strconv.Itoa(i)
-			cipher, _ := getFromS3(buckets[i%buckets_len], filename, *s3_downloader)
-			if cipher != nil {
-				shares_xor_encrypted = append(shares_xor_encrypted, cipher)
-			}
-		}
+// decryptShare returns the Shamir share encrypted in value. The data keys
+// are unwrapped once for all the shares of a local state.
+func (sh *ShamirMgr) decryptShare(value []byte, plainDex map[string][]byte) (string, error) {
+	var share Share
+	if err := json.Unmarshal(value, &share); err != nil {
+		return "", err
 	}
-	if sm_len > 0 {
-		secrets_len := len(secrets)
-		svc, err := sh.NewSecretsManager()
-		if err != nil {
-			return "", err
-		}
-		for i := 0; i < sm_len; i++ {
-			var key = pubKey + ":" + strconv.Itoa(i)
-			value, _ := getSecretValue(secrets[i%secrets_len], key, *svc)
-			if value != nil {
-				shares_xor_encrypted = append(shares_xor_encrypted, value)
-			}
-		}
+	kek := sh.kek
+	if share.Provider == "" {
+		kek = &kmsProvider{config: sh.shamirConfig.Kms}
 	}
-	if len(shares_xor_encrypted) < k {
-		return "", errors.New("shares number " + strconv.Itoa(len(shares_xor_encrypted)) + " is smaller than shamir threshold " + strconv.Itoa(k))
+	if share.Provider != "" && share.Provider != kek.Name() {
+		return "", fmt.Errorf("share was encrypted by provider %s, configured provider is %s", share.Provider, kek.Name())
 	}
-
-	//2-将分片私钥通过AES解密
-	var shares_xor []string
-	var plainDex []byte
-	for i := 0; i < len(shares_xor_encrypted); i++ {
-		var share Share
-		if err := json.Unmarshal(shares_xor_encrypted[i], &share); err != nil {
-			log.Error().Err(err).Msgf("shamir json unmarshal share index %d struct failed", i)
-			return "", err
-		}
-
-		//2-1 获取dex密文秘钥，通过kms解密得到dex明文
-		if plainDex == nil {
-			result, err := sh.kmsDecrypt(share.DexCipher)
-			if err != nil {
-				return "", err
-			}
-			plainDex = result
-		}
-		//2-2 通过Aes解密
-		shareCipher, err := AesDecrypt(share.ShareCipher, plainDex)
-		if err != nil {
+	dataKey, ok := plainDex[string(share.DexCipher)]
+	if !ok {
+		var err error
+		if dataKey, err = kek.UnwrapKey(share.DexCipher); err != nil {
 			return "", err
 		}
-		shares_xor = append(shares_xor, string(shareCipher))
+		plainDex[string(share.DexCipher)] = dataKey
 	}
-
-	//3-将解密后的分片私钥进行异或，异或的key与分片过程中使用的key一致
-	var shares_recover []string
-	for i := 0; i < n; i++ {
-		xor := ByXOR(shares_xor[i], sh.shamirConfig.Xor)
-		shares_recover = append(shares_recover, xor)
+	var shareXor []byte
+	var err error
+	if len(share.Nonce) == 0 {
+		shareXor, err = AesDecrypt(share.ShareCipher, dataKey)
+	} else {
+		shareXor, err = aesGCMOpen(dataKey, share.Nonce, share.ShareCipher, nil)
 	}
-
-	//4-将解密后的N个分片私钥通过Shamir算法恢复成原有私钥
-	privateKey_recover, err := sssas.Combine(shares_recover)
 	if err != nil {
-		log.Error().Err(err).Msg("shamir combine failed!")
 		return "", err
 	}
-	return privateKey_recover, nil
+	return ByXOR(string(shareXor), sh.shamirConfig.Xor), nil
 }
 
 func ByXOR(message, keywords string) string {
+	if len(keywords) == 0 {
+		return message
+	}
 	messageLen := len(message)
 	keywordsLen := len(keywords)
 	result := ""
@@ -315,8 +340,17 @@ func ByXOR(message, keywords string) string {
 	return result
 }
 
-func (sh *ShamirMgr) kmsEncrypt(plaintext []byte) ([]byte, error) {
-	sess, err := NewSession(sh.shamirConfig.Kms.Region, sh.shamirConfig.Kms.Aksk.Id, sh.shamirConfig.Kms.Aksk.Secret)
+// kmsProvider wraps the data keys with an AWS KMS key
+type kmsProvider struct {
+	config nodeconfig.KmsConfig
+}
+
+func (p *kmsProvider) Name() string {
+	return "kms"
+}
+
+func (p *kmsProvider) WrapKey(plaintext []byte) ([]byte, error) {
+	sess, err := NewSession(p.config.Region, p.config.Aksk.Id, p.config.Aksk.Secret)
 	if err != nil {
 		return nil, err
 	}
@@ -325,7 +359,7 @@ func (sh *ShamirMgr) kmsEncrypt(plaintext []byte) ([]byte, error) {
 
 	// Encrypt the data
 	result, err := svc.Encrypt(&kms.EncryptInput{
-		KeyId:     aws.String(sh.shamirConfig.Kms.KeyId),
+		KeyId:     aws.String(p.config.KeyId),
 		Plaintext: plaintext,
 	})
 
@@ -337,8 +371,8 @@ func (sh *ShamirMgr) kmsEncrypt(plaintext []byte) ([]byte, error) {
 	return result.CiphertextBlob, nil
 }
 
-func (sh *ShamirMgr) kmsDecrypt(ciphertext []byte) ([]byte, error) {
-	sess, err := NewSession(sh.shamirConfig.Kms.Region, sh.shamirConfig.Kms.Aksk.Id, sh.shamirConfig.Kms.Aksk.Secret)
+func (p *kmsProvider) UnwrapKey(ciphertext []byte) ([]byte, error) {
+	sess, err := NewSession(p.config.Region, p.config.Aksk.Id, p.config.Aksk.Secret)
 	if err != nil {
 		return nil, err
 	}
@@ -347,7 +381,7 @@ func (sh *ShamirMgr) kmsDecrypt(ciphertext []byte) ([]byte, error) {
 	// Decrypt the data
 
 	result, err := svc.Decrypt(&kms.DecryptInput{
-		KeyId:          aws.String(sh.shamirConfig.Kms.KeyId),
+		KeyId:          aws.String(p.config.KeyId),
 		CiphertextBlob: ciphertext,
 	})
 	if err != nil {
@@ -357,15 +391,6 @@ func (sh *ShamirMgr) kmsDecrypt(ciphertext []byte) ([]byte, error) {
 	return result.Plaintext, nil
 }
 
-func (sh *ShamirMgr) NewSecretsManager() (*secretsmanager.SecretsManager, error) {
-	sess, err := NewSession(sh.shamirConfig.Sm.Region, sh.shamirConfig.Sm.Aksk.Id, sh.shamirConfig.Sm.Aksk.Secret)
-	if err != nil {
-		return nil, err
-	}
-	svc := secretsmanager.New(sess)
-	return svc, nil
-}
-
 func putSecretValue(secretId, key string, value []byte, svc secretsmanager.SecretsManager) error {
 
 	uuid, err := createUUID(key)
@@ -409,26 +434,6 @@ func getSecretValue(secretId, key string, svc secretsmanager.SecretsManager) ([]
 	return result.SecretBinary, nil
 }
 
-func (sh *ShamirMgr) NewS3Downloader() (*s3manager.Downloader, error) {
-	sess, err := NewSession(sh.shamirConfig.S3.Region, sh.shamirConfig.S3.Aksk.Id, sh.shamirConfig.S3.Aksk.Secret)
-	if err != nil {
-		return nil, err
-	}
-
-	downloader := s3manager.NewDownloader(sess)
-	return downloader, nil
-}
-
-func (sh *ShamirMgr) NewS3Uploader() (*s3manager.Uploader, error) {
-	sess, err := NewSession(sh.shamirConfig.S3.Region, sh.shamirConfig.S3.Aksk.Id, sh.shamirConfig.S3.Aksk.Secret)
-	if err != nil {
-		return nil, err
-	}
-
-	uploader := s3manager.NewUploader(sess)
-	return uploader, nil
-}
-
 func uploadToS3(bucket, filename string, file []byte, uploader s3manager.Uploader) error {
 
 	_, err := uploader.Upload(&s3manager.UploadInput{
@@ -458,7 +463,7 @@ func getFromS3(bucket, filename string, downloader s3manager.Downloader) ([]byte
 		log.Error().Err(err).Msgf("Ubable to download file %q, %v", filename, err)
 		return nil, err
 	}
-	log.Info().Msgf("Downloaded", filename, numBytes, "bytes")
+	log.Info().Msgf("Downloaded %s %d bytes", filename, numBytes)
 	return file.Bytes(), nil
 
 }
@@ -476,24 +481,6 @@ func (sh *ShamirMgr) GetOneLocalState() *bkeygen.LocalPreParams {
 	return preParams
 }
 
-func (sh *ShamirMgr) GenerateDataKey() (*kms.GenerateDataKeyOutput, error) {
-	sess, err := NewSession(sh.shamirConfig.Kms.Region, sh.shamirConfig.Kms.Aksk.Id, sh.shamirConfig.Kms.Aksk.Secret)
-	if err != nil {
-		return nil, err
-	}
-	// Create KMS service client
-	svc := kms.New(sess)
-	result, err := svc.GenerateDataKey(&kms.GenerateDataKeyInput{
-		KeyId:   aws.String(sh.shamirConfig.Kms.KeyId),
-		KeySpec: aws.String("AES_256"),
-	})
-	if err != nil {
-		log.Error().Err(err).Msg("fail to generate data key")
-		return nil, err
-	}
-	return result, nil
-}
-
 func AesEncrypt(plaintext, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
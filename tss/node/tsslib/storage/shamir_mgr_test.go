@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nodeconfig "github.com/mantlenetworkio/mantle/tss/common"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShamirConfig(dirs []string) nodeconfig.ShamirConfig {
+	return nodeconfig.ShamirConfig{
+		Enable:     true,
+		N:          5,
+		K:          3,
+		LocalDirs:  strings.Join(dirs, ","),
+		Passphrase: "passphrase",
+		Xor:        "xor",
+	}
+}
+
+func dirLocations(dirs ...string) string {
+	var locations []string
+	for _, dir := range dirs {
+		locations = append(locations, "dir:"+dir)
+	}
+	return strings.Join(locations, ",")
+}
+
+func TestShamirRecover(t *testing.T) {
+	var dirs []string
+	for i := 0; i < 5; i++ {
+		dirs = append(dirs, t.TempDir())
+	}
+	config := newTestShamirConfig(dirs)
+	sh, err := NewShamirMgr(config)
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, sh.PutKeyFile(state))
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.share"))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(files))
+	}
+
+	// Any k locations rebuild the local state
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(dirs[4], dirs[1], dirs[2]))
+	require.NoError(t, err)
+	recovered, err := sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.NoError(t, err)
+	require.Equal(t, state.ParticipantKeys, recovered.ParticipantKeys)
+	require.Equal(t, state.Threshold, recovered.Threshold)
+
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(dirs[0], dirs[3]))
+	require.NoError(t, err)
+	_, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.EqualError(t, err, "shares number 2 is smaller than shamir threshold 3")
+
+	// The shares are found whatever location they were moved to
+	merged := t.TempDir()
+	for _, dir := range dirs[:3] {
+		files, err := filepath.Glob(filepath.Join(dir, "*.share"))
+		require.NoError(t, err)
+		buf, err := ioutil.ReadFile(files[0])
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(merged, filepath.Base(files[0])), buf, 0o600))
+	}
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(merged, merged))
+	require.NoError(t, err)
+	_, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.NoError(t, err)
+
+	// Corrupted shares and shares of another passphrase are skipped
+	files, err := filepath.Glob(filepath.Join(dirs[4], "*.share"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(files[0], []byte("{}"), 0o600))
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(dirs[4], dirs[1], dirs[2]))
+	require.NoError(t, err)
+	_, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.EqualError(t, err, "shares number 2 is smaller than shamir threshold 3")
+
+	config.Passphrase = "wrong"
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(dirs[0], dirs[1], dirs[2]))
+	require.NoError(t, err)
+	_, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.EqualError(t, err, "shares number 0 is smaller than shamir threshold 3")
+}
+
+func TestShamirKeyEpochs(t *testing.T) {
+	var dirs []string
+	for i := 0; i < 5; i++ {
+		dirs = append(dirs, t.TempDir())
+	}
+	sh, err := NewShamirMgr(newTestShamirConfig(dirs))
+	require.NoError(t, err)
+	state := newTestLocalState(t)
+	require.NoError(t, sh.PutKeyFile(state))
+
+	// A key share written again replaces the cached one
+	state.Threshold = 1
+	require.NoError(t, sh.PutKeyFile(state))
+	loaded, err := sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, loaded.Threshold)
+
+	// The key share of a resharing is kept apart from the one in use
+	reshared := state
+	reshared.KeyEpoch = 1
+	reshared.ParticipantKeys = []string{"b", "c", "d"}
+	require.NoError(t, sh.PutKeyFile(reshared))
+	sh, err = NewShamirMgr(newTestShamirConfig(dirs))
+	require.NoError(t, err)
+	loaded, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.NoError(t, err)
+	require.Equal(t, state.ParticipantKeys, loaded.ParticipantKeys)
+	loaded, err = sh.GetKeyFile(state.PubKey, 1, state.LocalPartyKey)
+	require.NoError(t, err)
+	require.Equal(t, reshared.ParticipantKeys, loaded.ParticipantKeys)
+
+	require.NoError(t, sh.DeleteKeyFile(state.PubKey, 0, state.LocalPartyKey))
+	_, err = sh.GetKeyFile(state.PubKey, 0, state.LocalPartyKey)
+	require.EqualError(t, err, "shares number 0 is smaller than shamir threshold 3")
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.share"))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(files))
+	}
+}
+
+func TestShamirConfig(t *testing.T) {
+	dir := t.TempDir()
+	config := newTestShamirConfig([]string{dir})
+	config.K = 6
+	_, err := NewShamirMgr(config)
+	require.EqualError(t, err, "invalid shamir threshold 6 of 5 shares")
+
+	config = newTestShamirConfig(nil)
+	_, err = NewShamirMgr(config)
+	require.EqualError(t, err, "no share location is configured")
+
+	_, err = NewShamirMgrWithLocations(config, "ftp:host")
+	require.EqualError(t, err, `unknown kind of share location "ftp:host"`)
+
+	// The threshold defaults to 4 of 5 shares
+	config = newTestShamirConfig([]string{dir, t.TempDir()})
+	config.N, config.K = 0, 0
+	sh, err := NewShamirMgr(config)
+	require.NoError(t, err)
+	require.Equal(t, 5, sh.n)
+	require.Equal(t, 4, sh.k)
+
+	config.Passphrase = ""
+	_, err = NewShamirMgr(config)
+	require.EqualError(t, err, "shamir needs a kms key id or a passphrase")
+
+	// No location may hold enough shares to rebuild the local state
+	config = newTestShamirConfig([]string{dir})
+	_, err = NewShamirMgr(config)
+	require.EqualError(t, err, "share location dir:"+dir+" would hold 5 of 5 shares, reaching the threshold 3")
+	other := t.TempDir()
+	config = newTestShamirConfig([]string{dir, other, dir})
+	_, err = NewShamirMgr(config)
+	require.EqualError(t, err, "share location dir:"+dir+" would hold 3 of 5 shares, reaching the threshold 3")
+	config = newTestShamirConfig([]string{dir, other, t.TempDir()})
+	_, err = NewShamirMgr(config)
+	require.NoError(t, err)
+
+	// Recovering managers read from any locations but do not write to them
+	sh, err = NewShamirMgrWithLocations(config, dirLocations(dir))
+	require.NoError(t, err)
+	require.EqualError(t, sh.PutKeyFile(newTestLocalState(t)), "share location dir:"+dir+" would hold 5 of 5 shares, reaching the threshold 3")
+}
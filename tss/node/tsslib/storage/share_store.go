@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	nodeconfig "github.com/mantlenetworkio/mantle/tss/common"
+)
+
+var ErrShareNotFound = errors.New("share not found")
+
+// ShareStore is a location keeping Shamir shares of the local key shares.
+// The shares of one local state are spread over several stores, so that
+// losing some of them does not lose the local state.
+type ShareStore interface {
+	String() string
+	PutShare(pubKey, localPartyKey string, index int, share []byte) error
+	// GetShare returns ErrShareNotFound when the store has no such share
+	GetShare(pubKey, localPartyKey string, index int) ([]byte, error)
+	// DeleteShare succeeds when the store has no such share
+	DeleteShare(pubKey, localPartyKey string, index int) error
+}
+
+// LocalShareStore keeps the shares in a directory, which can be a mounted
+// removable or network drive
+type LocalShareStore struct {
+	dir string
+}
+
+func NewLocalShareStore(dir string) (*LocalShareStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("fail to create share directory(%s): %w", dir, err)
+	}
+	return &LocalShareStore{dir: dir}, nil
+}
+
+func (l *LocalShareStore) String() string {
+	return "dir:" + l.dir
+}
+
+func (l *LocalShareStore) fileName(pubKey, localPartyKey string, index int) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%s-%s-%d.share", pubKey, localPartyKey, index))
+}
+
+func (l *LocalShareStore) PutShare(pubKey, localPartyKey string, index int, share []byte) error {
+	return writeFileAtomic(l.fileName(pubKey, localPartyKey, index), share)
+}
+
+func (l *LocalShareStore) GetShare(pubKey, localPartyKey string, index int) ([]byte, error) {
+	share, err := ioutil.ReadFile(l.fileName(pubKey, localPartyKey, index))
+	if os.IsNotExist(err) {
+		return nil, ErrShareNotFound
+	}
+	return share, err
+}
+
+func (l *LocalShareStore) DeleteShare(pubKey, localPartyKey string, index int) error {
+	if err := os.Remove(l.fileName(pubKey, localPartyKey, index)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// S3ShareStore keeps the shares in a bucket of AWS S3 or of any S3
+// compatible service, such as MinIO
+type S3ShareStore struct {
+	bucket     string
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+}
+
+func NewS3ShareStore(bucket string, config nodeconfig.S3Config) (*S3ShareStore, error) {
+	sess, err := NewSession(config.Region, config.Aksk.Id, config.Aksk.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if config.Endpoint != "" {
+		sess.Config.Endpoint = aws.String(config.Endpoint)
+		sess.Config.S3ForcePathStyle = aws.Bool(config.ForcePathStyle)
+	}
+	return &S3ShareStore{
+		bucket:     bucket,
+		uploader:   s3manager.NewUploader(sess),
+		downloader: s3manager.NewDownloader(sess),
+	}, nil
+}
+
+func (s *S3ShareStore) String() string {
+	return "s3:" + s.bucket
+}
+
+func (s *S3ShareStore) PutShare(pubKey, localPartyKey string, index int, share []byte) error {
+	var filename = pubKey + ":" + localPartyKey + ":" + strconv.Itoa(index)
+	return uploadToS3(s.bucket, filename, share, *s.uploader)
+}
+
+func (s *S3ShareStore) GetShare(pubKey, localPartyKey string, index int) ([]byte, error) {
+	var filename = pubKey + ":" + localPartyKey + ":" + strconv.Itoa(index)
+	share, err := getFromS3(s.bucket, filename, *s.downloader)
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrShareNotFound
+	}
+	return share, err
+}
+
+func (s *S3ShareStore) DeleteShare(pubKey, localPartyKey string, index int) error {
+	var filename = pubKey + ":" + localPartyKey + ":" + strconv.Itoa(index)
+	_, err := s.uploader.S3.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(filename),
+	})
+	return err
+}
+
+// SecretsManagerShareStore keeps the shares as versions of an AWS Secrets
+// Manager secret
+type SecretsManagerShareStore struct {
+	secretId string
+	svc      *secretsmanager.SecretsManager
+}
+
+func NewSecretsManagerShareStore(secretId string, config nodeconfig.SMConfig) (*SecretsManagerShareStore, error) {
+	sess, err := NewSession(config.Region, config.Aksk.Id, config.Aksk.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretsManagerShareStore{
+		secretId: secretId,
+		svc:      secretsmanager.New(sess),
+	}, nil
+}
+
+func (s *SecretsManagerShareStore) String() string {
+	return "sm:" + s.secretId
+}
+
+func (s *SecretsManagerShareStore) PutShare(pubKey, _ string, index int, share []byte) error {
+	var key = pubKey + ":" + strconv.Itoa(index)
+	return putSecretValue(s.secretId, key, share, *s.svc)
+}
+
+func (s *SecretsManagerShareStore) GetShare(pubKey, _ string, index int) ([]byte, error) {
+	var key = pubKey + ":" + strconv.Itoa(index)
+	share, err := getSecretValue(s.secretId, key, *s.svc)
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return nil, ErrShareNotFound
+	}
+	return share, err
+}
+
+// DeleteShare leaves the share in place, as the versions of a secret cannot
+// be deleted one by one. Secrets Manager deletes the unlabeled versions
+// past its version limit.
+func (s *SecretsManagerShareStore) DeleteShare(_, _ string, _ int) error {
+	return nil
+}
+
+// NewShareStores returns the stores of the locations, which are comma
+// separated and prefixed by their kind: dir:<path>, s3:<bucket> or
+// sm:<secret id>. The locations default to the S3 buckets, secrets and
+// directories of config.
+func NewShareStores(config nodeconfig.ShamirConfig, locations string) ([]ShareStore, error) {
+	if locations == "" {
+		locations = shamirConfigLocations(config)
+	}
+	var stores []ShareStore
+	for _, location := range strings.Split(locations, ",") {
+		location = strings.TrimSpace(location)
+		if location == "" {
+			continue
+		}
+		kind, name, ok := strings.Cut(location, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid share location %q", location)
+		}
+		var store ShareStore
+		var err error
+		switch kind {
+		case "dir":
+			store, err = NewLocalShareStore(name)
+		case "s3":
+			store, err = NewS3ShareStore(name, config.S3)
+		case "sm":
+			store, err = NewSecretsManagerShareStore(name, config.Sm)
+		default:
+			return nil, fmt.Errorf("unknown kind of share location %q", location)
+		}
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	if len(stores) == 0 {
+		return nil, errors.New("no share location is configured")
+	}
+	return stores, nil
+}
+
+func shamirConfigLocations(config nodeconfig.ShamirConfig) string {
+	var buf bytes.Buffer
+	add := func(kind string, names string) {
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				buf.WriteString(kind + ":" + name + ",")
+			}
+		}
+	}
+	add("s3", config.S3.Buckets)
+	add("sm", config.Sm.SecretIds)
+	add("dir", config.LocalDirs)
+	return strings.TrimSuffix(buf.String(), ",")
+}
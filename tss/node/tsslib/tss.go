@@ -15,6 +15,7 @@ import (
 	"github.com/mantlenetworkio/mantle/tss/node/tsslib/keysign"
 	"github.com/mantlenetworkio/mantle/tss/node/tsslib/monitor"
 	p2p2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/p2p"
+	"github.com/mantlenetworkio/mantle/tss/node/tsslib/resharing"
 	storage2 "github.com/mantlenetworkio/mantle/tss/node/tsslib/storage"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -31,6 +32,7 @@ type TssServer struct {
 	localNodePubKey  string
 	participants     map[string][]string
 	preParams        *bkeygen.LocalPreParams
+	preParamsPool    *storage2.PreParamsPool
 	tssKeyGenLocker  *sync.Mutex
 	stopChan         chan struct{}
 	stateManager     storage2.LocalStateManager
@@ -54,6 +56,7 @@ func NewTss(
 	secretsEnable bool,
 	secretId string,
 	shamirConfig tssconfig.ShamirConfig,
+	keystoreConfig tssconfig.KeystoreConfig,
 ) (*TssServer, error) {
 
 	pubkey := crypto.CompressPubkey(&priKey.PublicKey)
@@ -65,8 +68,13 @@ func NewTss(
 		log.Error().Err(err).Msg("ERROR: fail to get peer id by pub key")
 	}
 	log.Info().Msgf("peer id is (%s) \n", peerId)
-	stateManager, err := storage2.NewFileStateMgr(storageFolder)
+	kek, err := storage2.NewKeyEncryptionProvider(keystoreConfig)
 	if err != nil {
+		return nil, err
+	}
+	stateManager, err := storage2.NewFileStateMgr(storageFolder, kek)
+	if err != nil {
+		log.Error().Err(err).Msg("fail to create file state manager")
 		return nil, errors.New("fail to create file state manager")
 	}
 	var secretsManager *storage2.SecretsMgr
@@ -74,13 +82,13 @@ func NewTss(
 	if shamirConfig.Enable {
 		shamirManager, err = storage2.NewShamirMgr(shamirConfig)
 		if err != nil {
-			log.Error().Err(err).Msgf("fail to create shamir manager :%w", err)
+			log.Error().Err(err).Msgf("fail to create shamir manager :%v", err)
 			return nil, errors.New("fail to create shamir manager")
 		}
 	} else if secretsEnable {
 		secretsManager, err = storage2.NewSecretsMgr(secretId)
 		if err != nil {
-			log.Error().Err(err).Msgf("fail to create secrets manager :%w", err)
+			log.Error().Err(err).Msgf("fail to create secrets manager :%v", err)
 			return nil, errors.New("fail to create secrets manager")
 		}
 	}
@@ -109,12 +117,20 @@ func NewTss(
 	// to the number of available CPU cores.
 
 	// if there is no preParams file specified by user, and no default preParams file path exists, then generate a new preParams for user.
+	// With a pre params pool, every keygen takes its own preParams from the pool instead.
+	metrics := monitor.NewMetric()
 	var preParams *bkeygen.LocalPreParams
+	var preParamsPool *storage2.PreParamsPool
 	if len(preParamsFile) != 0 {
 		preParams, err = stateManager.GetLocalPreParams(preParamsFile)
 		if err != nil {
 			return nil, fmt.Errorf("fail to generate pre parameters: %w", err)
 		}
+	} else if conf.PreParamsPoolSize > 0 {
+		preParamsPool, err = storage2.NewPreParamsPool(storageFolder, conf.PreParamsPoolSize, conf.PreParamsPoolTimeout, kek, metrics)
+		if err != nil {
+			return nil, err
+		}
 	} else if shamirConfig.Enable {
 		preParams = shamirManager.GetOneLocalState()
 		if preParams == nil {
@@ -146,7 +162,7 @@ func NewTss(
 		}
 	}
 
-	if !preParams.Validate() {
+	if preParamsPool == nil && !preParams.Validate() {
 		return nil, errors.New("invalid preparams")
 	}
 
@@ -154,7 +170,6 @@ func NewTss(
 		return nil, fmt.Errorf("fail to start p2p network: %w", err)
 	}
 	//sn := keysign.NewSignatureNotifier(comm.GetHost())
-	metrics := monitor.NewMetric()
 	if conf.EnableMonitor {
 		metrics.Enable()
 	}
@@ -165,6 +180,7 @@ func NewTss(
 		localNodePubKey:  pubkeyHex,
 		participants:     make(map[string][]string),
 		preParams:        preParams,
+		preParamsPool:    preParamsPool,
 		tssKeyGenLocker:  &sync.Mutex{},
 		stopChan:         make(chan struct{}),
 		stateManager:     stateManager,
@@ -181,12 +197,18 @@ func NewTss(
 
 func (t *TssServer) Start() error {
 	log.Info().Msg("Starting the TSS servers")
+	if t.preParamsPool != nil {
+		t.preParamsPool.Start()
+	}
 	return nil
 }
 
 // Stop Tss server
 func (t *TssServer) Stop() {
 	close(t.stopChan)
+	if t.preParamsPool != nil {
+		t.preParamsPool.Stop()
+	}
 	// stop the p2p and finish the p2p wait group
 	err := t.p2pCommunication.Stop()
 	if err != nil {
@@ -208,6 +230,14 @@ func (t *TssServer) requestToMsgId(request interface{}) (string, error) {
 	case keysign.Request:
 		dat = value.Message
 		keys = value.SignerPubKeys
+	case resharing.Request:
+		dat = []byte(fmt.Sprintf("%s:%d", value.PubKey, value.KeyEpoch))
+		for _, key := range value.OldKeys {
+			keys = append(keys, "old:"+key)
+		}
+		for _, key := range value.NewKeys {
+			keys = append(keys, "new:"+key)
+		}
 	default:
 		t.logger.Error().Msg("unknown request type")
 		return "", errors.New("unknown request type")
@@ -230,6 +260,15 @@ func (t *TssServer) requestCheck(request interface{}) error {
 			t.logger.Error().Msg("check params : pub_keys size is smaller than threshold !")
 			return errors.New("check params : pub_keys size is smaller than threshold")
 		}
+	case resharing.Request:
+		if len(value.OldKeys) <= value.OldThreshold {
+			t.logger.Error().Msg("check params : old pub_keys size is smaller than old threshold !")
+			return errors.New("check params : old pub_keys size is smaller than old threshold")
+		}
+		if len(value.NewKeys) <= value.NewThreshold {
+			t.logger.Error().Msg("check params : new pub_keys size is smaller than new threshold !")
+			return errors.New("check params : new pub_keys size is smaller than new threshold")
+		}
 	case keysign.Request:
 		myPk, err := conversion.GetPubKeyFromPeerID(t.p2pCommunication.GetHost().ID().String())
 		if err != nil {
@@ -3,6 +3,11 @@ package client
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/mantlenetworkio/mantle/l2geth/log"
@@ -21,12 +26,14 @@ type WSClients struct {
 	Cli *tm.WSClient
 }
 
-func NewWSClient(remoteAddr, endpoint string, privKey *ecdsa.PrivateKey, pubkey string) (*WSClients, error) {
+// NewWSClient connects to the manager, with mutual TLS when tlsConfig is not nil.
+func NewWSClient(remoteAddr, endpoint string, privKey *ecdsa.PrivateKey, pubkey string, tlsConfig *tls.Config) (*WSClients, error) {
 	if client, err := tm.NewWS(remoteAddr, endpoint); err != nil {
 		return nil, err
 	} else {
 		client.PubKey = pubkey
 		client.PriKey = privKey
+		client.TLSConfig = tlsConfig
 
 		wsc := &WSClients{
 			Cli: client,
@@ -78,3 +85,25 @@ func (wsc *WSClients) rspListener() {
 		}
 	}
 }
+
+// LoadClientTLSConfig returns the TLS config of a node, presenting the
+// certificate of certFile and verifying the manager against the CA of caFile.
+func LoadClientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to load certificate: %w", err)
+	}
+	caPem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read CA file: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPem) {
+		return nil, errors.New("no certificate found in CA file")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      rootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
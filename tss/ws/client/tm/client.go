@@ -3,17 +3,19 @@ package tm
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gorilla/websocket"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
 	"github.com/rcrowley/go-metrics"
 	tmrand "github.com/tendermint/tendermint/libs/rand"
 	"github.com/tendermint/tendermint/libs/service"
@@ -26,6 +28,7 @@ const (
 	defaultWriteWait            = 0
 	defaultReadWait             = 0
 	defaultPingPeriod           = 0
+	defaultHandshakeWait        = 10 * time.Second
 )
 
 // WSClient is a JSON-RPC client, which uses WebSocket for communication with
@@ -39,9 +42,17 @@ type WSClient struct { // nolint: maligned
 	Endpoint string // /websocket/url/endpoint
 	Dialer   func(string, string) (net.Conn, error)
 
+	// the managers to fail over between, Address and Dialer are of the
+	// current one
+	remotes     []wsRemote
+	remoteIndex int
+
 	PubKey string
 	PriKey *ecdsa.PrivateKey
 
+	// TLSConfig turns on wss with the client certificate, nil for plain ws.
+	TLSConfig *tls.Config
+
 	// Single user facing channel to read RPCRequest from, closed only when the
 	// client is being stopped.
 	RequestsCh chan types.RPCRequest
@@ -85,14 +96,17 @@ type WSClient struct { // nolint: maligned
 	PingPongLatencyTimer metrics.Timer
 }
 
-// NewWS returns a new client. See the commentary on the func(*WSClient)
-// functions for a detailed description of how to configure ping period and
-// pong wait time. The endpoint argument must begin with a `/`.
-// An error is returned on invalid remote. The function panics when remote is nil.
-func NewWS(remoteAddr, endpoint string, options ...func(*WSClient)) (*WSClient, error) {
+// wsRemote is a manager the client may connect to.
+type wsRemote struct {
+	address  string
+	dialer   func(string, string) (net.Conn, error)
+	protocol string
+}
+
+func newWSRemote(remoteAddr string) (wsRemote, error) {
 	parsedURL, err := newParsedURL(remoteAddr)
 	if err != nil {
-		return nil, err
+		return wsRemote{}, err
 	}
 	// default to ws protocol, unless wss is explicitly specified
 	if parsedURL.Scheme != protoWSS {
@@ -101,20 +115,50 @@ func NewWS(remoteAddr, endpoint string, options ...func(*WSClient)) (*WSClient,
 
 	dialFn, err := makeHTTPDialer(remoteAddr)
 	if err != nil {
-		return nil, err
+		return wsRemote{}, err
+	}
+	return wsRemote{
+		address:  parsedURL.GetTrimmedHostWithPath(),
+		dialer:   dialFn,
+		protocol: parsedURL.Scheme,
+	}, nil
+}
+
+// NewWS returns a new client. See the commentary on the func(*WSClient)
+// functions for a detailed description of how to configure ping period and
+// pong wait time. The endpoint argument must begin with a `/`.
+// remoteAddr is a comma separated list of managers, the client connects to
+// the first one accepting it and fails over to the others on a reconnect.
+// An error is returned on invalid remote. The function panics when remote is nil.
+func NewWS(remoteAddr, endpoint string, options ...func(*WSClient)) (*WSClient, error) {
+	var remotes []wsRemote
+	for _, addr := range strings.Split(remoteAddr, ",") {
+		addr = strings.TrimSpace(addr)
+		if len(addr) == 0 {
+			continue
+		}
+		remote, err := newWSRemote(addr)
+		if err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, remote)
+	}
+	if len(remotes) == 0 {
+		return nil, fmt.Errorf("no remote address in %q", remoteAddr)
 	}
 
 	c := &WSClient{
-		Address:              parsedURL.GetTrimmedHostWithPath(),
-		Dialer:               dialFn,
+		Address:              remotes[0].address,
+		Dialer:               remotes[0].dialer,
 		Endpoint:             endpoint,
+		remotes:              remotes,
 		PingPongLatencyTimer: metrics.NewTimer(),
 
 		maxReconnectAttempts: defaultMaxReconnectAttempts,
 		readWait:             defaultReadWait,
 		writeWait:            defaultWriteWait,
 		pingPeriod:           defaultPingPeriod,
-		protocol:             parsedURL.Scheme,
+		protocol:             remotes[0].protocol,
 
 		// sentIDs: make(map[types.JSONRPCIntID]bool),
 	}
@@ -245,31 +289,83 @@ func (c *WSClient) nextRequestID() types.JSONRPCIntID {
 	return types.JSONRPCIntID(id)
 }
 
+// dial connects to the current manager, or to the next ones when it fails.
 func (c *WSClient) dial() error {
-	dialer := &websocket.Dialer{
-		NetDial: c.Dialer,
-		Proxy:   http.ProxyFromEnvironment,
+	var err error
+	for i := 0; i < len(c.remotes); i++ {
+		index := (c.remoteIndex + i) % len(c.remotes)
+		remote := c.remotes[index]
+		c.Address, c.Dialer, c.protocol = remote.address, remote.dialer, remote.protocol
+		if err = c.dialRemote(); err == nil {
+			if index != c.remoteIndex {
+				c.Logger.Info("failed over to another manager", "address", c.Address)
+				c.remoteIndex = index
+			}
+			return nil
+		}
+		if len(c.remotes) > 1 {
+			c.Logger.Error("failed to dial manager", "address", c.Address, "err", err)
+		}
 	}
-	rHeader := http.Header{}
-	timeStr := strconv.FormatInt(time.Now().Unix(), 10)
+	return err
+}
 
-	digestBz := crypto.Keccak256Hash([]byte(timeStr)).Bytes()
-	sig, err := crypto.Sign(digestBz, c.PriKey)
+func (c *WSClient) dialRemote() error {
+	dialer := &websocket.Dialer{
+		NetDial:         c.Dialer,
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: c.TLSConfig,
+	}
+	protocol := c.protocol
+	if c.TLSConfig != nil {
+		protocol = protoWSS
+	}
+	conn, _, err := dialer.Dial(protocol+"://"+c.Address+c.Endpoint, nil) // nolint:bodyclose
 	if err != nil {
 		return err
 	}
-
-	rHeader.Set("pubKey", c.PubKey)
-	rHeader.Set("sig", hex.EncodeToString(sig))
-	rHeader.Set("time", timeStr)
-	conn, _, err := dialer.Dial(c.protocol+"://"+c.Address+c.Endpoint, rHeader) // nolint:bodyclose
-	if err != nil {
+	if err := c.authenticate(conn); err != nil {
+		conn.Close()
 		return err
 	}
 	c.conn = conn
 	return nil
 }
 
+// authenticate answers the challenge of the manager with the signature of
+// the node key.
+func (c *WSClient) authenticate(conn *websocket.Conn) error {
+	deadline := time.Now().Add(defaultHandshakeWait)
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	var challenge tss.WsChallenge
+	if err := conn.ReadJSON(&challenge); err != nil {
+		return fmt.Errorf("failed to read challenge: %w", err)
+	}
+	sig, err := crypto.Sign(tss.WsChallengeDigest(challenge.Nonce), c.PriKey)
+	if err != nil {
+		return err
+	}
+	if err := conn.WriteJSON(tss.WsChallengeResponse{
+		PubKey:    c.PubKey,
+		Signature: hex.EncodeToString(sig),
+	}); err != nil {
+		return fmt.Errorf("failed to send challenge response: %w", err)
+	}
+	var result tss.WsAuthResult
+	if err := conn.ReadJSON(&result); err != nil {
+		return fmt.Errorf("failed to read auth result: %w", err)
+	}
+	if !result.Result {
+		return fmt.Errorf("rejected by the manager: %s", result.Reason)
+	}
+	return conn.SetReadDeadline(time.Time{})
+}
+
 // reconnect tries to redial up to maxReconnectAttempts with exponential
 // backoff.
 func (c *WSClient) reconnect() error {
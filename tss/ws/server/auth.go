@@ -0,0 +1,197 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/websocket"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
+)
+
+const defaultHandshakeWait = 10 * time.Second
+
+var (
+	errBadSignature = errors.New("bad signature")
+	errNotMember    = errors.New("not a tss member")
+)
+
+// MemberSource returns the public keys of the nodes allowed to connect, the
+// members of the current and the pending tss groups.
+type MemberSource func() ([]string, error)
+
+// SetMemberSource turns on the membership check of the connecting nodes. The
+// members are reloaded every interval until Stop, the sessions of the nodes
+// no longer members are closed. It replaces the reload of a former source.
+func (wm *WebsocketManager) SetMemberSource(source MemberSource, interval time.Duration) {
+	stop := make(chan struct{})
+	wm.memberLock.Lock()
+	if wm.memberStop != nil {
+		close(wm.memberStop)
+	}
+	wm.memberSource = source
+	wm.memberStop = stop
+	wm.memberLock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := wm.refreshMembers(); err != nil {
+					wm.logger.Error("failed to refresh tss members", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops reloading the members, the known members are still checked.
+func (wm *WebsocketManager) Stop() {
+	wm.memberLock.Lock()
+	defer wm.memberLock.Unlock()
+	if wm.memberStop != nil {
+		close(wm.memberStop)
+		wm.memberStop = nil
+	}
+}
+
+func (wm *WebsocketManager) refreshMembers() error {
+	wm.memberLock.Lock()
+	source := wm.memberSource
+	wm.memberLock.Unlock()
+
+	keys, err := source()
+	if err != nil {
+		return err
+	}
+	// an empty committee is rather a failed query, keep the known members
+	if len(keys) == 0 {
+		return errors.New("no tss members found")
+	}
+	members := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		members[strings.ToLower(key)] = struct{}{}
+	}
+	wm.memberLock.Lock()
+	wm.members = members
+	wm.memberLock.Unlock()
+
+	// reject the sessions of the removed members
+	removed := make([]*wsConnection, 0)
+	wm.scRWLock.RLock()
+	for pubKey, conn := range wm.conns {
+		if _, ok := members[strings.ToLower(pubKey)]; !ok {
+			removed = append(removed, conn)
+		}
+	}
+	wm.scRWLock.RUnlock()
+	for _, conn := range removed {
+		wm.logger.Info("close the session of the removed member", "public key", conn.nodePublicKey)
+		sessionRejectedCounter.Inc()
+		if err := conn.Stop(); err != nil {
+			wm.logger.Error("failed to stop connection", "public key", conn.nodePublicKey, "err", err)
+		}
+	}
+	return nil
+}
+
+func (wm *WebsocketManager) isMember(pubKey string) (bool, error) {
+	wm.memberLock.Lock()
+	source, loaded := wm.memberSource, wm.members != nil
+	wm.memberLock.Unlock()
+	if source == nil {
+		return true, nil
+	}
+	if !loaded {
+		if err := wm.refreshMembers(); err != nil {
+			return false, err
+		}
+	}
+	wm.memberLock.Lock()
+	defer wm.memberLock.Unlock()
+	_, ok := wm.members[strings.ToLower(pubKey)]
+	return ok, nil
+}
+
+// authenticate runs the challenge/response handshake on the new connection
+// and returns the public key of the node.
+func (wm *WebsocketManager) authenticate(wsConn *websocket.Conn) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	nonceHex := hex.EncodeToString(nonce)
+	deadline := time.Now().Add(defaultHandshakeWait)
+	if err := wsConn.SetWriteDeadline(deadline); err != nil {
+		return "", err
+	}
+	if err := wsConn.SetReadDeadline(deadline); err != nil {
+		return "", err
+	}
+	if err := wsConn.WriteJSON(tss.WsChallenge{Nonce: nonceHex}); err != nil {
+		return "", fmt.Errorf("fail to send challenge: %w", err)
+	}
+	var resp tss.WsChallengeResponse
+	if err := wsConn.ReadJSON(&resp); err != nil {
+		return "", fmt.Errorf("fail to read challenge response: %w", err)
+	}
+
+	pubKeyBytes, pubErr := hex.DecodeString(resp.PubKey)
+	sigBytes, sigErr := hex.DecodeString(resp.Signature)
+	if pubErr != nil || sigErr != nil || len(sigBytes) < 64 {
+		return "", errBadSignature
+	}
+	if !crypto.VerifySignature(pubKeyBytes, tss.WsChallengeDigest(nonceHex), sigBytes[:64]) {
+		return "", errBadSignature
+	}
+	member, err := wm.isMember(resp.PubKey)
+	if err != nil {
+		return "", fmt.Errorf("fail to check tss membership: %w", err)
+	}
+	if !member {
+		return "", errNotMember
+	}
+
+	if err := wsConn.WriteJSON(tss.WsAuthResult{Result: true}); err != nil {
+		return "", fmt.Errorf("fail to send auth result: %w", err)
+	}
+	// the connection manages its deadlines from now on
+	if err := wsConn.SetReadDeadline(time.Time{}); err != nil {
+		return "", err
+	}
+	return resp.PubKey, nil
+}
+
+// LoadServerTLSConfig returns the TLS config of the manager, which requires
+// the nodes to present a certificate signed by the CA of caFile.
+func LoadServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to load certificate: %w", err)
+	}
+	caPem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read CA file: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPem) {
+		return nil, errors.New("no certificate found in CA file")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mantlenetworkio/mantle/tss/ws/client/tm"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, urls ...string) *tm.WSClient {
+	priKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	remotes := make([]string, len(urls))
+	for i, url := range urls {
+		remotes[i] = "tcp://" + strings.TrimPrefix(url, "http://")
+	}
+	client, err := tm.NewWS(strings.Join(remotes, ","), "/ws", tm.MaxReconnectAttempts(0))
+	require.NoError(t, err)
+	client.PriKey = priKey
+	client.PubKey = hex.EncodeToString(crypto.CompressPubkey(&priKey.PublicKey))
+	return client
+}
+
+func newTestServer() (*WebsocketManager, *httptest.Server) {
+	wm := NewWebsocketManager()
+	wm.SetWsConnOptions(OnConnect(wm), OnDisconnect(func(remoteAddr, pubKey string) {
+		wm.clientDisconnected(pubKey)
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", wm.WebsocketHandler)
+	return wm, httptest.NewServer(mux)
+}
+
+func isAlive(wm *WebsocketManager, pubKey string) bool {
+	wm.scRWLock.RLock()
+	defer wm.scRWLock.RUnlock()
+	_, ok := wm.aliveNodes[pubKey]
+	return ok
+}
+
+func TestAuthenticate(t *testing.T) {
+	wm, ts := newTestServer()
+	defer ts.Close()
+
+	member := newTestClient(t, ts.URL)
+	stranger := newTestClient(t, ts.URL)
+	var lock sync.Mutex
+	members := []string{strings.ToUpper(member.PubKey)}
+	wm.SetMemberSource(func() ([]string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return members, nil
+	}, time.Hour)
+	defer wm.Stop()
+
+	require.NoError(t, member.Start())
+	defer member.Stop()
+	require.Eventually(t, func() bool {
+		return isAlive(wm, member.PubKey)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	err := stranger.Start()
+	require.Error(t, err)
+	require.ErrorContains(t, err, errNotMember.Error())
+
+	// the session of a removed member is closed
+	lock.Lock()
+	members = []string{stranger.PubKey}
+	lock.Unlock()
+	require.NoError(t, wm.refreshMembers())
+	require.Eventually(t, func() bool {
+		wm.scRWLock.RLock()
+		defer wm.scRWLock.RUnlock()
+		return len(wm.aliveNodes) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMemberSourceStop(t *testing.T) {
+	wm := NewWebsocketManager()
+	var lock sync.Mutex
+	calls := make(map[string]int)
+	source := func(name string) MemberSource {
+		return func() ([]string, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			calls[name]++
+			return []string{"member"}, nil
+		}
+	}
+	count := func(name string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return calls[name]
+	}
+
+	wm.SetMemberSource(source("first"), 5*time.Millisecond)
+	require.Eventually(t, func() bool { return count("first") > 0 }, 5*time.Second, 5*time.Millisecond)
+
+	// a new source replaces the reload of the former one
+	wm.SetMemberSource(source("second"), 5*time.Millisecond)
+	require.Eventually(t, func() bool { return count("second") > 0 }, 5*time.Second, 5*time.Millisecond)
+	first := count("first")
+
+	wm.Stop()
+	time.Sleep(20 * time.Millisecond)
+	second := count("second")
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, first, count("first"))
+	require.Equal(t, second, count("second"))
+	wm.Stop()
+}
+
+func TestFailover(t *testing.T) {
+	// the standby manager does not listen
+	standby := httptest.NewServer(http.NotFoundHandler())
+	standby.Close()
+	wm, leader := newTestServer()
+	defer leader.Close()
+
+	client := newTestClient(t, standby.URL, leader.URL)
+	require.NoError(t, client.Start())
+	defer client.Stop()
+	require.Eventually(t, func() bool {
+		return isAlive(wm, client.PubKey)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -2,18 +2,16 @@ package server
 
 import (
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"runtime/debug"
-	"strconv"
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gorilla/websocket"
+	tss "github.com/mantlenetworkio/mantle/tss/common"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/libs/service"
 	"github.com/tendermint/tendermint/rpc/jsonrpc/types"
@@ -40,7 +38,13 @@ type WebsocketManager struct {
 
 	sendChan   map[string]chan types.RPCRequest // node -> send channel
 	aliveNodes map[string]struct{}              // node -> struct{}{}
+	conns      map[string]*wsConnection         // node -> connection
 	scRWLock   *sync.RWMutex
+
+	memberSource MemberSource
+	members      map[string]struct{} // lower case public keys, nil before loaded
+	memberStop   chan struct{}       // closed to stop reloading the members
+	memberLock   *sync.Mutex
 }
 
 // NewWebsocketManager returns a new WebsocketManager that passes a map of
@@ -71,7 +75,10 @@ func NewWebsocketManager(
 
 		sendChan:   make(map[string]chan types.RPCRequest),
 		aliveNodes: make(map[string]struct{}),
+		conns:      make(map[string]*wsConnection),
 		scRWLock:   &sync.RWMutex{},
+
+		memberLock: &sync.Mutex{},
 	}
 }
 
@@ -124,14 +131,17 @@ func (wm *WebsocketManager) unregisterRecvChan(requestId string) {
 	delete(wm.recvChanMap, requestId)
 }
 
-func (wm *WebsocketManager) clientConnected(pubkey string, channel chan types.RPCRequest) {
+func (wm *WebsocketManager) clientConnected(wsc *wsConnection) {
 	wm.scRWLock.Lock()
 	defer wm.scRWLock.Unlock()
-	wm.sendChan[pubkey] = channel
+	pubkey := wsc.nodePublicKey
+	wm.sendChan[pubkey] = wsc.requestChan
 	if wm.aliveNodes == nil {
 		wm.aliveNodes = make(map[string]struct{})
 	}
 	wm.aliveNodes[pubkey] = struct{}{}
+	wm.conns[pubkey] = wsc
+	connectedNodesGauge.Set(float64(len(wm.aliveNodes)))
 	wm.logger.Info("new node connected", "public key", pubkey)
 }
 
@@ -141,6 +151,8 @@ func (wm *WebsocketManager) clientDisconnected(pubkey string) {
 
 	delete(wm.aliveNodes, pubkey)
 	delete(wm.sendChan, pubkey)
+	delete(wm.conns, pubkey)
+	connectedNodesGauge.Set(float64(len(wm.aliveNodes)))
 	wm.logger.Info("node disconnected", "public key", pubkey)
 }
 
@@ -159,30 +171,15 @@ func (wm *WebsocketManager) WebsocketHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}()
 
-	pubKey := r.Header.Get("pubKey")
-	timeStr := r.Header.Get("time")
-	sig := r.Header.Get("sig")
-
-	if len(pubKey) == 0 || len(timeStr) == 0 || len(sig) == 0 {
-		wm.logger.Error("Failed to establish connection", "err", errors.New("invalid header"))
-		return
-	}
-
-	timeInt64, err := strconv.ParseInt(timeStr, 10, 64)
-	if err != nil || time.Now().Unix()-timeInt64 > 5 {
-		wm.logger.Error("illegal timestamp", "err", err)
-		return
-	}
-
-	pubKeyBytes, pubErr := hex.DecodeString(pubKey)
-	sigBytes, sigErr := hex.DecodeString(sig)
-	if pubErr != nil || sigErr != nil {
-		wm.logger.Error("hex decode error for pubkey or sig", "err", err)
-		return
-	}
-	digestBz := crypto.Keccak256Hash([]byte(timeStr)).Bytes()
-	if !crypto.VerifySignature(pubKeyBytes, digestBz, sigBytes[:64]) {
-		wm.logger.Error("illegal signature", "publicKey", pubKey, "time", timeStr, "signature", sig)
+	pubKey, err := wm.authenticate(wsConn)
+	authCounter.WithLabelValues(authResult(err)).Inc()
+	if err != nil {
+		wm.logger.Error("Failed to authenticate node", "remote", wsConn.RemoteAddr(), "err", err)
+		if err == errBadSignature || err == errNotMember {
+			if err := wsConn.WriteJSON(tss.WsAuthResult{Reason: err.Error()}); err != nil {
+				wm.logger.Error("Failed to send auth result", "err", err)
+			}
+		}
 		return
 	}
 
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	authCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "Tss",
+			Subsystem: "Manager",
+			Name:      "ws_auth",
+			Help:      "The websocket authentications of the tss nodes",
+		},
+		[]string{"result"},
+	)
+	sessionRejectedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "Tss",
+			Subsystem: "Manager",
+			Name:      "ws_session_rejected",
+			Help:      "The websocket sessions closed as the node is no longer a tss member",
+		},
+	)
+	connectedNodesGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "Tss",
+			Subsystem: "Manager",
+			Name:      "ws_connected_nodes",
+			Help:      "The number of the connected tss nodes",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(authCounter)
+	prometheus.MustRegister(sessionRejectedCounter)
+	prometheus.MustRegister(connectedNodesGauge)
+}
+
+func authResult(err error) string {
+	switch err {
+	case nil:
+		return "success"
+	case errBadSignature:
+		return "bad_signature"
+	case errNotMember:
+		return "not_member"
+	default:
+		return "failed"
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	tmtypes "github.com/tendermint/tendermint/rpc/jsonrpc/types"
 	"net"
@@ -23,7 +24,9 @@ type WSServer struct {
 	WM       *WebsocketManager
 }
 
-func NewWSServer(localAddr string) (*WebsocketManager, error) {
+// NewWSServer serves the websocket of the nodes on localAddr, with mutual TLS
+// when tlsConfig is not nil.
+func NewWSServer(localAddr string, tlsConfig *tls.Config) (*WebsocketManager, error) {
 	wsServer := &WSServer{}
 	var err error
 
@@ -50,6 +53,9 @@ func NewWSServer(localAddr string) (*WebsocketManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	if tlsConfig != nil {
+		wsServer.Listener = tls.NewListener(wsServer.Listener, tlsConfig)
+	}
 
 	go func() {
 		if err := rpcserver.Serve(
@@ -67,7 +73,7 @@ func NewWSServer(localAddr string) (*WebsocketManager, error) {
 
 func OnConnect(wm *WebsocketManager) func(wsc *wsConnection) {
 	return func(wsc *wsConnection) {
-		wm.clientConnected(wsc.nodePublicKey, wsc.requestChan)
+		wm.clientConnected(wsc)
 		go func() {
 			for {
 				select {